@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/streamer"
+	"github.com/spf13/cobra"
+)
+
+// Print the differences as a JSON object.
+var diffJSONFlag bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-path> <new-path>",
+	Short: "Compare the manifests of two publications",
+	Long: `Compare the manifests of two publications.
+
+This command will parse two publication files (such as EPUB, PDF, audiobook,
+etc.) or Readium Web Publication Manifests, and report the semantic changes
+between them: metadata fields, and links added, removed, modified or moved in
+the reading order, resources, links and table of contents.
+
+Examples:
+  Print out the changes between two revisions of an EPUB.
+  $ rwp diff publication-v1.epub publication-v2.epub
+
+  List the HREFs of the resources added in a new revision with ` + "`jq`" + `.
+  $ rwp diff --json old.epub new.epub | jq -r '.links[] | select(.kind == "added") | .href'
+  `,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("expects the paths to the two publications to compare")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		s := streamer.New(streamer.Config{})
+		oldManifest, err := openManifest(s, filepath.Clean(args[0]))
+		if err != nil {
+			return err
+		}
+		newManifest, err := openManifest(s, filepath.Clean(args[1]))
+		if err != nil {
+			return err
+		}
+
+		diff := manifest.Diff(*oldManifest, *newManifest)
+
+		if diffJSONFlag {
+			jsonBytes, err := json.Marshal(diff)
+			if err != nil {
+				return fmt.Errorf("failed rendering JSON: %w", err)
+			}
+			fmt.Println(string(jsonBytes))
+			return nil
+		}
+
+		for _, change := range diff.Metadata {
+			fmt.Println(formatMetadataChange(change))
+		}
+		for _, change := range diff.Links {
+			fmt.Println(formatLinkChange(change))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffJSONFlag, "json", false, "Print the changes as a JSON object")
+}
+
+// Opens the manifest of the publication at [path], which can also be a standalone RWPM.
+func openManifest(s streamer.Streamer, path string) (*manifest.Manifest, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		bin, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading %s: %w", path, err)
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(bin, &raw); err != nil {
+			return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+		}
+		m, err := manifest.ManifestFromJSON(raw, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+		}
+		return m, nil
+	}
+
+	pub, err := s.Open(asset.File(path), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed opening %s: %w", path, err)
+	}
+	defer pub.Close()
+	return &pub.Manifest, nil
+}
+
+var changeSymbols = map[manifest.ChangeKind]string{
+	manifest.ChangeAdded:    "+",
+	manifest.ChangeRemoved:  "-",
+	manifest.ChangeModified: "~",
+	manifest.ChangeMoved:    ">",
+}
+
+func formatMetadataChange(c manifest.MetadataChange) string {
+	field := "metadata." + c.Field
+	if c.Collection != "" {
+		field = c.Collection + "." + field
+	}
+	if c.Language != "" {
+		field += "[" + c.Language + "]"
+	}
+	switch c.Kind {
+	case manifest.ChangeAdded:
+		return fmt.Sprintf("%s %s: %s", changeSymbols[c.Kind], field, formatJSONValue(c.New))
+	case manifest.ChangeRemoved:
+		return fmt.Sprintf("%s %s: %s", changeSymbols[c.Kind], field, formatJSONValue(c.Old))
+	default:
+		return fmt.Sprintf("%s %s: %s -> %s", changeSymbols[c.Kind], field, formatJSONValue(c.Old), formatJSONValue(c.New))
+	}
+}
+
+func formatLinkChange(c manifest.LinkChange) string {
+	line := fmt.Sprintf("%s %s %s", changeSymbols[c.Kind], c.Collection, c.Href)
+	switch c.Kind {
+	case manifest.ChangeModified:
+		line += " (" + strings.Join(c.Fields, ", ") + ")"
+	case manifest.ChangeMoved:
+		if c.OldParent != c.NewParent {
+			line += fmt.Sprintf(" (from %q to %q)", c.OldParent, c.NewParent)
+		}
+	}
+	return line
+}
+
+func formatJSONValue(v interface{}) string {
+	var sb strings.Builder
+	encoder := json.NewEncoder(&sb)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/readium/go-toolkit/pkg/asset"
 	"github.com/readium/go-toolkit/pkg/streamer"
@@ -20,6 +22,12 @@ var inferA11yFlag InferA11yMetadata
 // Infer the number of pages from the generated position list.
 var inferPageCountFlag bool
 
+// Walk a directory and generate a manifest for every publication found.
+var recursiveFlag bool
+
+// Number of publications opened concurrently in recursive mode.
+var workersFlag int
+
 var manifestCmd = &cobra.Command{
 	Use:   "manifest <pub-path>",
 	Short: "Generate a Readium Web Publication Manifest for a publication",
@@ -29,6 +37,13 @@ This command will parse a publication file (such as EPUB, PDF, audiobook, etc.)
 and build a Readium Web Publication Manifest for it. The JSON manifest is
 printed to stdout.
 
+With --recursive, the given directory is walked and every publication found is
+opened concurrently. Directories holding an exploded publication, such as an
+unzipped EPUB with a "mimetype" or "META-INF/container.xml" entry, are opened as
+a single publication. One JSON object is printed per line, holding the "path" of
+the publication and either its "manifest" or an "error". A summary of the
+failures, grouped by cause, is printed to stderr.
+
 Examples:
   Print out a compact JSON RWPM. 
   $ rwp manifest publication.epub
@@ -38,6 +53,9 @@ Examples:
 
   Extract the publication title with ` + "`jq`" + `.
   $ rwp manifest publication.epub | jq -r .metadata.title
+
+  Generate one JSON line per publication found in a directory, using 8 workers.
+  $ rwp manifest --recursive --workers 8 library/
   `,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
@@ -54,15 +72,23 @@ Examples:
 		cmd.SilenceUsage = true
 
 		path := filepath.Clean(args[0])
-		pub, err := streamer.New(streamer.Config{
+		s := streamer.New(streamer.Config{
 			InferA11yMetadata: streamer.InferA11yMetadata(inferA11yFlag),
 			InferPageCount:    inferPageCountFlag,
-		}).Open(
-			asset.File(path), "",
-		)
+		})
+
+		if recursiveFlag {
+			if indentFlag != "" {
+				return errors.New("--indent cannot be used with --recursive, which outputs JSON lines")
+			}
+			return runManifestBatch(s, path, workersFlag, os.Stdout, os.Stderr)
+		}
+
+		pub, err := s.Open(asset.File(path), "")
 		if err != nil {
 			return fmt.Errorf("failed opening %s: %w", path, err)
 		}
+		defer pub.Close()
 
 		var jsonBytes []byte
 		if indentFlag == "" {
@@ -84,6 +110,8 @@ func init() {
 	manifestCmd.Flags().StringVarP(&indentFlag, "indent", "i", "", "Indentation used to pretty-print")
 	manifestCmd.Flags().Var(&inferA11yFlag, "infer-a11y", "Infer accessibility metadata: no, merged, split")
 	manifestCmd.Flags().BoolVar(&inferPageCountFlag, "infer-page-count", false, "Infer the number of pages from the generated position list.")
+	manifestCmd.Flags().BoolVarP(&recursiveFlag, "recursive", "r", false, "Walk the given directory and print one JSON line per publication found")
+	manifestCmd.Flags().IntVarP(&workersFlag, "workers", "w", runtime.NumCPU(), "Number of publications opened concurrently with --recursive")
 }
 
 type InferA11yMetadata streamer.InferA11yMetadata
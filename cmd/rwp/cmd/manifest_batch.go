@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/streamer"
+)
+
+// A single line of output of `rwp manifest --recursive`.
+type batchResult struct {
+	Path     string             `json:"path"`               // Path of the publication file, as found while walking the directory.
+	Manifest *manifest.Manifest `json:"manifest,omitempty"` // Generated manifest, when the publication was opened successfully.
+	Error    *batchError        `json:"error,omitempty"`    // Reason why the publication couldn't be opened.
+
+	skipped bool // The file is not a publication and shouldn't be reported.
+}
+
+// Structured error reported in-line for a publication which failed to open.
+type batchError struct {
+	Cause   string `json:"cause"`   // Innermost error, used to group failures in the summary.
+	Message string `json:"message"` // Full error message.
+}
+
+func newBatchError(err error) *batchError {
+	cause := err
+	for {
+		inner := errors.Unwrap(cause)
+		if inner == nil {
+			break
+		}
+		cause = inner
+	}
+	return &batchError{
+		Cause:   cause.Error(),
+		Message: err.Error(),
+	}
+}
+
+// Entries marking a directory as an exploded publication, such as an unzipped EPUB, which is opened as a whole
+// instead of being walked.
+var publicationDirectoryMarkers = []string{"mimetype", filepath.Join("META-INF", "container.xml")}
+
+func isPublicationDirectory(path string) bool {
+	for _, marker := range publicationDirectoryMarkers {
+		if stat, err := os.Stat(filepath.Join(path, marker)); err == nil && stat.Mode().IsRegular() {
+			return true
+		}
+	}
+	return false
+}
+
+// Walks [root] and generates a manifest for every publication file or directory found, using a pool of [workers].
+// Results are printed to [stdout] as JSON lines, while a summary of the failures is printed to [stderr].
+func runManifestBatch(s streamer.Streamer, root string, workers int, stdout, stderr io.Writer) error {
+	stat, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string)
+	results := make(chan batchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				results <- openForBatch(s, path)
+			}
+		}()
+	}
+
+	var walkErr error
+	go func() {
+		walkErr = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				results <- batchResult{Path: path, Error: newBatchError(err)}
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if isPublicationDirectory(path) {
+					paths <- path
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() {
+				paths <- path
+			}
+			return nil
+		})
+		close(paths)
+		wg.Wait()
+		close(results)
+	}()
+
+	total := 0
+	failures := map[string]int{}
+	encoder := json.NewEncoder(stdout)
+	for result := range results {
+		if result.skipped {
+			continue
+		}
+		total++
+		err := encoder.Encode(result)
+		if result.Error != nil {
+			failures[result.Error.Cause]++
+		} else if err != nil {
+			failures[err.Error()]++
+		}
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+
+	return printBatchSummary(stderr, total, failures)
+}
+
+// Opens the publication at [path] and renders its manifest, making sure it is closed right away to keep the
+// number of opened file handles bounded by the number of workers.
+func openForBatch(s streamer.Streamer, path string) batchResult {
+	a := asset.File(path)
+	if !a.MediaType().IsPublication() {
+		return batchResult{Path: path, skipped: true}
+	}
+
+	pub, err := s.Open(a, "")
+	if err != nil {
+		return batchResult{Path: path, Error: newBatchError(err)}
+	}
+	defer pub.Close()
+
+	return batchResult{Path: path, Manifest: &pub.Manifest}
+}
+
+func printBatchSummary(w io.Writer, total int, failures map[string]int) error {
+	failed := 0
+	causes := make([]string, 0, len(failures))
+	for cause, count := range failures {
+		failed += count
+		causes = append(causes, cause)
+	}
+	// Most frequent causes first.
+	sort.Slice(causes, func(i, j int) bool {
+		if failures[causes[i]] == failures[causes[j]] {
+			return causes[i] < causes[j]
+		}
+		return failures[causes[i]] > failures[causes[j]]
+	})
+
+	fmt.Fprintf(w, "%d publications processed, %d succeeded, %d failed\n", total, total-failed, failed)
+	for _, cause := range causes {
+		fmt.Fprintf(w, "%8d  %s\n", failures[cause], cause)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed opening %d of %d publications", failed, total)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/streamer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestBatch(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := runManifestBatch(streamer.New(streamer.Config{}), "./testdata/library", 2, &stdout, &stderr)
+	assert.EqualError(t, err, "failed opening 1 of 2 publications")
+
+	var results []batchResult
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var result batchResult
+		if assert.NoError(t, json.Unmarshal([]byte(line), &result)) {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	if !assert.Len(t, results, 2) {
+		return
+	}
+
+	// The exploded EPUB is opened as a whole, and its content isn't walked.
+	broken, book := results[0], results[1]
+	assert.Equal(t, filepath.Join("testdata", "library", "broken.epub"), broken.Path)
+	assert.Nil(t, broken.Manifest)
+	if assert.NotNil(t, broken.Error) {
+		assert.Equal(t, "resource: error 404: file does not exist", broken.Error.Cause)
+	}
+	assert.Equal(t, filepath.Join("testdata", "library", "shelf", "book"), book.Path)
+	assert.Nil(t, book.Error)
+	if assert.NotNil(t, book.Manifest) {
+		assert.Equal(t, "Multiple Renditions (reflowable)", book.Manifest.Metadata.Title())
+	}
+
+	assert.Equal(t, "2 publications processed, 1 succeeded, 1 failed\n"+
+		"       1  resource: error 404: file does not exist\n", stderr.String())
+}
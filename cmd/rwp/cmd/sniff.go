@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/spf13/cobra"
+)
+
+// Print the sniffing trace as a JSON object.
+var sniffJSONFlag bool
+
+// Media type hint given to the sniffers.
+var sniffMediaTypeFlag string
+
+var sniffCmd = &cobra.Command{
+	Use:   "sniff <file>",
+	Short: "Detect the media type of a file and explain how it was detected",
+	Long: `Detect the media type of a file and explain how it was detected.
+
+This command runs the media type sniffers used to open publications on the
+given file, and prints every sniffer which ran, what it examined (file
+extension, declared media type, archive entries, JSON keys, etc.) and whether
+it accepted or rejected the file.
+
+Examples:
+  Explain why a ZIP archive is detected as a CBZ instead of an EPUB.
+  $ rwp sniff publication.zip
+
+  Give a media type hint, as declared by an HTTP server for example.
+  $ rwp sniff --media-type application/epub+zip download.bin
+  `,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("expects a path to the file to sniff")
+		} else if len(args) > 1 {
+			return errors.New("accepts a single path to a file")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path := filepath.Clean(args[0])
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		var mediaTypes []string
+		if sniffMediaTypeFlag != "" {
+			mediaTypes = []string{sniffMediaTypeFlag}
+		}
+		mt, trace := mediatype.OfFileWithTrace(file, mediaTypes, nil, mediatype.Sniffers)
+
+		if sniffJSONFlag {
+			jsonBytes, err := json.Marshal(map[string]interface{}{
+				"mediaType": mt,
+				"trace":     trace,
+			})
+			if err != nil {
+				return fmt.Errorf("failed rendering JSON for %s: %w", path, err)
+			}
+			fmt.Println(string(jsonBytes))
+			return nil
+		}
+
+		round := ""
+		for _, step := range trace.Steps {
+			if step.Round != round {
+				round = step.Round
+				fmt.Printf("%s sniffing:\n", round)
+			}
+			name := step.Sniffer
+			if name == "" {
+				name = "(no sniffer)"
+			}
+			if step.Result != nil {
+				fmt.Printf("  %s: accepted as %s\n", name, step.Result.String())
+			} else {
+				fmt.Printf("  %s: rejected\n", name)
+			}
+			for _, check := range step.Checks {
+				mark := "no "
+				if check.Matched {
+					mark = "yes"
+				}
+				fmt.Printf("    [%s] %s\n", mark, check.Check)
+			}
+		}
+
+		if mt == nil {
+			fmt.Println("Media type: unknown")
+		} else {
+			fmt.Printf("Media type: %s\n", mt.String())
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sniffCmd)
+	sniffCmd.Flags().BoolVar(&sniffJSONFlag, "json", false, "Print the media type and sniffing trace as a JSON object")
+	sniffCmd.Flags().StringVarP(&sniffMediaTypeFlag, "media-type", "m", "", "Media type hint, e.g. as declared by a server")
+}
@@ -6,6 +6,7 @@ require (
 	github.com/agext/regexp v1.3.0
 	github.com/deckarep/golang-set v1.7.1
 	github.com/gorilla/mux v1.7.4
+	github.com/nwaples/rardecode v1.1.3
 	github.com/opds-community/libopds2-go v0.0.0-20170628075933-9c163cf60f6e
 	github.com/pdfcpu/pdfcpu v0.3.13
 	github.com/pkg/errors v0.9.1
@@ -16,7 +17,7 @@ require (
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.8.1
 	github.com/stretchr/testify v1.7.0
-	github.com/trimmer-io/go-xmp v1.0.0
+	github.com/ulikunitz/xz v0.5.12
 	github.com/urfave/negroni v1.0.0
 	golang.org/x/net v0.7.0
 	golang.org/x/text v0.7.0
@@ -1,6 +1,7 @@
 package archive
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"os"
@@ -12,8 +13,11 @@ type ArchiveFactory interface {
 	OpenReader(reader ReaderAtCloser, size int64, password string, minimizeReads bool) (Archive, error) // Opens an archive from a reader.
 }
 
+// Opens ZIP, RAR and 7z archives, detected from their signature, as well as directories.
 type DefaultArchiveFactory struct {
 	gozipFactory    gozipArchiveFactory
+	rarFactory      rarArchiveFactory
+	sevenZipFactory sevenZipArchiveFactory
 	explodedFactory explodedArchiveFactory
 }
 
@@ -25,7 +29,22 @@ func (e DefaultArchiveFactory) Open(filepath string, password string) (Archive,
 	}
 	if st.IsDir() {
 		return e.explodedFactory.Open(filepath, password)
-	} else {
+	}
+
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, signatureLength)
+	n, _ := f.ReadAt(signature, 0)
+	f.Close()
+
+	switch {
+	case isRARSignature(signature[:n]):
+		return e.rarFactory.Open(filepath, password)
+	case is7zSignature(signature[:n]):
+		return e.sevenZipFactory.Open(filepath, password)
+	default:
 		return e.gozipFactory.Open(filepath, password)
 	}
 }
@@ -35,15 +54,46 @@ func (e DefaultArchiveFactory) OpenBytes(data []byte, password string) (Archive,
 	if data == nil {
 		return nil, errors.New("archive is nil")
 	}
-	return e.gozipFactory.OpenBytes(data, password)
+	switch {
+	case isRARSignature(data):
+		return e.rarFactory.OpenBytes(data, password)
+	case is7zSignature(data):
+		return e.sevenZipFactory.OpenBytes(data, password)
+	default:
+		return e.gozipFactory.OpenBytes(data, password)
+	}
 }
 
-// OpenBytes implements ArchiveFactory
+// OpenReader implements ArchiveFactory
 func (e DefaultArchiveFactory) OpenReader(reader ReaderAtCloser, size int64, password string, minimizeReads bool) (Archive, error) {
 	if reader == nil {
 		return nil, errors.New("archive is nil")
 	}
-	return e.gozipFactory.OpenReader(reader, size, password, minimizeReads)
+	signature := make([]byte, signatureLength)
+	n, _ := reader.ReadAt(signature, 0)
+	switch {
+	case isRARSignature(signature[:n]):
+		return e.rarFactory.OpenReader(reader, size, password, minimizeReads)
+	case is7zSignature(signature[:n]):
+		return e.sevenZipFactory.OpenReader(reader, size, password, minimizeReads)
+	default:
+		return e.gozipFactory.OpenReader(reader, size, password, minimizeReads)
+	}
+}
+
+// Number of bytes needed to recognize the signature of the supported archive formats.
+const signatureLength = 8
+
+// RAR 1.5 and RAR 5 archives start with "Rar!\x1A\x07".
+// Reference: https://www.rarlab.com/technote.htm
+func isRARSignature(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("Rar!\x1a\x07"))
+}
+
+// 7z archives start with "7z\xBC\xAF\x27\x1C".
+// Reference: https://py7zr.readthedocs.io/en/latest/archive_format.html
+func is7zSignature(data []byte) bool {
+	return bytes.HasPrefix(data, sevenZipSignature)
 }
 
 func NewArchiveFactory() DefaultArchiveFactory {
@@ -66,3 +116,60 @@ type Archive interface {
 	Entry(path string) (Entry, error) // Gets the entry at the given `path`.
 	Close()
 }
+
+// Reads the [start, end] range of an entry of the given [length] from [r], positioned at the beginning of the entry.
+// The whole entry is read when both [start] and [end] are 0, and the range is clamped to the entry's length.
+// Used by the archive formats which can only be read sequentially.
+func readEntryRange(r io.Reader, length int64, start int64, end int64) ([]byte, error) {
+	if end < start {
+		return nil, errors.New("range not satisfiable")
+	}
+	if start < 0 {
+		start = 0
+	}
+	if (start == 0 && end == 0) || end >= length {
+		end = length - 1
+	}
+	if start > end {
+		return []byte{}, nil
+	}
+	if start > 0 {
+		if _, err := io.CopyN(io.Discard, r, start); err != nil {
+			return nil, err
+		}
+	}
+	// The length is declared by the archive, so the buffer grows with the content actually read instead of being
+	// allocated up front.
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, end-start+1); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// Streams the [start, end] range of an entry from [r], positioned at the beginning of the entry, to [w].
+// The whole entry is streamed when both [start] and [end] are 0.
+func streamEntryRange(w io.Writer, r io.Reader, length int64, start int64, end int64) (int64, error) {
+	if end < start {
+		return -1, errors.New("range not satisfiable")
+	}
+	if start < 0 {
+		start = 0
+	}
+	if (start == 0 && end == 0) || end >= length {
+		end = length - 1
+	}
+	if start > end {
+		return 0, nil
+	}
+	if start > 0 {
+		if n, err := io.CopyN(io.Discard, r, start); err != nil {
+			return n, err
+		}
+	}
+	n, err := io.CopyN(w, r, end-start+1)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+	return n, nil
+}
@@ -0,0 +1,840 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// The 7z archive reader only supports the codecs used in practice for comic books and EPUBs: copy, LZMA, LZMA2, Deflate
+// and BZip2, with a single coder per folder. Filters (BCJ, delta, etc.) and encryption are not supported, and reported
+// with [ErrSevenZipUnsupportedCoder]. The content of the entries is verified against their CRC.
+// Reference: https://py7zr.readthedocs.io/en/latest/archive_format.html
+
+var sevenZipSignature = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+
+// Length of the signature header, which is followed by the packed streams.
+const sevenZipSignatureHeaderLength = 32
+
+// Property IDs of the 7z headers.
+const (
+	sevenZipEnd                   = 0x00
+	sevenZipHeader                = 0x01
+	sevenZipArchiveProperties     = 0x02
+	sevenZipAdditionalStreamsInfo = 0x03
+	sevenZipMainStreamsInfo       = 0x04
+	sevenZipFilesInfo             = 0x05
+	sevenZipPackInfo              = 0x06
+	sevenZipUnpackInfo            = 0x07
+	sevenZipSubStreamsInfo        = 0x08
+	sevenZipSize                  = 0x09
+	sevenZipCRC                   = 0x0A
+	sevenZipFolderID              = 0x0B
+	sevenZipCodersUnpackSize      = 0x0C
+	sevenZipNumUnpackStream       = 0x0D
+	sevenZipEmptyStream           = 0x0E
+	sevenZipEmptyFile             = 0x0F
+	sevenZipName                  = 0x11
+	sevenZipEncodedHeader         = 0x17
+)
+
+// Codec IDs of the supported 7z coders.
+var (
+	sevenZipCopy    = []byte{0x00}
+	sevenZipLZMA    = []byte{0x03, 0x01, 0x01}
+	sevenZipLZMA2   = []byte{0x21}
+	sevenZipDeflate = []byte{0x04, 0x01, 0x08}
+	sevenZipBZip2   = []byte{0x04, 0x02, 0x02}
+	sevenZipAES     = []byte{0x06, 0xF1, 0x07, 0x01}
+)
+
+var errSevenZipCorrupt = errors.New("7z archive is corrupt")
+
+// ErrSevenZipUnsupportedCoder is returned for 7z archives compressed with a codec or filter which is not supported.
+var ErrSevenZipUnsupportedCoder = errors.New("7z coder not supported")
+
+type sevenZipCoder struct {
+	id         []byte
+	properties []byte
+}
+
+// A folder is a solid block of files compressed together.
+type sevenZipFolder struct {
+	coder      sevenZipCoder
+	packOffset int64 // Absolute offset of the packed stream in the archive.
+	packSize   int64
+	unpackSize int64
+}
+
+type sevenZipArchiveEntry struct {
+	archive *sevenZipArchive
+	path    string
+	folder  int   // Index of the folder holding the content, or -1 for an empty file.
+	offset  int64 // Offset of the content in the unpacked folder.
+	size    int64
+	crc     *uint32 // Checksum of the content, when defined.
+}
+
+func (e sevenZipArchiveEntry) Path() string {
+	return e.path
+}
+
+func (e sevenZipArchiveEntry) Length() uint64 {
+	return uint64(e.size)
+}
+
+func (e sevenZipArchiveEntry) CompressedLength() uint64 {
+	if e.folder < 0 || bytes.Equal(e.archive.folders[e.folder].coder.id, sevenZipCopy) {
+		return 0
+	}
+	// Files in a solid block don't have their own compressed length, so this is a rough estimate.
+	folder := e.archive.folders[e.folder]
+	if folder.unpackSize == 0 {
+		return 0
+	}
+	return uint64(float64(e.size) * float64(folder.packSize) / float64(folder.unpackSize))
+}
+
+func (e sevenZipArchiveEntry) Read(start int64, end int64) ([]byte, error) {
+	var data []byte
+	err := e.archive.withEntryReader(e, func(r io.Reader) (err error) {
+		data, err = readEntryRange(r, e.size, start, end)
+		return
+	})
+	return data, err
+}
+
+func (e sevenZipArchiveEntry) Stream(w io.Writer, start int64, end int64) (int64, error) {
+	var n int64
+	err := e.archive.withEntryReader(e, func(r io.Reader) (err error) {
+		n, err = streamEntryRange(w, r, e.size, start, end)
+		return
+	})
+	if err != nil && n == 0 {
+		n = -1
+	}
+	return n, err
+}
+
+// An archive from a 7z file, such as a CB7.
+// Like for RAR, the content of a folder can only be decompressed sequentially, so the archive keeps a reader
+// positioned after the last entry read.
+type sevenZipArchive struct {
+	reader  io.ReaderAt
+	closer  func() error
+	folders []sevenZipFolder
+	entries []sevenZipArchiveEntry
+
+	mu      sync.Mutex
+	current *countingReader // Decompressing reader of the current folder, nil until an entry is read.
+	folder  int             // Index of the current folder.
+}
+
+func (a *sevenZipArchive) Close() {
+	a.closer()
+}
+
+func (a *sevenZipArchive) Entries() []Entry {
+	entries := make([]Entry, 0, len(a.entries))
+	for _, e := range a.entries {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (a *sevenZipArchive) Entry(p string) (Entry, error) {
+	if !fs.ValidPath(p) {
+		return nil, fs.ErrNotExist
+	}
+	cpath := path.Clean(p)
+	for _, e := range a.entries {
+		if e.path == cpath {
+			return e, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// Calls [f] with a reader positioned at the beginning of the content of [entry].
+func (a *sevenZipArchive) withEntryReader(entry sevenZipArchiveEntry, f func(r io.Reader) error) error {
+	if entry.folder < 0 {
+		return f(bytes.NewReader(nil))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The folder can only be decompressed forward, so we need to start over when going back.
+	if a.current == nil || a.folder != entry.folder || a.current.n > entry.offset {
+		r, err := a.folders[entry.folder].reader(a.reader)
+		if err != nil {
+			return err
+		}
+		a.current = &countingReader{r: r}
+		a.folder = entry.folder
+	}
+	if _, err := io.CopyN(io.Discard, a.current, entry.offset-a.current.n); err != nil {
+		a.current = nil
+		return err
+	}
+	content := io.LimitReader(a.current, entry.size)
+	if entry.crc == nil {
+		err := f(content)
+		if err != nil {
+			a.current = nil
+		}
+		return err
+	}
+
+	// The checksum covers the whole entry, so the rest of the entry is read even for a partial read.
+	hash := crc32.NewIEEE()
+	err := f(io.TeeReader(content, hash))
+	if err == nil {
+		_, err = io.Copy(hash, content)
+	}
+	if err == nil && hash.Sum32() != *entry.crc {
+		err = fmt.Errorf("7z entry %q failed the CRC check: %w", entry.path, errSevenZipCorrupt)
+	}
+	if err != nil {
+		a.current = nil
+	}
+	return err
+}
+
+// Counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Returns a reader decompressing the content of the folder.
+func (f sevenZipFolder) reader(ra io.ReaderAt) (io.Reader, error) {
+	packed := bufio.NewReader(io.NewSectionReader(ra, f.packOffset, f.packSize))
+	switch {
+	case bytes.Equal(f.coder.id, sevenZipCopy):
+		return packed, nil
+
+	case bytes.Equal(f.coder.id, sevenZipLZMA):
+		if len(f.coder.properties) != 5 {
+			return nil, errSevenZipCorrupt
+		}
+		// The LZMA coder properties are the first 5 bytes of a classic LZMA header, followed by the uncompressed size.
+		header := make([]byte, 13)
+		copy(header, f.coder.properties)
+		binary.LittleEndian.PutUint64(header[5:], uint64(f.unpackSize))
+		return lzma.NewReader(io.MultiReader(bytes.NewReader(header), packed))
+
+	case bytes.Equal(f.coder.id, sevenZipLZMA2):
+		if len(f.coder.properties) != 1 || f.coder.properties[0] > 40 {
+			return nil, errSevenZipCorrupt
+		}
+		dictCap := lzma.MaxDictCap
+		if p := f.coder.properties[0]; p < 40 {
+			dictCap = (2 | int(p&1)) << (p/2 + 11)
+		}
+		r, err := lzma.Reader2Config{DictCap: dictCap}.NewReader2(packed)
+		if err != nil {
+			return nil, err
+		}
+		return io.LimitReader(r, f.unpackSize), nil
+
+	case bytes.Equal(f.coder.id, sevenZipDeflate):
+		return flate.NewReader(packed), nil
+
+	case bytes.Equal(f.coder.id, sevenZipBZip2):
+		return bzip2.NewReader(packed), nil
+
+	case bytes.Equal(f.coder.id, sevenZipAES):
+		return nil, fmt.Errorf("%w: password-protected archives not supported", ErrSevenZipUnsupportedCoder)
+
+	default:
+		return nil, fmt.Errorf("%w: codec %X", ErrSevenZipUnsupportedCoder, f.coder.id)
+	}
+}
+
+// Reads the content of the folder, checking its CRC when available.
+// The unpack size is declared by the header, so the buffer grows with the content actually decompressed instead of
+// being allocated up front.
+func (f sevenZipFolder) readAll(ra io.ReaderAt, crc *uint32) ([]byte, error) {
+	r, err := f.reader(ra)
+	if err != nil {
+		return nil, err
+	}
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, f.unpackSize); err != nil {
+		if err == io.EOF {
+			err = errSevenZipCorrupt
+		}
+		return nil, err
+	}
+	if crc != nil && crc32.ChecksumIEEE(data.Bytes()) != *crc {
+		return nil, errSevenZipCorrupt
+	}
+	return data.Bytes(), nil
+}
+
+// Cursor over a 7z header.
+type sevenZipHeaderReader struct {
+	data        []byte
+	archiveSize int64 // Length of the archive, bounding the sizes and offsets declared in the header.
+	err         error
+}
+
+func (h *sevenZipHeaderReader) byte() byte {
+	if h.err != nil {
+		return 0
+	}
+	if len(h.data) == 0 {
+		h.err = errSevenZipCorrupt
+		return 0
+	}
+	b := h.data[0]
+	h.data = h.data[1:]
+	return b
+}
+
+func (h *sevenZipHeaderReader) bytes(n uint64) []byte {
+	if h.err != nil {
+		return nil
+	}
+	if uint64(len(h.data)) < n {
+		h.err = errSevenZipCorrupt
+		return nil
+	}
+	b := h.data[:n]
+	h.data = h.data[n:]
+	return b
+}
+
+func (h *sevenZipHeaderReader) uint32() uint32 {
+	b := h.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// Reads a variable-length number: the count of leading 1 bits of the first byte is the count of extra bytes.
+func (h *sevenZipHeaderReader) number() uint64 {
+	first := h.byte()
+	mask := byte(0x80)
+	var value uint64
+	for i := 0; i < 8; i++ {
+		if first&mask == 0 {
+			return value | uint64(first&(mask-1))<<(8*i)
+		}
+		value |= uint64(h.byte()) << (8 * i)
+		mask >>= 1
+	}
+	return value
+}
+
+// Reads a number used as a count of items, guarding against allocating huge slices on corrupt headers.
+func (h *sevenZipHeaderReader) count() int {
+	n := h.number()
+	if n > uint64(len(h.data))*8+8 || n > uint64(h.archiveSize) {
+		h.err = errSevenZipCorrupt
+		return 0
+	}
+	return int(n)
+}
+
+// Reads the size or offset of a packed stream, which must fit in the archive.
+func (h *sevenZipHeaderReader) packSize() int64 {
+	n := h.number()
+	if n > uint64(h.archiveSize) {
+		h.err = errSevenZipCorrupt
+		return 0
+	}
+	return int64(n)
+}
+
+// Reads the size of unpacked content, which can exceed the length of the archive but not be negative.
+func (h *sevenZipHeaderReader) unpackSize() int64 {
+	n := h.number()
+	if n > math.MaxInt64 {
+		h.err = errSevenZipCorrupt
+		return 0
+	}
+	return int64(n)
+}
+
+func (h *sevenZipHeaderReader) bits(n int) []bool {
+	bits := make([]bool, n)
+	var b byte
+	for i := 0; i < n; i++ {
+		if i%8 == 0 {
+			b = h.byte()
+		}
+		bits[i] = b&(0x80>>(i%8)) != 0
+	}
+	return bits
+}
+
+// Reads a list of CRCs, nil when not defined.
+func (h *sevenZipHeaderReader) digests(n int) []*uint32 {
+	var defined []bool
+	if h.byte() != 0 {
+		defined = make([]bool, n)
+		for i := range defined {
+			defined[i] = true
+		}
+	} else {
+		defined = h.bits(n)
+	}
+	digests := make([]*uint32, n)
+	for i := range digests {
+		if defined[i] {
+			crc := h.uint32()
+			digests[i] = &crc
+		}
+	}
+	return digests
+}
+
+func (h *sevenZipHeaderReader) skipProperty() {
+	h.bytes(h.number())
+}
+
+type sevenZipStreamsInfo struct {
+	folders       []sevenZipFolder
+	folderCRCs    []*uint32
+	streamCounts  []int       // Count of files in each folder.
+	streamSizes   [][]int64   // Sizes of the files in each folder.
+	streamCRCs    [][]*uint32 // Checksums of the files in each folder.
+	hasSubStreams bool
+}
+
+func (h *sevenZipHeaderReader) streamsInfo() sevenZipStreamsInfo {
+	var info sevenZipStreamsInfo
+	var packPos int64
+	var packSizes []int64
+
+	for h.err == nil {
+		switch h.byte() {
+		case sevenZipEnd:
+			return info
+
+		case sevenZipPackInfo:
+			packPos = h.packSize()
+			packSizes = make([]int64, h.count())
+			for h.err == nil {
+				id := h.byte()
+				if id == sevenZipEnd {
+					break
+				}
+				switch id {
+				case sevenZipSize:
+					for i := range packSizes {
+						packSizes[i] = h.packSize()
+					}
+				case sevenZipCRC:
+					h.digests(len(packSizes))
+				default:
+					h.skipProperty()
+				}
+			}
+
+		case sevenZipUnpackInfo:
+			if h.byte() != sevenZipFolderID {
+				h.err = errSevenZipCorrupt
+				break
+			}
+			numFolders := h.count()
+			if h.byte() != 0 { // External
+				h.err = errors.New("7z external folders not supported")
+				break
+			}
+			info.folders = make([]sevenZipFolder, numFolders)
+			offset := sevenZipSignatureHeaderLength + packPos
+			packIndex := 0
+			for i := range info.folders {
+				info.folders[i].coder = h.folder()
+				if packIndex >= len(packSizes) {
+					h.err = errSevenZipCorrupt
+					break
+				}
+				if offset+packSizes[packIndex] > h.archiveSize {
+					h.err = errSevenZipCorrupt
+					break
+				}
+				info.folders[i].packOffset = offset
+				info.folders[i].packSize = packSizes[packIndex]
+				offset += packSizes[packIndex]
+				packIndex++
+			}
+			for h.err == nil {
+				id := h.byte()
+				if id == sevenZipEnd {
+					break
+				}
+				switch id {
+				case sevenZipCodersUnpackSize:
+					for i := range info.folders {
+						info.folders[i].unpackSize = h.unpackSize()
+					}
+				case sevenZipCRC:
+					info.folderCRCs = h.digests(numFolders)
+				default:
+					h.skipProperty()
+				}
+			}
+
+		case sevenZipSubStreamsInfo:
+			info.hasSubStreams = true
+			info.streamCounts = make([]int, len(info.folders))
+			for i := range info.streamCounts {
+				info.streamCounts[i] = 1
+			}
+			id := h.byte()
+			if id == sevenZipNumUnpackStream {
+				total := 0
+				for i := range info.streamCounts {
+					info.streamCounts[i] = h.count()
+					total += info.streamCounts[i]
+				}
+				if total > len(h.data)*8+8 {
+					h.err = errSevenZipCorrupt
+					break
+				}
+				id = h.byte()
+			}
+			info.streamSizes = make([][]int64, len(info.folders))
+			for i, folder := range info.folders {
+				sizes := make([]int64, info.streamCounts[i])
+				var sum int64
+				for j := 0; j < len(sizes)-1; j++ {
+					if id == sevenZipSize {
+						sizes[j] = h.unpackSize()
+					}
+					sum += sizes[j]
+					if sum < 0 || sum > folder.unpackSize {
+						h.err = errSevenZipCorrupt
+						break
+					}
+				}
+				if h.err != nil {
+					break
+				}
+				if len(sizes) > 0 {
+					sizes[len(sizes)-1] = folder.unpackSize - sum
+				}
+				info.streamSizes[i] = sizes
+			}
+			if id == sevenZipSize {
+				id = h.byte()
+			}
+			// A file alone in its folder has the checksum of the folder.
+			info.streamCRCs = make([][]*uint32, len(info.folders))
+			for i, count := range info.streamCounts {
+				info.streamCRCs[i] = make([]*uint32, count)
+				if count == 1 && i < len(info.folderCRCs) {
+					info.streamCRCs[i][0] = info.folderCRCs[i]
+				}
+			}
+			for h.err == nil && id != sevenZipEnd {
+				if id == sevenZipCRC {
+					// CRCs of the files, except the ones alone in a folder with a known CRC.
+					n := 0
+					for i, count := range info.streamCounts {
+						if count != 1 || i >= len(info.folderCRCs) || info.folderCRCs[i] == nil {
+							n += count
+						}
+					}
+					digests := h.digests(n)
+					for i, count := range info.streamCounts {
+						if count == 1 && i < len(info.folderCRCs) && info.folderCRCs[i] != nil {
+							continue
+						}
+						for j := 0; j < count && len(digests) > 0; j++ {
+							info.streamCRCs[i][j] = digests[0]
+							digests = digests[1:]
+						}
+					}
+				} else {
+					h.skipProperty()
+				}
+				id = h.byte()
+			}
+
+		default:
+			h.err = errSevenZipCorrupt
+		}
+	}
+	return info
+}
+
+// Reads a folder definition, only supporting folders made of a single coder.
+func (h *sevenZipHeaderReader) folder() sevenZipCoder {
+	var coder sevenZipCoder
+	numCoders := h.count()
+	if numCoders != 1 {
+		if h.err == nil {
+			h.err = fmt.Errorf("%w: folders with several coders (e.g. filters)", ErrSevenZipUnsupportedCoder)
+		}
+		return coder
+	}
+	flags := h.byte()
+	coder.id = h.bytes(uint64(flags & 0x0F))
+	if flags&0x10 != 0 { // Complex coder
+		if h.number() != 1 || h.number() != 1 {
+			h.err = fmt.Errorf("%w: complex coders", ErrSevenZipUnsupportedCoder)
+		}
+	}
+	if flags&0x20 != 0 { // Has properties
+		coder.properties = h.bytes(h.number())
+	}
+	return coder
+}
+
+type sevenZipFileInfo struct {
+	name        string
+	emptyStream bool
+	emptyFile   bool
+}
+
+func (h *sevenZipHeaderReader) filesInfo() []sevenZipFileInfo {
+	files := make([]sevenZipFileInfo, h.count())
+	var emptyStreams int
+	for h.err == nil {
+		id := h.byte()
+		if id == sevenZipEnd {
+			break
+		}
+		property := &sevenZipHeaderReader{data: h.bytes(h.number()), archiveSize: h.archiveSize}
+		switch id {
+		case sevenZipEmptyStream:
+			emptyStreams = 0
+			for i, empty := range property.bits(len(files)) {
+				files[i].emptyStream = empty
+				if empty {
+					emptyStreams++
+				}
+			}
+		case sevenZipEmptyFile:
+			empty := property.bits(emptyStreams)
+			j := 0
+			for i := range files {
+				if files[i].emptyStream {
+					files[i].emptyFile = empty[j]
+					j++
+				}
+			}
+		case sevenZipName:
+			if property.byte() != 0 { // External
+				property.err = errors.New("7z external file names not supported")
+				break
+			}
+			for i := range files {
+				var name []uint16
+				for property.err == nil {
+					c := uint16(property.byte()) | uint16(property.byte())<<8
+					if c == 0 {
+						break
+					}
+					name = append(name, c)
+				}
+				files[i].name = string(utf16.Decode(name))
+			}
+		}
+		if property.err != nil {
+			h.err = property.err
+		}
+	}
+	return files
+}
+
+func newSevenZipArchive(reader io.ReaderAt, size int64, closer func() error) (Archive, error) {
+	signature := make([]byte, 32)
+	if _, err := reader.ReadAt(signature, 0); err != nil {
+		return nil, err
+	}
+	if !is7zSignature(signature) {
+		return nil, errors.New("not a 7z archive")
+	}
+	if crc32.ChecksumIEEE(signature[12:32]) != binary.LittleEndian.Uint32(signature[8:12]) {
+		return nil, errSevenZipCorrupt
+	}
+	nextHeaderOffset := int64(binary.LittleEndian.Uint64(signature[12:20]))
+	nextHeaderSize := int64(binary.LittleEndian.Uint64(signature[20:28]))
+	if nextHeaderOffset < 0 || nextHeaderSize < 0 || 32+nextHeaderOffset+nextHeaderSize > size {
+		return nil, errSevenZipCorrupt
+	}
+	data := make([]byte, nextHeaderSize)
+	if _, err := reader.ReadAt(data, 32+nextHeaderOffset); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(signature[28:32]) {
+		return nil, errSevenZipCorrupt
+	}
+
+	a := &sevenZipArchive{
+		reader: reader,
+		closer: closer,
+	}
+	h := &sevenZipHeaderReader{data: data, archiveSize: size}
+	if len(data) == 0 { // Empty archive
+		return a, nil
+	}
+
+	// The header itself is usually compressed.
+	for h.err == nil && h.data[0] == sevenZipEncodedHeader {
+		h.byte()
+		info := h.streamsInfo()
+		if h.err != nil {
+			return nil, h.err
+		}
+		if len(info.folders) == 0 {
+			return nil, errSevenZipCorrupt
+		}
+		var crc *uint32
+		if len(info.folderCRCs) > 0 {
+			crc = info.folderCRCs[0]
+		}
+		decoded, err := info.folders[0].readAll(reader, crc)
+		if err != nil {
+			return nil, err
+		}
+		h = &sevenZipHeaderReader{data: decoded, archiveSize: size}
+		if len(decoded) == 0 {
+			return nil, errSevenZipCorrupt
+		}
+	}
+
+	if h.byte() != sevenZipHeader {
+		return nil, errSevenZipCorrupt
+	}
+	var info sevenZipStreamsInfo
+	var files []sevenZipFileInfo
+	for h.err == nil {
+		id := h.byte()
+		if id == sevenZipEnd {
+			break
+		}
+		switch id {
+		case sevenZipArchiveProperties:
+			for h.err == nil && h.byte() != sevenZipEnd {
+				h.skipProperty()
+			}
+		case sevenZipAdditionalStreamsInfo:
+			h.streamsInfo()
+		case sevenZipMainStreamsInfo:
+			info = h.streamsInfo()
+		case sevenZipFilesInfo:
+			files = h.filesInfo()
+		default:
+			h.err = errSevenZipCorrupt
+		}
+	}
+	if h.err != nil {
+		return nil, h.err
+	}
+	a.folders = info.folders
+
+	// Files with content are stored in order in the folders.
+	if !info.hasSubStreams {
+		info.streamCounts = make([]int, len(info.folders))
+		info.streamSizes = make([][]int64, len(info.folders))
+		info.streamCRCs = make([][]*uint32, len(info.folders))
+		for i, folder := range info.folders {
+			info.streamCounts[i] = 1
+			info.streamSizes[i] = []int64{folder.unpackSize}
+			info.streamCRCs[i] = []*uint32{nil}
+			if i < len(info.folderCRCs) {
+				info.streamCRCs[i][0] = info.folderCRCs[i]
+			}
+		}
+	}
+	folder, index := 0, 0
+	var offset int64
+	for _, file := range files {
+		if file.emptyStream {
+			if file.emptyFile {
+				a.entries = append(a.entries, sevenZipArchiveEntry{
+					archive: a,
+					path:    path.Clean(file.name),
+					folder:  -1,
+				})
+			}
+			continue // Directory
+		}
+		for folder < len(info.folders) && index >= info.streamCounts[folder] {
+			folder++
+			index = 0
+			offset = 0
+		}
+		if folder >= len(info.folders) {
+			return nil, errSevenZipCorrupt
+		}
+		size := info.streamSizes[folder][index]
+		a.entries = append(a.entries, sevenZipArchiveEntry{
+			archive: a,
+			path:    path.Clean(file.name),
+			folder:  folder,
+			offset:  offset,
+			size:    size,
+			crc:     info.streamCRCs[folder][index],
+		})
+		offset += size
+		index++
+	}
+	return a, nil
+}
+
+type sevenZipArchiveFactory struct{}
+
+func (e sevenZipArchiveFactory) Open(filepath string, password string) (Archive, error) {
+	if password != "" {
+		return nil, errors.New("password-protected archives not supported")
+	}
+
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a, err := newSevenZipArchive(f, st.Size(), f.Close)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (e sevenZipArchiveFactory) OpenBytes(data []byte, password string) (Archive, error) {
+	if password != "" {
+		return nil, errors.New("password-protected archives not supported")
+	}
+	return newSevenZipArchive(bytes.NewReader(data), int64(len(data)), func() error { return nil })
+}
+
+func (e sevenZipArchiveFactory) OpenReader(reader ReaderAtCloser, size int64, password string, minimizeReads bool) (Archive, error) {
+	if password != "" {
+		return nil, errors.New("password-protected archives not supported")
+	}
+	return newSevenZipArchive(reader, size, reader.Close)
+}
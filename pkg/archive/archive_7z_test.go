@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a 7z archive storing the files in a single folder with the given coder, and their CRCs.
+func build7z(coder []byte, names []string, contents [][]byte) []byte {
+	var packed []byte
+	for _, c := range contents {
+		packed = append(packed, c...)
+	}
+
+	var h bytes.Buffer
+	h.Write([]byte{sevenZipHeader, sevenZipMainStreamsInfo})
+	h.Write([]byte{sevenZipPackInfo, 0, 1, sevenZipSize, byte(len(packed)), sevenZipEnd})
+	h.Write([]byte{sevenZipUnpackInfo, sevenZipFolderID, 1, 0})
+	h.Write([]byte{1, byte(len(coder))})
+	h.Write(coder)
+	h.Write([]byte{sevenZipCodersUnpackSize, byte(len(packed)), sevenZipEnd})
+	h.Write([]byte{sevenZipSubStreamsInfo, sevenZipNumUnpackStream, byte(len(contents)), sevenZipSize})
+	for _, c := range contents[:len(contents)-1] {
+		h.WriteByte(byte(len(c)))
+	}
+	h.Write([]byte{sevenZipCRC, 1})
+	for _, c := range contents {
+		binary.Write(&h, binary.LittleEndian, crc32.ChecksumIEEE(c))
+	}
+	h.Write([]byte{sevenZipEnd, sevenZipEnd})
+
+	var namesProperty bytes.Buffer
+	namesProperty.WriteByte(0)
+	for _, name := range names {
+		for _, c := range utf16.Encode([]rune(name + "\x00")) {
+			binary.Write(&namesProperty, binary.LittleEndian, c)
+		}
+	}
+	h.Write([]byte{sevenZipFilesInfo, byte(len(names)), sevenZipName, byte(namesProperty.Len())})
+	h.Write(namesProperty.Bytes())
+	h.Write([]byte{sevenZipEnd, sevenZipEnd})
+
+	return wrap7z(packed, h.Bytes())
+}
+
+// Builds a 7z archive from its packed streams and raw header.
+func wrap7z(packed []byte, header []byte) []byte {
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start[0:], uint64(len(packed)))
+	binary.LittleEndian.PutUint64(start[8:], uint64(len(header)))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(header))
+
+	var data []byte
+	data = append(data, sevenZipSignature...)
+	data = append(data, 0, 4)
+	crc := make([]byte, 4)
+	binary.LittleEndian.PutUint32(crc, crc32.ChecksumIEEE(start))
+	data = append(data, crc...)
+	data = append(data, start...)
+	data = append(data, packed...)
+	return append(data, header...)
+}
+
+func TestSevenZipArchiveVerifiesCRC(t *testing.T) {
+	data := build7z(sevenZipCopy, []string{"a.txt", "b.txt"}, [][]byte{[]byte("hello"), []byte("world")})
+	a, err := sevenZipArchiveFactory{}.OpenBytes(data, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	entry, err := a.Entry("b.txt")
+	assert.NoError(t, err)
+	content, err := entry.Read(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("world"), content)
+
+	// Corrupts the content of the first file.
+	data[32] = 'j'
+	a, err = sevenZipArchiveFactory{}.OpenBytes(data, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	entry, err = a.Entry("a.txt")
+	assert.NoError(t, err)
+	_, err = entry.Read(0, 0)
+	assert.True(t, errors.Is(err, errSevenZipCorrupt))
+	_, err = entry.Read(0, 1)
+	assert.True(t, errors.Is(err, errSevenZipCorrupt))
+
+	// The other entries are still readable.
+	entry, err = a.Entry("b.txt")
+	assert.NoError(t, err)
+	content, err = entry.Read(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("world"), content)
+}
+
+func TestSevenZipArchiveUnsupportedCoder(t *testing.T) {
+	bcj := []byte{0x03, 0x03, 0x01, 0x03}
+	a, err := sevenZipArchiveFactory{}.OpenBytes(build7z(bcj, []string{"a.exe"}, [][]byte{[]byte("MZ")}), "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	entry, err := a.Entry("a.exe")
+	assert.NoError(t, err)
+	_, err = entry.Read(0, 0)
+	assert.True(t, errors.Is(err, ErrSevenZipUnsupportedCoder))
+}
+
+// Encodes n as a 7z number on 9 bytes.
+func sevenZipNumber(n uint64) []byte {
+	b := make([]byte, 9)
+	b[0] = 0xFF
+	binary.LittleEndian.PutUint64(b[1:], n)
+	return b
+}
+
+func TestSevenZipArchiveCorruptHeader(t *testing.T) {
+	encodedHeader := func(unpackSize []byte) []byte {
+		h := []byte{sevenZipEncodedHeader, sevenZipPackInfo, 0, 1, sevenZipSize, 0, sevenZipEnd}
+		h = append(h, sevenZipUnpackInfo, sevenZipFolderID, 1, 0, 1, 1, 0x00, sevenZipCodersUnpackSize)
+		h = append(h, unpackSize...)
+		return append(h, sevenZipEnd, sevenZipEnd)
+	}
+	mainStreams := func(packInfo ...byte) []byte {
+		h := []byte{sevenZipHeader, sevenZipMainStreamsInfo, sevenZipPackInfo}
+		h = append(h, packInfo...)
+		return append(h, sevenZipEnd, sevenZipEnd, sevenZipEnd)
+	}
+
+	for name, header := range map[string][]byte{
+		"unpack size larger than the content": encodedHeader(sevenZipNumber(1 << 40)),
+		"negative unpack size":                encodedHeader(sevenZipNumber(math.MaxUint64)),
+		"pack size larger than the archive":   mainStreams(append([]byte{0, 1, sevenZipSize}, sevenZipNumber(1<<40)...)...),
+		"pack position beyond the archive":    mainStreams(append(sevenZipNumber(1<<62), 1, sevenZipSize, 0)...),
+		"huge count":                          mainStreams(append([]byte{0}, sevenZipNumber(1<<40)...)...),
+		"truncated":                           {sevenZipHeader, sevenZipMainStreamsInfo, sevenZipPackInfo},
+	} {
+		_, err := sevenZipArchiveFactory{}.OpenBytes(wrap7z(nil, header), "")
+		assert.True(t, errors.Is(err, errSevenZipCorrupt), name)
+	}
+}
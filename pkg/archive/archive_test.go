@@ -7,7 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var archives = []string{"./testdata/epub.epub", "./testdata/epub"}
+var archives = []string{"./testdata/epub.epub", "./testdata/epub", "./testdata/epub.rar", "./testdata/epub.7z"}
 
 var entryList = []string{
 	"mimetype",
@@ -0,0 +1,76 @@
+package asset
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+)
+
+// Represents a publication served over HTTP, such as a remote Readium Web Publication Manifest.
+type HTTPAsset struct {
+	client         *http.Client
+	url            string
+	mediatype      *mediatype.MediaType
+	knownMediaType *mediatype.MediaType
+}
+
+// Creates an [HTTPAsset] from its [url]. A nil [client] falls back on [http.DefaultClient].
+func HTTP(client *http.Client, url string) *HTTPAsset {
+	return &HTTPAsset{
+		client: client,
+		url:    url,
+	}
+}
+
+// Creates an [HTTPAsset] from its [url] and an optional media type, when known.
+func HTTPWithMediaType(client *http.Client, url string, mediatype *mediatype.MediaType) *HTTPAsset {
+	return &HTTPAsset{
+		client:         client,
+		url:            url,
+		knownMediaType: mediatype,
+	}
+}
+
+// Name implements PublicationAsset
+func (a *HTTPAsset) Name() string {
+	u, err := url.Parse(a.url)
+	if err != nil || u.Path == "" {
+		return a.url
+	}
+	return path.Base(u.Path)
+}
+
+// MediaType implements PublicationAsset
+// When not known, the media type is sniffed from the Content-Type header and the content of the resource.
+func (a *HTTPAsset) MediaType() mediatype.MediaType {
+	if a.mediatype == nil {
+		if a.knownMediaType != nil {
+			a.mediatype = a.knownMediaType
+		} else {
+			f, err := fetcher.NewHTTPFetcher(a.client, "", nil)
+			if err == nil {
+				res := f.Get(manifest.Link{Href: a.url})
+				// Like for local files, only a bounded prefix of the resource is sniffed.
+				data, rerr := res.Read(0, mediatype.MaxReadSize-1)
+				if rerr == nil {
+					a.mediatype = mediatype.OfBytes(data, []string{res.Link().Type}, []string{strings.TrimPrefix(path.Ext(a.Name()), ".")}, mediatype.Sniffers)
+				}
+			}
+			if a.mediatype == nil { // Still nothing found
+				a.mediatype = &mediatype.Binary
+			}
+		}
+	}
+	return *a.mediatype
+}
+
+// CreateFetcher implements PublicationAsset
+// The fetcher only lists the asset itself, other resources are resolved relative to its URL.
+func (a *HTTPAsset) CreateFetcher(dependencies Dependencies, credentials string) (fetcher.Fetcher, error) {
+	return fetcher.NewHTTPFetcher(a.client, a.url, manifest.LinkList{{Href: a.url}})
+}
@@ -0,0 +1,256 @@
+package fetcher
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Declarations of the charset are only looked up at the beginning of the document, like HTML user agents do.
+const charsetPrescanLength = 1024
+
+var (
+	xmlEncodingRegexp = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([^"']*)["']`)
+	metaCharsetRegexp = regexp.MustCompile(`(?i)<meta\s[^>]*?charset\s*=\s*["']?\s*([a-z0-9._:-]+)`)
+	byteOrderMarks    = []struct {
+		bom      []byte
+		name     string
+		encoding encoding.Encoding
+	}{
+		{[]byte{0xEF, 0xBB, 0xBF}, "utf-8", unicode.UTF8},
+		{[]byte{0xFE, 0xFF}, "utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+		{[]byte{0xFF, 0xFE}, "utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+	}
+)
+
+// DetectCharset returns the character encoding of the text [data] with the media type [mt], and its name.
+//
+// The encoding is determined in order by the byte order mark, the charset parameter of the media type, the XML
+// declaration, the HTML <meta charset> or http-equiv declaration and, as a last resort, by a statistical analysis
+// of the content. Legacy content with an unknown encoding is assumed to be windows-1252.
+func DetectCharset(data []byte, mt mediatype.MediaType) (encoding.Encoding, string) {
+	enc, name, _ := detectCharset(data, mt)
+	return enc, name
+}
+
+// Returns the detected encoding, its name and the length of the byte order mark.
+func detectCharset(data []byte, mt mediatype.MediaType) (encoding.Encoding, string, int) {
+	for _, m := range byteOrderMarks {
+		if bytes.HasPrefix(data, m.bom) {
+			return m.encoding, m.name, len(m.bom)
+		}
+	}
+	if cs, ok := mt.Parameters["charset"]; ok {
+		if enc, name := lookupCharset(cs); enc != nil {
+			return enc, name, 0
+		}
+	}
+
+	prescan := data
+	if len(prescan) > charsetPrescanLength {
+		prescan = prescan[:charsetPrescanLength]
+	}
+	if m := xmlEncodingRegexp.FindSubmatch(prescan); m != nil {
+		if enc, name := lookupCharset(string(m[1])); enc != nil {
+			return enc, name, 0
+		}
+	}
+	if m := metaCharsetRegexp.FindSubmatch(prescan); m != nil {
+		if enc, name := lookupCharset(string(m[1])); enc != nil {
+			return enc, name, 0
+		}
+	}
+
+	enc, name := guessCharset(data)
+	return enc, name, 0
+}
+
+// Returns the encoding with the given label. The document being read as bytes, a UTF-16 declaration without a byte
+// order mark can only be a mistake for UTF-8.
+func lookupCharset(label string) (encoding.Encoding, string) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if strings.HasPrefix(label, "utf-16") {
+		label = "utf-8"
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, ""
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return nil, ""
+	}
+	return enc, name
+}
+
+// Guesses the encoding of undeclared content.
+func guessCharset(data []byte) (encoding.Encoding, string) {
+	if utf8.Valid(data) {
+		return unicode.UTF8, "utf-8"
+	}
+
+	// Non-ASCII characters of Western languages are usually isolated between ASCII letters, while CJK encodings use
+	// sequences of non-ASCII bytes.
+	runs, isolated := 0, 0
+	for i := 0; i < len(data); {
+		if data[i] < 0x80 {
+			i++
+			continue
+		}
+		start := i
+		for i < len(data) && data[i] >= 0x80 {
+			i++
+		}
+		runs++
+		if i-start == 1 {
+			isolated++
+		}
+	}
+	if isolated*2 < runs {
+		candidates := []struct {
+			name     string
+			encoding encoding.Encoding
+		}{
+			{"shift_jis", japanese.ShiftJIS},
+			{"gb18030", simplifiedchinese.GB18030},
+		}
+		var best encoding.Encoding
+		bestName, bestScore := "", 0
+		for _, c := range candidates {
+			decoded, err := c.encoding.NewDecoder().Bytes(data)
+			if err != nil {
+				continue
+			}
+			if score, ok := cjkScore(decoded); ok && score > bestScore {
+				best, bestName, bestScore = c.encoding, c.name, score
+			}
+		}
+		if best != nil {
+			return best, bestName
+		}
+	}
+	return charmap.Windows1252, "windows-1252"
+}
+
+// Scores how likely the decoded text is to be a CJK text, or returns false if the decoding failed.
+// Kana are frequent in Japanese, while half-width katakana and private use characters are rare in any text.
+func cjkScore(text []byte) (int, bool) {
+	score := 0
+	for _, r := range string(text) {
+		switch {
+		case r == utf8.RuneError:
+			return 0, false
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f':
+			return 0, false
+		case r < 0x80:
+		case r >= 0x3040 && r <= 0x30FF: // Hiragana and katakana
+			score += 3
+		case r >= 0x4E00 && r <= 0x9FFF: // CJK unified ideographs
+			score += 2
+		case r >= 0x3000 && r <= 0x303F, r >= 0xFF01 && r <= 0xFF60: // CJK punctuation and full-width forms
+			score++
+		case r >= 0xFF61 && r <= 0xFF9F: // Half-width katakana
+			score--
+		default:
+			score -= 2
+		}
+	}
+	return score, true
+}
+
+// DecodeText decodes the text [data] with the media type [mt] to UTF-8, using the charset found by [DetectCharset].
+// The byte order mark is removed.
+func DecodeText(data []byte, mt mediatype.MediaType) (string, error) {
+	decoded, _, err := decodeText(data, mt)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// Decodes the text [data] with the media type [mt] to UTF-8, and returns it with the name of its original charset.
+func decodeText(data []byte, mt mediatype.MediaType) ([]byte, string, error) {
+	enc, name, bom := detectCharset(data, mt)
+	data = data[bom:]
+	if name == "utf-8" {
+		return data, name, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, name, nil
+}
+
+// Decodes the XML document [data] to UTF-8, and updates its XML declaration accordingly.
+func decodeXML(data []byte, mt mediatype.MediaType) ([]byte, string, error) {
+	decoded, name, err := decodeText(data, mt)
+	if err != nil || name == "utf-8" {
+		return decoded, name, err
+	}
+	if m := xmlEncodingRegexp.FindSubmatchIndex(decoded); m != nil {
+		decoded = append(append(append([]byte{}, decoded[:m[2]]...), "UTF-8"...), decoded[m[3]:]...)
+	}
+	return decoded, name, nil
+}
+
+// Reads the full content of the XML resource, decoded to UTF-8.
+func readResourceAsUTF8XML(r Resource) ([]byte, *ResourceError) {
+	data, ex := r.Read(0, 0)
+	if ex != nil {
+		return nil, ex
+	}
+	data, cs, err := decodeXML(data, linkMediaType(r.Link()))
+	if err != nil {
+		return nil, Other(err)
+	}
+	recordCharset(r, cs)
+	return data, nil
+}
+
+// Records the charset [name] decoded from the resource in the type of its link, when it is not the default UTF-8.
+func recordCharset(r Resource, name string) {
+	if name == "utf-8" {
+		return
+	}
+	if cr, ok := r.(interface{ setCharset(name string) }); ok {
+		cr.setCharset(name)
+	}
+}
+
+// Returns the media type of the link as declared, since resolving it to a known media type drops its charset.
+func linkMediaType(link manifest.Link) mediatype.MediaType {
+	mt, err := mediatype.NewOfString(link.Type)
+	if err != nil {
+		return mediatype.Binary
+	}
+	return mt
+}
+
+// Returns the link with the charset [name] added to its media type, unless it already declares one.
+func linkWithCharset(link manifest.Link, name string) manifest.Link {
+	mt, err := mediatype.NewOfString(link.Type)
+	if err != nil {
+		return link
+	}
+	if _, ok := mt.Parameters["charset"]; ok {
+		return link
+	}
+	params := map[string]string{"charset": name}
+	for k, v := range mt.Parameters {
+		params[k] = v
+	}
+	link.Type = mime.FormatMediaType(mt.Type+"/"+mt.SubType, params)
+	return link
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return data
+}
+
+func detectedCharset(data []byte, mediaType string) string {
+	mt, err := mediatype.NewOfString(mediaType)
+	if err != nil {
+		mt = mediatype.Binary
+	}
+	_, name := DetectCharset(data, mt)
+	return name
+}
+
+func TestDetectCharsetFromBOM(t *testing.T) {
+	assert.Equal(t, "utf-8", detectedCharset([]byte("\xEF\xBB\xBFcafé"), "text/html;charset=windows-1252"))
+	assert.Equal(t, "utf-16le", detectedCharset([]byte("\xFF\xFEc\x00a\x00"), ""))
+	assert.Equal(t, "utf-16be", detectedCharset([]byte("\xFE\xFF\x00c\x00a"), ""))
+}
+
+func TestDetectCharsetFromMediaType(t *testing.T) {
+	assert.Equal(t, "iso-8859-2", detectedCharset([]byte("caf\xe9"), "text/plain;charset=ISO-8859-2"))
+	// ISO-8859-1 is an alias of windows-1252 for the web.
+	assert.Equal(t, "windows-1252", detectedCharset([]byte("caf\xe9"), "text/plain;charset=iso-8859-1"))
+}
+
+func TestDetectCharsetFromXMLDeclaration(t *testing.T) {
+	assert.Equal(t, "iso-8859-2", detectedCharset([]byte(`<?xml version="1.0" encoding="ISO-8859-2"?><p>caf`+"\xe9</p>"), "application/xhtml+xml"))
+	assert.Equal(t, "shift_jis", detectedCharset([]byte(`<?xml version='1.0' encoding='Shift_JIS'?><p/>`), ""))
+	// A declaration of UTF-16 without byte order mark is a mistake.
+	assert.Equal(t, "utf-8", detectedCharset([]byte(`<?xml version="1.0" encoding="UTF-16"?><p/>`), ""))
+}
+
+func TestDetectCharsetFromHTMLMeta(t *testing.T) {
+	assert.Equal(t, "windows-1250", detectedCharset([]byte(`<html><head><meta charset="windows-1250"></head></html>`), "text/html"))
+	assert.Equal(t, "euc-kr", detectedCharset([]byte(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=EUC-KR"/></head></html>`), "text/html"))
+}
+
+func TestDetectCharsetFromContent(t *testing.T) {
+	assert.Equal(t, "utf-8", detectedCharset([]byte("<p>Le Petit Café, élève</p>"), ""))
+	assert.Equal(t, "windows-1252", detectedCharset(encode(t, charmap.Windows1252, "<p>Le Petit Café, l’élève à l’école</p>"), ""))
+	assert.Equal(t, "shift_jis", detectedCharset(encode(t, japanese.ShiftJIS, "<p>吾輩は猫である。名前はまだ無い。</p>"), "application/xhtml+xml"))
+	assert.Equal(t, "gb18030", detectedCharset(encode(t, simplifiedchinese.GB18030, "<p>道可道，非常道。名可名，非常名。</p>"), "application/xhtml+xml"))
+}
+
+func TestReadAsStringDecodesCharset(t *testing.T) {
+	data := encode(t, charmap.Windows1252, "Le Petit Café")
+	resource := NewBytesResource(manifest.Link{Href: "/text.txt", Type: "text/plain"}, func() []byte { return data })
+	str, err := resource.ReadAsString()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Le Petit Café", str)
+	}
+	assert.Equal(t, "text/plain; charset=windows-1252", resource.Link().Type)
+}
+
+func TestReadAsStringKeepsDeclaredCharset(t *testing.T) {
+	data := encode(t, charmap.ISO8859_2, "Łódź")
+	resource := NewBytesResource(manifest.Link{Href: "/text.txt", Type: "text/plain;charset=iso-8859-2"}, func() []byte { return data })
+	str, err := resource.ReadAsString()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Łódź", str)
+	}
+	assert.Equal(t, "text/plain;charset=iso-8859-2", resource.Link().Type)
+}
+
+func TestReadAsXMLDecodesCharset(t *testing.T) {
+	data := append([]byte(`<?xml version="1.0" encoding="Shift_JIS"?>`), encode(t, japanese.ShiftJIS, "<p>吾輩は猫である</p>")...)
+	resource := NewBytesResource(manifest.Link{Href: "/chapter.xhtml", Type: "application/xhtml+xml"}, func() []byte { return data })
+	n, err := resource.ReadAsXML(nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "吾輩は猫である", n.SelectElement("/p").InnerText())
+	}
+	assert.Equal(t, "application/xhtml+xml; charset=shift_jis", resource.Link().Type)
+
+	// Undeclared legacy encodings are detected as well.
+	data = encode(t, charmap.Windows1252, "<p>Le Petit Café</p>")
+	resource = NewBytesResource(manifest.Link{Href: "/chapter.xhtml"}, func() []byte { return data })
+	n, err = resource.ReadAsXML(nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Le Petit Café", n.SelectElement("/p").InnerText())
+	}
+}
+
+func TestReadAsXMLDecodesUTF16(t *testing.T) {
+	data := []byte{0xFF, 0xFE}
+	for _, c := range `<p>café</p>` {
+		data = append(data, byte(c), byte(c>>8))
+	}
+	resource := NewBytesResource(manifest.Link{Href: "/chapter.xhtml"}, func() []byte { return data })
+	n, err := resource.ReadAsXML(nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "café", n.SelectElement("/p").InnerText())
+	}
+}
@@ -157,10 +157,14 @@ func (r *entryResource) Length() (int64, *ResourceError) {
 }
 
 // ReadAsString implements Resource
-func (r *entryResource) ReadAsString() (string, *ResourceError) { // TODO determine how charset is needed
+func (r *entryResource) ReadAsString() (string, *ResourceError) {
 	return ReadResourceAsString(r)
 }
 
+func (r *entryResource) setCharset(name string) {
+	r.link = linkWithCharset(r.link, name)
+}
+
 // ReadAsJSON implements Resource
 func (r *entryResource) ReadAsJSON() (map[string]interface{}, *ResourceError) {
 	return ReadResourceAsJSON(r)
@@ -243,6 +243,10 @@ func (r *FileResource) ReadAsString() (string, *ResourceError) {
 	return ReadResourceAsString(r)
 }
 
+func (r *FileResource) setCharset(name string) {
+	r.link = linkWithCharset(r.link, name)
+}
+
 // ReadAsJSON implements Resource
 func (r *FileResource) ReadAsJSON() (map[string]interface{}, *ResourceError) {
 	return ReadResourceAsJSON(r)
@@ -0,0 +1,387 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// Default number of times a failed HTTP request is retried.
+const DefaultHTTPMaxRetries = 3
+
+// Default delay before retrying a failed HTTP request, doubled after each attempt.
+const DefaultHTTPRetryDelay = 500 * time.Millisecond
+
+// Provides access to resources served over HTTP, such as the resources of a remote Readium Web Publication.
+type HTTPFetcher struct {
+	client  *http.Client
+	baseURL *url.URL
+	links   manifest.LinkList
+
+	MaxRetries int             // Number of times a request failing with a network or transient server error is retried.
+	RetryDelay time.Duration   // Delay before the first retry, doubled after each attempt.
+	Context    context.Context // Cancels the pending requests and retries when done. Defaults to context.Background().
+}
+
+// Links implements Fetcher
+// The resources available on an HTTP server can't be listed, so only the links given when creating the fetcher are
+// returned.
+func (f *HTTPFetcher) Links() (manifest.LinkList, error) {
+	return f.links, nil
+}
+
+// Get implements Fetcher
+func (f *HTTPFetcher) Get(link manifest.Link) Resource {
+	u, err := f.resolve(link.Href)
+	if err != nil {
+		return NewFailureResource(link, NotFound(err))
+	}
+	return &HTTPResource{
+		fetcher: f,
+		link:    link,
+		url:     u,
+		length:  -1,
+	}
+}
+
+// Close implements Fetcher
+func (f *HTTPFetcher) Close() {}
+
+func (f *HTTPFetcher) context() context.Context {
+	if f.Context == nil {
+		return context.Background()
+	}
+	return f.Context
+}
+
+// Resolves an HREF to an absolute HTTP URL.
+// HREFs starting with a slash are relative to the base URL of the fetcher, not to the root of the server, like in a
+// package.
+func (f *HTTPFetcher) resolve(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		if f.baseURL == nil {
+			return "", errors.New("no base URL to resolve " + href)
+		}
+		if u, err = url.Parse(strings.TrimPrefix(href, "/")); err != nil {
+			return "", err
+		}
+		u = f.baseURL.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("unsupported URL scheme " + u.Scheme)
+	}
+	return u.String(), nil
+}
+
+// Creates an [HTTPFetcher] serving the resources relative to [baseURL], which can be empty if all the HREFs are
+// absolute URLs. [links] are the known resources returned by [HTTPFetcher.Links].
+// A nil [client] falls back on [http.DefaultClient].
+func NewHTTPFetcher(client *http.Client, baseURL string, links manifest.LinkList) (*HTTPFetcher, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	f := &HTTPFetcher{
+		client:     client,
+		links:      links,
+		MaxRetries: DefaultHTTPMaxRetries,
+		RetryDelay: DefaultHTTPRetryDelay,
+	}
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, errors.New("base URL " + baseURL + " is not an HTTP URL")
+		}
+		f.baseURL = u
+	}
+	return f, nil
+}
+
+// A resource served over HTTP. Ranges are read with HTTP range requests.
+type HTTPResource struct {
+	fetcher *HTTPFetcher
+	link    manifest.Link
+	url     string
+
+	mu          sync.Mutex
+	length      int64  // Length from the Content-Length header, -1 if unknown.
+	contentType string // Content-Type header of the last response.
+}
+
+// File implements Resource
+func (r *HTTPResource) File() string {
+	return ""
+}
+
+// Close implements Resource
+func (r *HTTPResource) Close() {}
+
+// Link implements Resource
+// The media type of the link is set from the Content-Type header of the server, once the resource was requested.
+func (r *HTTPResource) Link() manifest.Link {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link := r.link
+	if link.Type == "" && r.contentType != "" {
+		link.Type = r.contentType
+	}
+	return link
+}
+
+// URL of the resource.
+func (r *HTTPResource) URL() string {
+	return r.url
+}
+
+// Length implements Resource
+func (r *HTTPResource) Length() (int64, *ResourceError) {
+	r.mu.Lock()
+	length := r.length
+	r.mu.Unlock()
+	if length >= 0 {
+		return length, nil
+	}
+
+	resp, ex := r.request(http.MethodHead, 0, 0)
+	if ex == nil {
+		resp.Body.Close()
+		r.mu.Lock()
+		length = r.length
+		r.mu.Unlock()
+		if length >= 0 {
+			return length, nil
+		}
+	}
+
+	// Some servers don't support HEAD requests or don't return the length, so the content is read instead.
+	data, ex := r.Read(0, 0)
+	if ex != nil {
+		return 0, ex
+	}
+	return int64(len(data)), nil
+}
+
+// Read implements Resource
+func (r *HTTPResource) Read(start int64, end int64) ([]byte, *ResourceError) {
+	if end < start {
+		return nil, RangeNotSatisfiable(errors.New("end of range smaller than start"))
+	}
+	resp, ex := r.request(http.MethodGet, start, end)
+	if ex != nil {
+		if ex.Code == CodeRequestedRangeNotSatisfiable {
+			return []byte{}, nil // The range is after the end of the resource.
+		}
+		return nil, ex
+	}
+	defer resp.Body.Close()
+
+	body, ex := rangeOfBody(resp, start, end)
+	if ex != nil {
+		return nil, ex
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, httpErrorToException(err)
+	}
+	return data, nil
+}
+
+// Stream implements Resource
+func (r *HTTPResource) Stream(w io.Writer, start int64, end int64) (int64, *ResourceError) {
+	if end < start {
+		return -1, RangeNotSatisfiable(errors.New("end of range smaller than start"))
+	}
+	resp, ex := r.request(http.MethodGet, start, end)
+	if ex != nil {
+		if ex.Code == CodeRequestedRangeNotSatisfiable {
+			return 0, nil
+		}
+		return -1, ex
+	}
+	defer resp.Body.Close()
+
+	body, ex := rangeOfBody(resp, start, end)
+	if ex != nil {
+		return -1, ex
+	}
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, httpErrorToException(err)
+	}
+	return n, nil
+}
+
+// ReadAsString implements Resource
+func (r *HTTPResource) ReadAsString() (string, *ResourceError) {
+	return ReadResourceAsString(r)
+}
+
+func (r *HTTPResource) setCharset(name string) {
+	link := linkWithCharset(r.Link(), name)
+	r.mu.Lock()
+	r.link = link
+	r.mu.Unlock()
+}
+
+// ReadAsJSON implements Resource
+func (r *HTTPResource) ReadAsJSON() (map[string]interface{}, *ResourceError) {
+	return ReadResourceAsJSON(r)
+}
+
+// ReadAsXML implements Resource
+func (r *HTTPResource) ReadAsXML(prefixes map[string]string) (*xmlquery.Node, *ResourceError) {
+	return ReadResourceAsXML(r, prefixes)
+}
+
+// Performs a request for the given range, retrying with an exponential backoff on network failures and transient
+// server errors. The caller is responsible for closing the body of the response.
+// The retries stop when the context of the fetcher is done, or when waiting would exceed the timeout of the client.
+func (r *HTTPResource) request(method string, start int64, end int64) (*http.Response, *ResourceError) {
+	ctx := r.fetcher.context()
+	began := time.Now()
+	delay := r.fetcher.RetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, ex := r.doRequest(ctx, method, start, end)
+		if ex == nil || attempt >= r.fetcher.MaxRetries || !isRetryable(ex) {
+			return resp, ex
+		}
+		if timeout := r.fetcher.client.Timeout; timeout > 0 && time.Since(began)+delay > timeout {
+			return resp, ex
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, httpErrorToException(ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (r *HTTPResource) doRequest(ctx context.Context, method string, start int64, end int64) (*http.Response, *ResourceError) {
+	req, err := http.NewRequestWithContext(ctx, method, r.url, nil)
+	if err != nil {
+		return nil, BadRequest(err)
+	}
+	if start != 0 || end != 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10))
+	}
+	resp, err := r.fetcher.client.Do(req)
+	if err != nil {
+		return nil, httpErrorToException(err)
+	}
+	if ex := httpStatusToException(resp.StatusCode); ex != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ex
+	}
+
+	r.mu.Lock()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err == nil {
+			r.contentType = contentType
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if resp.ContentLength >= 0 {
+			r.length = resp.ContentLength
+		}
+	case http.StatusPartialContent:
+		// Content-Range: bytes <start>-<end>/<length>
+		contentRange := resp.Header.Get("Content-Range")
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+			if length, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				r.length = length
+			}
+		}
+	}
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Returns the part of the response body in the requested range, in case the server ignored the Range header.
+func rangeOfBody(resp *http.Response, start int64, end int64) (io.Reader, *ResourceError) {
+	if resp.StatusCode == http.StatusPartialContent || (start == 0 && end == 0) {
+		return resp.Body, nil
+	}
+	if start > 0 {
+		if _, err := io.CopyN(io.Discard, resp.Body, start); err != nil {
+			if err == io.EOF {
+				return strings.NewReader(""), nil
+			}
+			return nil, httpErrorToException(err)
+		}
+	}
+	return io.LimitReader(resp.Body, end-start+1), nil
+}
+
+// Whether a request failing with [ex] might succeed if retried.
+func isRetryable(ex *ResourceError) bool {
+	switch ex.Code {
+	case Offline, CodeGatewayTimeout, CodeServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// Converts an HTTP error status to an exception, or returns nil for a successful status.
+func httpStatusToException(status int) *ResourceError {
+	if status < 400 {
+		return nil
+	}
+	cause := fmt.Errorf("HTTP status %d %s", status, http.StatusText(status))
+	switch status {
+	case http.StatusBadRequest:
+		return BadRequest(cause)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return Forbidden(cause)
+	case http.StatusNotFound, http.StatusGone:
+		return NotFound(cause)
+	case http.StatusRequestedRangeNotSatisfiable:
+		return RangeNotSatisfiable(cause)
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests:
+		return Unavailable(cause)
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return Timeout(cause)
+	}
+	return Other(cause)
+}
+
+// Converts an error of the HTTP client to an exception.
+// Network failures, such as a DNS lookup failing or a refused connection, are reported as [Offline].
+func httpErrorToException(err error) *ResourceError {
+	if errors.Is(err, context.Canceled) {
+		return NewResourceErrorWithCause(Cancelled, err)
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout(err)
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return NewResourceErrorWithCause(Offline, err)
+	}
+	return Other(err)
+}
@@ -0,0 +1,211 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func withHTTPFetcher(t *testing.T, handler http.Handler, f func(server *httptest.Server, fetcher *HTTPFetcher)) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	fetcher, err := NewHTTPFetcher(server.Client(), server.URL+"/pub/manifest.json", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	fetcher.RetryDelay = time.Millisecond
+	f(server, fetcher)
+}
+
+// Serves "/pub/chapter.html" with support for range requests.
+var testHTTPContent = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/pub/chapter.html" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "chapter.html", time.Time{}, strings.NewReader("<html>chapter</html>"))
+})
+
+func TestHTTPFetcherRead(t *testing.T) {
+	withHTTPFetcher(t, testHTTPContent, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		resource := fetcher.Get(manifest.Link{Href: "/chapter.html"})
+		data, err := resource.Read(0, 0)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "<html>chapter</html>", string(data))
+		}
+		assert.Equal(t, "text/html; charset=utf-8", resource.Link().Type)
+
+		// Relative and absolute HREFs
+		for _, href := range []string{"chapter.html", server.URL + "/pub/chapter.html"} {
+			data, err = fetcher.Get(manifest.Link{Href: href}).Read(0, 0)
+			if assert.Nil(t, err, href) {
+				assert.Equal(t, "<html>chapter</html>", string(data), href)
+			}
+		}
+	})
+}
+
+func TestHTTPFetcherReadRange(t *testing.T) {
+	withHTTPFetcher(t, testHTTPContent, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		resource := fetcher.Get(manifest.Link{Href: "/chapter.html"})
+		data, err := resource.Read(6, 12)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "chapter", string(data))
+		}
+		data, err = resource.Read(14, 100)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "/html>", string(data), "the range is clamped to the length")
+		}
+		data, err = resource.Read(100, 200)
+		if assert.Nil(t, err) {
+			assert.Empty(t, data)
+		}
+
+		var b bytes.Buffer
+		n, err := resource.Stream(&b, 0, 5)
+		if assert.Nil(t, err) {
+			assert.EqualValues(t, 6, n)
+			assert.Equal(t, "<html>", b.String())
+		}
+	})
+}
+
+func TestHTTPFetcherReadRangeIgnoredByServer(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	})
+	withHTTPFetcher(t, handler, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		data, err := fetcher.Get(manifest.Link{Href: "/file"}).Read(2, 4)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "234", string(data))
+		}
+	})
+}
+
+func TestHTTPFetcherLength(t *testing.T) {
+	withHTTPFetcher(t, testHTTPContent, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		length, err := fetcher.Get(manifest.Link{Href: "/chapter.html"}).Length()
+		if assert.Nil(t, err) {
+			assert.EqualValues(t, 20, length)
+		}
+	})
+}
+
+func TestHTTPFetcherStatusErrors(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pub/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/pub/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	withHTTPFetcher(t, handler, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		_, err := fetcher.Get(manifest.Link{Href: "/unknown"}).Read(0, 0)
+		assert.Equal(t, CodeNotFound, err.Code)
+		_, err = fetcher.Get(manifest.Link{Href: "/forbidden"}).Read(0, 0)
+		assert.Equal(t, CodeForbidden, err.Code)
+		_, err = fetcher.Get(manifest.Link{Href: "/error"}).Read(0, 0)
+		assert.Equal(t, CodeInternalServerError, err.Code)
+		_, err = fetcher.Get(manifest.Link{Href: "file:///etc/passwd"}).Read(0, 0)
+		assert.Equal(t, CodeNotFound, err.Code)
+	})
+}
+
+func TestHTTPFetcherRetries(t *testing.T) {
+	var attempts int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("finally"))
+	})
+	withHTTPFetcher(t, handler, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		data, err := fetcher.Get(manifest.Link{Href: "/flaky"}).Read(0, 0)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "finally", string(data))
+		}
+		assert.EqualValues(t, 3, atomic.LoadInt32(&attempts))
+
+		atomic.StoreInt32(&attempts, -10)
+		fetcher.MaxRetries = 2
+		_, err = fetcher.Get(manifest.Link{Href: "/flaky"}).Read(0, 0)
+		assert.Equal(t, CodeServiceUnavailable, err.Code)
+		assert.EqualValues(t, -7, atomic.LoadInt32(&attempts), "the request is attempted 3 times")
+	})
+}
+
+func TestHTTPFetcherRetriesCancelled(t *testing.T) {
+	var attempts int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	withHTTPFetcher(t, handler, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		ctx, cancel := context.WithCancel(context.Background())
+		fetcher.Context = ctx
+		fetcher.RetryDelay = time.Hour
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		began := time.Now()
+		_, err := fetcher.Get(manifest.Link{Href: "/flaky"}).Read(0, 0)
+		assert.Equal(t, Cancelled, err.Code)
+		assert.Less(t, time.Since(began), time.Minute)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&attempts))
+	})
+
+	// The retries don't wait beyond the timeout of the client.
+	withHTTPFetcher(t, handler, func(server *httptest.Server, fetcher *HTTPFetcher) {
+		atomic.StoreInt32(&attempts, 0)
+		fetcher.client = &http.Client{Timeout: time.Second}
+		fetcher.RetryDelay = time.Hour
+		_, err := fetcher.Get(manifest.Link{Href: "/flaky"}).Read(0, 0)
+		assert.Equal(t, CodeServiceUnavailable, err.Code)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&attempts))
+	})
+}
+
+func TestHTTPFetcherNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(testHTTPContent)
+	url := server.URL
+	server.Close()
+
+	fetcher, _ := NewHTTPFetcher(nil, url+"/", nil)
+	fetcher.MaxRetries = 0
+	_, err := fetcher.Get(manifest.Link{Href: "/chapter.html"}).Read(0, 0)
+	assert.Equal(t, Offline, err.Code)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer slow.Close()
+	fetcher, _ = NewHTTPFetcher(&http.Client{Timeout: 10 * time.Millisecond}, slow.URL, nil)
+	fetcher.MaxRetries = 0
+	_, err = fetcher.Get(manifest.Link{Href: "/chapter.html"}).Read(0, 0)
+	assert.Equal(t, CodeGatewayTimeout, err.Code)
+}
+
+func TestHTTPFetcherLinks(t *testing.T) {
+	links := manifest.LinkList{{Href: "https://example.com/manifest.json"}}
+	fetcher, err := NewHTTPFetcher(nil, "", links)
+	if assert.NoError(t, err) {
+		l, err := fetcher.Links()
+		assert.NoError(t, err)
+		assert.Equal(t, links, l)
+	}
+
+	_, err = NewHTTPFetcher(nil, "file:///tmp/", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Serves resources generated in memory, such as the documents converted from a publication, on top of a child
+// fetcher serving the other resources.
+type OverlayFetcher struct {
+	fetcher Fetcher
+	links   manifest.LinkList
+	loaders map[string]func() ([]byte, *ResourceError)
+}
+
+// Add serves the content returned by [loader] at the HREF of [link], instead of the child fetcher.
+// When [loader] fails, its error is returned when accessing the resource.
+func (f *OverlayFetcher) Add(link manifest.Link, loader func() ([]byte, *ResourceError)) {
+	if _, ok := f.loaders[link.Href]; ok {
+		for i := range f.links {
+			if f.links[i].Href == link.Href {
+				f.links[i] = link
+			}
+		}
+	} else {
+		f.links = append(f.links, link)
+	}
+	f.loaders[link.Href] = loader
+}
+
+// Links implements Fetcher
+// Only the links of the resources added to the overlay are returned.
+func (f *OverlayFetcher) Links() (manifest.LinkList, error) {
+	return f.links, nil
+}
+
+// Get implements Fetcher
+func (f *OverlayFetcher) Get(link manifest.Link) Resource {
+	href := strings.SplitN(link.Href, "#", 2)[0]
+	loader, ok := f.loaders[href]
+	if !ok {
+		return f.fetcher.Get(link)
+	}
+	if link.Type == "" {
+		if known := f.links.FirstWithHref(href); known != nil {
+			link.Type = known.Type
+		}
+	}
+
+	data, ex := loader()
+	if ex != nil {
+		return NewFailureResource(link, ex)
+	}
+	return NewBytesResource(link, func() []byte {
+		return data
+	})
+}
+
+// Close implements Fetcher
+func (f *OverlayFetcher) Close() {
+	f.fetcher.Close()
+}
+
+func NewOverlayFetcher(fetcher Fetcher) *OverlayFetcher {
+	return &OverlayFetcher{
+		fetcher: fetcher,
+		loaders: make(map[string]func() ([]byte, *ResourceError)),
+	}
+}
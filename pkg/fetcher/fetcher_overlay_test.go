@@ -0,0 +1,56 @@
+package fetcher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlayFetcher(t *testing.T) {
+	f := NewOverlayFetcher(testFileFetcher)
+	f.Add(manifest.Link{Href: "/doc.xhtml", Type: "application/xhtml+xml"}, func() ([]byte, *ResourceError) {
+		return []byte("<html/>"), nil
+	})
+	f.Add(manifest.Link{Href: "/file_href", Type: "text/css"}, func() ([]byte, *ResourceError) {
+		return []byte("p {}"), nil
+	})
+
+	links, err := f.Links()
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.LinkList{
+		{Href: "/doc.xhtml", Type: "application/xhtml+xml"},
+		{Href: "/file_href", Type: "text/css"},
+	}, links)
+
+	res := f.Get(manifest.Link{Href: "/doc.xhtml#part"})
+	assert.Equal(t, "application/xhtml+xml", res.Link().Type)
+	data, rerr := res.ReadAsString()
+	if assert.Nil(t, rerr) {
+		assert.Equal(t, "<html/>", data)
+	}
+
+	// Added resources take precedence over the child fetcher.
+	data, rerr = f.Get(manifest.Link{Href: "/file_href"}).ReadAsString()
+	if assert.Nil(t, rerr) {
+		assert.Equal(t, "p {}", data)
+	}
+
+	// Other resources are served by the child fetcher.
+	_, rerr = f.Get(manifest.Link{Href: "/unknown"}).Read(0, 0)
+	if assert.NotNil(t, rerr) {
+		assert.Equal(t, CodeNotFound, rerr.Code)
+	}
+
+	// The failure of a loader is returned when accessing the resource.
+	f.Add(manifest.Link{Href: "/image.png", Type: "image/png"}, func() ([]byte, *ResourceError) {
+		return nil, Other(errors.New("unreadable image"))
+	})
+	res = f.Get(manifest.Link{Href: "/image.png"})
+	assert.Equal(t, "image/png", res.Link().Type)
+	_, rerr = res.Read(0, 0)
+	if assert.NotNil(t, rerr) {
+		assert.Equal(t, CodeInternalServerError, rerr.Code)
+	}
+}
@@ -2,16 +2,14 @@ package fetcher
 
 import (
 	"encoding/json"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"strings"
 
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/xmlquery"
-	"golang.org/x/text/encoding/unicode"
 )
 
 /**
@@ -61,19 +59,18 @@ type Resource interface {
 	ReadAsXML(prefixes map[string]string) (*xmlquery.Node, *ResourceError)
 }
 
+// Reads the full content of the resource as a string, decoded from the charset declared in its link or detected
+// from its content. The detected charset is then added to the type of the resource's link.
 func ReadResourceAsString(r Resource) (string, *ResourceError) {
 	bytes, ex := r.Read(0, 0)
 	if ex != nil {
 		return "", ex
 	}
-	cs := r.Link().MediaType().Charset()
-	if cs == nil {
-		cs = unicode.UTF8
-	}
-	utf8bytes, err := cs.NewDecoder().Bytes(bytes)
+	utf8bytes, cs, err := decodeText(bytes, linkMediaType(r.Link()))
 	if err != nil {
 		return "", Other(err)
 	}
+	recordCharset(r, cs)
 	return string(utf8bytes), nil
 }
 
@@ -90,18 +87,13 @@ func ReadResourceAsJSON(r Resource) (map[string]interface{}, *ResourceError) {
 	return object, nil
 }
 
+// Reads the full content of the resource as an XML document, decoded like [ReadResourceAsString].
 func ReadResourceAsXML(r Resource, prefixes map[string]string) (*xmlquery.Node, *ResourceError) {
-	bytes, ex := r.Read(0, 0)
+	bytes, ex := readResourceAsUTF8XML(r)
 	if ex != nil {
 		return nil, ex
 	}
-	node, err := xmlquery.ParseWithOptions(strings.NewReader(string(bytes)), xmlquery.ParserOptions{
-		Prefixes: prefixes,
-		Decoder: &xmlquery.DecoderOptions{
-			Strict: true,
-			Entity: xml.HTMLEntity,
-		},
-	})
+	node, err := parseXML(bytes, prefixes, true)
 	if err != nil {
 		return nil, Other(err)
 	}
@@ -362,11 +354,118 @@ func (r ProxyResource) ReadAsXML(prefixes map[string]string) (*xmlquery.Node, *R
  */
 type TransformingResource struct {
 	resource   Resource
+	link       manifest.Link
+	transform  func(data []byte) ([]byte, error)
 	cacheBytes bool
 	_bytes     []byte
 }
 
-// TODO TransformingResource
+// File implements Resource
+func (r *TransformingResource) File() string {
+	return ""
+}
+
+// Close implements Resource
+func (r *TransformingResource) Close() {
+	r.resource.Close()
+}
+
+// Link implements Resource
+func (r *TransformingResource) Link() manifest.Link {
+	return r.link
+}
+
+// Length implements Resource
+func (r *TransformingResource) Length() (int64, *ResourceError) {
+	data, ex := r.bytes()
+	if ex != nil {
+		return 0, ex
+	}
+	return int64(len(data)), nil
+}
+
+// Read implements Resource
+func (r *TransformingResource) Read(start int64, end int64) ([]byte, *ResourceError) {
+	if end < start {
+		return nil, RangeNotSatisfiable(errors.New("end of range smaller than start"))
+	}
+	data, ex := r.bytes()
+	if ex != nil {
+		return nil, ex
+	}
+	if start == 0 && end == 0 {
+		return data, nil
+	}
+	length := int64(len(data))
+	if start >= length {
+		return []byte{}, nil
+	}
+	if end >= length {
+		end = length - 1
+	}
+	return data[start : end+1], nil
+}
+
+// Stream implements Resource
+func (r *TransformingResource) Stream(w io.Writer, start int64, end int64) (int64, *ResourceError) {
+	data, ex := r.Read(start, end)
+	if ex != nil {
+		return -1, ex
+	}
+	n, err := w.Write(data)
+	if err != nil {
+		return int64(n), Other(err)
+	}
+	return int64(n), nil
+}
+
+// ReadAsString implements Resource
+func (r *TransformingResource) ReadAsString() (string, *ResourceError) {
+	return ReadResourceAsString(r)
+}
+
+func (r *TransformingResource) setCharset(name string) {
+	r.link = linkWithCharset(r.link, name)
+}
+
+// ReadAsJSON implements Resource
+func (r *TransformingResource) ReadAsJSON() (map[string]interface{}, *ResourceError) {
+	return ReadResourceAsJSON(r)
+}
+
+// ReadAsXML implements Resource
+func (r *TransformingResource) ReadAsXML(prefixes map[string]string) (*xmlquery.Node, *ResourceError) {
+	return ReadResourceAsXML(r, prefixes)
+}
+
+func (r *TransformingResource) bytes() ([]byte, *ResourceError) {
+	if r._bytes != nil {
+		return r._bytes, nil
+	}
+	data, ex := r.resource.Read(0, 0)
+	if ex != nil {
+		return nil, ex
+	}
+	data, err := r.transform(data)
+	if err != nil {
+		return nil, Other(err)
+	}
+	if r.cacheBytes {
+		r._bytes = data
+	}
+	return data, nil
+}
+
+// Creates a [TransformingResource] serving the content of [resource] converted by [transform].
+// The [link] of the resulting resource usually declares the media type of the converted content.
+func NewTransformingResource(resource Resource, link manifest.Link, transform func(data []byte) ([]byte, error), cacheBytes bool) *TransformingResource {
+	return &TransformingResource{
+		resource:   resource,
+		link:       link,
+		transform:  transform,
+		cacheBytes: cacheBytes,
+	}
+}
 
 // TODO LazyResource
 
@@ -54,16 +54,16 @@ func (r *BytesResource) Read(start int64, end int64) ([]byte, *ResourceError) {
 		return r._bytes, nil
 	}
 
-	// Bounds check
+	// Bounds check, the end of the range being inclusive.
 	length := int64(len(r._bytes))
-	if start > (length - 1) {
-		start = length - 1
+	if start >= length {
+		return []byte{}, nil
 	}
-	if end > length {
-		end = length
+	if end >= length {
+		end = length - 1
 	}
 
-	return r._bytes[start:end], nil
+	return r._bytes[start : end+1], nil
 }
 
 // Stream implements Resource
@@ -72,15 +72,11 @@ func (r *BytesResource) Stream(w io.Writer, start int64, end int64) (int64, *Res
 		err := RangeNotSatisfiable(errors.New("end of range smaller than start"))
 		return -1, err
 	}
-	if r._bytes == nil {
-		r._bytes = r.loader()
-	}
-	var buff *bytes.Buffer
-	if start == 0 && end == 0 {
-		buff = bytes.NewBuffer(r._bytes)
-	} else {
-		buff = bytes.NewBuffer(r._bytes[start:end])
+	data, ex := r.Read(start, end)
+	if ex != nil {
+		return -1, ex
 	}
+	buff := bytes.NewBuffer(data)
 	n, err := io.Copy(w, buff)
 	if err != nil {
 		return n, Other(err)
@@ -93,6 +89,10 @@ func (r *BytesResource) ReadAsString() (string, *ResourceError) {
 	return ReadResourceAsString(r)
 }
 
+func (r *BytesResource) setCharset(name string) {
+	r.link = linkWithCharset(r.link, name)
+}
+
 // ReadAsJSON implements Resource
 func (r *BytesResource) ReadAsJSON() (map[string]interface{}, *ResourceError) {
 	return ReadResourceAsJSON(r)
@@ -0,0 +1,231 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/readium/xmlquery"
+	"golang.org/x/net/html"
+)
+
+// XMLRecovery is the policy used to recover from malformed XML resources, which would fail to parse with the strict
+// parser of [ReadResourceAsXML]. Publications produced by retailers often contain a stray tag or an unescaped "&".
+type XMLRecovery struct {
+	Lenient bool            // Retries with a non-strict decoder, tolerating unescaped characters, unknown entities and mismatched tags.
+	HTML    bool            // Retries with an HTML5 parser, when the media type of the resource link is (X)HTML.
+	Report  func(XMLRepair) // Called for each resource which was recovered, when not nil.
+}
+
+var (
+	// Fails on any malformed XML resource.
+	StrictXML = XMLRecovery{}
+
+	// Recovers with all the available parsers.
+	DefaultXMLRecovery = XMLRecovery{Lenient: true, HTML: true}
+)
+
+// XMLRepairMethod is the parser which recovered a malformed XML resource.
+type XMLRepairMethod string
+
+const (
+	XMLRepairLenient XMLRepairMethod = "lenient"
+	XMLRepairHTML    XMLRepairMethod = "html"
+)
+
+// XMLRepair records a malformed XML resource which was recovered.
+type XMLRepair struct {
+	Href   string          // HREF of the recovered resource.
+	Method XMLRepairMethod // Parser which recovered the resource.
+	Err    error           // Error raised by the strict parser.
+}
+
+func (r XMLRepair) String() string {
+	return fmt.Sprintf("recovered malformed XML in %s with the %s parser: %v", r.Href, r.Method, r.Err)
+}
+
+// ReadResourceAsXMLWithRecovery reads the full content as a generic XML document like [ReadResourceAsXML], and
+// attempts to recover from a malformed document according to the [recovery] policy.
+func ReadResourceAsXMLWithRecovery(r Resource, prefixes map[string]string, recovery XMLRecovery) (*xmlquery.Node, *ResourceError) {
+	data, ex := readResourceAsUTF8XML(r)
+	if ex != nil {
+		return nil, ex
+	}
+	node, err := parseXML(data, prefixes, true)
+	if err == nil {
+		return node, nil
+	}
+
+	repaired := func(node *xmlquery.Node, method XMLRepairMethod) (*xmlquery.Node, *ResourceError) {
+		if recovery.Report != nil {
+			recovery.Report(XMLRepair{Href: r.Link().Href, Method: method, Err: err})
+		}
+		return node, nil
+	}
+	// The HTML5 parser comes first for (X)HTML resources, since it knows which end tags are implied, e.g. for
+	// the list items of a navigation document.
+	if recovery.HTML && r.Link().MediaType().IsHTML() {
+		if node, herr := parseHTMLAsXML(data, prefixes); herr == nil {
+			return repaired(node, XMLRepairHTML)
+		}
+	}
+	if recovery.Lenient {
+		if node, lerr := parseXML(data, prefixes, false); lerr == nil {
+			return repaired(node, XMLRepairLenient)
+		}
+	}
+	return nil, Other(err)
+}
+
+func parseXML(data []byte, prefixes map[string]string, strict bool) (*xmlquery.Node, error) {
+	return xmlquery.ParseWithOptions(bytes.NewReader(data), xmlquery.ParserOptions{
+		Prefixes: prefixes,
+		Decoder: &xmlquery.DecoderOptions{
+			Strict: strict,
+			Entity: xml.HTMLEntity,
+		},
+	})
+}
+
+// Parses an (X)HTML document with an HTML5 parser, and converts the resulting tree to a well-formed XHTML document.
+func parseHTMLAsXML(data []byte, prefixes map[string]string) (*xmlquery.Node, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Namespace declarations are hoisted to the root element, since HTML parsers don't scope them.
+	namespaces := map[string]string{
+		"xlink": "http://www.w3.org/1999/xlink",
+	}
+	for ns, prefix := range prefixes {
+		namespaces[prefix] = ns
+	}
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for _, a := range n.Attr {
+			if prefix := strings.TrimPrefix(a.Key, "xmlns:"); prefix != a.Key && a.Namespace == "" && a.Val != "" {
+				namespaces[prefix] = a.Val
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(doc)
+
+	w := htmlToXMLWriter{namespaces: namespaces, used: make(map[string]bool)}
+	var body bytes.Buffer
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		w.write(&body, c, "")
+	}
+
+	// Declares the namespaces of the prefixes used in the document on the root element.
+	used := make([]string, 0, len(w.used))
+	for prefix := range w.used {
+		used = append(used, prefix)
+	}
+	sort.Strings(used)
+	var declarations strings.Builder
+	for _, prefix := range used {
+		declarations.WriteString(" xmlns:" + prefix + `="`)
+		xml.EscapeText(&declarations, []byte(namespaces[prefix]))
+		declarations.WriteString(`"`)
+	}
+	out := body.String()
+	if i := strings.IndexAny(out, " />"); i > 0 && declarations.Len() > 0 {
+		out = out[:i] + declarations.String() + out[i:]
+	}
+	return parseXML([]byte(out), prefixes, true)
+}
+
+var htmlNamespaces = map[string]string{
+	"":     "http://www.w3.org/1999/xhtml",
+	"svg":  "http://www.w3.org/2000/svg",
+	"math": "http://www.w3.org/1998/Math/MathML",
+}
+
+type htmlToXMLWriter struct {
+	namespaces map[string]string // Known namespaces by prefix.
+	used       map[string]bool   // Prefixes used in the document.
+}
+
+func (w htmlToXMLWriter) write(b *bytes.Buffer, n *html.Node, parentNS string) {
+	switch n.Type {
+	case html.TextNode:
+		xml.EscapeText(b, []byte(n.Data))
+	case html.ElementNode:
+		name, ok := w.qualify(n.Data)
+		if !ok {
+			// Drops the unknown prefix of the element.
+			name = n.Data[strings.LastIndex(n.Data, ":")+1:]
+			if !isXMLName(name) {
+				return
+			}
+		}
+		b.WriteString("<" + name)
+		if n.Namespace != parentNS || n.Parent == nil || n.Parent.Type == html.DocumentNode {
+			b.WriteString(` xmlns="` + htmlNamespaces[n.Namespace] + `"`)
+		}
+		for _, a := range n.Attr {
+			key := a.Key
+			if a.Namespace != "" {
+				key = a.Namespace + ":" + a.Key
+			}
+			if key == "xmlns" || strings.HasPrefix(key, "xmlns:") {
+				continue
+			}
+			if key, ok := w.qualify(key); ok {
+				b.WriteString(" " + key + `="`)
+				xml.EscapeText(b, []byte(a.Val))
+				b.WriteString(`"`)
+			}
+		}
+		if n.FirstChild == nil {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteString(">")
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.write(b, c, n.Namespace)
+		}
+		b.WriteString("</" + name + ">")
+	}
+}
+
+// Returns the qualified name if it is a valid XML name whose prefix is known.
+func (w htmlToXMLWriter) qualify(name string) (string, bool) {
+	prefix, local := "", name
+	if i := strings.Index(name, ":"); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+	if !isXMLName(local) || (prefix != "" && !isXMLName(prefix)) {
+		return "", false
+	}
+	if prefix != "" && prefix != "xml" {
+		if _, ok := w.namespaces[prefix]; !ok {
+			return "", false
+		}
+		w.used[prefix] = true
+	}
+	return name, true
+}
+
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
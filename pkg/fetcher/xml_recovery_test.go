@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const malformedNav = `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<body><nav epub:type="toc"><ol>
+<li><a href="a.xhtml">Tom & Jerry</a>
+<li><a href="b.xhtml">Chapter&nbsp;2</a>
+</ol></nav>
+<svg><image xlink:href="cover.png"/></svg></body></html>`
+
+func readWithRecovery(link manifest.Link, data string, recovery XMLRecovery) (*xmlquery.Node, []XMLRepair, *ResourceError) {
+	var repairs []XMLRepair
+	recovery.Report = func(repair XMLRepair) {
+		repairs = append(repairs, repair)
+	}
+	n, err := ReadResourceAsXMLWithRecovery(NewBytesResource(link, func() []byte { return []byte(data) }), map[string]string{
+		"http://www.w3.org/1999/xhtml": "html",
+		"http://www.idpf.org/2007/ops": "epub",
+	}, recovery)
+	return n, repairs, err
+}
+
+func TestXMLRecoveryWellFormedDocument(t *testing.T) {
+	n, repairs, err := readWithRecovery(manifest.Link{Href: "/package.opf"}, `<package><title>Title</title></package>`, DefaultXMLRecovery)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Title", n.SelectElement("/package/title").InnerText())
+	}
+	assert.Empty(t, repairs)
+}
+
+func TestXMLRecoveryStrict(t *testing.T) {
+	_, repairs, err := readWithRecovery(manifest.Link{Href: "/package.opf"}, `<package><title>Tom & Jerry</title></package>`, StrictXML)
+	assert.NotNil(t, err)
+	assert.Empty(t, repairs)
+}
+
+func TestXMLRecoveryLenient(t *testing.T) {
+	n, repairs, err := readWithRecovery(manifest.Link{Href: "/package.opf"}, `<package><title>Tom & Jerry<b></title><creator>Hanna</package>`, XMLRecovery{Lenient: true})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Tom & Jerry", n.SelectElement("/package/title").InnerText())
+		assert.Equal(t, "Hanna", n.SelectElement("/package/creator").InnerText())
+	}
+	if assert.Len(t, repairs, 1) {
+		assert.Equal(t, "/package.opf", repairs[0].Href)
+		assert.Equal(t, XMLRepairLenient, repairs[0].Method)
+		assert.NotNil(t, repairs[0].Err)
+	}
+}
+
+func TestXMLRecoveryHTML(t *testing.T) {
+	n, repairs, err := readWithRecovery(manifest.Link{Href: "/nav.xhtml", Type: "application/xhtml+xml"}, malformedNav, DefaultXMLRecovery)
+	if !assert.Nil(t, err) {
+		return
+	}
+	items := n.SelectElements("//*[local-name()='nav']/*[local-name()='ol']/*[local-name()='li']/*[local-name()='a']")
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Tom & Jerry", items[0].InnerText())
+		assert.Equal(t, "Chapter\u00a02", items[1].InnerText())
+	}
+	image := n.SelectElement("//*[local-name()='image']")
+	if assert.NotNil(t, image) {
+		assert.Equal(t, "http://www.w3.org/2000/svg", image.NamespaceURI)
+		assert.Equal(t, "cover.png", image.SelectAttr("xlink:href"))
+	}
+	if assert.Len(t, repairs, 1) {
+		assert.Equal(t, XMLRepairHTML, repairs[0].Method)
+	}
+}
+
+func TestXMLRecoveryHTMLOnlyForHTMLResources(t *testing.T) {
+	_, _, err := readWithRecovery(manifest.Link{Href: "/toc.ncx"}, `<ncx><navMap></ncx`, XMLRecovery{HTML: true})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,396 @@
+// Package bitmap reads the format and dimensions of bitmap images from their headers, without decoding them.
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Number of bytes at the beginning of a file needed by [SniffMediaType].
+const MagicLength = 32
+
+// Format and dimensions of a bitmap image.
+type Header struct {
+	MediaType string
+	Width     uint // In pixels.
+	Height    uint // In pixels.
+}
+
+var (
+	ErrUnsupported = errors.New("unsupported bitmap format")
+	ErrInvalid     = errors.New("invalid bitmap header")
+)
+
+// Detects the media type of a bitmap from the magic bytes at the beginning of the file.
+// Returns an empty string if the format is not recognized.
+// Reference: https://en.wikipedia.org/wiki/List_of_file_signatures
+func SniffMediaType(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(magic, []byte("GIF87a")), bytes.HasPrefix(magic, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(magic, []byte("\xFF\xD8\xFF")):
+		return "image/jpeg"
+	case len(magic) >= 12 && bytes.HasPrefix(magic, []byte("RIFF")) && bytes.Equal(magic[8:12], []byte("WEBP")):
+		return "image/webp"
+	case bytes.HasPrefix(magic, []byte("II*\x00")), bytes.HasPrefix(magic, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(magic, []byte("\xFF\x0A")), bytes.HasPrefix(magic, jxlContainerSignature):
+		return "image/jxl"
+	case isAVIF(magic):
+		return "image/avif"
+	case isBMP(magic):
+		return "image/bmp"
+	}
+	return ""
+}
+
+// Reads the format and dimensions of the bitmap from its header.
+// Only the few bytes needed are read from [r], which makes it cheap to use with ranged requests.
+func ReadHeader(r io.ReaderAt) (*Header, error) {
+	magic := make([]byte, MagicLength)
+	n, rerr := r.ReadAt(magic, 0)
+	if rerr != nil && rerr != io.EOF {
+		return nil, rerr
+	}
+	magic = magic[:n]
+
+	header := &Header{MediaType: SniffMediaType(magic)}
+	var width, height uint32
+	var err error
+	switch header.MediaType {
+	case "image/png":
+		// The IHDR chunk is always first.
+		if len(magic) < 24 {
+			return nil, ErrInvalid
+		}
+		width, height = binary.BigEndian.Uint32(magic[16:20]), binary.BigEndian.Uint32(magic[20:24])
+	case "image/gif":
+		if len(magic) < 10 {
+			return nil, ErrInvalid
+		}
+		width, height = uint32(binary.LittleEndian.Uint16(magic[6:8])), uint32(binary.LittleEndian.Uint16(magic[8:10]))
+	case "image/bmp":
+		width, height, err = readBMPDimensions(magic)
+	case "image/jpeg":
+		width, height, err = readJPEGDimensions(r)
+	case "image/webp":
+		width, height, err = readWebPDimensions(r)
+	case "image/tiff":
+		width, height, err = readTIFFDimensions(r)
+	case "image/avif":
+		width, height, err = readAVIFDimensions(r)
+	case "image/jxl":
+		width, height, err = readJXLDimensions(r, magic)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 || height == 0 {
+		return nil, ErrInvalid
+	}
+	header.Width, header.Height = uint(width), uint(height)
+	return header, nil
+}
+
+// Reads exactly len(p) bytes at [off], or fails.
+func readFull(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func isBMP(magic []byte) bool {
+	// "BM" is too short to be reliable, so the reserved fields and the size of the DIB header are checked as well.
+	if len(magic) < 18 || !bytes.HasPrefix(magic, []byte("BM")) || !bytes.Equal(magic[6:10], []byte{0, 0, 0, 0}) {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(magic[14:18]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+// Reference: https://en.wikipedia.org/wiki/BMP_file_format#DIB_header_(bitmap_information_header)
+func readBMPDimensions(magic []byte) (uint32, uint32, error) {
+	if len(magic) < 26 {
+		return 0, 0, ErrInvalid
+	}
+	if binary.LittleEndian.Uint32(magic[14:18]) == 12 { // OS/2 BITMAPCOREHEADER
+		return uint32(binary.LittleEndian.Uint16(magic[18:20])), uint32(binary.LittleEndian.Uint16(magic[20:22])), nil
+	}
+	width := int32(binary.LittleEndian.Uint32(magic[18:22]))
+	height := int32(binary.LittleEndian.Uint32(magic[22:26]))
+	if height < 0 { // Top-down bitmap
+		height = -height
+	}
+	if width < 0 {
+		return 0, 0, ErrInvalid
+	}
+	return uint32(width), uint32(height), nil
+}
+
+// Maximum number of JPEG segments skipped before finding the frame header.
+const jpegMaxSegments = 1024
+
+// Finds the dimensions in the Start Of Frame segment, skipping the segments before it.
+// Reference: https://www.w3.org/Graphics/JPEG/itu-t81.pdf (Annex B)
+func readJPEGDimensions(r io.ReaderAt) (uint32, uint32, error) {
+	offset := int64(2)
+	marker := make([]byte, 4)
+	for i := 0; i < jpegMaxSegments; i++ {
+		if err := readFull(r, marker, offset); err != nil {
+			return 0, 0, err
+		}
+		if marker[0] != 0xFF {
+			return 0, 0, ErrInvalid
+		}
+		switch m := marker[1]; {
+		case m == 0xFF: // Fill byte
+			offset++
+			continue
+		case m == 0x01 || (m >= 0xD0 && m <= 0xD7): // Markers without a segment
+			offset += 2
+			continue
+		case m == 0xD9 || m == 0xDA: // End of image or start of scan
+			return 0, 0, ErrInvalid
+		case m >= 0xC0 && m <= 0xCF && m != 0xC4 && m != 0xC8 && m != 0xCC: // Start of frame
+			frame := make([]byte, 5)
+			if err := readFull(r, frame, offset+4); err != nil {
+				return 0, 0, err
+			}
+			return uint32(binary.BigEndian.Uint16(frame[3:5])), uint32(binary.BigEndian.Uint16(frame[1:3])), nil
+		}
+		offset += 2 + int64(binary.BigEndian.Uint16(marker[2:4]))
+	}
+	return 0, 0, ErrInvalid
+}
+
+// Reference: https://developers.google.com/speed/webp/docs/riff_container
+func readWebPDimensions(r io.ReaderAt) (uint32, uint32, error) {
+	chunk := make([]byte, 30)
+	if err := readFull(r, chunk, 0); err != nil {
+		return 0, 0, err
+	}
+	switch string(chunk[12:16]) {
+	case "VP8 ": // Lossy
+		if !bytes.Equal(chunk[23:26], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0, ErrInvalid
+		}
+		return uint32(binary.LittleEndian.Uint16(chunk[26:28]) & 0x3FFF), uint32(binary.LittleEndian.Uint16(chunk[28:30]) & 0x3FFF), nil
+	case "VP8L": // Lossless
+		if chunk[20] != 0x2F {
+			return 0, 0, ErrInvalid
+		}
+		bits := binary.LittleEndian.Uint32(chunk[21:25])
+		return bits&0x3FFF + 1, (bits>>14)&0x3FFF + 1, nil
+	case "VP8X": // Extended
+		width := uint32(chunk[24]) | uint32(chunk[25])<<8 | uint32(chunk[26])<<16
+		height := uint32(chunk[27]) | uint32(chunk[28])<<8 | uint32(chunk[29])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, ErrInvalid
+}
+
+// Reads the ImageWidth and ImageLength tags of the first IFD.
+// Reference: https://www.itu.int/itudoc/itu-t/com16/tiff-fx/docs/tiff6.pdf (Section 2)
+func readTIFFDimensions(r io.ReaderAt) (uint32, uint32, error) {
+	header := make([]byte, 8)
+	if err := readFull(r, header, 0); err != nil {
+		return 0, 0, err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[0] == 'M' {
+		order = binary.BigEndian
+	}
+	offset := int64(order.Uint32(header[4:8]))
+	count := make([]byte, 2)
+	if err := readFull(r, count, offset); err != nil {
+		return 0, 0, err
+	}
+	entries := make([]byte, 12*int(order.Uint16(count)))
+	if err := readFull(r, entries, offset+2); err != nil {
+		return 0, 0, err
+	}
+	var width, height uint32
+	for i := 0; i+12 <= len(entries); i += 12 {
+		entry := entries[i : i+12]
+		var value uint32
+		switch order.Uint16(entry[2:4]) {
+		case 3: // SHORT
+			value = uint32(order.Uint16(entry[8:10]))
+		case 4: // LONG
+			value = order.Uint32(entry[8:12])
+		default:
+			continue
+		}
+		switch order.Uint16(entry[0:2]) {
+		case 256:
+			width = value
+		case 257:
+			height = value
+		}
+	}
+	return width, height, nil
+}
+
+func isAVIF(magic []byte) bool {
+	if len(magic) < 16 || !bytes.Equal(magic[4:8], []byte("ftyp")) {
+		return false
+	}
+	// Major brand, followed by the compatible brands.
+	end := int(binary.BigEndian.Uint32(magic[0:4]))
+	if end > len(magic) {
+		end = len(magic)
+	}
+	for i := 8; i+4 <= end; i += 4 {
+		if i == 12 {
+			continue // Minor version
+		}
+		if brand := string(magic[i : i+4]); brand == "avif" || brand == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the first box of type [typ] between [start] and [end] in an ISO Base Media File.
+// Returns the offsets of its content.
+func findBox(r io.ReaderAt, start int64, end int64, typ string) (int64, int64, bool) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if err := readFull(r, header[:8], offset); err != nil {
+			return 0, 0, false
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0: // Extends to the end of the file
+			size = end - offset
+		case 1: // 64-bit size
+			if err := readFull(r, header[8:16], offset+8); err != nil {
+				return 0, 0, false
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return 0, 0, false
+		}
+		if string(header[4:8]) == typ {
+			return offset + headerSize, offset + size, true
+		}
+		offset += size
+	}
+	return 0, 0, false
+}
+
+// Reads the largest image spatial extents property, to get the size of the primary image rather than the one of a
+// grid tile or a thumbnail.
+// Reference: https://aomediacodec.github.io/av1-avif/
+func readAVIFDimensions(r io.ReaderAt) (uint32, uint32, error) {
+	start, end := int64(0), int64(math.MaxInt64)
+	for _, typ := range []string{"meta", "iprp", "ipco"} {
+		var ok bool
+		if start, end, ok = findBox(r, start, end, typ); !ok {
+			return 0, 0, ErrInvalid
+		}
+		if typ == "meta" {
+			start += 4 // Version and flags of the full box
+		}
+	}
+
+	var width, height uint32
+	ispe := make([]byte, 12)
+	for start < end {
+		boxStart, boxEnd, ok := findBox(r, start, end, "ispe")
+		if !ok {
+			break
+		}
+		if err := readFull(r, ispe, boxStart); err != nil {
+			return 0, 0, err
+		}
+		w, h := binary.BigEndian.Uint32(ispe[4:8]), binary.BigEndian.Uint32(ispe[8:12])
+		if uint64(w)*uint64(h) > uint64(width)*uint64(height) {
+			width, height = w, h
+		}
+		start = boxEnd
+	}
+	return width, height, nil
+}
+
+var jxlContainerSignature = []byte("\x00\x00\x00\x0CJXL \x0D\x0A\x87\x0A")
+
+// Reads the SizeHeader of a JPEG XL codestream, which may be wrapped in an ISO BMFF container.
+// Reference: ISO/IEC 18181-1, section A.3
+func readJXLDimensions(r io.ReaderAt, magic []byte) (uint32, uint32, error) {
+	codestream := int64(0)
+	if bytes.HasPrefix(magic, jxlContainerSignature) {
+		start, _, ok := findBox(r, int64(len(jxlContainerSignature)), math.MaxInt64, "jxlc")
+		if !ok {
+			// The codestream can be split in partial boxes, prefixed by their index.
+			if start, _, ok = findBox(r, int64(len(jxlContainerSignature)), math.MaxInt64, "jxlp"); !ok {
+				return 0, 0, ErrInvalid
+			}
+			start += 4
+		}
+		codestream = start
+	}
+
+	data := make([]byte, 2+12)
+	n, err := r.ReadAt(data, codestream)
+	if err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+	if n < 4 || !bytes.HasPrefix(data, []byte("\xFF\x0A")) {
+		return 0, 0, ErrInvalid
+	}
+	bits := jxlBitReader{data: data[2:n]}
+
+	small := bits.read(1) == 1
+	readSize := func() uint32 {
+		if small {
+			return (bits.read(5) + 1) * 8
+		}
+		return 1 + bits.read([]uint{9, 13, 18, 30}[bits.read(2)])
+	}
+	height := readSize()
+	ratio := bits.read(3)
+	if ratio == 0 {
+		return readSize(), height, nil
+	}
+	ratios := [][2]uint64{{1, 1}, {12, 10}, {4, 3}, {3, 2}, {16, 9}, {5, 4}, {2, 1}}
+	width := uint64(height) * ratios[ratio-1][0] / ratios[ratio-1][1]
+	return uint32(width), height, nil
+}
+
+// Reads the bits of a JPEG XL codestream, least significant bit first.
+type jxlBitReader struct {
+	data []byte
+	pos  uint
+}
+
+func (b *jxlBitReader) read(n uint) uint32 {
+	var value uint32
+	for i := uint(0); i < n; i++ {
+		index := b.pos / 8
+		if int(index) >= len(b.data) {
+			break
+		}
+		value |= uint32(b.data[index]>>(b.pos%8)&1) << i
+		b.pos++
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestHeader(t *testing.T, data []byte) *Header {
+	header, err := ReadHeader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	return header
+}
+
+func TestReadHeaderPNG(t *testing.T) {
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0DIHDR\x00\x00\x03\x20\x00\x00\x04\xB0\x08\x02\x00\x00\x00")
+	assert.Equal(t, &Header{MediaType: "image/png", Width: 800, Height: 1200}, readTestHeader(t, data))
+}
+
+func TestReadHeaderGIF(t *testing.T) {
+	data := []byte("GIF89a\x20\x03\xB0\x04\x00\x00\x00")
+	assert.Equal(t, &Header{MediaType: "image/gif", Width: 800, Height: 1200}, readTestHeader(t, data))
+}
+
+func TestReadHeaderJPEG(t *testing.T) {
+	data := []byte("\xFF\xD8")
+	data = append(data, "\xFF\xE0\x00\x10JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"...)
+	data = append(data, "\xFF\xFF\xDB\x00\x04\x00\x00"...) // Fill byte and quantization table
+	data = append(data, "\xFF\xC2\x00\x11\x08\x04\xB0\x03\x20\x03\x01\x22\x00\x02\x11\x01\x03\x11\x01"...)
+	data = append(data, "\xFF\xDA"...)
+	assert.Equal(t, &Header{MediaType: "image/jpeg", Width: 800, Height: 1200}, readTestHeader(t, data))
+
+	_, err := ReadHeader(bytes.NewReader([]byte("\xFF\xD8\xFF\xDA\x00\x02")))
+	assert.Error(t, err, "no frame header before the scan")
+}
+
+func TestReadHeaderWebP(t *testing.T) {
+	riff := func(chunk string, data []byte) []byte {
+		b := []byte("RIFF\x00\x00\x00\x00WEBP" + chunk + "\x00\x00\x00\x00")
+		return append(b, data...)
+	}
+	lossy := riff("VP8 ", []byte("\x00\x00\x00\x9D\x01\x2A\x20\x03\xB0\x04"))
+	assert.Equal(t, &Header{MediaType: "image/webp", Width: 800, Height: 1200}, readTestHeader(t, lossy))
+
+	bits := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bits, (800-1)|(1200-1)<<14)
+	lossless := riff("VP8L", append([]byte{0x2F}, append(bits, 0, 0, 0, 0, 0)...))
+	assert.Equal(t, &Header{MediaType: "image/webp", Width: 800, Height: 1200}, readTestHeader(t, lossless))
+
+	extended := riff("VP8X", []byte("\x00\x00\x00\x00\x1F\x03\x00\xAF\x04\x00"))
+	assert.Equal(t, &Header{MediaType: "image/webp", Width: 800, Height: 1200}, readTestHeader(t, extended))
+}
+
+func TestReadHeaderBMP(t *testing.T) {
+	data := make([]byte, 54)
+	copy(data, "BM")
+	binary.LittleEndian.PutUint32(data[14:], 40)
+	binary.LittleEndian.PutUint32(data[18:], 800)
+	binary.LittleEndian.PutUint32(data[22:], uint32(0xFFFFFFFF-1200+1)) // Top-down
+	assert.Equal(t, &Header{MediaType: "image/bmp", Width: 800, Height: 1200}, readTestHeader(t, data))
+
+	assert.Equal(t, "", SniffMediaType([]byte("BM is not a bitmap, it's text")))
+}
+
+func TestReadHeaderTIFF(t *testing.T) {
+	data := []byte("MM\x00*\x00\x00\x00\x08\x00\x02" +
+		"\x01\x00\x00\x03\x00\x00\x00\x01\x03\x20\x00\x00" + // ImageWidth, SHORT
+		"\x01\x01\x00\x04\x00\x00\x00\x01\x00\x00\x04\xB0") // ImageLength, LONG
+	assert.Equal(t, &Header{MediaType: "image/tiff", Width: 800, Height: 1200}, readTestHeader(t, data))
+}
+
+func TestReadHeaderAVIF(t *testing.T) {
+	box := func(typ string, data ...[]byte) []byte {
+		content := bytes.Join(data, nil)
+		b := make([]byte, 4, 8+len(content))
+		binary.BigEndian.PutUint32(b, uint32(8+len(content)))
+		return append(append(b, typ...), content...)
+	}
+	ispe := func(width, height uint32) []byte {
+		b := make([]byte, 12)
+		binary.BigEndian.PutUint32(b[4:], width)
+		binary.BigEndian.PutUint32(b[8:], height)
+		return box("ispe", b)
+	}
+	data := bytes.Join([][]byte{
+		box("ftyp", []byte("mif1\x00\x00\x00\x00mif1avifmiaf")),
+		box("meta",
+			[]byte{0, 0, 0, 0},
+			box("hdlr", make([]byte, 24)),
+			box("iprp", box("ipco", ispe(400, 600), box("pixi", make([]byte, 8)), ispe(800, 1200))),
+		),
+		box("mdat"),
+	}, nil)
+	assert.Equal(t, &Header{MediaType: "image/avif", Width: 800, Height: 1200}, readTestHeader(t, data))
+}
+
+// Writes bits least significant first, as in a JPEG XL codestream.
+type testBitWriter struct {
+	data []byte
+	pos  uint
+}
+
+func (w *testBitWriter) write(n uint, value uint32) {
+	for i := uint(0); i < n; i++ {
+		if w.pos%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte((value>>i)&1) << (w.pos % 8)
+		w.pos++
+	}
+}
+
+func TestReadHeaderJXL(t *testing.T) {
+	// Small size with a 4:3 ratio
+	w := &testBitWriter{}
+	w.write(1, 1)
+	w.write(5, 192/8-1)
+	w.write(3, 3)
+	data := append([]byte("\xFF\x0A"), w.data...)
+	assert.Equal(t, &Header{MediaType: "image/jxl", Width: 256, Height: 192}, readTestHeader(t, append(data, 0, 0, 0)))
+
+	// Explicit size, in a container
+	w = &testBitWriter{}
+	w.write(1, 0)
+	w.write(2, 1)
+	w.write(13, 1201-1)
+	w.write(3, 0)
+	w.write(2, 0)
+	w.write(9, 333-1)
+	codestream := append([]byte("\xFF\x0A"), w.data...)
+	data = append([]byte(nil), jxlContainerSignature...)
+	data = append(data, "\x00\x00\x00\x14ftypjxl \x00\x00\x00\x00jxl "...)
+	jxlc := make([]byte, 4)
+	binary.BigEndian.PutUint32(jxlc, uint32(8+len(codestream)))
+	data = append(append(append(data, jxlc...), "jxlc"...), codestream...)
+	assert.Equal(t, &Header{MediaType: "image/jxl", Width: 333, Height: 1201}, readTestHeader(t, data))
+}
+
+func TestReadHeaderUnsupported(t *testing.T) {
+	_, err := ReadHeader(bytes.NewReader([]byte("%PDF-1.4")))
+	assert.Equal(t, ErrUnsupported, err)
+}
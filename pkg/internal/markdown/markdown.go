@@ -0,0 +1,744 @@
+// Package markdown renders Markdown documents to XHTML.
+//
+// The common subset of CommonMark is supported: ATX and setext headings, paragraphs, block quotes, ordered and
+// unordered lists, fenced and indented code blocks, thematic breaks, emphasis, code spans, links, images, autolinks
+// and hard line breaks. Raw HTML is escaped, to keep the output a well-formed XML document.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Heading of a rendered document.
+type Heading struct {
+	Level int    // From 1 to 6.
+	ID    string // Generated identifier of the heading element, unique in the document.
+	Title string // Plain text of the heading.
+}
+
+// Result of the rendering of a Markdown document.
+type Document struct {
+	Body     string    // XHTML content of the <body> element.
+	Headings []Heading // Headings of the document, in order.
+}
+
+// Title returns the text of the first heading with the highest level, or an empty string.
+func (d Document) Title() string {
+	var title string
+	level := 7
+	for _, h := range d.Headings {
+		if h.Level < level {
+			level = h.Level
+			title = h.Title
+		}
+	}
+	return title
+}
+
+// Render converts the [source] Markdown document to XHTML.
+func Render(source string) Document {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.TrimPrefix(source, "\uFEFF")
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	r := &renderer{ids: map[string]int{}}
+	r.renderBlocks(lines, false)
+	return Document{
+		Body:     r.out.String(),
+		Headings: r.headings,
+	}
+}
+
+// ToXHTML wraps the rendered [source] in a complete XHTML document, with the given [title] and [language], which
+// are optional.
+func ToXHTML(source string, title string, language string) ([]byte, Document) {
+	doc := Render(source)
+	if title == "" {
+		title = doc.Title()
+	}
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<!DOCTYPE html>\n")
+	sb.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\"")
+	if language != "" {
+		lang := escape(language)
+		sb.WriteString(" lang=\"" + lang + "\" xml:lang=\"" + lang + "\"")
+	}
+	sb.WriteString(">\n<head>\n<meta charset=\"UTF-8\"/>\n<title>")
+	sb.WriteString(escape(title))
+	sb.WriteString("</title>\n</head>\n<body>\n")
+	sb.WriteString(doc.Body)
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String()), doc
+}
+
+type renderer struct {
+	out      strings.Builder
+	headings []Heading
+	ids      map[string]int
+}
+
+var (
+	atxHeadingMatcher   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextMatcher       = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	thematicMatcher     = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceMatcher        = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	bulletItemMatcher   = regexp.MustCompile(`^( {0,3})([-*+])( +|$)`)
+	orderedItemMatcher  = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])( +|$)`)
+	blockquoteMatcher   = regexp.MustCompile(`^ {0,3}> ?`)
+	entityMatcher       = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	autolinkMatcher     = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailAutolinkMatch  = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*)>`)
+	linkDestTitleMatch  = regexp.MustCompile(`^\(\s*(<[^<>\n]*>|[^\s()]*(?:\([^\s()]*\)[^\s()]*)*)(?:\s+("[^"]*"|'[^']*'|\([^()]*\)))?\s*\)`)
+	slugInvalidMatcher  = regexp.MustCompile(`[^\p{L}\p{N}\s_-]+`)
+	slugSpacesMatcher   = regexp.MustCompile(`[\s]+`)
+	indentedCodeMatcher = regexp.MustCompile(`^(?: {4})`)
+)
+
+// Renders the blocks of [lines]. The paragraphs of a [tight] list item are not wrapped in <p> elements.
+func (r *renderer) renderBlocks(lines []string, tight bool) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			if !tight {
+				r.out.WriteString("<p>")
+			}
+			r.out.WriteString(renderInline(strings.TrimSpace(strings.Join(paragraph, "\n"))))
+			if !tight {
+				r.out.WriteString("</p>")
+			}
+			r.out.WriteString("\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if isBlank(line) {
+			flush()
+			continue
+		}
+
+		// Setext heading underlining a paragraph.
+		if len(paragraph) > 0 {
+			if m := setextMatcher.FindStringSubmatch(line); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				r.heading(level, strings.TrimSpace(strings.Join(paragraph, "\n")))
+				paragraph = nil
+				continue
+			}
+		}
+
+		if thematicMatcher.MatchString(line) {
+			flush()
+			r.out.WriteString("<hr/>\n")
+			continue
+		}
+
+		if m := atxHeadingMatcher.FindStringSubmatch(line); m != nil {
+			flush()
+			r.heading(len(m[1]), m[2])
+			continue
+		}
+
+		if m := fenceMatcher.FindStringSubmatch(line); m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+			flush()
+			indent, fence := len(m[1]), m[2]
+			var code []string
+			i++
+			for ; i < len(lines); i++ {
+				trimmed := strings.TrimLeft(lines[i], " ")
+				if len(lines[i])-len(trimmed) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t") == "" {
+					break
+				}
+				code = append(code, trimLeadingSpaces(lines[i], indent))
+			}
+			r.codeBlock(code, strings.Fields(m[3]))
+			continue
+		}
+
+		if len(paragraph) == 0 && indentedCodeMatcher.MatchString(line) {
+			var code []string
+			for ; i < len(lines); i++ {
+				if !isBlank(lines[i]) && !indentedCodeMatcher.MatchString(lines[i]) {
+					break
+				}
+				code = append(code, trimLeadingSpaces(lines[i], 4))
+			}
+			i--
+			for len(code) > 0 && isBlank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			r.codeBlock(code, nil)
+			continue
+		}
+
+		if blockquoteMatcher.MatchString(line) {
+			flush()
+			var quote []string
+			for ; i < len(lines); i++ {
+				if loc := blockquoteMatcher.FindStringIndex(lines[i]); loc != nil {
+					quote = append(quote, lines[i][loc[1]:])
+				} else if !isBlank(lines[i]) && len(quote) > 0 && !isBlank(quote[len(quote)-1]) && !startsBlock(lines[i]) {
+					quote = append(quote, lines[i]) // Lazy continuation
+				} else {
+					break
+				}
+			}
+			i--
+			r.out.WriteString("<blockquote>\n")
+			r.renderBlocks(quote, false)
+			r.out.WriteString("</blockquote>\n")
+			continue
+		}
+
+		if marker := listMarkerOf(line); marker != nil && (len(paragraph) == 0 || !marker.empty) {
+			flush()
+			i = r.list(lines, i, marker) - 1
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+	}
+	flush()
+}
+
+func (r *renderer) heading(level int, text string) {
+	title := plainText(text)
+	id := r.uniqueID(title)
+	r.headings = append(r.headings, Heading{Level: level, ID: id, Title: title})
+	tag := "h" + strconv.Itoa(level)
+	r.out.WriteString("<" + tag + " id=\"" + escape(id) + "\">")
+	r.out.WriteString(renderInline(strings.TrimSpace(text)))
+	r.out.WriteString("</" + tag + ">\n")
+}
+
+func (r *renderer) codeBlock(code []string, info []string) {
+	r.out.WriteString("<pre><code")
+	if len(info) > 0 {
+		r.out.WriteString(" class=\"language-" + escape(info[0]) + "\"")
+	}
+	r.out.WriteString(">")
+	for _, line := range code {
+		r.out.WriteString(escape(line))
+		r.out.WriteString("\n")
+	}
+	r.out.WriteString("</code></pre>\n")
+}
+
+type listMarker struct {
+	ordered   bool
+	delimiter byte // '-', '*', '+', '.' or ')'
+	start     int
+	width     int  // Width of the marker with its indentation and following spaces.
+	empty     bool // Whether the item starts with a blank line.
+}
+
+func listMarkerOf(line string) *listMarker {
+	if m := bulletItemMatcher.FindStringSubmatch(line); m != nil && !thematicMatcher.MatchString(line) {
+		return newListMarker(false, m[2][0], 0, len(m[1])+1, m[3], line[len(m[0]):])
+	}
+	if m := orderedItemMatcher.FindStringSubmatch(line); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		return newListMarker(true, m[3][0], start, len(m[1])+len(m[2])+1, m[4], line[len(m[0]):])
+	}
+	return nil
+}
+
+func newListMarker(ordered bool, delimiter byte, start int, width int, spaces string, rest string) *listMarker {
+	marker := &listMarker{ordered: ordered, delimiter: delimiter, start: start, empty: isBlank(rest)}
+	switch {
+	case marker.empty || len(spaces) > 4:
+		// The content starts one space after the marker, the other spaces belong to an indented code block.
+		marker.width = width + 1
+	default:
+		marker.width = width + len(spaces)
+	}
+	return marker
+}
+
+// Renders the list starting at [lines][start], and returns the index of the first line following it.
+func (r *renderer) list(lines []string, start int, marker *listMarker) int {
+	var items [][]string
+	loose := false
+	i := start
+	for i < len(lines) {
+		m := listMarkerOf(lines[i])
+		if m == nil || m.ordered != marker.ordered || m.delimiter != marker.delimiter {
+			break
+		}
+		item := []string{""}
+		if !m.empty {
+			item[0] = lines[i][m.width:]
+		}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				item = append(item, "")
+				i++
+				continue
+			}
+			if indentOf(line) >= m.width {
+				item = append(item, trimLeadingSpaces(line, m.width))
+				i++
+				continue
+			}
+			if !isBlank(item[len(item)-1]) && !startsBlock(line) {
+				item = append(item, line) // Lazy continuation
+				i++
+				continue
+			}
+			break
+		}
+		// Trailing blank lines separate the item from the next one.
+		trailing := 0
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+			trailing++
+		}
+		if isBlank(item[0]) && len(item) == 1 {
+			item = nil // Empty item, its first line isn't a blank line separating it from the next one.
+		}
+		if trailing > 0 && i < len(lines) {
+			if next := listMarkerOf(lines[i]); next != nil && next.ordered == marker.ordered && next.delimiter == marker.delimiter {
+				loose = true
+			}
+		}
+		if containsBlankBetweenBlocks(item) {
+			loose = true
+		}
+		items = append(items, item)
+	}
+	// Blank lines after the last item aren't part of the list.
+	for i > start && isBlank(lines[i-1]) {
+		i--
+	}
+
+	if marker.ordered {
+		if marker.start != 1 {
+			r.out.WriteString("<ol start=\"" + strconv.Itoa(marker.start) + "\">\n")
+		} else {
+			r.out.WriteString("<ol>\n")
+		}
+	} else {
+		r.out.WriteString("<ul>\n")
+	}
+	for _, item := range items {
+		sub := &renderer{ids: r.ids}
+		sub.renderBlocks(item, !loose)
+		r.headings = append(r.headings, sub.headings...)
+		r.out.WriteString("<li>")
+		r.out.WriteString(strings.TrimSuffix(sub.out.String(), "\n"))
+		r.out.WriteString("</li>\n")
+	}
+	if marker.ordered {
+		r.out.WriteString("</ol>\n")
+	} else {
+		r.out.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// Whether a blank line separates two blocks of a list item, making the list loose. The blank lines of a nested list
+// only make the nested list loose.
+func containsBlankBetweenBlocks(item []string) bool {
+	inFence := false
+	nested := 0 // Content indentation of the nested list, if any.
+	for i, line := range item {
+		if fenceMatcher.MatchString(line) {
+			inFence = !inFence
+		}
+		if inFence {
+			continue
+		}
+		if !isBlank(line) {
+			if m := listMarkerOf(line); m != nil {
+				nested = m.width
+			} else if indentOf(line) < nested {
+				nested = 0
+			}
+			continue
+		}
+		if i == 0 || i == len(item)-1 || indentOf(item[i+1]) >= 4 {
+			continue
+		}
+		if next := item[i+1]; nested > 0 && (indentOf(next) >= nested || listMarkerOf(next) != nil) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Whether the [line] starts a block interrupting a paragraph.
+func startsBlock(line string) bool {
+	return thematicMatcher.MatchString(line) ||
+		atxHeadingMatcher.MatchString(line) ||
+		fenceMatcher.MatchString(line) ||
+		blockquoteMatcher.MatchString(line) ||
+		listMarkerOf(line) != nil
+}
+
+// Generates a unique identifier from the title of a heading.
+func (r *renderer) uniqueID(title string) string {
+	id := strings.ToLower(strings.TrimSpace(title))
+	id = slugInvalidMatcher.ReplaceAllString(id, "")
+	id = slugSpacesMatcher.ReplaceAllString(strings.TrimSpace(id), "-")
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) {
+		id = "section-" + id
+		id = strings.TrimSuffix(id, "-")
+	}
+	r.ids[id]++
+	if n := r.ids[id]; n > 1 {
+		id += "-" + strconv.Itoa(n-1)
+	}
+	return id
+}
+
+func renderInline(text string) string {
+	var sb strings.Builder
+	inline(&sb, text)
+	return sb.String()
+}
+
+func inline(sb *strings.Builder, text string) {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch c {
+		case '\\':
+			if i+1 < len(text) && isASCIIPunctuation(text[i+1]) {
+				sb.WriteString(escape(text[i+1 : i+2]))
+				i += 2
+				continue
+			}
+			if i+1 < len(text) && text[i+1] == '\n' {
+				sb.WriteString("<br/>\n")
+				i += 2
+				continue
+			}
+
+		case '`':
+			n := countRun(text, i, '`')
+			if end := findCodeSpanEnd(text, i+n, n); end >= 0 {
+				code := strings.ReplaceAll(text[i+n:end], "\n", " ")
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				sb.WriteString("<code>" + escape(code) + "</code>")
+				i = end + n
+				continue
+			}
+			sb.WriteString(text[i : i+n])
+			i += n
+			continue
+
+		case '!':
+			if i+1 < len(text) && text[i+1] == '[' {
+				if label, dest, title, end, ok := parseLink(text, i+1); ok {
+					sb.WriteString("<img src=\"" + escape(dest) + "\" alt=\"" + escape(plainText(label)) + "\"")
+					if title != "" {
+						sb.WriteString(" title=\"" + escape(title) + "\"")
+					}
+					sb.WriteString("/>")
+					i = end
+					continue
+				}
+			}
+
+		case '[':
+			if label, dest, title, end, ok := parseLink(text, i); ok {
+				sb.WriteString("<a href=\"" + escape(dest) + "\"")
+				if title != "" {
+					sb.WriteString(" title=\"" + escape(title) + "\"")
+				}
+				sb.WriteString(">")
+				inline(sb, label)
+				sb.WriteString("</a>")
+				i = end
+				continue
+			}
+
+		case '<':
+			if m := autolinkMatcher.FindStringSubmatch(text[i:]); m != nil {
+				sb.WriteString("<a href=\"" + escape(m[1]) + "\">" + escape(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+			if m := emailAutolinkMatch.FindStringSubmatch(text[i:]); m != nil {
+				sb.WriteString("<a href=\"mailto:" + escape(m[1]) + "\">" + escape(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+
+		case '&':
+			// Entities are decoded, because XHTML only defines the XML ones.
+			if m := entityMatcher.FindString(text[i:]); m != "" {
+				sb.WriteString(escape(html.UnescapeString(m)))
+				i += len(m)
+				continue
+			}
+
+		case '*', '_':
+			if end, n, ok := findEmphasisEnd(text, i); ok {
+				content := text[i+n : end]
+				switch n {
+				case 1:
+					sb.WriteString("<em>")
+					inline(sb, content)
+					sb.WriteString("</em>")
+				case 2:
+					sb.WriteString("<strong>")
+					inline(sb, content)
+					sb.WriteString("</strong>")
+				default:
+					sb.WriteString("<em><strong>")
+					inline(sb, content)
+					sb.WriteString("</strong></em>")
+				}
+				i = end + n
+				continue
+			}
+			n := countRun(text, i, c)
+			sb.WriteString(text[i : i+n])
+			i += n
+			continue
+
+		case ' ', '\n':
+			// Two spaces at the end of a line are a hard line break.
+			n := countRun(text, i, ' ')
+			if i+n < len(text) && text[i+n] == '\n' {
+				if n >= 2 {
+					sb.WriteString("<br/>")
+				}
+				sb.WriteString("\n")
+				i += n + 1
+				for i < len(text) && text[i] == ' ' {
+					i++
+				}
+				continue
+			}
+			if n > 0 {
+				sb.WriteString(text[i : i+n])
+				i += n
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i:])
+		sb.WriteString(escape(text[i : i+size]))
+		i += size
+	}
+}
+
+// Parses a link starting with the opening bracket at [text][start].
+func parseLink(text string, start int) (label string, dest string, title string, end int, ok bool) {
+	depth := 0
+	closing := -1
+	for i := start; i < len(text) && closing < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			n := countRun(text, i, '`')
+			if e := findCodeSpanEnd(text, i+n, n); e >= 0 {
+				i = e + n - 1
+			} else {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 {
+		return
+	}
+	m := linkDestTitleMatch.FindStringSubmatch(text[closing+1:])
+	if m == nil {
+		return
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(m[1], "<"), ">")
+	dest = unescapeBackslashes(html.UnescapeString(dest))
+	if len(m[2]) >= 2 {
+		title = unescapeBackslashes(html.UnescapeString(m[2][1 : len(m[2])-1]))
+	}
+	return text[start+1 : closing], dest, title, closing + 1 + len(m[0]), true
+}
+
+// Finds the end of the emphasis opened by the delimiter run at [text][start], returning the index of the closing run
+// and the number of delimiters used.
+func findEmphasisEnd(text string, start int) (end int, n int, ok bool) {
+	c := text[start]
+	run := countRun(text, start, c)
+	after := start + run
+	if after >= len(text) || isSpace(text[after]) {
+		return // Not left-flanking
+	}
+	if c == '_' && start > 0 && isWordByte(text[start-1]) {
+		return // Intraword underscores are literal
+	}
+	if run > 3 {
+		return
+	}
+	for n = run; n > 0; n-- {
+		for i := start + n; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+				continue
+			case '`':
+				k := countRun(text, i, '`')
+				if e := findCodeSpanEnd(text, i+k, k); e >= 0 {
+					i = e + k - 1
+				} else {
+					i += k - 1
+				}
+				continue
+			case c:
+				k := countRun(text, i, c)
+				if k >= n && i > start+n && !isSpace(text[i-1]) && (c != '_' || i+k >= len(text) || !isWordByte(text[i+k])) {
+					return i, n, true
+				}
+				i += k - 1
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Finds a closing run of exactly [n] backticks from [start].
+func findCodeSpanEnd(text string, start int, n int) int {
+	for i := start; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		k := countRun(text, i, '`')
+		if k == n {
+			return i
+		}
+		i += k
+	}
+	return -1
+}
+
+func countRun(text string, start int, c byte) int {
+	n := 0
+	for start+n < len(text) && text[start+n] == c {
+		n++
+	}
+	return n
+}
+
+// Returns the text content of an inline Markdown fragment, without markup.
+func plainText(text string) string {
+	rendered := renderInline(strings.TrimSpace(text))
+	var sb strings.Builder
+	inTag := false
+	for _, r := range rendered {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(sb.String())), " ")
+}
+
+// Escapes the special characters of XML in [s], and drops the characters which are not allowed in an XML document,
+// such as control characters. Invalid UTF-8 sequences are replaced with U+FFFD.
+func escape(s string) string {
+	return html.EscapeString(strings.Map(func(r rune) rune {
+		if isXMLChar(r) {
+			return r
+		}
+		return -1
+	}, s))
+}
+
+// Reference: https://www.w3.org/TR/xml/#charsets
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= unicode.MaxRune)
+}
+
+func unescapeBackslashes(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunctuation(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := 4 - col%4
+			sb.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+		} else {
+			sb.WriteRune(r)
+			col++
+		}
+	}
+	return sb.String()
+}
+
+func trimLeadingSpaces(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,114 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHeadings(t *testing.T) {
+	doc := Render("# Moby-Dick\n\nChapter *One*\n=============\n\n## Loomings ##\n\n### 1. Loomings\n\n## Loomings\n")
+	assert.Equal(t, `<h1 id="moby-dick">Moby-Dick</h1>
+<h1 id="chapter-one">Chapter <em>One</em></h1>
+<h2 id="loomings">Loomings</h2>
+<h3 id="section-1-loomings">1. Loomings</h3>
+<h2 id="loomings-1">Loomings</h2>
+`, doc.Body)
+	assert.Equal(t, []Heading{
+		{Level: 1, ID: "moby-dick", Title: "Moby-Dick"},
+		{Level: 1, ID: "chapter-one", Title: "Chapter One"},
+		{Level: 2, ID: "loomings", Title: "Loomings"},
+		{Level: 3, ID: "section-1-loomings", Title: "1. Loomings"},
+		{Level: 2, ID: "loomings-1", Title: "Loomings"},
+	}, doc.Headings)
+	assert.Equal(t, "Moby-Dick", doc.Title())
+}
+
+func TestRenderParagraphs(t *testing.T) {
+	assert.Equal(t, "<p>Call me Ishmael.\nSome years ago<br/>\nnever mind.</p>\n<p>Second &amp; last &lt;p&gt;</p>\n",
+		Render("Call me Ishmael.\nSome years ago  \n  never mind.\n\nSecond & last <p>").Body)
+}
+
+func TestRenderInline(t *testing.T) {
+	assert.Equal(t, "<p><strong>bold</strong>, <em>italic</em>, <em><strong>both</strong></em>, <em>under</em> and snake_case_name</p>\n",
+		Render("**bold**, *italic*, ***both***, _under_ and snake_case_name").Body)
+	assert.Equal(t, "<p><code>a * b</code> and <code>`tick`</code> and 2 * 3 * 4</p>\n",
+		Render("`a * b` and `` `tick` `` and 2 * 3 * 4").Body)
+	assert.Equal(t, `<p><a href="http://example.com/a_b" title="Example">a <em>link</em></a> <img src="img/whale.png" alt="The whale"/> <a href="https://readium.org">https://readium.org</a></p>`+"\n",
+		Render(`[a *link*](http://example.com/a_b "Example") ![The whale](img/whale.png) <https://readium.org>`).Body)
+	assert.Equal(t, "<p> café *not emphasis* [not a link]</p>\n",
+		Render(`&nbsp;caf&eacute; \*not emphasis\* [not a link]`).Body)
+}
+
+func TestRenderBlocks(t *testing.T) {
+	assert.Equal(t, "<blockquote>\n<p>Quoted\nlazy</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n<hr/>\n",
+		Render("> Quoted\nlazy\n>\n> > nested\n\n* * *").Body)
+	assert.Equal(t, "<pre><code class=\"language-go\">func main() {\n    &lt;-done\n}\n</code></pre>\n<pre><code>indented\n\ncode\n</code></pre>\n",
+		Render("```go\nfunc main() {\n    <-done\n}\n```\n\n    indented\n\n    code\n").Body)
+}
+
+func TestRenderLists(t *testing.T) {
+	assert.Equal(t, "<ul>\n<li>one</li>\n<li>two\n<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol></li>\n</ul>\n<p>After</p>\n",
+		Render("- one\n- two\n   3. three\n   4. four\n\nAfter").Body)
+	assert.Equal(t, "<ol>\n<li><p>first</p>\n<p>continued</p></li>\n<li><p>second</p></li>\n</ol>\n",
+		Render("1. first\n\n   continued\n2. second").Body)
+	assert.Equal(t, "<ul>\n<li>a</li>\n</ul>\n<ul>\n<li>b</li>\n</ul>\n",
+		Render("- a\n+ b").Body, "changing the bullet starts a new list")
+}
+
+func TestToXHTMLIsWellFormed(t *testing.T) {
+	data, doc := ToXHTML("# Title & <Co>\n\n* [link](a?b=1&c=2)\n* ![img](x.png)\n\n<div>raw</div>", "", "en")
+	assert.Equal(t, "Title & <Co>", doc.Title())
+	assert.Contains(t, string(data), `<html xmlns="http://www.w3.org/1999/xhtml" lang="en" xml:lang="en">`)
+	assert.Contains(t, string(data), "<title>Title &amp; &lt;Co&gt;</title>")
+	assert.NoError(t, parseXML(data))
+}
+
+func TestRenderNestedListsInTightItems(t *testing.T) {
+	body := Render("* *\n  * 0").Body
+	assert.Equal(t, "<ul>\n<li><ul>\n<li></li>\n<li>0</li>\n</ul></li>\n</ul>\n", body)
+	assert.NoError(t, parseXML([]byte("<body>"+body+"</body>")))
+	assert.Equal(t, "<ul>\n<li>a\n<ul>\n<li><p>b</p>\n<p>c</p></li>\n</ul></li>\n</ul>\n",
+		Render("- a\n  - b\n\n    c").Body, "a loose list nested in a tight item keeps its paragraphs")
+}
+
+func TestRenderDropsInvalidXMLCharacters(t *testing.T) {
+	assert.Equal(t, "<p>ab &amp;c\u00e9</p>\n", Render("a\x02b &#1;&amp;c\u00e9\x1b").Body)
+}
+
+func FuzzRender(f *testing.F) {
+	for _, seed := range []string{
+		"# Title\n\nSome *emphasis* and `code`.",
+		"* *\n  * 0",
+		"1. first\n\n   continued\n2. second",
+		"> quote\n> - item\n\n```go\ncode\n```",
+		"[link](<a b> \"title\") ![img](x.png) <http://a.b> &#1; \x02",
+		"- a\n\n  - b\n\n    c\n- d",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, source string) {
+		data, _ := ToXHTML(source, "", "")
+		if err := parseXML(data); err != nil {
+			t.Errorf("Render(%q) is not well-formed: %v\n%s", source, err, data)
+		}
+	})
+}
+
+// Parses the XML document [data] strictly, with the entities of XML only.
+func parseXML(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
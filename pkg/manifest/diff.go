@@ -0,0 +1,559 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind of change found when comparing two manifests.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+	ChangeMoved    ChangeKind = "moved"
+)
+
+// Change of a single metadata field between two manifests.
+// For localized strings (title, subtitle, sortAs), each translation is compared separately. A field whose values
+// were only reordered, such as the authors, is reported as moved.
+type MetadataChange struct {
+	Kind       ChangeKind  `json:"kind"`
+	Collection string      `json:"collection,omitempty"` // Role of the subcollection holding the metadata, empty for the publication.
+	Field      string      `json:"field"`                // RWPM key of the field, e.g. `title` or `author`.
+	Language   string      `json:"language,omitempty"`   // BCP 47 tag of the translation, for localized strings.
+	Old        interface{} `json:"old,omitempty"`        // JSON representation of the old value.
+	New        interface{} `json:"new,omitempty"`        // JSON representation of the new value.
+}
+
+// Change of a [Link] between two manifests, identified by its HREF in a given collection.
+type LinkChange struct {
+	Kind       ChangeKind `json:"kind"`
+	Collection string     `json:"collection"`       // RWPM key of the collection, e.g. `readingOrder`, `toc` or `pageList`.
+	Href       string     `json:"href"`             // HREF of the link.
+	Fields     []string   `json:"fields,omitempty"` // Link properties which were modified.
+	Old        *Link      `json:"old,omitempty"`
+	New        *Link      `json:"new,omitempty"`
+	OldParent  string     `json:"oldParent,omitempty"` // For a moved TOC entry, HREF of its previous parent.
+	NewParent  string     `json:"newParent,omitempty"` // For a moved TOC entry, HREF of its new parent.
+}
+
+// Typed differences between two manifests.
+type ManifestDiff struct {
+	Metadata []MetadataChange `json:"metadata,omitempty"`
+	Links    []LinkChange     `json:"links,omitempty"`
+}
+
+// Returns whether the two compared manifests are semantically identical.
+func (d ManifestDiff) IsEmpty() bool {
+	return len(d.Metadata) == 0 && len(d.Links) == 0
+}
+
+// Compares two manifests and reports the semantic changes needed to go from [old] to [new].
+//
+// Unlike a textual diff of the JSON representations, the comparison is not affected by the order of
+// the keys, the relations of a link or the shorthand of a single value written as a string instead of an array.
+// Links are matched by HREF, and by occurrence when the same HREF is used several times, so a resource is
+// reported as modified rather than removed then added again. The subcollections, such as the page list or the
+// landmarks, are compared too.
+func Diff(old Manifest, new Manifest) ManifestDiff {
+	diff := ManifestDiff{
+		Metadata: diffMetadata(old.Metadata, new.Metadata),
+	}
+	diff.Links = append(diff.Links, diffLinkList("readingOrder", old.ReadingOrder, new.ReadingOrder, true)...)
+	diff.Links = append(diff.Links, diffLinkList("resources", old.Resources, new.Resources, false)...)
+	diff.Links = append(diff.Links, diffLinkList("links", old.Links, new.Links, false)...)
+	diff.Links = append(diff.Links, diffTableOfContents(old.TableOfContents, new.TableOfContents)...)
+	diff.diffSubcollections("", old.Subcollections, new.Subcollections)
+	return diff
+}
+
+// Compares the subcollections of two manifests or collections, identified by their role, e.g. `pageList`,
+// suffixed with their index when a role has several collections, and prefixed with the roles of their parents.
+func (d *ManifestDiff) diffSubcollections(prefix string, old PublicationCollectionMap, new PublicationCollectionMap) {
+	for _, role := range unionKeys(old, new) {
+		oldCollections, newCollections := old[role], new[role]
+		count := len(oldCollections)
+		if len(newCollections) > count {
+			count = len(newCollections)
+		}
+		for i := 0; i < count; i++ {
+			var oc, nc PublicationCollection
+			if i < len(oldCollections) {
+				oc = oldCollections[i]
+			}
+			if i < len(newCollections) {
+				nc = newCollections[i]
+			}
+			name := prefix + role
+			if count > 1 {
+				name += "[" + strconv.Itoa(i) + "]"
+			}
+			d.Metadata = append(d.Metadata, diffJSONFields(name, oc.Metadata, nc.Metadata)...)
+			d.Links = append(d.Links, diffLinkList(name, oc.Links, nc.Links, true)...)
+			d.diffSubcollections(name+"/", oc.Subcollections, nc.Subcollections)
+		}
+	}
+}
+
+var localizedMetadataFields = map[string]struct{}{
+	"title": {}, "subtitle": {}, "sortAs": {},
+}
+
+func diffMetadata(old Metadata, new Metadata) []MetadataChange {
+	oldJSON := metadataToJSONMap(old)
+	newJSON := metadataToJSONMap(new)
+
+	var changes []MetadataChange
+	for _, field := range unionKeys(oldJSON, newJSON) {
+		if _, ok := localizedMetadataFields[field]; ok {
+			changes = append(changes, diffLocalizedString(field, oldJSON[field], newJSON[field])...)
+			continue
+		}
+		changes = append(changes, diffJSONField(field, oldJSON, newJSON)...)
+	}
+	return changes
+}
+
+// Compares the metadata of two subcollections with the given role.
+func diffJSONFields(collection string, old map[string]interface{}, new map[string]interface{}) []MetadataChange {
+	var changes []MetadataChange
+	for _, field := range unionKeys(old, new) {
+		for _, c := range diffJSONField(field, old, new) {
+			c.Collection = collection
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func diffJSONField(field string, old map[string]interface{}, new map[string]interface{}) []MetadataChange {
+	ov, oldOk := old[field]
+	nv, newOk := new[field]
+	switch {
+	case !oldOk:
+		return []MetadataChange{{Kind: ChangeAdded, Field: field, New: nv}}
+	case !newOk:
+		return []MetadataChange{{Kind: ChangeRemoved, Field: field, Old: ov}}
+	}
+	on, nn := normalizeJSONValue(ov), normalizeJSONValue(nv)
+	switch {
+	case reflect.DeepEqual(on, nn):
+		return nil
+	case sameJSONItems(on, nn):
+		return []MetadataChange{{Kind: ChangeMoved, Field: field, Old: ov, New: nv}}
+	default:
+		return []MetadataChange{{Kind: ChangeModified, Field: field, Old: ov, New: nv}}
+	}
+}
+
+func metadataToJSONMap(m Metadata) map[string]interface{} {
+	// The marshalling writes in the OtherMetadata map, so we need to make a copy first.
+	other := make(map[string]interface{}, len(m.OtherMetadata))
+	for k, v := range m.OtherMetadata {
+		other[k] = v
+	}
+	m.OtherMetadata = other
+
+	var res map[string]interface{}
+	bin, err := json.Marshal(m)
+	if err != nil {
+		return res
+	}
+	json.Unmarshal(bin, &res)
+	return res
+}
+
+func diffLocalizedString(field string, old interface{}, new interface{}) []MetadataChange {
+	oldTranslations := localizedStringTranslations(old)
+	newTranslations := localizedStringTranslations(new)
+
+	var changes []MetadataChange
+	for _, lang := range unionKeys(oldTranslations, newTranslations) {
+		ov, oldOk := oldTranslations[lang]
+		nv, newOk := newTranslations[lang]
+		switch {
+		case !oldOk:
+			changes = append(changes, MetadataChange{Kind: ChangeAdded, Field: field, Language: lang, New: nv})
+		case !newOk:
+			changes = append(changes, MetadataChange{Kind: ChangeRemoved, Field: field, Language: lang, Old: ov})
+		case ov != nv:
+			changes = append(changes, MetadataChange{Kind: ChangeModified, Field: field, Language: lang, Old: ov, New: nv})
+		}
+	}
+	return changes
+}
+
+func localizedStringTranslations(raw interface{}) map[string]interface{} {
+	if raw == nil {
+		return nil
+	}
+	ls, err := LocalizedStringFromJSON(raw)
+	if err != nil {
+		return nil
+	}
+	translations := make(map[string]interface{}, len(ls.Translations))
+	for k, v := range ls.Translations {
+		translations[k] = v
+	}
+	return translations
+}
+
+// Normalizes a generic JSON value so that a single string compares equal to an array holding only this string,
+// which are interchangeable in RWPM. The order of arrays is kept, as it is meaningful, e.g. for the authors.
+func normalizeJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 1 {
+			if s, ok := v[0].(string); ok {
+				return s
+			}
+		}
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = normalizeJSONValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			res[k] = normalizeJSONValue(item)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+// Returns whether two normalized JSON arrays hold the same items, regardless of their order.
+func sameJSONItems(a interface{}, b interface{}) bool {
+	aItems, ok := a.([]interface{})
+	if !ok {
+		return false
+	}
+	bItems, ok := b.([]interface{})
+	if !ok || len(aItems) != len(bItems) {
+		return false
+	}
+	keys := make([]string, len(aItems))
+	for i, item := range aItems {
+		bin, _ := json.Marshal(item)
+		keys[i] = string(bin)
+	}
+	others := make([]string, len(bItems))
+	for i, item := range bItems {
+		bin, _ := json.Marshal(item)
+		others[i] = string(bin)
+	}
+	return sameStringSet(keys, others)
+}
+
+func unionKeys[T any](a map[string]T, b map[string]T) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Normalizes an HREF to match links regardless of their leading slash.
+func diffHrefKey(href string) string {
+	return "/" + strings.TrimPrefix(href, "/")
+}
+
+// A link identified by its HREF, suffixed with its occurrence count when the same HREF is used several times.
+type keyedLink struct {
+	key  string
+	link Link
+}
+
+func keyLinks(links LinkList) []keyedLink {
+	res := make([]keyedLink, 0, len(links))
+	occurrences := map[string]int{}
+	for _, l := range links {
+		res = append(res, keyedLink{key: occurrenceKey(occurrences, l.Href), link: l})
+	}
+	return res
+}
+
+// Returns the key of the [href] normalized with [diffHrefKey], suffixed with its occurrence count after the first.
+func occurrenceKey(occurrences map[string]int, href string) string {
+	key := diffHrefKey(href)
+	occurrences[key]++
+	if n := occurrences[key]; n > 1 {
+		key = key + "#" + strconv.Itoa(n)
+	}
+	return key
+}
+
+// Compares two lists of links matched by HREF and occurrence. When [ordered] is true, links present in both lists
+// but in a different relative order are reported as moved.
+func diffLinkList(collection string, old LinkList, new LinkList, ordered bool) []LinkChange {
+	oldLinks, newLinks := keyLinks(old), keyLinks(new)
+	oldByKey := make(map[string]Link, len(oldLinks))
+	for _, l := range oldLinks {
+		oldByKey[l.key] = l.link
+	}
+	newByKey := make(map[string]Link, len(newLinks))
+	for _, l := range newLinks {
+		newByKey[l.key] = l.link
+	}
+
+	var changes []LinkChange
+	for _, l := range oldLinks {
+		if _, ok := newByKey[l.key]; !ok {
+			ol := l.link
+			changes = append(changes, LinkChange{Kind: ChangeRemoved, Collection: collection, Href: ol.Href, Old: &ol})
+		}
+	}
+	for _, l := range newLinks {
+		ol, ok := oldByKey[l.key]
+		nl := l.link
+		if !ok {
+			changes = append(changes, LinkChange{Kind: ChangeAdded, Collection: collection, Href: nl.Href, New: &nl})
+		} else if fields := diffLinkFields(ol, nl); len(fields) > 0 {
+			changes = append(changes, LinkChange{Kind: ChangeModified, Collection: collection, Href: nl.Href, Fields: fields, Old: &ol, New: &nl})
+		}
+	}
+
+	if ordered {
+		oldKeys := make([]string, 0, len(oldLinks))
+		for _, l := range oldLinks {
+			if _, ok := newByKey[l.key]; ok {
+				oldKeys = append(oldKeys, l.key)
+			}
+		}
+		newKeys := make([]string, 0, len(newLinks))
+		for _, l := range newLinks {
+			if _, ok := oldByKey[l.key]; ok {
+				newKeys = append(newKeys, l.key)
+			}
+		}
+		stable := longestCommonSubsequence(oldKeys, newKeys)
+		for _, key := range newKeys {
+			if _, ok := stable[key]; !ok {
+				ol, nl := oldByKey[key], newByKey[key]
+				changes = append(changes, LinkChange{Kind: ChangeMoved, Collection: collection, Href: nl.Href, Old: &ol, New: &nl})
+			}
+		}
+	}
+
+	return changes
+}
+
+// Returns the names of the properties which differ between two links with the same HREF.
+// Children are not compared, as they are diffed separately for the table of contents.
+func diffLinkFields(old Link, new Link) []string {
+	var fields []string
+	if old.Type != new.Type {
+		fields = append(fields, "type")
+	}
+	if old.Templated != new.Templated {
+		fields = append(fields, "templated")
+	}
+	if old.Title != new.Title {
+		fields = append(fields, "title")
+	}
+	if !sameStringSet(old.Rels, new.Rels) {
+		fields = append(fields, "rel")
+	}
+	if !reflect.DeepEqual(normalizeJSONValue(propertiesToJSON(old.Properties)), normalizeJSONValue(propertiesToJSON(new.Properties))) {
+		fields = append(fields, "properties")
+	}
+	if old.Height != new.Height {
+		fields = append(fields, "height")
+	}
+	if old.Width != new.Width {
+		fields = append(fields, "width")
+	}
+	if old.Bitrate != new.Bitrate {
+		fields = append(fields, "bitrate")
+	}
+	if old.Duration != new.Duration {
+		fields = append(fields, "duration")
+	}
+	if !sameStringSet(old.Languages, new.Languages) {
+		fields = append(fields, "language")
+	}
+	if len(diffLinkList("alternate", old.Alternates, new.Alternates, false)) > 0 {
+		fields = append(fields, "alternate")
+	}
+	return fields
+}
+
+func propertiesToJSON(p Properties) interface{} {
+	if len(p) == 0 {
+		return nil
+	}
+	var res interface{}
+	bin, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	json.Unmarshal(bin, &res)
+	return res
+}
+
+func sameStringSet(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]int, len(a))
+	for _, v := range a {
+		set[v]++
+	}
+	for _, v := range b {
+		set[v]--
+		if set[v] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Computes a longest common subsequence of two lists of unique keys, returned as a set.
+func longestCommonSubsequence(a []string, b []string) map[string]struct{} {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	res := make(map[string]struct{}, lengths[0][0])
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		if a[i] == b[j] {
+			res[a[i]] = struct{}{}
+			i++
+			j++
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return res
+}
+
+// Entry of a flattened table of contents.
+type tocEntry struct {
+	key    string
+	link   Link
+	parent string // Key of the parent entry, empty for a root entry.
+}
+
+// Flattens a table of contents in document order. Entries are identified by their HREF, suffixed with
+// their occurrence count when the same HREF is used multiple times.
+func flattenTableOfContents(toc LinkList) []tocEntry {
+	var entries []tocEntry
+	occurrences := map[string]int{}
+
+	var flatten func(links LinkList, parent string)
+	flatten = func(links LinkList, parent string) {
+		for _, l := range links {
+			key := occurrenceKey(occurrences, l.Href)
+			entries = append(entries, tocEntry{key: key, link: l, parent: parent})
+			flatten(l.Children, key)
+		}
+	}
+	flatten(toc, "")
+	return entries
+}
+
+func diffTableOfContents(old LinkList, new LinkList) []LinkChange {
+	const collection = "toc"
+	oldEntries := flattenTableOfContents(old)
+	newEntries := flattenTableOfContents(new)
+
+	oldByKey := make(map[string]tocEntry, len(oldEntries))
+	for _, e := range oldEntries {
+		oldByKey[e.key] = e
+	}
+	newByKey := make(map[string]tocEntry, len(newEntries))
+	for _, e := range newEntries {
+		newByKey[e.key] = e
+	}
+	parentHref := func(entries map[string]tocEntry, key string) string {
+		if e, ok := entries[key]; ok {
+			return e.link.Href
+		}
+		return ""
+	}
+
+	var changes []LinkChange
+	for _, e := range oldEntries {
+		if _, ok := newByKey[e.key]; !ok {
+			ol := e.link
+			ol.Children = nil
+			changes = append(changes, LinkChange{Kind: ChangeRemoved, Collection: collection, Href: ol.Href, Old: &ol})
+		}
+	}
+
+	// Entries which kept the same parent are reported as moved when their order among their siblings changed.
+	oldSiblings := map[string][]string{}
+	for _, e := range oldEntries {
+		if ne, ok := newByKey[e.key]; ok && ne.parent == e.parent {
+			oldSiblings[e.parent] = append(oldSiblings[e.parent], e.key)
+		}
+	}
+	newSiblings := map[string][]string{}
+	for _, e := range newEntries {
+		if oe, ok := oldByKey[e.key]; ok && oe.parent == e.parent {
+			newSiblings[e.parent] = append(newSiblings[e.parent], e.key)
+		}
+	}
+	stable := map[string]struct{}{}
+	for parent, keys := range newSiblings {
+		for key := range longestCommonSubsequence(oldSiblings[parent], keys) {
+			stable[key] = struct{}{}
+		}
+	}
+
+	for _, e := range newEntries {
+		nl := e.link
+		nl.Children = nil
+		oe, ok := oldByKey[e.key]
+		if !ok {
+			changes = append(changes, LinkChange{Kind: ChangeAdded, Collection: collection, Href: nl.Href, New: &nl})
+			continue
+		}
+		ol := oe.link
+		ol.Children = nil
+		if fields := diffLinkFields(ol, nl); len(fields) > 0 {
+			changes = append(changes, LinkChange{Kind: ChangeModified, Collection: collection, Href: nl.Href, Fields: fields, Old: &ol, New: &nl})
+		}
+		_, inOrder := stable[e.key]
+		if oe.parent != e.parent || !inOrder {
+			changes = append(changes, LinkChange{
+				Kind:       ChangeMoved,
+				Collection: collection,
+				Href:       nl.Href,
+				Old:        &ol,
+				New:        &nl,
+				OldParent:  parentHref(oldByKey, oe.parent),
+				NewParent:  parentHref(newByKey, e.parent),
+			})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,204 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffIdenticalManifests(t *testing.T) {
+	m := Manifest{
+		Metadata: Metadata{
+			LocalizedTitle: NewLocalizedStringFromString("Title"),
+			Languages:      Strings{"en", "fr"},
+		},
+		ReadingOrder: LinkList{{Href: "/chap1.html"}, {Href: "/chap2.html"}},
+	}
+	other := Manifest{
+		Metadata: Metadata{
+			LocalizedTitle: NewLocalizedStringFromString("Title"),
+			Languages:      Strings{"en", "fr"},
+		},
+		ReadingOrder: LinkList{{Href: "chap1.html"}, {Href: "chap2.html"}},
+	}
+	assert.True(t, Diff(m, other).IsEmpty())
+}
+
+func TestDiffMetadataOrder(t *testing.T) {
+	diff := Diff(
+		Manifest{Metadata: Metadata{
+			Languages: Strings{"en", "fr"},
+			Authors: Contributors{
+				{LocalizedName: NewLocalizedStringFromString("First")},
+				{LocalizedName: NewLocalizedStringFromString("Second")},
+			},
+			Publishers: Contributors{{LocalizedName: NewLocalizedStringFromString("Publisher")}},
+		}},
+		Manifest{Metadata: Metadata{
+			Languages: Strings{"fr", "en"},
+			Authors: Contributors{
+				{LocalizedName: NewLocalizedStringFromString("Second")},
+				{LocalizedName: NewLocalizedStringFromString("First")},
+			},
+			Publishers: Contributors{
+				{LocalizedName: NewLocalizedStringFromString("Publisher")},
+				{LocalizedName: NewLocalizedStringFromString("Imprint")},
+			},
+		}},
+	)
+	assert.Equal(t, []MetadataChange{
+		{Kind: ChangeMoved, Field: "author", Old: []interface{}{"First", "Second"}, New: []interface{}{"Second", "First"}},
+		{Kind: ChangeMoved, Field: "language", Old: []interface{}{"en", "fr"}, New: []interface{}{"fr", "en"}},
+		{Kind: ChangeModified, Field: "publisher", Old: "Publisher", New: []interface{}{"Publisher", "Imprint"}},
+	}, diff.Metadata)
+}
+
+func TestDiffMetadataShorthand(t *testing.T) {
+	assert.Nil(t, diffJSONField("language", map[string]interface{}{"language": "en"}, map[string]interface{}{"language": []interface{}{"en"}}))
+	assert.Equal(t, []MetadataChange{{
+		Kind: ChangeModified, Field: "author",
+		Old: map[string]interface{}{"name": "A"}, New: []interface{}{map[string]interface{}{"name": "A", "role": "x"}},
+	}}, diffJSONField("author",
+		map[string]interface{}{"author": map[string]interface{}{"name": "A"}},
+		map[string]interface{}{"author": []interface{}{map[string]interface{}{"name": "A", "role": "x"}}},
+	))
+}
+
+func TestDiffLocalizedTitle(t *testing.T) {
+	diff := Diff(
+		Manifest{Metadata: Metadata{LocalizedTitle: NewLocalizedStringFromStrings(map[string]string{
+			"en": "Title", "fr": "Titre",
+		})}},
+		Manifest{Metadata: Metadata{LocalizedTitle: NewLocalizedStringFromStrings(map[string]string{
+			"en": "New title", "de": "Titel",
+		})}},
+	)
+	assert.Equal(t, []MetadataChange{
+		{Kind: ChangeAdded, Field: "title", Language: "de", New: "Titel"},
+		{Kind: ChangeModified, Field: "title", Language: "en", Old: "Title", New: "New title"},
+		{Kind: ChangeRemoved, Field: "title", Language: "fr", Old: "Titre"},
+	}, diff.Metadata)
+}
+
+func TestDiffMetadataFields(t *testing.T) {
+	diff := Diff(
+		Manifest{Metadata: Metadata{
+			LocalizedTitle: NewLocalizedStringFromString("Title"),
+			Authors:        Contributors{{LocalizedName: NewLocalizedStringFromString("Author")}},
+			Description:    "Description",
+		}},
+		Manifest{Metadata: Metadata{
+			LocalizedTitle: NewLocalizedStringFromString("Title"),
+			Authors:        Contributors{{LocalizedName: NewLocalizedStringFromString("Other author")}},
+			Identifier:     "urn:isbn:9780000000000",
+		}},
+	)
+	assert.Equal(t, []MetadataChange{
+		{Kind: ChangeModified, Field: "author", Old: "Author", New: "Other author"},
+		{Kind: ChangeRemoved, Field: "description", Old: "Description"},
+		{Kind: ChangeAdded, Field: "identifier", New: "urn:isbn:9780000000000"},
+	}, diff.Metadata)
+}
+
+func TestDiffLinks(t *testing.T) {
+	diff := Diff(
+		Manifest{
+			ReadingOrder: LinkList{{Href: "/chap1.html"}, {Href: "/chap2.html"}, {Href: "/chap3.html"}},
+			Resources:    LinkList{{Href: "/style.css", Type: "text/css"}, {Href: "/cover.jpg"}},
+		},
+		Manifest{
+			ReadingOrder: LinkList{{Href: "/chap2.html"}, {Href: "/chap1.html"}, {Href: "/chap4.html"}},
+			Resources:    LinkList{{Href: "/style.css", Type: "text/css", Rels: Strings{"stylesheet"}}},
+		},
+	)
+	assert.Equal(t, []LinkChange{
+		{Kind: ChangeRemoved, Collection: "readingOrder", Href: "/chap3.html", Old: &Link{Href: "/chap3.html"}},
+		{Kind: ChangeAdded, Collection: "readingOrder", Href: "/chap4.html", New: &Link{Href: "/chap4.html"}},
+		{Kind: ChangeMoved, Collection: "readingOrder", Href: "/chap1.html", Old: &Link{Href: "/chap1.html"}, New: &Link{Href: "/chap1.html"}},
+		{Kind: ChangeRemoved, Collection: "resources", Href: "/cover.jpg", Old: &Link{Href: "/cover.jpg"}},
+		{
+			Kind: ChangeModified, Collection: "resources", Href: "/style.css", Fields: []string{"rel"},
+			Old: &Link{Href: "/style.css", Type: "text/css"},
+			New: &Link{Href: "/style.css", Type: "text/css", Rels: Strings{"stylesheet"}},
+		},
+	}, diff.Links)
+}
+
+func TestDiffTableOfContentsMoves(t *testing.T) {
+	diff := Diff(
+		Manifest{TableOfContents: LinkList{
+			{Href: "/part1.html", Title: "Part 1", Children: LinkList{
+				{Href: "/chap1.html", Title: "Chapter 1"},
+				{Href: "/chap2.html", Title: "Chapter 2"},
+			}},
+			{Href: "/part2.html", Title: "Part 2"},
+		}},
+		Manifest{TableOfContents: LinkList{
+			{Href: "/part1.html", Title: "Part 1", Children: LinkList{
+				{Href: "/chap1.html", Title: "Chapter One"},
+			}},
+			{Href: "/part2.html", Title: "Part 2", Children: LinkList{
+				{Href: "/chap2.html", Title: "Chapter 2"},
+			}},
+		}},
+	)
+	assert.Equal(t, []LinkChange{
+		{
+			Kind: ChangeModified, Collection: "toc", Href: "/chap1.html", Fields: []string{"title"},
+			Old: &Link{Href: "/chap1.html", Title: "Chapter 1"},
+			New: &Link{Href: "/chap1.html", Title: "Chapter One"},
+		},
+		{
+			Kind: ChangeMoved, Collection: "toc", Href: "/chap2.html",
+			Old:       &Link{Href: "/chap2.html", Title: "Chapter 2"},
+			New:       &Link{Href: "/chap2.html", Title: "Chapter 2"},
+			OldParent: "/part1.html",
+			NewParent: "/part2.html",
+		},
+	}, diff.Links)
+}
+
+func TestDiffDuplicateHrefs(t *testing.T) {
+	diff := Diff(
+		Manifest{ReadingOrder: LinkList{{Href: "/a.html"}, {Href: "/b.html"}, {Href: "/a.html", Title: "Again"}}},
+		Manifest{ReadingOrder: LinkList{{Href: "/a.html"}, {Href: "/b.html"}, {Href: "/a.html", Title: "Once more"}, {Href: "/a.html"}}},
+	)
+	assert.Equal(t, []LinkChange{
+		{
+			Kind: ChangeModified, Collection: "readingOrder", Href: "/a.html", Fields: []string{"title"},
+			Old: &Link{Href: "/a.html", Title: "Again"},
+			New: &Link{Href: "/a.html", Title: "Once more"},
+		},
+		{Kind: ChangeAdded, Collection: "readingOrder", Href: "/a.html", New: &Link{Href: "/a.html"}},
+	}, diff.Links)
+}
+
+func TestDiffSubcollections(t *testing.T) {
+	diff := Diff(
+		Manifest{Subcollections: PublicationCollectionMap{
+			"pageList": {{Links: LinkList{{Href: "/chap1.html#p1", Title: "1"}, {Href: "/chap1.html#p2", Title: "2"}}}},
+			"landmarks": {{
+				Metadata: map[string]interface{}{"title": "Landmarks"},
+				Links:    LinkList{{Href: "/cover.html"}},
+			}},
+		}},
+		Manifest{Subcollections: PublicationCollectionMap{
+			"pageList": {{Links: LinkList{{Href: "/chap1.html#p2", Title: "2"}, {Href: "/chap1.html#p1", Title: "1"}}}},
+			"landmarks": {{
+				Metadata: map[string]interface{}{"title": "Guide"},
+				Links:    LinkList{{Href: "/cover.html"}, {Href: "/toc.html"}},
+			}},
+		}},
+	)
+	assert.Equal(t, []MetadataChange{
+		{Kind: ChangeModified, Collection: "landmarks", Field: "title", Old: "Landmarks", New: "Guide"},
+	}, diff.Metadata)
+	assert.Equal(t, []LinkChange{
+		{Kind: ChangeAdded, Collection: "landmarks", Href: "/toc.html", New: &Link{Href: "/toc.html"}},
+		{
+			Kind: ChangeMoved, Collection: "pageList", Href: "/chap1.html#p1",
+			Old: &Link{Href: "/chap1.html#p1", Title: "1"},
+			New: &Link{Href: "/chap1.html#p1", Title: "1"},
+		},
+	}, diff.Links)
+}
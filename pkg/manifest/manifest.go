@@ -33,9 +33,9 @@ func (m Manifest) ConformsTo(profile Profile) bool {
 
 	switch profile {
 	case ProfileAudiobook:
-		return m.Links.AllAreAudio()
+		return m.ReadingOrder.AllAreAudio()
 	case ProfileDivina:
-		return m.Links.AllAreBitmap()
+		return m.ReadingOrder.AllAreBitmap()
 	case ProfileEPUB:
 		// EPUB needs to be explicitly indicated in `conformsTo`, otherwise it could be a regular Web Publication.
 		for _, v := range m.Metadata.ConformsTo {
@@ -44,7 +44,7 @@ func (m Manifest) ConformsTo(profile Profile) bool {
 			}
 		}
 	case ProfilePDF:
-		return m.Links.AllMatchMediaType(&mediatype.PDF)
+		return m.ReadingOrder.AllMatchMediaType(&mediatype.PDF)
 	default:
 		for _, v := range m.Metadata.ConformsTo {
 			if v == profile {
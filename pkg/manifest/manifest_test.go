@@ -350,3 +350,23 @@ func TestManifestHrefResolvedToRootRemotePackage(t *testing.T) {
 
 	assert.Equal(t, "http://example.com/directory/chap1.html", m.ReadingOrder[0].Href)
 }
+
+func TestManifestConformsToProfileFromReadingOrder(t *testing.T) {
+	m := Manifest{
+		Links:        LinkList{{Href: "manifest.json", Rels: []string{"self"}, Type: "application/webpub+json"}},
+		ReadingOrder: LinkList{{Href: "t1.mp3", Type: "audio/mpeg"}, {Href: "t2.mp3", Type: "audio/mpeg"}},
+	}
+	assert.True(t, m.ConformsTo(ProfileAudiobook))
+	assert.False(t, m.ConformsTo(ProfileDivina))
+	assert.False(t, m.ConformsTo(ProfilePDF))
+
+	m.ReadingOrder = LinkList{{Href: "p1.jpg", Type: "image/jpeg"}}
+	assert.True(t, m.ConformsTo(ProfileDivina))
+	assert.False(t, m.ConformsTo(ProfileAudiobook))
+
+	m.ReadingOrder = LinkList{{Href: "doc.pdf", Type: "application/pdf"}}
+	assert.True(t, m.ConformsTo(ProfilePDF))
+
+	m.ReadingOrder = nil
+	assert.False(t, m.ConformsTo(ProfileAudiobook))
+}
@@ -23,6 +23,7 @@ type Presentation struct {
 	Overflow    *Overflow    `json:"overflow,omitempty"`    // Suggested method for handling overflow while displaying the linked resource.
 	Spread      *Spread      `json:"spread,omitempty"`      // Indicates the condition to be met for the linked resource to be rendered within a synthetic spread.
 	Layout      *EPUBLayout  `json:"layout,omitempty"`      // Hints how the layout of the resource should be presented (EPUB extension).
+	Viewport    *Viewport    `json:"viewport,omitempty"`    // Default viewport of the fixed-layout resources, which is the most common size of the resources (EPUB extension).
 }
 
 const PresentationDefaultClipped = false    // Default value for Presentation.Clipped
@@ -74,6 +75,12 @@ const (
 	EPUBLayoutReflowable EPUBLayout = "reflowable"
 )
 
+// Viewport is the initial containing block of a fixed-layout resource, in CSS pixels.
+type Viewport struct {
+	Width  uint `json:"width"`
+	Height uint `json:"height"`
+}
+
 func (p *Presentation) setDefaults() {
 	if p.Fit == nil {
 		def := FitContain // Default value for [Fit], if not specified.
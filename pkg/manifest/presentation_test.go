@@ -22,7 +22,8 @@ func TestPresentationMarshalFullJSON(t *testing.T) {
 		"orientation": "landscape",
 		"overflow": "paginated",
 		"spread": "both",
-		"layout": "fixed"
+		"layout": "fixed",
+		"viewport": {"width": 1200, "height": 1600}
 	}`), &p))
 	assert.Equal(t, Presentation{
 		Clipped:     newBool(true),
@@ -32,6 +33,7 @@ func TestPresentationMarshalFullJSON(t *testing.T) {
 		Overflow:    (*Overflow)(newString("paginated")),
 		Spread:      (*Spread)(newString("both")),
 		Layout:      (*EPUBLayout)(newString("fixed")),
+		Viewport:    &Viewport{Width: 1200, Height: 1600},
 	}, p, "Presentation should be equal to given JSON")
 }
 
@@ -50,6 +52,7 @@ func TestPresentationFullJSON(t *testing.T) {
 		Overflow:    (*Overflow)(newString("paginated")),
 		Spread:      (*Spread)(newString("both")),
 		Layout:      (*EPUBLayout)(newString("fixed")),
+		Viewport:    &Viewport{Width: 1200, Height: 1600},
 	})
 	assert.NoError(t, err)
 	assert.JSONEq(t, `{
@@ -58,6 +61,7 @@ func TestPresentationFullJSON(t *testing.T) {
 		"orientation": "landscape",
 		"overflow": "paginated",
 		"spread": "both",
-		"layout": "fixed"
+		"layout": "fixed",
+		"viewport": {"width": 1200, "height": 1600}
 	}`, string(p), "JSON of Presentation should be equal to JSON representation")
 }
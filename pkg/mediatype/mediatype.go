@@ -110,6 +110,11 @@ func NewOfString(str string) (MediaType, error) {
 	return New(str, "", "")
 }
 
+// The default file extension for this media type, e.g. `epub` for `application/epub+zip`, if it is known.
+func (mt MediaType) FileExtension() string {
+	return mt.fileExtension
+}
+
 // Structured syntax suffix, e.g. `+zip` in `application/epub+zip`.
 //
 // Gives a hint on the underlying structure of this media type.
@@ -246,7 +251,7 @@ func (mt MediaType) MatchesFromString(other ...string) bool {
 
 // Returns whether this media type is structured as a ZIP archive.
 func (mt MediaType) IsZIP() bool {
-	return mt.Matches(&ZIP, &LCPProtectedAudiobook, &LCPProtectedPDF) ||
+	return mt.Matches(&ZIP, &LCPProtectedAudiobook, &LCPProtectedPDF, &FB2ZIP) ||
 		mt.StructuredSyntaxSuffix() == "+zip"
 }
 
@@ -293,7 +298,7 @@ func (mt MediaType) IsRwpm() bool {
 // Returns whether this media type is of a publication file.
 func (mt MediaType) IsPublication() bool {
 	return mt.Matches(
-		&ReadiumAudiobook, &ReadiumAudiobookManifest, &CBZ, &Divina, &DivinaManifest, &EPUB, &LCPProtectedAudiobook,
-		&LCPProtectedPDF, &LPF, &PDF, &W3CWPUBManifest, &ReadiumWebpub, &ReadiumWebpubManifest, &ZAB,
+		&ReadiumAudiobook, &ReadiumAudiobookManifest, &AZW3, &CB7, &CBR, &CBZ, &DAISY, &Divina, &DivinaManifest, &EPUB, &FB2, &FB2ZIP,
+		&LCPProtectedAudiobook, &LCPProtectedPDF, &LPF, &MOBI, &PDF, &W3CWPUBManifest, &ReadiumWebpub, &ReadiumWebpubManifest, &ZAB,
 	)
 }
@@ -3,6 +3,7 @@ package mediatype
 import (
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // The default sniffers provided by Readium 2 to resolve a [MediaType].
@@ -10,7 +11,7 @@ import (
 // The sniffers order is important, because some formats are subsets of other formats.
 var Sniffers = []Sniffer{
 	SniffXHTML, SniffHTML, SniffOPDS, SniffLCPLicense, SniffBitmap,
-	SniffWebpub, SniffW3CWPUB, SniffEPUB, SniffLPF, SniffArchive, SniffPDF,
+	SniffWebpub, SniffW3CWPUB, SniffEPUB, SniffLPF, SniffFB2, SniffDAISY, SniffMOBI, SniffArchive, SniffPDF, SniffText,
 	// Note SniffSystem isn't here!
 }
 
@@ -20,14 +21,20 @@ var Sniffers = []Sniffer{
 // - Light Sniffing checks only the provided file extension or media type hints.
 // - Heavy Sniffing reads the bytes to perform more advanced sniffing.
 func of(content SnifferContent, mediaTypes []string, fileExtensions []string, sniffers []Sniffer) *MediaType {
+	return ofWithTrace(content, mediaTypes, fileExtensions, sniffers, nil)
+}
+
+// Same as [of], but records every sniffer run in [trace] when it is not nil.
+func ofWithTrace(content SnifferContent, mediaTypes []string, fileExtensions []string, sniffers []Sniffer, trace *SnifferTrace) *MediaType {
 
 	// Light sniffing with only media type hints
 	if len(mediaTypes) > 0 {
 		context := SnifferContext{
 			mediaTypes: mediaTypes,
+			trace:      trace,
 		}
 		for _, sniffer := range sniffers {
-			mediaType := sniffer(context)
+			mediaType := trace.run(SnifferRoundLightMediaTypes, sniffer, context)
 			if mediaType != nil {
 				return mediaType
 			}
@@ -39,9 +46,10 @@ func of(content SnifferContent, mediaTypes []string, fileExtensions []string, sn
 		context := SnifferContext{
 			mediaTypes:     mediaTypes,
 			fileExtensions: fileExtensions,
+			trace:          trace,
 		}
 		for _, sniffer := range sniffers {
-			mediaType := sniffer(context)
+			mediaType := trace.run(SnifferRoundLightExtensions, sniffer, context)
 			if mediaType != nil {
 				return mediaType
 			}
@@ -54,9 +62,10 @@ func of(content SnifferContent, mediaTypes []string, fileExtensions []string, sn
 			content:        content,
 			mediaTypes:     mediaTypes,
 			fileExtensions: fileExtensions,
+			trace:          trace,
 		}
 		for _, sniffer := range sniffers {
-			mediaType := sniffer(context)
+			mediaType := trace.run(SnifferRoundHeavy, sniffer, context)
 			if mediaType != nil {
 				return mediaType
 			}
@@ -71,18 +80,24 @@ func of(content SnifferContent, mediaTypes []string, fileExtensions []string, sn
 		content:        content,
 		mediaTypes:     mediaTypes,
 		fileExtensions: fileExtensions,
+		trace:          trace,
 	}
-	if c := SniffSystem(context); c != nil {
+	if c := trace.run(SnifferRoundSystem, SniffSystem, context); c != nil {
 		return c
 	}
 
 	// If nothing else worked, we try to parse the first valid media type hint.
+	if len(mediaTypes) > 0 {
+		trace.begin(SnifferRoundHintFallback, "")
+	}
 	for _, mediaType := range mediaTypes {
 		if mediaType == "" {
 			continue // Blank mediatype
 		}
 		mt, err := New(mediaType, "", "")
+		trace.record(err == nil, "media type hint %q is valid", mediaType)
 		if err == nil {
+			trace.end(&mt)
 			return &mt
 		}
 	}
@@ -111,19 +126,33 @@ func OfExtension(extension string) *MediaType {
 
 // Resolves a format from a file
 func OfFile(file *os.File, mediaTypes []string, extensions []string, sniffers []Sniffer) *MediaType {
+	return ofFile(file, mediaTypes, extensions, sniffers, nil)
+}
+
+// Resolves a format from a file, explaining how it was resolved in the returned [SnifferTrace].
+func OfFileWithTrace(file *os.File, mediaTypes []string, extensions []string, sniffers []Sniffer) (*MediaType, *SnifferTrace) {
+	trace := &SnifferTrace{}
+	return ofFile(file, mediaTypes, extensions, sniffers, trace), trace
+}
+
+func ofFile(file *os.File, mediaTypes []string, extensions []string, sniffers []Sniffer, trace *SnifferTrace) *MediaType {
 	if file != nil {
-		ext := filepath.Ext(file.Name())
-		if ext != "" {
-			ext = ext[1:] // Remove the leading "."
-			if extensions == nil {
-				extensions = []string{ext}
-			} else {
-				extensions = append(extensions, ext)
-			}
-		}
+		extensions = append(extensions, fileNameExtensions(file.Name())...)
 	}
+	return ofWithTrace(NewSnifferFileContent(file), mediaTypes, extensions, sniffers, trace)
+}
 
-	return of(NewSnifferFileContent(file), mediaTypes, extensions, sniffers)
+// Returns the extensions of a file name, starting with the compound extension of names such as "book.fb2.zip".
+func fileNameExtensions(name string) []string {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return nil
+	}
+	extensions := []string{ext[1:]} // Remove the leading "."
+	if inner := filepath.Ext(strings.TrimSuffix(filepath.Base(name), ext)); inner != "" {
+		extensions = append([]string{inner[1:] + ext}, extensions...)
+	}
+	return extensions
 }
 
 // Resolves a format from a file, and nothing else
@@ -133,10 +162,20 @@ func OfFileOnly(file *os.File) *MediaType {
 
 // Resolves a format from bytes, e.g. from an HTTP response.
 func OfBytes(bytes []byte, mediaTypes []string, extensions []string, sniffers []Sniffer) *MediaType {
-	return of(NewSnifferBytesContent(bytes), mediaTypes, extensions, sniffers)
+	return ofBytes(bytes, mediaTypes, extensions, sniffers, nil)
+}
+
+// Resolves a format from bytes, explaining how it was resolved in the returned [SnifferTrace].
+func OfBytesWithTrace(bytes []byte, mediaTypes []string, extensions []string, sniffers []Sniffer) (*MediaType, *SnifferTrace) {
+	trace := &SnifferTrace{}
+	return ofBytes(bytes, mediaTypes, extensions, sniffers, trace), trace
+}
+
+func ofBytes(bytes []byte, mediaTypes []string, extensions []string, sniffers []Sniffer, trace *SnifferTrace) *MediaType {
+	return ofWithTrace(NewSnifferBytesContent(bytes), mediaTypes, extensions, sniffers, trace)
 }
 
 // Resolves a format from bytes, e.g. from an HTTP response, and nothing else
 func OfBytesOnly(bytes []byte) *MediaType {
-	return of(NewSnifferBytesContent(bytes), nil, nil, Sniffers)
+	return OfBytes(bytes, nil, nil, Sniffers)
 }
@@ -1,11 +1,14 @@
 package mediatype
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"mime"
 	"path/filepath"
 	"strings"
 
+	"github.com/readium/go-toolkit/pkg/internal/bitmap"
 	"github.com/readium/go-toolkit/pkg/internal/extensions"
 )
 
@@ -47,7 +50,9 @@ func SniffHTML(context SnifferContext) *MediaType {
 		return nil
 	}
 	// Compare the lowercased first 15 characters with the target
-	if strings.ToLower(ts[:15]) == "<!doctype html>" {
+	isDoctype := strings.ToLower(ts[:15]) == "<!doctype html>"
+	context.Tracef(isDoctype, "content starts with <!DOCTYPE html>")
+	if isDoctype {
 		return &HTML
 	}
 
@@ -138,7 +143,28 @@ func SniffBitmap(context SnifferContext) *MediaType {
 		return &WEBP
 	}
 
-	// TODO read magic bytes?
+	if magic := context.Read(0, bitmap.MagicLength-1); magic != nil {
+		mediaType := bitmap.SniffMediaType(magic)
+		context.Tracef(mediaType != "", "content starts with the magic bytes of a bitmap")
+		switch mediaType {
+		case "image/avif":
+			return &AVIF
+		case "image/bmp":
+			return &BMP
+		case "image/gif":
+			return &GIF
+		case "image/jpeg":
+			return &JPEG
+		case "image/jxl":
+			return &JXL
+		case "image/png":
+			return &PNG
+		case "image/tiff":
+			return &TIFF
+		case "image/webp":
+			return &WEBP
+		}
+	}
 
 	return nil
 }
@@ -180,20 +206,22 @@ func SniffWebpub(context SnifferContext) *MediaType {
 	return nil
 }
 
-// Sniffs a W3C Web Publication Manifest.
+// Sniffs a W3C Web Publication Manifest, or its successor the W3C Publication Manifest.
 func SniffW3CWPUB(context SnifferContext) *MediaType {
 	if js := context.ContentAsJSON(); js != nil {
 		if ctx, ok := js["@context"]; ok {
-			if context, ok := ctx.([]interface{}); ok {
-				for _, v := range context {
+			if ctxs, ok := ctx.([]interface{}); ok {
+				for _, v := range ctxs {
 					if val, ok := v.(string); ok {
-						if val == "https://www.w3.org/ns/wp-context" {
+						if val == "https://www.w3.org/ns/wp-context" || val == "https://www.w3.org/ns/pub-context" {
+							context.Tracef(true, "JSON @context contains %q", val)
 							return &W3CWPUBManifest
 						}
 					}
 				}
 			}
 		}
+		context.Tracef(false, "JSON @context contains %q or %q", "https://www.w3.org/ns/wp-context", "https://www.w3.org/ns/pub-context")
 	}
 
 	return nil
@@ -207,7 +235,9 @@ func SniffEPUB(context SnifferContext) *MediaType {
 	}
 
 	if mimetype := context.ReadArchiveEntryAt("mimetype"); mimetype != nil {
-		if strings.TrimSpace(string(mimetype)) == "application/epub+zip" {
+		declared := strings.TrimSpace(string(mimetype))
+		context.Tracef(declared == "application/epub+zip", "archive entry \"mimetype\" declares %q", declared)
+		if declared == "application/epub+zip" {
 			return &EPUB
 		}
 	}
@@ -231,23 +261,117 @@ func SniffLPF(context SnifferContext) *MediaType {
 		var js map[string]interface{}
 		if err := json.Unmarshal(entry, &js); err == nil && js != nil {
 			if ctx, ok := js["@context"]; ok {
-				if context, ok := ctx.([]interface{}); ok {
-					for _, v := range context {
+				if ctxs, ok := ctx.([]interface{}); ok {
+					for _, v := range ctxs {
 						if val, ok := v.(string); ok {
 							if val == "https://www.w3.org/ns/pub-context" {
+								context.Tracef(true, "publication.json @context contains %q", val)
 								return &LPF
 							}
 						}
 					}
 				}
 			}
+			context.Tracef(false, "publication.json @context contains %q", "https://www.w3.org/ns/pub-context")
+		}
+	}
+
+	return nil
+}
+
+// Sniffs a FictionBook document, or a ZIP archive containing one.
+// Reference: http://www.fictionbook.org/index.php/Eng:XML_Schema_Fictionbook_2.1
+func SniffFB2(context SnifferContext) *MediaType {
+	if context.HasFileExtension("fb2") || context.HasMediaType("application/x-fictionbook+xml", "text/fb2+xml") {
+		return &FB2
+	}
+	if context.HasFileExtension("fbz", "fb2.zip") || context.HasMediaType("application/x-zip-compressed-fb2") {
+		return &FB2ZIP
+	}
+
+	// The root element is looked up in the first bytes rather than by parsing the document, as FB2 files are often
+	// encoded in a legacy charset which the XML decoder doesn't support.
+	if head := context.Read(0, 1023); head != nil {
+		isFB2 := bytes.Contains(head, []byte("<FictionBook"))
+		context.Tracef(isFB2, "content starts with a <FictionBook> root element")
+		if isFB2 {
+			return &FB2
+		}
+	}
+
+	if archive, err := context.ContentAsArchive(); err == nil && archive != nil {
+		var entries []string
+		for _, entry := range archive.Entries() {
+			if !extensions.IsHiddenOrThumbs(entry.Path()) {
+				entries = append(entries, entry.Path())
+			}
+		}
+		isFB2ZIP := len(entries) == 1 && strings.ToLower(filepath.Ext(entries[0])) == ".fb2"
+		context.Tracef(isFB2ZIP, "archive only contains a single FB2 document")
+		if isFB2ZIP {
+			return &FB2ZIP
+		}
+	}
+
+	return nil
+}
+
+// Sniffs a DAISY Digital Talking Book, either a DAISY 2.02 fileset (NCC) or a DAISY 3 one (OPF, NCX and SMIL).
+// Reference: https://daisy.org/activities/standards/daisy/
+func SniffDAISY(context SnifferContext) *MediaType {
+	if context.HasFileExtension("daisy") || context.HasMediaType("application/daisy+zip") {
+		return &DAISY
+	}
+
+	if archive, err := context.ContentAsArchive(); err == nil && archive != nil {
+		found := make(map[string]bool)
+		for _, entry := range archive.Entries() {
+			if extensions.IsHiddenOrThumbs(entry.Path()) {
+				continue
+			}
+			name := strings.ToLower(filepath.Base(entry.Path()))
+			if name == "ncc.html" || name == "ncc.htm" {
+				context.Tracef(true, "archive contains a DAISY 2.02 navigation control center %q", entry.Path())
+				return &DAISY
+			}
+			found[filepath.Ext(name)] = true
+		}
+		isDAISY3 := found[".opf"] && found[".ncx"] && found[".smil"]
+		context.Tracef(isDAISY3, "archive contains a DAISY 3 package, navigation control file and SMIL files")
+		if isDAISY3 {
+			return &DAISY
 		}
 	}
 
 	return nil
 }
 
-// Authorized extensions for resources in a CBZ archive.
+// Sniffs a Mobipocket (MOBI) or Kindle Format 8 (AZW3) e-book.
+// Reference: https://wiki.mobileread.com/wiki/MOBI
+func SniffMOBI(context SnifferContext) *MediaType {
+	if context.HasFileExtension("azw3") || context.HasMediaType("application/vnd.amazon.mobi8-ebook", "application/x-mobi8-ebook") {
+		return &AZW3
+	}
+	if context.HasFileExtension("mobi", "prc") || context.HasMediaType("application/x-mobipocket-ebook") {
+		return &MOBI
+	}
+
+	// The Palm Database header declares the type and creator of the file, followed by the offset of the first record
+	// which holds the MOBI header.
+	head := context.Read(0, 81)
+	if len(head) < 82 || string(head[60:68]) != "BOOKMOBI" {
+		return nil
+	}
+	context.Tracef(true, "content is a Palm Database with the BOOKMOBI type")
+	offset := int64(binary.BigEndian.Uint32(head[78:82]))
+	if version := context.Read(offset+36, offset+39); len(version) == 4 && binary.BigEndian.Uint32(version) == 8 {
+		context.Tracef(true, "MOBI header declares the KF8 file version")
+		return &AZW3
+	}
+	return &MOBI
+}
+
+// Authorized extensions for resources in a Comic Book Archive (CBZ, CBR or CB7).
 // Reference: https://wiki.mobileread.com/wiki/CBR_and_CBZ
 var cbz_extensions = map[string]struct{}{
 	"bmp": {}, "dib": {}, "gif": {}, "jif": {}, "jfi": {}, "jfif": {}, "jpg": {}, "jpeg": {}, "png": {}, "tif": {}, "tiff": {}, "webp": {}, // Bitmap. Note there's no AVIF or JXL
@@ -257,15 +381,21 @@ var cbz_extensions = map[string]struct{}{
 // Authorized extensions for resources in a ZAB archive (Zipped Audio Book).
 var zab_extensions = map[string]struct{}{
 	"aac": {}, "aiff": {}, "alac": {}, "flac": {}, "m4a": {}, "m4b": {}, "mp3": {}, "ogg": {}, "oga": {}, "mogg": {}, "opus": {}, "wav": {}, "webm": {}, // Audio
-	"asx": {}, "bio": {}, "m3u": {}, "m3u8": {}, "pla": {}, "pls": {}, "smil": {}, "vlc": {}, "wpl": {}, "xspf": {}, "zpl": {}, // Playlist
+	"asx": {}, "bio": {}, "cue": {}, "m3u": {}, "m3u8": {}, "pla": {}, "pls": {}, "smil": {}, "vlc": {}, "wpl": {}, "xspf": {}, "zpl": {}, // Playlist
 }
 
 // Sniffs a simple Archive-based format, like Comic Book Archive or Zipped Audio Book.
 // Reference: https://wiki.mobileread.com/wiki/CBR_and_CBZ
 func SniffArchive(context SnifferContext) *MediaType {
-	if context.HasFileExtension("cbz") || context.HasMediaType("application/vnd.comicbook+zip", "application/x-cbz", "application/x-cbr") {
+	if context.HasFileExtension("cbz") || context.HasMediaType("application/vnd.comicbook+zip", "application/x-cbz") {
 		return &CBZ
 	}
+	if context.HasFileExtension("cbr") || context.HasMediaType("application/vnd.comicbook-rar", "application/x-cbr") {
+		return &CBR
+	}
+	if context.HasFileExtension("cb7") || context.HasMediaType("application/x-cb7") {
+		return &CB7
+	}
 	if context.HasFileExtension("zab") {
 		return &ZAB
 	}
@@ -282,17 +412,35 @@ func SniffArchive(context SnifferContext) *MediaType {
 				}
 				_, contains := exts[fext]
 				if !contains { // File extension not it allowed extensions
+					if context.IsTracing() {
+						context.Tracef(false, "archive entry %q has an allowed extension", zf.Path())
+					}
 					return false
 				}
 			}
 			return true
 		}
 
-		if archiveContainsOnlyExtensions(cbz_extensions) {
-			return &CBZ
+		isComic := archiveContainsOnlyExtensions(cbz_extensions)
+		context.Tracef(isComic, "archive only contains bitmaps and comic metadata")
+		if isComic {
+			switch {
+			case hasMagicBytes(context, "Rar!\x1a\x07"):
+				return &CBR
+			case hasMagicBytes(context, "7z\xbc\xaf\x27\x1c"):
+				return &CB7
+			default:
+				return &CBZ
+			}
 		}
 
-		if archiveContainsOnlyExtensions(zab_extensions) {
+		// ZAB is only defined for ZIP archives, or their exploded directories.
+		if !context.isDirectory() && !hasMagicBytes(context, "PK\x03\x04") {
+			return nil
+		}
+		isZAB := archiveContainsOnlyExtensions(zab_extensions)
+		context.Tracef(isZAB, "archive only contains audio files and playlists")
+		if isZAB {
 			return &ZAB
 		}
 	}
@@ -300,19 +448,45 @@ func SniffArchive(context SnifferContext) *MediaType {
 	return nil
 }
 
+// Returns whether the content starts with the given magic bytes, identifying the archive format.
+func hasMagicBytes(context SnifferContext, magic string) bool {
+	matched := string(context.Read(0, int64(len(magic)-1))) == magic
+	if matched {
+		context.Tracef(true, "content starts with the magic bytes %q", magic)
+	}
+	return matched
+}
+
 // Sniffs a PDF document.
 // Reference: https://www.loc.gov/preservation/digital/formats/fdd/fdd000123.shtml
 func SniffPDF(context SnifferContext) *MediaType {
 	if context.HasFileExtension("pdf") || context.HasMediaType("application/pdf") {
 		return &PDF
 	}
-	if string(context.Read(0, 4)) == "%PDF-" {
+	magic := context.Read(0, 4)
+	if magic != nil {
+		context.Tracef(string(magic) == "%PDF-", "content starts with the magic bytes %q", "%PDF-")
+	}
+	if string(magic) == "%PDF-" {
 		return &PDF
 	}
 
 	return nil
 }
 
+// Sniffs a plain text or Markdown document.
+// Text documents can't be reliably recognized from their content, so only the hints are used.
+func SniffText(context SnifferContext) *MediaType {
+	if context.HasFileExtension("md", "markdown") || context.HasMediaType("text/markdown", "text/x-markdown") {
+		return &Markdown
+	}
+	if context.HasFileExtension("txt", "text") || context.HasMediaType("text/plain") {
+		return &Text
+	}
+
+	return nil
+}
+
 func SniffSystem(context SnifferContext) *MediaType {
 	for _, mt := range context.MediaTypes() {
 		mts := mt.String()
@@ -330,6 +504,7 @@ func SniffSystem(context SnifferContext) *MediaType {
 			exr = ".html" // Fix for Go's first html extension being .htm
 		}
 		if nmt, err := New(nm, "", exr[1:]); err == nil {
+			context.Tracef(true, "media type %q is registered in the system with extension %q", mts, exr)
 			return &nmt
 		}
 	}
@@ -337,6 +512,7 @@ func SniffSystem(context SnifferContext) *MediaType {
 	for _, ext := range context.FileExtensions() {
 		nm := mime.TypeByExtension("." + ext)
 		if nm == "" {
+			context.Tracef(false, "file extension %q is registered in the system", ext)
 			continue
 		}
 		exts, err := mime.ExtensionsByType(nm)
@@ -349,6 +525,7 @@ func SniffSystem(context SnifferContext) *MediaType {
 		}
 		nm = strings.TrimSuffix(nm, "; charset=utf-8") // Fix for Go assuming file's content is UTF-8
 		if nmt, err := New(nm, "", exr[1:]); err == nil {
+			context.Tracef(true, "file extension %q is registered in the system as %q", ext, nm)
 			return &nmt
 		}
 	}
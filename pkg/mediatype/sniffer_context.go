@@ -16,6 +16,7 @@ type SnifferContext struct {
 	content        SnifferContent // Underlying content holder.
 	mediaTypes     []string       // Media type hints.
 	fileExtensions []string       // File extension hints.
+	trace          *SnifferTrace  // Records what the sniffers examine, when debugging.
 
 	// Memoized data
 	_charset                encoding.Encoding
@@ -65,8 +66,27 @@ func (s *SnifferContext) Charset() encoding.Encoding {
 	return nil
 }
 
+// Returns whether the sniffing is being traced, to avoid building expensive explanations for nothing.
+func (s SnifferContext) IsTracing() bool {
+	return s.trace != nil
+}
+
+// Records an explanation of what the running sniffer examined, and whether it matched, when the sniffing is being traced.
+// Custom sniffers can use it to explain why they accepted or rejected a file.
+func (s SnifferContext) Tracef(matched bool, format string, args ...interface{}) {
+	s.trace.record(matched, format, args...)
+}
+
 // Returns whether this context has any of the given file extensions, ignoring case.
 func (s SnifferContext) HasFileExtension(fileExtensions ...string) bool {
+	matched := s.hasFileExtension(fileExtensions...)
+	if len(s.fileExtensions) > 0 {
+		s.Tracef(matched, "file extension %v is one of %v", s.fileExtensions, fileExtensions)
+	}
+	return matched
+}
+
+func (s SnifferContext) hasFileExtension(fileExtensions ...string) bool {
 	selfExtensions := s.FileExtensions()
 	for _, fileExtension := range fileExtensions {
 		lowerExt := strings.ToLower(fileExtension)
@@ -81,6 +101,14 @@ func (s SnifferContext) HasFileExtension(fileExtensions ...string) bool {
 
 // Returns whether this context has any of the given media type, ignoring case and extra parameters.
 func (s SnifferContext) HasMediaType(mediaTypes ...string) bool {
+	matched := s.hasMediaType(mediaTypes...)
+	if len(s.mediaTypes) > 0 {
+		s.Tracef(matched, "media type %v is one of %v", s.mediaTypes, mediaTypes)
+	}
+	return matched
+}
+
+func (s SnifferContext) hasMediaType(mediaTypes ...string) bool {
 	selfMediaTypes := s.MediaTypes()
 	for _, rmt := range mediaTypes {
 		nmt, err := NewOfString(rmt)
@@ -134,8 +162,10 @@ func (s SnifferContext) ContentAsXML() *XMLNode {
 		}
 		err := xml.NewDecoder(stream).Decode(&n)
 		if err != nil {
+			s.Tracef(false, "content is an XML document: %v", err)
 			return nil
 		}
+		s.Tracef(true, "content is an XML document with root element {%s}%s", n.XMLName.Space, n.XMLName.Local)
 		s._contentAsXML = &n
 	}
 	return s._contentAsXML
@@ -144,6 +174,18 @@ func (s SnifferContext) ContentAsXML() *XMLNode {
 // Content as an Archive instance.
 // Warning: Archive is only supported for a local file, for now.
 func (s *SnifferContext) ContentAsArchive() (archive.Archive, error) {
+	a, err := s.contentAsArchive()
+	if err != nil {
+		if s.content != nil {
+			s.Tracef(false, "content is an archive: %v", err)
+		}
+	} else {
+		s.Tracef(true, "content is an archive with %d entries", len(a.Entries()))
+	}
+	return a, err
+}
+
+func (s *SnifferContext) contentAsArchive() (archive.Archive, error) {
 	if !s._loadedContentAsArchive {
 		s._loadedContentAsArchive = true
 		switch s.content.(type) {
@@ -174,6 +216,16 @@ func (s *SnifferContext) ContentAsArchive() (archive.Archive, error) {
 	return s._contentAsArchive, nil
 }
 
+// Returns whether the content is a directory, such as an exploded archive.
+func (s SnifferContext) isDirectory() bool {
+	fc, ok := s.content.(SnifferFileContent)
+	if !ok {
+		return false
+	}
+	info, err := fc.file.Stat()
+	return err == nil && info.IsDir()
+}
+
 // Content parsed as generic JSON interface.
 func (s SnifferContext) ContentAsJSON() map[string]interface{} {
 	if !s._loadedContentAsJSON {
@@ -185,6 +237,7 @@ func (s SnifferContext) ContentAsJSON() map[string]interface{} {
 		var jd map[string]interface{}
 		err := json.NewDecoder(stream).Decode(&jd)
 		if err != nil {
+			s.Tracef(false, "content is a JSON object: %v", err)
 			return nil
 		}
 		s._contentAsJSON = jd
@@ -253,39 +306,52 @@ func (s SnifferContext) ContainsJSONKeys(keys ...string) bool {
 	for _, key := range keys {
 		_, ok := js[key]
 		if !ok {
+			s.Tracef(false, "JSON object contains the keys %v: missing %q", keys, key)
 			return false
 		}
 	}
+	s.Tracef(true, "JSON object contains the keys %v", keys)
 	return true
 }
 
 // Returns whether an Archive entry exists in this file.
 func (s SnifferContext) ContainsArchiveEntryAt(path string) bool {
-	a, err := s.ContentAsArchive()
+	a, err := s.contentAsArchive()
 	if err != nil {
+		if s.content != nil {
+			s.Tracef(false, "archive contains the entry %q: content is not an archive: %v", path, err)
+		}
 		return false
 	}
 	_, err = a.Entry(path)
 	if err != nil {
+		s.Tracef(false, "archive contains the entry %q", path)
 		return false
 	}
+	s.Tracef(true, "archive contains the entry %q", path)
 	return true
 }
 
 // Returns the Archive entry data at the given [path] in this file.
 func (s SnifferContext) ReadArchiveEntryAt(path string) []byte {
-	a, err := s.ContentAsArchive()
+	a, err := s.contentAsArchive()
 	if err != nil {
+		if s.content != nil {
+			s.Tracef(false, "archive contains the entry %q: content is not an archive: %v", path, err)
+		}
 		return nil
 	}
 	f, err := a.Entry(path)
 	if err != nil {
+		s.Tracef(false, "archive contains the entry %q", path)
 		return nil
 	}
 	data, err := f.Read(0, 0)
 	if err != nil {
+		s.Tracef(false, "archive entry %q is readable: %v", path, err)
 		return nil
 	}
+	s.Tracef(true, "archive contains the entry %q", path)
 	return data
 }
 
@@ -115,7 +115,6 @@ func TestSniffCBZ(t *testing.T) {
 	assert.Equal(t, &CBZ, OfExtension("cbz"))
 	assert.Equal(t, &CBZ, OfString("application/vnd.comicbook+zip"))
 	assert.Equal(t, &CBZ, OfString("application/x-cbz"))
-	assert.Equal(t, &CBZ, OfString("application/x-cbr"))
 
 	testCbz, err := os.Open(filepath.Join("testdata", "cbz.unknown"))
 	assert.NoError(t, err)
@@ -123,6 +122,27 @@ func TestSniffCBZ(t *testing.T) {
 	assert.Equal(t, &CBZ, OfFileOnly(testCbz))
 }
 
+func TestSniffCBR(t *testing.T) {
+	assert.Equal(t, &CBR, OfExtension("cbr"))
+	assert.Equal(t, &CBR, OfString("application/vnd.comicbook-rar"))
+	assert.Equal(t, &CBR, OfString("application/x-cbr"))
+
+	testCbr, err := os.Open(filepath.Join("testdata", "cbr.unknown"))
+	assert.NoError(t, err)
+	defer testCbr.Close()
+	assert.Equal(t, &CBR, OfFileOnly(testCbr))
+}
+
+func TestSniffCB7(t *testing.T) {
+	assert.Equal(t, &CB7, OfExtension("cb7"))
+	assert.Equal(t, &CB7, OfString("application/x-cb7"))
+
+	testCb7, err := os.Open(filepath.Join("testdata", "cb7.unknown"))
+	assert.NoError(t, err)
+	defer testCb7.Close()
+	assert.Equal(t, &CB7, OfFileOnly(testCb7))
+}
+
 func TestSniffDiViNa(t *testing.T) {
 	assert.Equal(t, &Divina, OfExtension("divina"))
 	assert.Equal(t, &Divina, OfString("application/divina+zip"))
@@ -146,6 +166,64 @@ func TestSniffEPUB(t *testing.T) {
 	assert.Equal(t, &EPUB, OfFileOnly(testEpub))
 }
 
+func TestSniffDAISY(t *testing.T) {
+	assert.Equal(t, &DAISY, OfExtension("daisy"))
+	assert.Equal(t, &DAISY, OfString("application/daisy+zip"))
+
+	testDAISY2, err := os.Open(filepath.Join("testdata", "daisy2.unknown"))
+	assert.NoError(t, err)
+	defer testDAISY2.Close()
+	assert.Equal(t, &DAISY, OfFileOnly(testDAISY2))
+
+	testDAISY3, err := os.Open(filepath.Join("testdata", "daisy3.unknown"))
+	assert.NoError(t, err)
+	defer testDAISY3.Close()
+	assert.Equal(t, &DAISY, OfFileOnly(testDAISY3))
+}
+
+func TestSniffMOBI(t *testing.T) {
+	assert.Equal(t, &MOBI, OfExtension("mobi"))
+	assert.Equal(t, &MOBI, OfExtension("prc"))
+	assert.Equal(t, &MOBI, OfString("application/x-mobipocket-ebook"))
+	assert.Equal(t, &AZW3, OfExtension("azw3"))
+	assert.Equal(t, &AZW3, OfString("application/vnd.amazon.mobi8-ebook"))
+
+	testMOBI, err := os.Open(filepath.Join("testdata", "mobi.unknown"))
+	assert.NoError(t, err)
+	defer testMOBI.Close()
+	assert.Equal(t, &MOBI, OfFileOnly(testMOBI))
+
+	testAZW3, err := os.Open(filepath.Join("testdata", "azw3.unknown"))
+	assert.NoError(t, err)
+	defer testAZW3.Close()
+	assert.Equal(t, &AZW3, OfFileOnly(testAZW3))
+}
+
+func TestSniffFB2(t *testing.T) {
+	assert.Equal(t, &FB2, OfExtension("fb2"))
+	assert.Equal(t, &FB2, OfString("application/x-fictionbook+xml"))
+	assert.Equal(t, &FB2ZIP, OfExtension("fbz"))
+	assert.Equal(t, &FB2ZIP, OfExtension("fb2.zip"))
+	assert.Equal(t, &FB2ZIP, OfString("application/x-zip-compressed-fb2"))
+
+	testFB2, err := os.Open(filepath.Join("testdata", "fb2.unknown"))
+	assert.NoError(t, err)
+	defer testFB2.Close()
+	assert.Equal(t, &FB2, OfFileOnly(testFB2))
+
+	testFB2ZIP, err := os.Open(filepath.Join("testdata", "fb2-zip.unknown"))
+	assert.NoError(t, err)
+	defer testFB2ZIP.Close()
+	assert.Equal(t, &FB2ZIP, OfFileOnly(testFB2ZIP))
+
+	// The compound extension of the file name is used, even when the content can't be sniffed.
+	named, err := os.Create(filepath.Join(t.TempDir(), "book.fb2.zip"))
+	if assert.NoError(t, err) {
+		defer named.Close()
+		assert.Equal(t, &FB2ZIP, OfFileOnly(named))
+	}
+}
+
 func TestSniffGIF(t *testing.T) {
 	assert.Equal(t, &GIF, OfExtension("gif"))
 	assert.Equal(t, &GIF, OfString("image/gif"))
@@ -304,9 +382,31 @@ func TestSniffPDF(t *testing.T) {
 	assert.Equal(t, &PDF, OfFileOnly(testPDF))
 }
 
+func TestSniffText(t *testing.T) {
+	assert.Equal(t, &Text, OfExtension("txt"))
+	assert.Equal(t, &Text, OfString("text/plain"))
+	assert.Equal(t, &Text, OfString("text/plain; charset=utf-8"))
+	assert.Equal(t, &Markdown, OfExtension("md"))
+	assert.Equal(t, &Markdown, OfExtension("markdown"))
+	assert.Equal(t, &Markdown, OfString("text/markdown"))
+	assert.Equal(t, &Markdown, OfString("text/x-markdown"))
+}
+
 func TestSniffPNG(t *testing.T) {
 	assert.Equal(t, &PNG, OfExtension("png"))
 	assert.Equal(t, &PNG, OfString("image/png"))
+
+	testPNG, err := os.Open(filepath.Join("testdata", "png.unknown"))
+	assert.NoError(t, err)
+	defer testPNG.Close()
+	assert.Equal(t, &PNG, OfFileOnly(testPNG))
+}
+
+func TestSniffBitmapMagicBytes(t *testing.T) {
+	assert.Equal(t, &GIF, OfBytesOnly([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00")))
+	assert.Equal(t, &JPEG, OfBytesOnly([]byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00")))
+	assert.Equal(t, &WEBP, OfBytesOnly([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")))
+	assert.Equal(t, &AVIF, OfBytesOnly([]byte("\x00\x00\x00\x1Cftypmif1\x00\x00\x00\x00mif1avifmiaf")))
 }
 
 func TestSniffTIFF(t *testing.T) {
@@ -341,6 +441,11 @@ func TestSniffW3CWPUBManifest(t *testing.T) {
 	assert.NoError(t, err)
 	defer testW3CWPUB.Close()
 	assert.Equal(t, &W3CWPUBManifest, OfFileOnly(testW3CWPUB))
+
+	testW3CPublication, err := os.Open(filepath.Join("testdata", "w3c-publication.json"))
+	assert.NoError(t, err)
+	defer testW3CPublication.Close()
+	assert.Equal(t, &W3CWPUBManifest, OfFileOnly(testW3CPublication))
 }
 
 func TestSniffZAB(t *testing.T) {
@@ -352,6 +457,13 @@ func TestSniffZAB(t *testing.T) {
 	assert.Equal(t, &ZAB, OfFileOnly(testZAB))
 }
 
+func TestSniffExplodedZAB(t *testing.T) {
+	testZAB, err := os.Open(filepath.Join("testdata", "zab-exploded"))
+	assert.NoError(t, err)
+	defer testZAB.Close()
+	assert.Equal(t, &ZAB, OfFileOnly(testZAB))
+}
+
 func TestSniffJSON(t *testing.T) {
 	assert.Equal(t, &JSON, OfString("application/json"))
 	assert.Equal(t, &JSON, OfString("application/json; charset=utf-8"))
@@ -386,3 +498,38 @@ func TestSniffSystemMediaTypesFromBytes(t *testing.T) {
 	assert.Equal(t, png, OfFileOnly(testPNG))
 }
 */
+
+func TestSnifferTraceExplainsDetection(t *testing.T) {
+	testCbz, err := os.Open(filepath.Join("testdata", "cbz.unknown"))
+	assert.NoError(t, err)
+	defer testCbz.Close()
+
+	mt, trace := OfFileWithTrace(testCbz, nil, nil, Sniffers)
+	assert.Equal(t, &CBZ, mt)
+	assert.Equal(t, &CBZ, trace.Result())
+	assert.Equal(t, OfFileOnly(testCbz), mt, "tracing doesn't change the result")
+
+	last := trace.Steps[len(trace.Steps)-1]
+	assert.Equal(t, SnifferRoundHeavy, last.Round)
+	assert.Equal(t, "SniffArchive", last.Sniffer)
+	assert.Contains(t, last.Checks, SnifferTraceItem{Check: "archive only contains bitmaps and comic metadata", Matched: true})
+
+	for _, step := range trace.Steps {
+		if step.Sniffer == "SniffEPUB" && step.Round == SnifferRoundHeavy {
+			assert.Nil(t, step.Result)
+			assert.Equal(t, []SnifferTraceItem{
+				{Check: "file extension [unknown] is one of [epub]", Matched: false},
+				{Check: `archive contains the entry "mimetype"`, Matched: false},
+			}, step.Checks)
+		}
+	}
+}
+
+func TestSnifferTraceLightSniffing(t *testing.T) {
+	mt, trace := OfBytesWithTrace(nil, []string{"application/epub+zip"}, nil, Sniffers)
+	assert.Equal(t, &EPUB, mt)
+	last := trace.Steps[len(trace.Steps)-1]
+	assert.Equal(t, SnifferRoundLightMediaTypes, last.Round)
+	assert.Equal(t, "SniffEPUB", last.Sniffer)
+	assert.Equal(t, &EPUB, last.Result)
+}
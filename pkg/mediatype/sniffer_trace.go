@@ -0,0 +1,102 @@
+package mediatype
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Rounds of the media type resolution, in the order they are run.
+const (
+	SnifferRoundLightMediaTypes = "light (media type hints)"
+	SnifferRoundLightExtensions = "light (media type and file extension hints)"
+	SnifferRoundHeavy           = "heavy (content)"
+	SnifferRoundSystem          = "system"
+	SnifferRoundHintFallback    = "media type hint fallback"
+)
+
+// Explains how a [MediaType] was resolved, for debugging misdetected files.
+// It holds every sniffer run, with what they examined and whether they accepted the file.
+type SnifferTrace struct {
+	Steps []SnifferTraceStep `json:"steps"`
+}
+
+// A single run of a sniffer during a given round of the resolution.
+type SnifferTraceStep struct {
+	Round   string             `json:"round"`
+	Sniffer string             `json:"sniffer"`
+	Checks  []SnifferTraceItem `json:"checks,omitempty"` // What was examined by the sniffer, in order.
+	Result  *MediaType         `json:"result,omitempty"` // Media type returned by the sniffer, when it accepted the file.
+}
+
+// Something examined by a sniffer, such as a file extension, a declared media type, an archive entry or JSON keys.
+type SnifferTraceItem struct {
+	Check   string `json:"check"`
+	Matched bool   `json:"matched"`
+}
+
+// Returns the media type finally resolved, if any.
+func (t *SnifferTrace) Result() *MediaType {
+	if t == nil {
+		return nil
+	}
+	for _, step := range t.Steps {
+		if step.Result != nil {
+			return step.Result
+		}
+	}
+	return nil
+}
+
+func (t *SnifferTrace) begin(round string, sniffer string) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, SnifferTraceStep{
+		Round:   round,
+		Sniffer: sniffer,
+	})
+}
+
+func (t *SnifferTrace) end(result *MediaType) {
+	if t == nil || len(t.Steps) == 0 {
+		return
+	}
+	t.Steps[len(t.Steps)-1].Result = result
+}
+
+func (t *SnifferTrace) record(matched bool, format string, args ...interface{}) {
+	if t == nil || len(t.Steps) == 0 {
+		return
+	}
+	step := &t.Steps[len(t.Steps)-1]
+	step.Checks = append(step.Checks, SnifferTraceItem{
+		Check:   fmt.Sprintf(format, args...),
+		Matched: matched,
+	})
+}
+
+// Runs a sniffer, recording its result in the trace.
+func (t *SnifferTrace) run(round string, sniffer Sniffer, context SnifferContext) *MediaType {
+	if t == nil {
+		return sniffer(context)
+	}
+	t.begin(round, snifferName(sniffer))
+	mediaType := sniffer(context)
+	t.end(mediaType)
+	return mediaType
+}
+
+// Name of a sniffer function, e.g. `SniffEPUB`.
+func snifferName(sniffer Sniffer) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(sniffer).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -6,12 +6,19 @@ var AIFF, _ = New("audio/aiff", "", "aiff")
 var AVI, _ = New("video/x-msvideo", "", "avi")
 var AVIF, _ = New("image/avif", "", "avif")
 var Binary, _ = New("application/octet-stream", "", "")
+var AZW3, _ = New("application/vnd.amazon.mobi8-ebook", "Kindle Format 8", "azw3")
 var BMP, _ = New("image/bmp", "Bitmap Image File", "bmp")
+var CB7, _ = New("application/x-cb7", "Comic Book Archive", "cb7")
+var CBR, _ = New("application/vnd.comicbook-rar", "Comic Book Archive", "cbr")
 var CBZ, _ = New("application/vnd.comicbook+zip", "Comic Book Archive", "cbz")
 var CSS, _ = New("text/css", "Cascading Style Sheets", "css")
+var DAISY, _ = New("application/daisy+zip", "DAISY Digital Talking Book", "daisy")
 var Divina, _ = New("application/divina+zip", "Digital Visual Narratives", "divina")
 var DivinaManifest, _ = New("application/divina+json", "Digital Visual Narratives", "json")
+var DTBook, _ = New("application/x-dtbook+xml", "DAISY Digital Talking Book XML", "xml")
 var EPUB, _ = New("application/epub+zip", "EPUB", "epub")
+var FB2, _ = New("application/x-fictionbook+xml", "FictionBook", "fb2")
+var FB2ZIP, _ = New("application/x-zip-compressed-fb2", "FictionBook", "fb2.zip")
 var GIF, _ = New("image/gif", "", "gif")
 var GZ, _ = New("application/gzip", "", "gz")
 var HTML, _ = New("text/html", "Hypertext Markup Language", "html")
@@ -24,6 +31,8 @@ var LCPProtectedAudiobook, _ = New("application/audiobook+lcp", "LCP Protected A
 var LCPProtectedPDF, _ = New("application/pdf+lcp", "LCP Protected PDF", "lcpdf")
 var LCPStatusDocument, _ = New("application/vnd.readium.license.status.v1.0+json", "LCP Status Document", "")
 var LPF, _ = New("application/lpf+zip", "Lightweight Packaging Format", "lpf")
+var Markdown, _ = New("text/markdown", "Markdown", "md")
+var MOBI, _ = New("application/x-mobipocket-ebook", "Mobipocket", "mobi")
 var MP3, _ = New("audio/mpeg", "", "mp3")
 var MPEG, _ = New("video/mpeg", "", "mpeg")
 var NCX, _ = New("application/x-dtbncx+xml", "Navigation Control File", "ncx")
@@ -34,6 +43,7 @@ var OPDS1Entry, _ = New("application/atom+xml;type=entry;profile=opds-catalog",
 var OPDS2, _ = New("application/opds+json", "", "")
 var OPDS2Publication, _ = New("application/opds-publication+json", "", "")
 var OPDSAuthentication, _ = New("application/opds-authentication+json", "", "")
+var OPF, _ = New("application/oebps-package+xml", "EPUB Package Document", "opf")
 var OPUS, _ = New("audio/opus", "", "opus")
 var OTF, _ = New("font/otf", "OpenType Font", "otf")
 var PDF, _ = New("application/pdf", "PDF", "pdf")
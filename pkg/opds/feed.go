@@ -0,0 +1,128 @@
+package opds
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Feed is an OPDS catalog feed, with its navigation links, publications, facets and groups.
+// Its JSON representation is an OPDS 2 feed.
+// https://drafts.opds.io/opds-2.0.html#2-collections
+type Feed struct {
+	Metadata     FeedMetadata
+	Links        manifest.LinkList
+	Facets       []Facet
+	Groups       []Group
+	Publications []manifest.Manifest
+	Navigation   manifest.LinkList
+}
+
+// FeedMetadata holds the metadata of a feed, facet or group.
+// https://drafts.opds.io/schema/feed-metadata.schema.json
+type FeedMetadata struct {
+	Identifier    string     `json:"identifier,omitempty"`
+	Type          string     `json:"@type,omitempty"`
+	Title         string     `json:"title"`
+	Subtitle      string     `json:"subtitle,omitempty"`
+	Modified      *time.Time `json:"modified,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	NumberOfItems *uint      `json:"numberOfItems,omitempty"`
+	ItemsPerPage  *uint      `json:"itemsPerPage,omitempty"`
+	CurrentPage   *uint      `json:"currentPage,omitempty"`
+}
+
+// Facet is a set of links used to filter or sort the feed, e.g. by genre or by language.
+type Facet struct {
+	Metadata FeedMetadata      `json:"metadata"`
+	Links    manifest.LinkList `json:"links"`
+}
+
+// Group is a named subset of the feed, e.g. the featured publications of a catalog.
+type Group struct {
+	Metadata     FeedMetadata
+	Links        manifest.LinkList
+	Publications []manifest.Manifest
+	Navigation   manifest.LinkList
+}
+
+// Finds the first [Link] with the given relation in the feed's links.
+func (f Feed) LinkWithRel(rel string) *manifest.Link {
+	for i, link := range f.Links {
+		for _, r := range link.Rels {
+			if r == rel {
+				return &f.Links[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (f Feed) MarshalJSON() ([]byte, error) {
+	res := map[string]interface{}{
+		"metadata": f.Metadata,
+		"links":    linksOrEmpty(f.Links),
+	}
+	if len(f.Facets) > 0 {
+		res["facets"] = f.Facets
+	}
+	if len(f.Groups) > 0 {
+		res["groups"] = f.Groups
+	}
+	if len(f.Publications) > 0 {
+		res["publications"] = publicationsToJSON(f.Publications)
+	}
+	if len(f.Navigation) > 0 {
+		res["navigation"] = f.Navigation
+	}
+	return json.Marshal(res)
+}
+
+func (g Group) MarshalJSON() ([]byte, error) {
+	res := map[string]interface{}{
+		"metadata": g.Metadata,
+	}
+	if len(g.Links) > 0 {
+		res["links"] = g.Links
+	}
+	if len(g.Publications) > 0 {
+		res["publications"] = publicationsToJSON(g.Publications)
+	}
+	if len(g.Navigation) > 0 {
+		res["navigation"] = g.Navigation
+	}
+	return json.Marshal(res)
+}
+
+// PublicationJSON returns the OPDS 2 JSON representation of a publication, e.g. parsed from an OPDS 1 entry.
+func PublicationJSON(publication manifest.Manifest) ([]byte, error) {
+	return json.Marshal(publicationToMap(publication))
+}
+
+func publicationsToJSON(publications []manifest.Manifest) []map[string]interface{} {
+	res := make([]map[string]interface{}, 0, len(publications))
+	for _, p := range publications {
+		res = append(res, publicationToMap(p))
+	}
+	return res
+}
+
+// OPDS 2 publications are manifests without a reading order, whose covers are in the images collection.
+// https://drafts.opds.io/schema/publication.schema.json
+func publicationToMap(publication manifest.Manifest) map[string]interface{} {
+	m := publication.ToMap(nil)
+	delete(m, "@context")
+	if len(publication.ReadingOrder) == 0 {
+		delete(m, "readingOrder")
+	}
+	m["links"] = linksOrEmpty(publication.Links)
+	return m
+}
+
+func linksOrEmpty(links manifest.LinkList) manifest.LinkList {
+	if links == nil {
+		return manifest.LinkList{}
+	}
+	return links
+}
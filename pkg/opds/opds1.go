@@ -0,0 +1,426 @@
+package opds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// Namespaces
+const (
+	NamespaceAtom       = "http://www.w3.org/2005/Atom"
+	NamespaceOPDS       = "http://opds-spec.org/2010/catalog"
+	NamespaceDC         = "http://purl.org/dc/elements/1.1/"
+	NamespaceDCTerms    = "http://purl.org/dc/terms/"
+	NamespaceOpenSearch = "http://a9.com/-/spec/opensearch/1.1/"
+	NamespaceThread     = "http://purl.org/syndication/thread/1.0"
+)
+
+// Relations of the OPDS 1 links.
+// https://specs.opds.io/opds-1.2#4-opds-catalog-entry-documents
+const (
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelFacet       = "http://opds-spec.org/facet"
+	RelGroup       = "http://opds-spec.org/group"
+)
+
+// Relations of the links to the cover and thumbnail of a publication, stored in its images collection.
+var imageRels = []string{
+	"http://opds-spec.org/image",
+	"http://opds-spec.org/image/thumbnail",
+	"http://opds-spec.org/cover",
+	"http://opds-spec.org/thumbnail",
+	"x-stanza-cover-image",
+	"x-stanza-cover-image-thumbnail",
+}
+
+// ParseOPDS1 parses an OPDS 1.x Atom feed. Relative links are resolved against the [baseURL] of the feed, when
+// given.
+//
+// Entries with acquisition links become publications, while the other ones are navigation links. Entries linked
+// to a group are gathered into this group, and facet links are gathered by facet group.
+// The search link still targets the OpenSearch description, see [ParseOpenSearchDescription].
+// Reference: https://specs.opds.io/opds-1.2
+func ParseOPDS1(data []byte, baseURL string) (*Feed, error) {
+	root, err := parseXML(data, "feed")
+	if err != nil {
+		return nil, err
+	}
+	p := opds1Parser{baseURL: baseURL}
+
+	feed := &Feed{Metadata: FeedMetadata{
+		Identifier: p.text(root, NamespaceAtom, "id"),
+		Title:      p.text(root, NamespaceAtom, "title"),
+		Subtitle:   p.text(root, NamespaceAtom, "subtitle"),
+		Modified:   extensions.ParseDate(p.text(root, NamespaceAtom, "updated")),
+	}}
+	feed.Metadata.NumberOfItems = p.uint(root, NamespaceOpenSearch, "totalResults")
+	feed.Metadata.ItemsPerPage = p.uint(root, NamespaceOpenSearch, "itemsPerPage")
+	if start := p.uint(root, NamespaceOpenSearch, "startIndex"); start != nil && *start > 0 && feed.Metadata.ItemsPerPage != nil && *feed.Metadata.ItemsPerPage > 0 {
+		page := (*start-1) / *feed.Metadata.ItemsPerPage + 1
+		feed.Metadata.CurrentPage = &page
+	}
+
+	for _, el := range root.SelectElements(nsSelect(NamespaceAtom, "link")) {
+		link := p.link(el)
+		if !hasRel(link, RelFacet) {
+			feed.Links = append(feed.Links, link)
+			continue
+		}
+		// The active facet is the one of the current feed.
+		link.Rels = nil
+		if el.SelectAttr("activeFacet") == "true" || attrNS(el, NamespaceOPDS, "activeFacet") == "true" {
+			link.Rels = manifest.Strings{"self"}
+		}
+		title := attrNS(el, NamespaceOPDS, "facetGroup")
+		facet := findFacet(feed, title)
+		facet.Links = append(facet.Links, link)
+	}
+
+	for _, el := range root.SelectElements(nsSelect(NamespaceAtom, "entry")) {
+		group := p.group(feed, el)
+		if isPublication(el) {
+			publication := p.publication(el)
+			if group != nil {
+				group.Publications = append(group.Publications, publication)
+			} else {
+				feed.Publications = append(feed.Publications, publication)
+			}
+		} else if link := p.navigationLink(el); link != nil {
+			if group != nil {
+				group.Navigation = append(group.Navigation, *link)
+			} else {
+				feed.Navigation = append(feed.Navigation, *link)
+			}
+		}
+	}
+	return feed, nil
+}
+
+// ParseOPDS1Entry parses a standalone OPDS 1.x entry document into a publication.
+func ParseOPDS1Entry(data []byte, baseURL string) (*manifest.Manifest, error) {
+	root, err := parseXML(data, "entry")
+	if err != nil {
+		return nil, err
+	}
+	publication := opds1Parser{baseURL: baseURL}.publication(root)
+	return &publication, nil
+}
+
+// Parses the XML document and returns its Atom root element with the given local name.
+func parseXML(data []byte, name string) (*xmlquery.Node, error) {
+	doc, err := parseXMLDocument(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing OPDS 1 document")
+	}
+	root := doc.SelectElement(nsSelect(NamespaceAtom, name))
+	if root == nil {
+		return nil, errors.Errorf("OPDS 1 document has no Atom %s element", name)
+	}
+	return root, nil
+}
+
+// Catalogs are often generated by hand-written templates, so the parser is lenient.
+func parseXMLDocument(data []byte) (*xmlquery.Node, error) {
+	return xmlquery.ParseWithOptions(bytes.NewReader(data), xmlquery.ParserOptions{
+		Decoder: &xmlquery.DecoderOptions{
+			Strict: false,
+			Entity: xml.HTMLEntity,
+		},
+	})
+}
+
+type opds1Parser struct {
+	baseURL string
+}
+
+// Returns whether the entry is a publication, which is acquired through its acquisition links.
+func isPublication(entry *xmlquery.Node) bool {
+	for _, el := range entry.SelectElements(nsSelect(NamespaceAtom, "link")) {
+		if strings.HasPrefix(el.SelectAttr("rel"), RelAcquisition) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds or creates the group of the entry, declared with a collection link.
+func (p opds1Parser) group(feed *Feed, entry *xmlquery.Node) *Group {
+	for _, el := range entry.SelectElements(nsSelect(NamespaceAtom, "link")) {
+		rel := el.SelectAttr("rel")
+		if rel != "collection" && rel != RelGroup {
+			continue
+		}
+		link := p.link(el)
+		for i, group := range feed.Groups {
+			if len(group.Links) > 0 && group.Links[0].Href == link.Href {
+				return &feed.Groups[i]
+			}
+		}
+		link.Rels = manifest.Strings{"self"}
+		feed.Groups = append(feed.Groups, Group{
+			Metadata: FeedMetadata{Title: link.Title},
+			Links:    manifest.LinkList{link},
+		})
+		return &feed.Groups[len(feed.Groups)-1]
+	}
+	return nil
+}
+
+func findFacet(feed *Feed, title string) *Facet {
+	for i, facet := range feed.Facets {
+		if facet.Metadata.Title == title {
+			return &feed.Facets[i]
+		}
+	}
+	feed.Facets = append(feed.Facets, Facet{Metadata: FeedMetadata{Title: title}})
+	return &feed.Facets[len(feed.Facets)-1]
+}
+
+// Converts a navigation entry to a link targeting its feed, titled with the entry.
+func (p opds1Parser) navigationLink(entry *xmlquery.Node) *manifest.Link {
+	var found *manifest.Link
+	for _, el := range entry.SelectElements(nsSelect(NamespaceAtom, "link")) {
+		link := p.link(el)
+		if hasRel(link, "collection") || hasRel(link, RelGroup) {
+			continue
+		}
+		if found == nil || (!strings.Contains(found.Type, "opds-catalog") && strings.Contains(link.Type, "opds-catalog")) {
+			found = &link
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	if title := p.text(entry, NamespaceAtom, "title"); title != "" {
+		found.Title = title
+	}
+	return found
+}
+
+// Converts an entry to a publication manifest.
+// Reference: https://specs.opds.io/opds-1.2#51-metadata
+func (p opds1Parser) publication(entry *xmlquery.Node) manifest.Manifest {
+	metadata := manifest.Metadata{
+		Identifier:     p.text(entry, NamespaceDCTerms, "identifier"),
+		LocalizedTitle: manifest.NewLocalizedStringFromString(p.text(entry, NamespaceAtom, "title")),
+		Modified:       extensions.ParseDate(p.text(entry, NamespaceAtom, "updated")),
+		Published:      extensions.ParseDate(p.text(entry, NamespaceDCTerms, "issued")),
+		Authors:        p.contributors(entry, NamespaceAtom, "author"),
+		Contributors:   p.contributors(entry, NamespaceAtom, "contributor"),
+	}
+	if metadata.Identifier == "" {
+		metadata.Identifier = p.text(entry, NamespaceDC, "identifier")
+	}
+	if metadata.Identifier == "" {
+		metadata.Identifier = p.text(entry, NamespaceAtom, "id")
+	}
+	if metadata.Published == nil {
+		metadata.Published = extensions.ParseDate(p.text(entry, NamespaceAtom, "published"))
+	}
+	if subtitle := p.text(entry, NamespaceAtom, "subtitle"); subtitle != "" {
+		localized := manifest.NewLocalizedStringFromString(subtitle)
+		metadata.LocalizedSubtitle = &localized
+	}
+	for _, ns := range []string{NamespaceDCTerms, NamespaceDC} {
+		for _, el := range entry.SelectElements(nsSelect(ns, "language")) {
+			if language := strings.TrimSpace(el.InnerText()); language != "" {
+				metadata.Languages = append(metadata.Languages, language)
+			}
+		}
+		for _, el := range entry.SelectElements(nsSelect(ns, "publisher")) {
+			if name := strings.TrimSpace(el.InnerText()); name != "" {
+				metadata.Publishers = append(metadata.Publishers, manifest.Contributor{
+					LocalizedName: manifest.NewLocalizedStringFromString(name),
+				})
+			}
+		}
+	}
+	for _, el := range entry.SelectElements(nsSelect(NamespaceAtom, "category")) {
+		term, label := el.SelectAttr("term"), el.SelectAttr("label")
+		if label == "" {
+			label = term
+		}
+		if label == "" {
+			continue
+		}
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(label),
+			Scheme:        el.SelectAttr("scheme"),
+			Code:          term,
+		})
+	}
+	metadata.Description = p.text(entry, NamespaceAtom, "summary")
+	if metadata.Description == "" {
+		metadata.Description = p.text(entry, NamespaceAtom, "content")
+	}
+
+	publication := manifest.Manifest{Metadata: metadata}
+	var images manifest.LinkList
+	for _, el := range entry.SelectElements(nsSelect(NamespaceAtom, "link")) {
+		link := p.link(el)
+		if hasRel(link, "collection") || hasRel(link, RelGroup) {
+			// Represented by the group containing the publication.
+			continue
+		} else if hasAnyRel(link, imageRels) {
+			images = append(images, link)
+		} else {
+			publication.Links = append(publication.Links, link)
+		}
+	}
+	if len(images) > 0 {
+		publication.Subcollections = manifest.PublicationCollectionMap{
+			"images": {{Links: images}},
+		}
+	}
+	return publication
+}
+
+func (p opds1Parser) contributors(entry *xmlquery.Node, ns string, name string) manifest.Contributors {
+	var contributors manifest.Contributors
+	for _, el := range entry.SelectElements(nsSelect(ns, name)) {
+		contributor := manifest.Contributor{
+			LocalizedName: manifest.NewLocalizedStringFromString(p.text(el, NamespaceAtom, "name")),
+		}
+		if contributor.Name() == "" {
+			continue
+		}
+		if uri := p.text(el, NamespaceAtom, "uri"); uri != "" {
+			contributor.Links = manifest.LinkList{{Href: p.resolve(uri)}}
+		}
+		contributors = append(contributors, contributor)
+	}
+	return contributors
+}
+
+// Converts an Atom link, with its OPDS properties.
+func (p opds1Parser) link(el *xmlquery.Node) manifest.Link {
+	link := manifest.Link{
+		Href:  p.resolve(el.SelectAttr("href")),
+		Type:  el.SelectAttr("type"),
+		Title: el.SelectAttr("title"),
+	}
+	if rel := el.SelectAttr("rel"); rel != "" {
+		link.Rels = manifest.Strings{rel}
+	}
+	if count, err := strconv.ParseUint(attrNS(el, NamespaceThread, "count"), 10, 32); err == nil {
+		link.Properties.Add(manifest.Properties{PropertyNumberOfItems: uint(count)})
+	}
+
+	var acquisitions []Acquisition
+	for _, child := range el.SelectElements(nsSelect(NamespaceOPDS, "indirectAcquisition")) {
+		acquisitions = append(acquisitions, indirectAcquisition(child))
+	}
+	if len(acquisitions) > 0 {
+		link.Properties.Add(manifest.Properties{PropertyIndirectAcquisition: acquisitions})
+	}
+	if el := el.SelectElement(nsSelect(NamespaceOPDS, "price")); el != nil {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(el.InnerText()), 64); err == nil {
+			link.Properties.Add(manifest.Properties{PropertyPrice: Price{
+				Currency: el.SelectAttr("currencycode"),
+				Value:    value,
+			}})
+		}
+	}
+	if el := el.SelectElement(nsSelect(NamespaceOPDS, "holds")); el != nil {
+		link.Properties.Add(manifest.Properties{PropertyHolds: Holds{
+			Total:    parseUint(el.SelectAttr("total")),
+			Position: parseUint(el.SelectAttr("position")),
+		}})
+	}
+	if el := el.SelectElement(nsSelect(NamespaceOPDS, "copies")); el != nil {
+		link.Properties.Add(manifest.Properties{PropertyCopies: Copies{
+			Total:     parseUint(el.SelectAttr("total")),
+			Available: parseUint(el.SelectAttr("available")),
+		}})
+	}
+	if el := el.SelectElement(nsSelect(NamespaceOPDS, "availability")); el != nil {
+		if state := el.SelectAttr("status"); state != "" {
+			link.Properties.Add(manifest.Properties{PropertyAvailability: Availability{
+				State: AvailabilityState(state),
+				Since: extensions.ParseDate(el.SelectAttr("since")),
+				Until: extensions.ParseDate(el.SelectAttr("until")),
+			}})
+		}
+	}
+	return link
+}
+
+func indirectAcquisition(el *xmlquery.Node) Acquisition {
+	acquisition := Acquisition{Type: el.SelectAttr("type")}
+	for _, child := range el.SelectElements(nsSelect(NamespaceOPDS, "indirectAcquisition")) {
+		acquisition.Children = append(acquisition.Children, indirectAcquisition(child))
+	}
+	return acquisition
+}
+
+// Resolves a link of the document against its base URL.
+func (p opds1Parser) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if p.baseURL == "" || href == "" {
+		return href
+	}
+	base, err := url.Parse(p.baseURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil || ref.IsAbs() {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// Returns the trimmed text of the first child element with the given name.
+func (p opds1Parser) text(n *xmlquery.Node, ns string, name string) string {
+	if el := n.SelectElement(nsSelect(ns, name)); el != nil {
+		return strings.TrimSpace(el.InnerText())
+	}
+	return ""
+}
+
+func (p opds1Parser) uint(n *xmlquery.Node, ns string, name string) *uint {
+	return parseUint(p.text(n, ns, name))
+}
+
+func parseUint(s string) *uint {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return nil
+	}
+	u := uint(v)
+	return &u
+}
+
+func hasRel(link manifest.Link, rel string) bool {
+	return extensions.Contains(link.Rels, rel)
+}
+
+func hasAnyRel(link manifest.Link, rels []string) bool {
+	for _, rel := range rels {
+		if hasRel(link, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func nsSelect(namespace, localName string) string {
+	return "*[namespace-uri()='" + namespace + "' and local-name()='" + localName + "']"
+}
+
+func attrNS(n *xmlquery.Node, ns, name string) string {
+	for _, a := range n.Attr {
+		if a.NamespaceURI == ns && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
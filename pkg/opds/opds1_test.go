@@ -0,0 +1,277 @@
+package opds
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFeed(t *testing.T, name string) *Feed {
+	data, err := os.ReadFile("testdata/" + name)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	feed, err := ParseOPDS1(data, "https://example.org/opds-catalogs/root.xml")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return feed
+}
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestParseOPDS1NavigationFeed(t *testing.T) {
+	feed := parseFeed(t, "navigation.xml")
+
+	assert.Equal(t, "OPDS Catalog Root Example", feed.Metadata.Title)
+	assert.Equal(t, "urn:uuid:2853dacf-ed79-42f5-8e8a-a7bb3d1ae6a2", feed.Metadata.Identifier)
+	assert.Equal(t, time.Date(2010, 1, 10, 10, 1, 11, 0, time.UTC), *feed.Metadata.Modified)
+	assert.Nil(t, feed.Metadata.NumberOfItems)
+	assert.Empty(t, feed.Publications)
+
+	assert.Equal(t, manifest.LinkList{
+		{Href: "https://example.org/opds-catalogs/root.xml", Type: "application/atom+xml;profile=opds-catalog;kind=navigation", Rels: manifest.Strings{"self"}},
+		{Href: "https://example.org/opds-catalogs/root.xml", Type: "application/atom+xml;profile=opds-catalog;kind=navigation", Rels: manifest.Strings{"start"}},
+		{Href: "https://example.org/opds-catalogs/opensearch.xml", Type: "application/opensearchdescription+xml", Rels: manifest.Strings{"search"}},
+	}, feed.Links)
+	assert.Equal(t, "https://example.org/opds-catalogs/opensearch.xml", feed.LinkWithRel("search").Href)
+
+	assert.Equal(t, manifest.LinkList{
+		{
+			Href:       "https://example.org/opds-catalogs/popular.xml",
+			Type:       "application/atom+xml;profile=opds-catalog;kind=acquisition",
+			Title:      "Popular Publications",
+			Rels:       manifest.Strings{"http://opds-spec.org/sort/popular"},
+			Properties: manifest.Properties{"numberOfItems": uint(42)},
+		},
+		{
+			Href:  "https://example.org/opds-catalogs/new.xml",
+			Type:  "application/atom+xml;profile=opds-catalog;kind=acquisition",
+			Title: "New Publications",
+			Rels:  manifest.Strings{"http://opds-spec.org/sort/new"},
+		},
+	}, feed.Navigation)
+	assert.Equal(t, uintPtr(42), NumberOfItems(feed.Navigation[0].Properties))
+
+	// The catalog feed is preferred to the alternate HTML page.
+	assert.Equal(t, []Group{{
+		Metadata: FeedMetadata{Title: "Genres"},
+		Links: manifest.LinkList{
+			{Href: "https://example.org/opds-catalogs/genres.xml", Title: "Genres", Rels: manifest.Strings{"self"}},
+		},
+		Navigation: manifest.LinkList{
+			{Href: "https://example.org/opds-catalogs/sf.xml", Type: "application/atom+xml;profile=opds-catalog;kind=acquisition", Title: "Science-Fiction", Rels: manifest.Strings{"subsection"}},
+		},
+	}}, feed.Groups)
+}
+
+func TestParseOPDS1AcquisitionFeed(t *testing.T) {
+	feed := parseFeed(t, "acquisition.xml")
+
+	assert.Equal(t, uintPtr(95), feed.Metadata.NumberOfItems)
+	assert.Equal(t, uintPtr(20), feed.Metadata.ItemsPerPage)
+	assert.Equal(t, uintPtr(3), feed.Metadata.CurrentPage)
+	assert.Equal(t, "https://example.org/opds-catalogs/unpopular.xml?page=4", feed.LinkWithRel("next").Href)
+	assert.Equal(t, "https://example.org/opds-catalogs/unpopular.xml?page=2", feed.LinkWithRel("previous").Href)
+	assert.Nil(t, feed.LinkWithRel("http://opds-spec.org/facet"))
+
+	if assert.Len(t, feed.Facets, 2) {
+		assert.Equal(t, "Genre", feed.Facets[0].Metadata.Title)
+		assert.Equal(t, manifest.LinkList{
+			{
+				Href:       "https://example.org/opds-catalogs/unpopular.xml?genre=fiction",
+				Type:       "application/atom+xml;profile=opds-catalog;kind=acquisition",
+				Title:      "Fiction",
+				Rels:       manifest.Strings{"self"},
+				Properties: manifest.Properties{"numberOfItems": uint(60)},
+			},
+			{
+				Href:       "https://example.org/opds-catalogs/unpopular.xml?genre=poetry",
+				Type:       "application/atom+xml;profile=opds-catalog;kind=acquisition",
+				Title:      "Poetry",
+				Properties: manifest.Properties{"numberOfItems": uint(35)},
+			},
+		}, feed.Facets[0].Links)
+		assert.Equal(t, "Language", feed.Facets[1].Metadata.Title)
+		assert.Len(t, feed.Facets[1].Links, 1)
+	}
+
+	if assert.Len(t, feed.Groups, 1) {
+		assert.Equal(t, "Featured", feed.Groups[0].Metadata.Title)
+		if assert.Len(t, feed.Groups[0].Publications, 1) {
+			p := feed.Groups[0].Publications[0]
+			assert.Equal(t, "Modern Poetry", p.Metadata.Title())
+			assert.Equal(t, manifest.LinkList{
+				{Href: "https://example.org/content/free/poetry.epub", Type: "application/epub+zip", Rels: manifest.Strings{"http://opds-spec.org/acquisition/open-access"}},
+			}, p.Links)
+		}
+	}
+}
+
+func TestParseOPDS1Publication(t *testing.T) {
+	feed := parseFeed(t, "acquisition.xml")
+	if !assert.Len(t, feed.Publications, 1) {
+		return
+	}
+	p := feed.Publications[0]
+
+	m := p.Metadata
+	assert.Equal(t, "Bob, Son of Bob", m.Title())
+	assert.Equal(t, "urn:isbn:9780000000001", m.Identifier)
+	assert.Equal(t, manifest.Strings{"en"}, m.Languages)
+	assert.Equal(t, time.Date(1917, 1, 1, 0, 0, 0, 0, time.UTC), *m.Published)
+	assert.Equal(t, time.Date(2010, 1, 10, 10, 1, 11, 0, time.UTC), *m.Modified)
+	assert.Equal(t, "The story of the son of the Bob and the gallant part he played in the lives of a man and a woman.", m.Description)
+	assert.Equal(t, manifest.Contributors{{
+		LocalizedName: manifest.NewLocalizedStringFromString("Bob the Recursive"),
+		Links:         []manifest.Link{{Href: "https://example.org/opds-catalogs/authors/bob.xml"}},
+	}}, m.Authors)
+	if assert.Len(t, m.Publishers, 1) {
+		assert.Equal(t, "Pub Lisher", m.Publishers[0].Name())
+	}
+	assert.Equal(t, []manifest.Subject{{
+		LocalizedName: manifest.NewLocalizedStringFromString("Men's Adventure"),
+		Scheme:        "http://www.bisg.org/standards/bisac_subject/",
+		Code:          "FIC020000",
+	}}, m.Subjects)
+
+	assert.Equal(t, manifest.PublicationCollectionMap{
+		"images": {{Links: manifest.LinkList{
+			{Href: "https://example.org/covers/4561.lrg.png", Type: "image/png", Rels: manifest.Strings{"http://opds-spec.org/image"}},
+			{Href: "https://example.org/covers/4561.thmb.gif", Type: "image/gif", Rels: manifest.Strings{"http://opds-spec.org/image/thumbnail"}},
+		}}},
+	}, p.Subcollections)
+
+	if !assert.Len(t, p.Links, 4) {
+		return
+	}
+	assert.Equal(t, manifest.Link{
+		Href: "https://example.org/content/free/4561.epub",
+		Type: "application/epub+zip",
+		Rels: manifest.Strings{"http://opds-spec.org/acquisition"},
+	}, p.Links[1])
+
+	buy := p.Links[2].Properties
+	assert.Equal(t, &Price{Currency: "USD", Value: 18.99}, PriceOf(buy))
+	assert.Equal(t, []Acquisition{{
+		Type:     "application/vnd.readium.lcp.license.v1.0+json",
+		Children: []Acquisition{{Type: "application/epub+zip"}},
+	}}, IndirectAcquisitions(buy))
+	assert.Nil(t, HoldsOf(buy))
+
+	borrow := p.Links[3].Properties
+	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, &Availability{State: AvailabilityUnavailable, Since: &since}, AvailabilityOf(borrow))
+	assert.Equal(t, &Holds{Total: uintPtr(3), Position: uintPtr(2)}, HoldsOf(borrow))
+	assert.Equal(t, &Copies{Total: uintPtr(5), Available: uintPtr(0)}, CopiesOf(borrow))
+	assert.Nil(t, PriceOf(borrow))
+}
+
+func TestParseOPDS1Entry(t *testing.T) {
+	data, err := os.ReadFile("testdata/entry.xml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := ParseOPDS1Entry(data, "https://example.org/ebooks/35.atom")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m := p.Metadata
+	assert.Equal(t, "The Time Machine", m.Title())
+	assert.Equal(t, "An Invention", m.Subtitle())
+	assert.Equal(t, "http://example.org/ebooks/35", m.Identifier)
+	assert.Equal(t, manifest.Strings{"en", "fr"}, m.Languages)
+	assert.Equal(t, time.Date(1895, 5, 7, 0, 0, 0, 0, time.UTC), *m.Published)
+	assert.Equal(t, "A time traveller's journey to the year 802,701.", m.Description)
+	if assert.Len(t, m.Authors, 1) && assert.Len(t, m.Contributors, 1) {
+		assert.Equal(t, "H. G. Wells", m.Authors[0].Name())
+		assert.Equal(t, "A. Translator", m.Contributors[0].Name())
+	}
+	assert.Equal(t, manifest.LinkList{
+		{Href: "https://example.org/ebooks/35.epub", Type: "application/epub+zip", Rels: manifest.Strings{"http://opds-spec.org/acquisition/open-access"}},
+	}, p.Links)
+	assert.Equal(t, []manifest.Link{
+		{Href: "https://example.org/ebooks/35.cover.jpg", Type: "image/jpeg", Rels: manifest.Strings{"x-stanza-cover-image"}},
+	}, p.Subcollections["images"][0].Links)
+}
+
+func TestParseOPDS1RejectsOtherDocuments(t *testing.T) {
+	_, err := ParseOPDS1([]byte(`<html><body>Not a feed</body></html>`), "")
+	assert.Error(t, err)
+	_, err = ParseOPDS1Entry([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"/>`), "")
+	assert.Error(t, err)
+}
+
+func TestParseOpenSearchDescription(t *testing.T) {
+	data, err := os.ReadFile("testdata/opensearch.xml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	link, err := ParseOpenSearchDescription(data, "https://example.org/opensearch.xml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &manifest.Link{
+		Href:      "https://example.org/opds/search?q={searchTerms}&page={startPage}",
+		Type:      "application/atom+xml;profile=opds-catalog;kind=acquisition",
+		Templated: true,
+		Title:     "Catalog Search",
+		Rels:      manifest.Strings{"search"},
+	}, link)
+	assert.Equal(t, []string{"searchTerms", "startPage"}, link.TemplateParameters())
+}
+
+func TestOPDS1ToOPDS2JSON(t *testing.T) {
+	feed := parseFeed(t, "acquisition.xml")
+	data, err := json.Marshal(feed)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var object map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal(data, &object)) {
+		return
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"identifier":    "urn:uuid:433a5d6a-0b8c-4933-af65-4ca4f02763eb",
+		"title":         "Unpopular Publications",
+		"modified":      "2010-01-10T10:01:10Z",
+		"numberOfItems": 95.0,
+		"itemsPerPage":  20.0,
+		"currentPage":   3.0,
+	}, object["metadata"])
+	assert.Len(t, object["links"], 5)
+	assert.Len(t, object["facets"], 2)
+	assert.Len(t, object["groups"], 1)
+	assert.NotContains(t, object, "navigation")
+
+	publications := object["publications"].([]interface{})
+	if !assert.Len(t, publications, 1) {
+		return
+	}
+	publication := publications[0].(map[string]interface{})
+	assert.NotContains(t, publication, "@context")
+	assert.NotContains(t, publication, "readingOrder")
+	assert.Len(t, publication["images"], 2)
+	buy := publication["links"].([]interface{})[2].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"indirectAcquisition": []interface{}{map[string]interface{}{
+			"type":  "application/vnd.readium.lcp.license.v1.0+json",
+			"child": []interface{}{map[string]interface{}{"type": "application/epub+zip"}},
+		}},
+		"price": map[string]interface{}{"currency": "USD", "value": 18.99},
+	}, buy["properties"])
+
+	// The properties of the converted links can be read back.
+	link, err := manifest.LinkFromJSON(buy, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &Price{Currency: "USD", Value: 18.99}, PriceOf(link.Properties))
+	}
+}
@@ -0,0 +1,51 @@
+package opds
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// ParseOpenSearchDescription parses the OpenSearch description targeted by the search link of an OPDS 1 feed, and
+// returns a templated search link to its Atom results, which can replace the original search link.
+//
+// The optional parameters of OpenSearch, such as {startPage?}, are converted to URI template variables.
+// Reference: https://github.com/dewitt/opensearch/blob/master/opensearch-1-1-draft-6.md#opensearch-description-document
+func ParseOpenSearchDescription(data []byte, baseURL string) (*manifest.Link, error) {
+	doc, err := parseXMLDocument(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing OpenSearch description")
+	}
+	p := opds1Parser{baseURL: baseURL}
+	root := doc.SelectElement(nsSelect(NamespaceOpenSearch, "OpenSearchDescription"))
+	if root == nil {
+		return nil, errors.New("OpenSearch description has no OpenSearchDescription element")
+	}
+
+	// The results of an OPDS catalog are Atom feeds, preferably flagged with the OPDS profile.
+	var template, typ string
+	for _, el := range root.SelectElements(nsSelect(NamespaceOpenSearch, "Url")) {
+		t := el.SelectAttr("type")
+		if !strings.HasPrefix(t, "application/atom+xml") || strings.Contains(t, "type=entry") {
+			continue
+		}
+		if template == "" || (!strings.Contains(typ, "opds-catalog") && strings.Contains(t, "opds-catalog")) {
+			template, typ = el.SelectAttr("template"), t
+		}
+	}
+	if template == "" {
+		return nil, errors.New("OpenSearch description has no Atom URL template")
+	}
+
+	link := &manifest.Link{
+		Href:      p.resolve(strings.ReplaceAll(template, "?}", "}")),
+		Type:      typ,
+		Templated: true,
+		Rels:      manifest.Strings{"search"},
+	}
+	if name := p.text(root, NamespaceOpenSearch, "ShortName"); name != "" {
+		link.Title = name
+	}
+	return link, nil
+}
@@ -0,0 +1,128 @@
+package opds
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// OPDS extensions of the link properties.
+// https://drafts.opds.io/schema/properties.schema.json
+const (
+	PropertyIndirectAcquisition = "indirectAcquisition"
+	PropertyPrice               = "price"
+	PropertyNumberOfItems       = "numberOfItems"
+	PropertyHolds               = "holds"
+	PropertyCopies              = "copies"
+	PropertyAvailability        = "availability"
+)
+
+// Acquisition describes the media type of a resource obtained through an acquisition link, when it differs from
+// the link's type. For example, an EPUB protected by LCP is acquired through a license document.
+type Acquisition struct {
+	Type     string        `json:"type"`
+	Children []Acquisition `json:"child,omitempty"`
+}
+
+// Price of a publication, for a buy or borrow acquisition link.
+type Price struct {
+	Currency string  `json:"currency"` // ISO 4217 currency code.
+	Value    float64 `json:"value"`
+}
+
+// Holds of a publication in a library.
+type Holds struct {
+	Total    *uint `json:"total,omitempty"`
+	Position *uint `json:"position,omitempty"`
+}
+
+// Copies of a publication held by a library.
+type Copies struct {
+	Total     *uint `json:"total,omitempty"`
+	Available *uint `json:"available,omitempty"`
+}
+
+// AvailabilityState is the state of a publication for an acquisition link.
+type AvailabilityState string
+
+const (
+	AvailabilityAvailable   AvailabilityState = "available"
+	AvailabilityUnavailable AvailabilityState = "unavailable"
+	AvailabilityReserved    AvailabilityState = "reserved"
+	AvailabilityReady       AvailabilityState = "ready"
+)
+
+// Availability of a publication for an acquisition link.
+type Availability struct {
+	State AvailabilityState `json:"state"`
+	Since *time.Time        `json:"since,omitempty"`
+	Until *time.Time        `json:"until,omitempty"`
+}
+
+// Returns the indirect acquisitions of a link.
+func IndirectAcquisitions(p manifest.Properties) []Acquisition {
+	var acquisitions []Acquisition
+	if !decodeProperty(p, PropertyIndirectAcquisition, &acquisitions) {
+		return nil
+	}
+	return acquisitions
+}
+
+// Returns the price of a publication for an acquisition link.
+func PriceOf(p manifest.Properties) *Price {
+	var price Price
+	if !decodeProperty(p, PropertyPrice, &price) {
+		return nil
+	}
+	return &price
+}
+
+// Returns the number of items of the feed targeted by a navigation link.
+func NumberOfItems(p manifest.Properties) *uint {
+	var count uint
+	if !decodeProperty(p, PropertyNumberOfItems, &count) {
+		return nil
+	}
+	return &count
+}
+
+// Returns the holds of a publication for an acquisition link.
+func HoldsOf(p manifest.Properties) *Holds {
+	var holds Holds
+	if !decodeProperty(p, PropertyHolds, &holds) {
+		return nil
+	}
+	return &holds
+}
+
+// Returns the copies of a publication for an acquisition link.
+func CopiesOf(p manifest.Properties) *Copies {
+	var copies Copies
+	if !decodeProperty(p, PropertyCopies, &copies) {
+		return nil
+	}
+	return &copies
+}
+
+// Returns the availability of a publication for an acquisition link.
+func AvailabilityOf(p manifest.Properties) *Availability {
+	var availability Availability
+	if !decodeProperty(p, PropertyAvailability, &availability) {
+		return nil
+	}
+	return &availability
+}
+
+// Properties hold either the typed values set by the OPDS 1 parser, or the raw JSON values of an OPDS 2 feed.
+func decodeProperty(p manifest.Properties, key string, value interface{}) bool {
+	raw := p.Get(key)
+	if raw == nil {
+		return false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, value) == nil
+}
@@ -0,0 +1,88 @@
+package daisy
+
+import (
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+)
+
+// Metadata of a DAISY fileset, indexed by lowercased property name, e.g. "dc:title" or "ncc:narrator".
+//
+// DAISY 2.02 declares it with <meta> elements in the NCC, while DAISY 3 uses the Dublin Core elements and
+// <x-metadata> of the package document, completed by the <head> of the DTBook documents.
+type metadata map[string][]string
+
+func (m metadata) add(name, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if name == "" || value == "" {
+		return
+	}
+	m[strings.ToLower(name)] = append(m[strings.ToLower(name)], value)
+}
+
+// Adds the properties of [other] which are missing from this metadata.
+func (m metadata) merge(other metadata) {
+	for name, values := range other {
+		if _, ok := m[name]; !ok {
+			m[name] = values
+		}
+	}
+}
+
+// Returns the first value of the first property found among [names].
+func (m metadata) first(names ...string) string {
+	for _, name := range names {
+		if values := m[name]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func (m metadata) all(names ...string) []string {
+	var values []string
+	for _, name := range names {
+		values = append(values, m[name]...)
+	}
+	return values
+}
+
+// Maps the DAISY metadata to the RWPM metadata.
+// References:
+//   - https://daisy.org/activities/standards/daisy/daisy-2/daisy-format-2-02-specification/#ncc-meta
+//   - https://daisy.org/activities/standards/daisy/daisy-3/z39-86-2005-r2012-specifications-for-the-digital-talking-book/#Metadata
+func (m metadata) manifest() manifest.Metadata {
+	contributors := func(names ...string) manifest.Contributors {
+		var contributors manifest.Contributors
+		for _, name := range m.all(names...) {
+			contributors = append(contributors, manifest.Contributor{
+				LocalizedName: manifest.NewLocalizedStringFromString(name),
+			})
+		}
+		return contributors
+	}
+
+	metadata := manifest.Metadata{
+		Identifier:  m.first("dc:identifier", "dtb:uid", "ncc:identifier"),
+		Authors:     contributors("dc:creator"),
+		Narrators:   contributors("ncc:narrator", "dtb:narrator"),
+		Publishers:  contributors("dc:publisher"),
+		Description: m.first("dc:description"),
+		Published:   extensions.ParseDate(m.first("dc:date")),
+	}
+	if title := m.first("dc:title"); title != "" {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(title)
+	}
+	metadata.Languages = m.all("dc:language")
+	for _, subject := range m.all("dc:subject") {
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(subject),
+		})
+	}
+	if duration := epub.ParseClockValue(m.first("ncc:totaltime", "dtb:totaltime")); duration != nil && *duration > 0 {
+		metadata.Duration = duration
+	}
+	return metadata
+}
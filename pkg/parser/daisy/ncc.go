@@ -0,0 +1,185 @@
+package daisy
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/util"
+	"golang.org/x/net/html"
+)
+
+// Reads a DAISY 2.02 fileset from its Navigation Control Center.
+// Reference: https://daisy.org/activities/standards/daisy/daisy-2/daisy-format-2-02-specification/#ncc
+func readNCC(f fetcher.Fetcher, links manifest.LinkList, link manifest.Link) (*fileset, error) {
+	// The NCC is usually XHTML, but older productions are often malformed HTML in a legacy charset.
+	data, rerr := f.Get(link).ReadAsString()
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed reading NCC")
+	}
+	doc, err := html.Parse(strings.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing NCC")
+	}
+
+	fs := &fileset{Metadata: make(metadata)}
+	var headings []heading
+	var smils []string
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				fs.Metadata.add(htmlAttr(n, "name"), htmlAttr(n, "content"))
+			case "title":
+				if fs.Metadata.first("dc:title") == "" {
+					fs.Metadata.add("dc:title", htmlText(n))
+				}
+			case "a":
+				if href := resolveHref(htmlAttr(n, "href"), link.Href); href != "" {
+					smils = appendUnique(smils, strings.SplitN(href, "#", 2)[0])
+				}
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				if l := nccLink(n, link.Href); l != nil {
+					headings = append(headings, heading{Level: int(n.Data[1] - '0'), Link: *l})
+				}
+			case "span":
+				for _, class := range strings.Fields(htmlAttr(n, "class")) {
+					if class == "page-normal" || class == "page-front" || class == "page-special" {
+						if l := nccLink(n, link.Href); l != nil {
+							fs.PageList = append(fs.PageList, *l)
+						}
+						break
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+	fs.TOC = nestHeadings(headings)
+
+	// The master SMIL file, when available, lists the SMIL files in reading order. Otherwise they are played in the
+	// order of the NCC.
+	fs.SMILs = smils
+	for _, l := range links {
+		if strings.EqualFold(path.Base(l.Href), "master.smil") {
+			if refs := readMasterSMIL(f, l); len(refs) > 0 {
+				fs.SMILs = refs
+			}
+			break
+		}
+	}
+	return fs, nil
+}
+
+// Returns the HREFs of the SMIL files referenced by a DAISY 2.02 master SMIL file.
+func readMasterSMIL(f fetcher.Fetcher, link manifest.Link) []string {
+	document, rerr := f.Get(link).ReadAsXML(nil)
+	if rerr != nil {
+		// TODO log
+		return nil
+	}
+	var refs []string
+	for _, ref := range document.SelectElements("//*[local-name()='body']//*[local-name()='ref']") {
+		if href := resolveHref(ref.SelectAttr("src"), link.Href); href != "" {
+			refs = appendUnique(refs, strings.SplitN(href, "#", 2)[0])
+		}
+	}
+	return refs
+}
+
+// Creates a link targeting the SMIL element referenced by the anchor of an NCC element.
+func nccLink(n *html.Node, base string) *manifest.Link {
+	a := findHTMLElement(n, "a")
+	if a == nil {
+		return nil
+	}
+	href := resolveHref(htmlAttr(a, "href"), base)
+	if href == "" {
+		return nil
+	}
+	return &manifest.Link{Href: href, Title: htmlText(n)}
+}
+
+type heading struct {
+	Level int
+	Link  manifest.Link
+}
+
+// Nests the headings of the NCC according to their level, e.g. a <h2> following a <h1> becomes its child.
+func nestHeadings(headings []heading) manifest.LinkList {
+	var links manifest.LinkList
+	for len(headings) > 0 {
+		h := headings[0]
+		end := 1
+		for end < len(headings) && headings[end].Level > h.Level {
+			end++
+		}
+		h.Link.Children = nestHeadings(headings[1:end])
+		links = append(links, h.Link)
+		headings = headings[end:]
+	}
+	return links
+}
+
+func findHTMLElement(n *html.Node, name string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == name {
+			return c
+		}
+		if found := findHTMLElement(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func htmlText(n *html.Node) string {
+	var b strings.Builder
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Resolves an HREF found in the resource at [base], or returns an empty string if it is invalid.
+func resolveHref(href string, base string) string {
+	if strings.TrimSpace(href) == "" {
+		return ""
+	}
+	resolved, err := util.NewHREF(href, base).String()
+	if err != nil {
+		return ""
+	}
+	return resolved
+}
+
+func appendUnique(hrefs []string, href string) []string {
+	for _, h := range hrefs {
+		if h == href {
+			return hrefs
+		}
+	}
+	return append(hrefs, href)
+}
@@ -0,0 +1,116 @@
+package daisy
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/xmlquery"
+)
+
+const namespaceDC = "http://purl.org/dc/elements/1.1/"
+
+// Reads a DAISY 3 fileset from its package document, NCX and DTBook documents.
+// Reference: https://daisy.org/activities/standards/daisy/daisy-3/z39-86-2005-r2012-specifications-for-the-digital-talking-book/#Package
+func readPackage(f fetcher.Fetcher, link manifest.Link) (*fileset, error) {
+	document, rerr := f.Get(link).ReadAsXML(nil)
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed parsing package document")
+	}
+	pkg := document.SelectElement("//*[local-name()='package']")
+	if pkg == nil {
+		return nil, errors.New("invalid package document: missing package root element")
+	}
+
+	fs := &fileset{
+		Metadata:  make(metadata),
+		TextTypes: make(map[string]string),
+	}
+	if md := pkg.SelectElement("*[local-name()='metadata']"); md != nil {
+		readPackageMetadata(fs.Metadata, md)
+	}
+
+	items := make(map[string]string)
+	var ncx string
+	var texts []string
+	for _, item := range pkg.SelectElements("*[local-name()='manifest']/*[local-name()='item']") {
+		href := resolveHref(item.SelectAttr("href"), link.Href)
+		if href == "" {
+			continue
+		}
+		items[item.SelectAttr("id")] = href
+		mediaType := item.SelectAttr("media-type")
+		switch {
+		case mediaType == mediatype.NCX.String() || (ncx == "" && strings.HasSuffix(strings.ToLower(href), ".ncx")):
+			ncx = href
+		case mediaType == mediatype.DTBook.String():
+			fs.TextTypes[href] = mediaType
+			texts = append(texts, href)
+		}
+	}
+	for _, itemref := range pkg.SelectElements("*[local-name()='spine']/*[local-name()='itemref']") {
+		if href, ok := items[itemref.SelectAttr("idref")]; ok {
+			fs.SMILs = appendUnique(fs.SMILs, href)
+		}
+	}
+
+	if ncx != "" {
+		if document, rerr := f.Get(manifest.Link{Href: ncx}).ReadAsXML(nil); rerr == nil {
+			nav := epub.ParseNCX(document, ncx)
+			fs.TOC = nav["toc"]
+			fs.PageList = nav["page-list"]
+		} // TODO log otherwise
+	}
+
+	// The DTBook documents may declare metadata missing from the package document, as well as the title and author
+	// of the book in the front matter.
+	for _, href := range texts {
+		document, rerr := f.Get(manifest.Link{Href: href}).ReadAsXML(nil)
+		if rerr != nil {
+			// TODO log
+			continue
+		}
+		fs.Metadata.merge(readDTBookMetadata(document))
+	}
+
+	return fs, nil
+}
+
+// Reads the Dublin Core elements and the <meta> elements of the package document, with or without their
+// <dc-metadata> and <x-metadata> wrappers.
+func readPackageMetadata(m metadata, md *xmlquery.Node) {
+	var visit func(n *xmlquery.Node)
+	visit = func(n *xmlquery.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != xmlquery.ElementNode {
+				continue
+			}
+			switch {
+			case c.NamespaceURI == namespaceDC:
+				m.add("dc:"+c.Data, c.InnerText())
+			case c.Data == "meta":
+				m.add(c.SelectAttr("name"), c.SelectAttr("content"))
+			default:
+				visit(c)
+			}
+		}
+	}
+	visit(md)
+}
+
+func readDTBookMetadata(document *xmlquery.Node) metadata {
+	m := make(metadata)
+	for _, meta := range document.SelectElements("//*[local-name()='dtbook']/*[local-name()='head']/*[local-name()='meta']") {
+		m.add(meta.SelectAttr("name"), meta.SelectAttr("content"))
+	}
+	if el := document.SelectElement("//*[local-name()='frontmatter']/*[local-name()='doctitle']"); el != nil {
+		m.add("dc:title", el.InnerText())
+	}
+	if el := document.SelectElement("//*[local-name()='frontmatter']/*[local-name()='docauthor']"); el != nil {
+		m.add("dc:creator", el.InnerText())
+	}
+	return m
+}
@@ -0,0 +1,342 @@
+package daisy
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/pub"
+)
+
+// Parses a DAISY Digital Talking Book, either a DAISY 2.02 fileset or a DAISY 3 (ANSI/NISO Z39.86) one.
+//
+// Books narrated with audio are converted to an audiobook, whose table of contents and page list point to the audio
+// clips synchronized by the SMIL files. When they also contain the text of the book, each narrated fragment is
+// listed in the "guided" subcollection with the text as an alternate. Text-only books use the text documents as
+// their reading order.
+// References:
+//   - https://daisy.org/activities/standards/daisy/daisy-2/daisy-format-2-02-specification/
+//   - https://daisy.org/activities/standards/daisy/daisy-3/z39-86-2005-r2012-specifications-for-the-digital-talking-book/
+type Parser struct {
+}
+
+func NewParser() Parser {
+	return Parser{}
+}
+
+// Navigation and structure of a DAISY fileset, before its SMIL files are resolved.
+type fileset struct {
+	Metadata  metadata
+	SMILs     []string          // HREFs of the SMIL files, in reading order.
+	TOC       manifest.LinkList // Links targeting SMIL elements.
+	PageList  manifest.LinkList // Links targeting SMIL elements.
+	TextTypes map[string]string // Media types of the text documents declared by the fileset, by HREF.
+}
+
+// Parse implements PublicationParser
+func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	if !asset.MediaType().Equal(&mediatype.DAISY) {
+		return nil, nil
+	}
+
+	links, err := f.Links()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch links")
+	}
+	var fs *fileset
+	if link := findLink(links, ".opf"); link != nil {
+		fs, err = readPackage(f, *link)
+	} else if link := findLink(links, "ncc.html", "ncc.htm"); link != nil {
+		fs, err = readNCC(f, links, *link)
+	} else {
+		return nil, errors.New("unable to find DAISY package document or NCC")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := book{fileset: fs, links: links, targets: make(map[string]int)}
+	for _, href := range fs.SMILs {
+		document, rerr := f.Get(manifest.Link{Href: href}).ReadAsXML(nil)
+		if rerr != nil {
+			return nil, errors.Wrapf(rerr, "failed parsing SMIL file %s", href)
+		}
+		b.addSMIL(href, parseSMIL(document, href))
+	}
+
+	metadata := fs.Metadata.manifest()
+	if metadata.Title() == "" {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(fallbackTitle(asset.Name()))
+	}
+
+	var manifest manifest.Manifest
+	var services map[string]pub.ServiceFactory
+	if b.hasAudio() {
+		manifest, services = b.audiobook(metadata)
+	} else {
+		manifest, services = b.textBook(metadata)
+	}
+	if len(manifest.ReadingOrder) == 0 {
+		return nil, errors.New("DAISY fileset has no audio or text content")
+	}
+	return pub.NewBuilder(manifest, f, pub.NewServicesBuilder(services)), nil
+}
+
+// Finds the first resource with the given file name (e.g. "ncc.html") or extension (e.g. ".opf").
+func findLink(links manifest.LinkList, suffixes ...string) *manifest.Link {
+	for i, link := range links {
+		if extensions.IsHiddenOrThumbs(link.Href) {
+			continue
+		}
+		name := strings.ToLower(path.Base(link.Href))
+		for _, suffix := range suffixes {
+			if name == suffix || (strings.HasPrefix(suffix, ".") && path.Ext(name) == suffix) {
+				return &links[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Derives a title from the file name, e.g. "book" for "book.zip".
+func fallbackTitle(name string) string {
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// Content of a DAISY fileset, once its SMIL files are flattened into segments.
+type book struct {
+	*fileset
+	links    manifest.LinkList
+	segments []segment
+	targets  map[string]int // Index of the segment targeted by SMIL HREFs, with or without an element ID.
+}
+
+func (b *book) addSMIL(href string, segments []segment) {
+	if len(segments) == 0 {
+		return
+	}
+	b.targets[href] = len(b.segments)
+	for i, s := range segments {
+		for _, id := range s.IDs {
+			if _, ok := b.targets[href+"#"+id]; !ok {
+				b.targets[href+"#"+id] = len(b.segments) + i
+			}
+		}
+	}
+	b.segments = append(b.segments, segments...)
+}
+
+func (b *book) hasAudio() bool {
+	for _, s := range b.segments {
+		if len(s.Clips) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the first segment matching [accept], starting from the one targeted by [href].
+func (b *book) resolve(href string, accept func(s segment) bool) *segment {
+	i, ok := b.targets[href]
+	if !ok {
+		// The SMIL element doesn't exist, so we fall back on the start of the SMIL file.
+		if i, ok = b.targets[strings.SplitN(href, "#", 2)[0]]; !ok {
+			return nil
+		}
+	}
+	for ; i < len(b.segments); i++ {
+		if accept(b.segments[i]) {
+			return &b.segments[i]
+		}
+	}
+	return nil
+}
+
+// Replaces the SMIL targets of [links] with the HREFs returned by [resolve].
+// Links which can't be resolved are dropped, but their children are kept.
+func resolveLinks(links manifest.LinkList, resolve func(href string) string) manifest.LinkList {
+	var resolved manifest.LinkList
+	for _, link := range links {
+		children := resolveLinks(link.Children, resolve)
+		href := resolve(link.Href)
+		if href == "" {
+			resolved = append(resolved, children...)
+			continue
+		}
+		resolved = append(resolved, manifest.Link{
+			Href:     href,
+			Title:    link.Title,
+			Children: children,
+		})
+	}
+	return resolved
+}
+
+// Media type of the resource at [href], as declared by the fileset or the fetcher.
+func (b *book) linkType(href string) string {
+	if t, ok := b.TextTypes[href]; ok {
+		return t
+	}
+	if link := b.links.FirstWithHref(href); link != nil && link.Type != "" {
+		return link.Type
+	}
+	if mt := mediatype.OfExtension(strings.TrimPrefix(path.Ext(href), ".")); mt != nil {
+		return mt.String()
+	}
+	return ""
+}
+
+// Resources of the fileset which are not part of the reading order.
+func (b *book) resources(readingOrder manifest.LinkList) manifest.LinkList {
+	var resources manifest.LinkList
+	for _, link := range b.links {
+		if extensions.IsHiddenOrThumbs(link.Href) || readingOrder.FirstWithHref(link.Href) != nil {
+			continue
+		}
+		resources = append(resources, manifest.Link{Href: link.Href, Type: b.linkType(link.Href)})
+	}
+	return resources
+}
+
+// Builds an audiobook from the audio clips of the SMIL files.
+func (b *book) audiobook(metadata manifest.Metadata) (manifest.Manifest, map[string]pub.ServiceFactory) {
+	hasClips := func(s segment) bool {
+		return len(s.Clips) > 0
+	}
+
+	var readingOrder manifest.LinkList
+	durations := make(map[string]float64)
+	for _, s := range b.segments {
+		for _, c := range s.Clips {
+			if _, ok := durations[c.Href]; !ok {
+				readingOrder = append(readingOrder, manifest.Link{Href: c.Href, Type: b.linkType(c.Href)})
+				durations[c.Href] = 0
+			}
+			if c.End > durations[c.Href] {
+				durations[c.Href] = c.End
+			}
+		}
+	}
+	var totalDuration float64
+	for i, link := range readingOrder {
+		readingOrder[i].Duration = durations[link.Href]
+		if totalDuration >= 0 && readingOrder[i].Duration > 0 {
+			totalDuration += readingOrder[i].Duration
+		} else {
+			totalDuration = -1 // Unknown when any file has no duration.
+		}
+	}
+	if metadata.Duration == nil && totalDuration > 0 {
+		metadata.Duration = &totalDuration
+	}
+	metadata.ConformsTo = manifest.Profiles{manifest.ProfileAudiobook}
+
+	resolve := func(href string) string {
+		s := b.resolve(href, hasClips)
+		if s == nil {
+			return ""
+		}
+		return s.Clips[0].Href + "#t=" + formatTime(s.Clips[0].Begin)
+	}
+
+	m := manifest.Manifest{
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       b.resources(readingOrder),
+		TableOfContents: resolveLinks(b.TOC, resolve),
+	}
+	if pageList := resolveLinks(b.PageList, resolve); len(pageList) > 0 {
+		m.Subcollections = manifest.PublicationCollectionMap{
+			"pageList": {{Links: pageList}},
+		}
+	}
+	if guided := b.guidedNavigation(); len(guided) > 0 {
+		if m.Subcollections == nil {
+			m.Subcollections = make(manifest.PublicationCollectionMap)
+		}
+		m.Subcollections["guided"] = []manifest.PublicationCollection{{Links: guided}}
+	}
+
+	return m, map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: pub.AudioPositionsServiceFactory(pub.DefaultAudioPositionsInterval, "audio/*"),
+		pub.LocatorService_Name:   pub.AudioLocatorServiceFactory(),
+	}
+}
+
+// Lists the narrated text fragments in reading order, as audio clips having the text fragment as an alternate.
+// Consecutive clips of the same audio file are merged.
+func (b *book) guidedNavigation() manifest.LinkList {
+	var guided manifest.LinkList
+	for _, s := range b.segments {
+		if s.Text == "" {
+			continue
+		}
+		text := manifest.Link{Href: s.Text, Type: b.linkType(strings.SplitN(s.Text, "#", 2)[0])}
+		for i := 0; i < len(s.Clips); {
+			c := s.Clips[i]
+			for i++; i < len(s.Clips) && s.Clips[i].Href == c.Href && s.Clips[i].Begin == c.End; i++ {
+				c.End = s.Clips[i].End
+			}
+			fragment := "#t=" + formatTime(c.Begin)
+			if c.End >= 0 {
+				fragment += "," + formatTime(c.End)
+			}
+			guided = append(guided, manifest.Link{
+				Href:       c.Href + fragment,
+				Type:       b.linkType(c.Href),
+				Alternates: manifest.LinkList{text},
+			})
+		}
+	}
+	return guided
+}
+
+// Builds a publication from the text documents of a fileset without audio, such as a DAISY 3 "textNCX" book.
+func (b *book) textBook(metadata manifest.Metadata) (manifest.Manifest, map[string]pub.ServiceFactory) {
+	hasText := func(s segment) bool {
+		return s.Text != ""
+	}
+
+	var readingOrder manifest.LinkList
+	for _, s := range b.segments {
+		if href := strings.SplitN(s.Text, "#", 2)[0]; href != "" && readingOrder.FirstWithHref(href) == nil {
+			readingOrder = append(readingOrder, manifest.Link{Href: href, Type: b.linkType(href)})
+		}
+	}
+
+	resolve := func(href string) string {
+		if s := b.resolve(href, hasText); s != nil {
+			return s.Text
+		}
+		return ""
+	}
+
+	m := manifest.Manifest{
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       b.resources(readingOrder),
+		TableOfContents: resolveLinks(b.TOC, resolve),
+	}
+	if pageList := resolveLinks(b.PageList, resolve); len(pageList) > 0 {
+		m.Subcollections = manifest.PublicationCollectionMap{
+			"pageList": {{Links: pageList}},
+		}
+	}
+
+	return m, map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: epub.PositionsServiceFactory(epub.OriginalLength{PageLength: 1024}),
+	}
+}
+
+func formatTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
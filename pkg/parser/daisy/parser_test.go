@@ -0,0 +1,158 @@
+package daisy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withDAISY(t *testing.T, filepath string, f func(*pub.Publication)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewParser().Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	f(p.Build())
+}
+
+func TestDAISY2Metadata(t *testing.T) {
+	withDAISY(t, "./testdata/raven.zip", func(p *pub.Publication) {
+		m := p.Manifest.Metadata
+		assert.Equal(t, "The Raven", m.Title())
+		assert.Equal(t, "urn:uuid:7b1f5a6e-0d4c-4a43-9a0e-6f6d6a3b2c11", m.Identifier)
+		assert.Equal(t, manifest.Strings{"en"}, m.Languages)
+		assert.Equal(t, manifest.Profiles{manifest.ProfileAudiobook}, m.ConformsTo)
+		assert.Equal(t, time.Date(2001, 5, 17, 0, 0, 0, 0, time.UTC), *m.Published)
+		assert.Equal(t, 20.0, *m.Duration)
+		if assert.Len(t, m.Authors, 1) {
+			assert.Equal(t, "Edgar Allan Poe", m.Authors[0].Name())
+		}
+		if assert.Len(t, m.Narrators, 1) {
+			assert.Equal(t, "Jane Doe", m.Narrators[0].Name())
+		}
+		if assert.Len(t, m.Publishers, 1) {
+			assert.Equal(t, "Talking Books Inc.", m.Publishers[0].Name())
+		}
+	})
+}
+
+func TestDAISY2Navigation(t *testing.T) {
+	withDAISY(t, "./testdata/raven.zip", func(p *pub.Publication) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/raven/raven1.mp3", Type: "audio/mpeg", Duration: 8},
+			{Href: "/raven/raven2.mp3", Type: "audio/mpeg", Duration: 12},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/raven/raven1.mp3#t=0", Title: "The Raven", Children: manifest.LinkList{
+				{Href: "/raven/raven1.mp3#t=2.5", Title: "Stanza 1"},
+				{Href: "/raven/raven2.mp3#t=1.5", Title: "Stanza 2"},
+			}},
+			{Href: "/raven/raven2.mp3#t=6", Title: "Afterword"},
+		}, p.Manifest.TableOfContents)
+		if pageList := p.Manifest.Subcollections["pageList"]; assert.Len(t, pageList, 1) {
+			assert.Equal(t, manifest.LinkList{{Href: "/raven/raven2.mp3#t=0", Title: "1"}}, manifest.LinkList(pageList[0].Links))
+		}
+	})
+}
+
+func TestDAISY2GuidedNavigation(t *testing.T) {
+	withDAISY(t, "./testdata/raven.zip", func(p *pub.Publication) {
+		if guided := p.Manifest.Subcollections["guided"]; assert.Len(t, guided, 1) && assert.Len(t, guided[0].Links, 5) {
+			// Consecutive clips of the same paragraph are merged.
+			assert.Equal(t, manifest.Link{
+				Href: "/raven/raven1.mp3#t=2.5,8",
+				Type: "audio/mpeg",
+				Alternates: manifest.LinkList{
+					{Href: "/raven/content.html#c2", Type: "text/html"},
+				},
+			}, guided[0].Links[1])
+		}
+		assert.NotNil(t, p.Manifest.Resources.FirstWithHref("/raven/content.html"))
+	})
+}
+
+func TestDAISY3Audio(t *testing.T) {
+	withDAISY(t, "./testdata/alice.zip", func(p *pub.Publication) {
+		m := p.Manifest.Metadata
+		assert.Equal(t, "Alice in Wonderland", m.Title())
+		assert.Equal(t, "us-nls-alice", m.Identifier)
+		assert.Equal(t, manifest.Strings{"en-US"}, m.Languages)
+		assert.Equal(t, 21.25, *m.Duration)
+		// Declared in the DTBook document only.
+		assert.Equal(t, "A girl falls down a rabbit hole.", m.Description)
+		assert.Equal(t, time.Date(2005, 7, 1, 0, 0, 0, 0, time.UTC), *m.Published)
+		if assert.Len(t, m.Publishers, 1) {
+			assert.Equal(t, "Daisy Press", m.Publishers[0].Name())
+		}
+		if assert.Len(t, m.Narrators, 1) {
+			assert.Equal(t, "John Smith", m.Narrators[0].Name())
+		}
+		if assert.Len(t, m.Subjects, 1) {
+			assert.Equal(t, "Fiction", m.Subjects[0].Name())
+		}
+
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/audio/alice1.mp3", Type: "audio/mpeg", Duration: 15},
+			{Href: "/audio/alice2.mp3", Type: "audio/mpeg", Duration: 6.25},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/audio/alice1.mp3#t=3.5", Title: "Down the Rabbit-Hole", Children: manifest.LinkList{
+				{Href: "/audio/alice1.mp3#t=9", Title: "The Hall"},
+			}},
+			{Href: "/audio/alice2.mp3#t=0", Title: "The Pool of Tears"},
+		}, p.Manifest.TableOfContents)
+		if pageList := p.Manifest.Subcollections["pageList"]; assert.Len(t, pageList, 1) {
+			assert.Equal(t, manifest.LinkList{{Href: "/audio/alice1.mp3#t=5", Title: "1"}}, manifest.LinkList(pageList[0].Links))
+		}
+		if guided := p.Manifest.Subcollections["guided"]; assert.Len(t, guided, 1) && assert.Len(t, guided[0].Links, 9) {
+			assert.Equal(t, "/audio/alice2.mp3#t=2,6.25", guided[0].Links[8].Href)
+			assert.Equal(t, manifest.LinkList{
+				{Href: "/dtbook.xml#d8", Type: "application/x-dtbook+xml"},
+			}, guided[0].Links[8].Alternates)
+		}
+	})
+}
+
+func TestDAISY3Text(t *testing.T) {
+	withDAISY(t, "./testdata/alice-text.zip", func(p *pub.Publication) {
+		assert.Equal(t, "Alice in Wonderland", p.Manifest.Metadata.Title())
+		assert.Empty(t, p.Manifest.Metadata.ConformsTo)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/dtbook.xml", Type: "application/x-dtbook+xml"},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/dtbook.xml#d3", Title: "Down the Rabbit-Hole", Children: manifest.LinkList{
+				{Href: "/dtbook.xml#d5", Title: "The Hall"},
+			}},
+			{Href: "/dtbook.xml#d7", Title: "The Pool of Tears"},
+		}, p.Manifest.TableOfContents)
+		assert.Empty(t, p.Manifest.Subcollections["guided"])
+	})
+}
+
+func TestDAISYNestHeadings(t *testing.T) {
+	assert.Equal(t, manifest.LinkList{
+		{Href: "a", Children: manifest.LinkList{
+			{Href: "b", Children: manifest.LinkList{{Href: "c"}}},
+			{Href: "d"},
+		}},
+		{Href: "e"},
+	}, nestHeadings([]heading{
+		{Level: 1, Link: manifest.Link{Href: "a"}},
+		{Level: 3, Link: manifest.Link{Href: "b"}},
+		{Level: 4, Link: manifest.Link{Href: "c"}},
+		{Level: 2, Link: manifest.Link{Href: "d"}},
+		{Level: 1, Link: manifest.Link{Href: "e"}},
+	}))
+}
@@ -0,0 +1,114 @@
+package daisy
+
+import (
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/xmlquery"
+)
+
+// A synchronization point of a SMIL file, usually a <par> element, pairing a text fragment with the audio clips
+// narrating it.
+type segment struct {
+	IDs   []string // IDs of the SMIL elements pointing to this segment, used as NCC and NCX targets.
+	Text  string   // HREF of the text fragment, if any.
+	Clips []clip
+}
+
+// Part of an audio file, in seconds. [End] is negative when unknown.
+type clip struct {
+	Href  string
+	Begin float64
+	End   float64
+}
+
+// Flattens the body of a SMIL file into an ordered list of segments.
+// Both SMIL 1.0 (DAISY 2.02) and the DAISY 3 profile of SMIL 2.0 are supported.
+// Reference: https://daisy.org/activities/standards/daisy/daisy-2/daisy-format-2-02-specification/#smil
+func parseSMIL(document *xmlquery.Node, href string) []segment {
+	body := document.SelectElement("//*[local-name()='smil']/*[local-name()='body']")
+	if body == nil {
+		return nil
+	}
+	p := smilParser{href: href}
+	p.walk(body)
+	return p.segments
+}
+
+type smilParser struct {
+	href     string
+	segments []segment
+	pending  []string // IDs of the enclosing <seq> elements, attached to the next segment.
+}
+
+func (p *smilParser) walk(parent *xmlquery.Node) {
+	for n := parent.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != xmlquery.ElementNode {
+			continue
+		}
+		switch n.Data {
+		case "par", "text", "audio":
+			p.add(n)
+		default:
+			if id := n.SelectAttr("id"); id != "" {
+				p.pending = append(p.pending, id)
+			}
+			p.walk(n)
+		}
+	}
+}
+
+// Adds a segment for a <par> element, or a standalone <text> or <audio> one.
+func (p *smilParser) add(n *xmlquery.Node) {
+	s := segment{IDs: p.pending}
+	p.pending = nil
+	var visit func(n *xmlquery.Node)
+	visit = func(n *xmlquery.Node) {
+		if id := n.SelectAttr("id"); id != "" {
+			s.IDs = append(s.IDs, id)
+		}
+		switch n.Data {
+		case "text":
+			if s.Text == "" {
+				s.Text = resolveHref(n.SelectAttr("src"), p.href)
+			}
+		case "audio":
+			if c := p.clip(n); c != nil {
+				s.Clips = append(s.Clips, *c)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == xmlquery.ElementNode {
+				visit(c)
+			}
+		}
+	}
+	visit(n)
+	p.segments = append(p.segments, s)
+}
+
+func (p *smilParser) clip(n *xmlquery.Node) *clip {
+	href := resolveHref(n.SelectAttr("src"), p.href)
+	if href == "" {
+		return nil
+	}
+	c := clip{Href: href, End: -1}
+	if begin := clockAttr(n, "clipBegin", "clip-begin"); begin != nil {
+		c.Begin = *begin
+	}
+	if end := clockAttr(n, "clipEnd", "clip-end"); end != nil {
+		c.End = *end
+	}
+	return &c
+}
+
+// Reads a clock value from the first available attribute. SMIL 1.0 values are prefixed with "npt=", e.g.
+// clip-begin="npt=2.5s".
+func clockAttr(n *xmlquery.Node, names ...string) *float64 {
+	for _, name := range names {
+		if value := n.SelectAttr(name); value != "" {
+			return epub.ParseClockValue(strings.TrimPrefix(strings.TrimSpace(value), "npt="))
+		}
+	}
+	return nil
+}
@@ -12,6 +12,8 @@ const (
 	NamespaceXHTML = "http://www.w3.org/1999/xhtml"
 	NamespaceSMIL  = "http://www.w3.org/ns/SMIL"
 	NamespaceNCX   = "http://www.daisy.org/z3986/2005/ncx/"
+
+	NamespaceRendition = "http://www.idpf.org/2013/rendition"
 )
 
 // Vocabularies
@@ -12,6 +12,8 @@ import (
 
 type Parser struct {
 	reflowablePositionsStrategy ReflowableStrategy
+	renditionSelector           RenditionSelector
+	xmlRecovery                 fetcher.XMLRecovery
 }
 
 func NewParser(strategy ReflowableStrategy) Parser {
@@ -20,9 +22,27 @@ func NewParser(strategy ReflowableStrategy) Parser {
 	}
 	return Parser{
 		reflowablePositionsStrategy: strategy,
+		renditionSelector:           DefaultRendition{},
+		xmlRecovery:                 fetcher.DefaultXMLRecovery,
 	}
 }
 
+// Returns a copy of the parser opening the rendition chosen by [selector], when the EPUB has multiple renditions.
+func (p Parser) WithRenditionSelector(selector RenditionSelector) Parser {
+	if selector == nil {
+		selector = DefaultRendition{}
+	}
+	p.renditionSelector = selector
+	return p
+}
+
+// Returns a copy of the parser recovering from malformed XML documents according to [recovery].
+// By default, the parser recovers from all the errors it can, without reporting them.
+func (p Parser) WithXMLRecovery(recovery fetcher.XMLRecovery) Parser {
+	p.xmlRecovery = recovery
+	return p
+}
+
 // Parse implements PublicationParser
 func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
 	fallbackTitle := asset.Name()
@@ -31,20 +51,26 @@ func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Bui
 		return nil, nil
 	}
 
-	opfPath, err := GetRootFilePath(f)
+	renditions, err := getRenditions(f, p.xmlRecovery)
 	if err != nil {
 		return nil, err
 	}
-	if opfPath[0] != '/' {
-		opfPath = "/" + opfPath
+	selector := p.renditionSelector
+	if selector == nil {
+		selector = DefaultRendition{}
+	}
+	selected := selector.SelectRendition(renditions)
+	if selected < 0 || selected >= len(renditions) {
+		selected = 0
 	}
+	opfPath := renditions[selected].Path
 
-	opfXmlDocument, errx := f.Get(manifest.Link{Href: opfPath}).ReadAsXML(map[string]string{
-		NamespaceOPF:                         "opf",
-		NamespaceDC:                          "dc",
-		VocabularyDCTerms:                    "dcterms",
-		"http://www.idpf.org/2013/rendition": "rendition",
-	})
+	opfXmlDocument, errx := readXML(f, manifest.Link{Href: opfPath}, map[string]string{
+		NamespaceOPF:       "opf",
+		NamespaceDC:        "dc",
+		VocabularyDCTerms:  "dcterms",
+		NamespaceRendition: "rendition",
+	}, p.xmlRecovery)
 	if errx != nil {
 		return nil, errx
 	}
@@ -57,15 +83,25 @@ func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Bui
 	manifest := PublicationFactory{
 		FallbackTitle:   fallbackTitle,
 		PackageDocument: *packageDocument,
-		NavigationData:  parseNavigationData(*packageDocument, f),
-		EncryptionData:  parseEncryptionData(f),
-		DisplayOptions:  parseDisplayOptions(f),
+		NavigationData:  parseNavigationData(*packageDocument, f, p.xmlRecovery),
+		EncryptionData:  parseEncryptionData(f, p.xmlRecovery),
+		DisplayOptions:  parseDisplayOptions(f, p.xmlRecovery),
 	}.Create()
 
+	// The other renditions are exposed as alternates of the opened one.
+	for i, rendition := range renditions {
+		if i != selected {
+			link := rendition.Link()
+			link.Rels = []string{"alternate"}
+			manifest.Links = append(manifest.Links, link)
+		}
+	}
+
 	ffetcher := f
 	if manifest.Metadata.Identifier != "" {
 		ffetcher = fetcher.NewTransformingFetcher(f, NewDeobfuscator(manifest.Metadata.Identifier).Transform)
 	}
+	addViewports(&manifest, ffetcher)
 
 	builder := pub.NewServicesBuilder(map[string]pub.ServiceFactory{
 		pub.PositionsService_Name: PositionsServiceFactory(p.reflowablePositionsStrategy),
@@ -73,19 +109,19 @@ func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Bui
 	return pub.NewBuilder(manifest, ffetcher, builder), nil
 }
 
-func parseEncryptionData(fetcher fetcher.Fetcher) (ret map[string]manifest.Encryption) {
-	n, err := fetcher.Get(manifest.Link{Href: "/META-INF/encryption.xml"}).ReadAsXML(map[string]string{
+func parseEncryptionData(fetcher fetcher.Fetcher, recovery fetcher.XMLRecovery) (ret map[string]manifest.Encryption) {
+	n, err := readXML(fetcher, manifest.Link{Href: "/META-INF/encryption.xml"}, map[string]string{
 		NamespaceENC:  "enc",
 		NamespaceSIG:  "ds",
 		NamespaceCOMP: "comp",
-	})
+	}, recovery)
 	if err != nil {
 		return
 	}
 	return ParseEncryption(n)
 }
 
-func parseNavigationData(packageDocument PackageDocument, fetcher fetcher.Fetcher) (ret map[string]manifest.LinkList) {
+func parseNavigationData(packageDocument PackageDocument, fetcher fetcher.Fetcher, recovery fetcher.XMLRecovery) (ret map[string]manifest.LinkList) {
 	ret = make(map[string]manifest.LinkList)
 	if packageDocument.EPUBVersion < 3.0 {
 		var ncxItem *Item
@@ -111,9 +147,9 @@ func parseNavigationData(packageDocument PackageDocument, fetcher fetcher.Fetche
 		if err != nil {
 			return
 		}
-		n, nerr := fetcher.Get(manifest.Link{Href: ncxPath}).ReadAsXML(map[string]string{
+		n, nerr := readXML(fetcher, manifest.Link{Href: ncxPath, Type: ncxItem.MediaType}, map[string]string{
 			NamespaceNCX: "ncx",
-		})
+		}, recovery)
 		if nerr != nil {
 			return
 		}
@@ -138,10 +174,11 @@ func parseNavigationData(packageDocument PackageDocument, fetcher fetcher.Fetche
 		if err != nil {
 			return
 		}
-		n, errx := fetcher.Get(manifest.Link{Href: navPath}).ReadAsXML(map[string]string{
+		// The media type lets a malformed navigation document be recovered with an HTML5 parser.
+		n, errx := readXML(fetcher, manifest.Link{Href: navPath, Type: navItem.MediaType}, map[string]string{
 			NamespaceXHTML: "html",
 			NamespaceOPS:   "epub",
-		})
+		}, recovery)
 		if errx != nil {
 			return
 		}
@@ -150,11 +187,11 @@ func parseNavigationData(packageDocument PackageDocument, fetcher fetcher.Fetche
 	return
 }
 
-func parseDisplayOptions(fetcher fetcher.Fetcher) (ret map[string]string) {
+func parseDisplayOptions(fetcher fetcher.Fetcher, recovery fetcher.XMLRecovery) (ret map[string]string) {
 	ret = make(map[string]string)
-	displayOptionsXml, err := fetcher.Get(manifest.Link{Href: "/META-INF/com.apple.ibooks.display-options.xml"}).ReadAsXML(nil)
+	displayOptionsXml, err := readXML(fetcher, manifest.Link{Href: "/META-INF/com.apple.ibooks.display-options.xml"}, nil, recovery)
 	if err != nil {
-		displayOptionsXml, err = fetcher.Get(manifest.Link{Href: "/META-INF/com.kobobooks.display-options.xml"}).ReadAsXML(nil)
+		displayOptionsXml, err = readXML(fetcher, manifest.Link{Href: "/META-INF/com.kobobooks.display-options.xml"}, nil, recovery)
 		if err != nil {
 			return
 		}
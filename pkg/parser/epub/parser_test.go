@@ -0,0 +1,65 @@
+package epub
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestEPUB(dir string, parser Parser) (*pub.Builder, error) {
+	a := asset.FileWithMediaType(dir, &mediatype.EPUB)
+	f, err := a.CreateFetcher(asset.Dependencies{ArchiveFactory: archive.NewArchiveFactory()}, "")
+	if err != nil {
+		return nil, err
+	}
+	return parser.Parse(a, f)
+}
+
+func openTestEPUB(t *testing.T, dir string, parser Parser) *pub.Publication {
+	builder, err := parseTestEPUB(dir, parser)
+	if !assert.NoError(t, err) || !assert.NotNil(t, builder) {
+		t.FailNow()
+	}
+	return builder.Build()
+}
+
+func TestParserRecoversMalformedXML(t *testing.T) {
+	var repairs []fetcher.XMLRepair
+	p := openTestEPUB(t, "./testdata/malformed", NewParser(nil).WithXMLRecovery(fetcher.XMLRecovery{
+		Lenient: true,
+		HTML:    true,
+		Report: func(repair fetcher.XMLRepair) {
+			repairs = append(repairs, repair)
+		},
+	}))
+
+	assert.Equal(t, "Pride & Prejudice", p.Manifest.Metadata.Title())
+	assert.Equal(t, manifest.LinkList{
+		{Href: "/OEBPS/chapter1.xhtml", Title: "Chapter 1"},
+		{Href: "/OEBPS/chapter2.xhtml", Title: "Chapter 2"},
+	}, p.Manifest.TableOfContents)
+
+	if assert.Len(t, repairs, 2) {
+		assert.Equal(t, "/OEBPS/package.opf", repairs[0].Href)
+		assert.Equal(t, fetcher.XMLRepairLenient, repairs[0].Method)
+		assert.Equal(t, "/OEBPS/nav.xhtml", repairs[1].Href)
+		assert.Equal(t, fetcher.XMLRepairHTML, repairs[1].Method)
+	}
+}
+
+func TestParserRecoversMalformedXMLByDefault(t *testing.T) {
+	p := openTestEPUB(t, "./testdata/malformed", NewParser(nil))
+	assert.Equal(t, "Pride & Prejudice", p.Manifest.Metadata.Title())
+	assert.Len(t, p.Manifest.TableOfContents, 2)
+}
+
+func TestParserStrictXML(t *testing.T) {
+	_, err := parseTestEPUB("./testdata/malformed", NewParser(nil).WithXMLRecovery(fetcher.StrictXML))
+	assert.Error(t, err)
+}
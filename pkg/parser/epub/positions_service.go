@@ -129,9 +129,14 @@ func PositionsServiceFactory(reflowableStrategy ReflowableStrategy) pub.ServiceF
 		if reflowableStrategy == nil {
 			reflowableStrategy = RecommendedReflowableStrategy
 		}
+		presentation := context.Manifest.Metadata.Presentation
+		if presentation == nil {
+			// Web Publications don't necessarily declare their presentation.
+			presentation = &manifest.Presentation{}
+		}
 		return &PositionsService{
 			readingOrder:       context.Manifest.ReadingOrder,
-			presentation:       context.Manifest.Metadata.Presentation,
+			presentation:       presentation,
 			fetcher:            context.Fetcher,
 			reflowableStrategy: reflowableStrategy,
 		}
@@ -160,7 +165,7 @@ func (l OriginalLength) PositionCount(resource fetcher.Resource) uint {
 		length, _ = resource.Length()
 	}
 
-	return uint(math.Min(math.Ceil(float64(length)/float64(l.PageLength)), 1))
+	return uint(math.Max(math.Ceil(float64(length)/float64(l.PageLength)), 1))
 }
 
 // Use the archive entry length (whether it is compressed or stored) and split it by the given [PageLength].
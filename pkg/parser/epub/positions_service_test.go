@@ -3,6 +3,8 @@ package epub
 import (
 	"testing"
 
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +31,15 @@ func TestEPUBPositionsServiceEmptyReadingOrder(t *testing.T) {
 	}}, service.Positions())
 }
 */
+
+func TestEPUBPositionsServiceOriginalLengthStrategy(t *testing.T) {
+	strategy := OriginalLength{PageLength: 50}
+	resource := func(length int) fetcher.Resource {
+		return fetcher.NewBytesResource(manifest.Link{Href: "res"}, func() []byte {
+			return make([]byte, length)
+		})
+	}
+	assert.Equal(t, uint(1), strategy.PositionCount(resource(0)))
+	assert.Equal(t, uint(1), strategy.PositionCount(resource(50)))
+	assert.Equal(t, uint(3), strategy.PositionCount(resource(101)))
+}
@@ -0,0 +1,199 @@
+package epub
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/xmlquery"
+)
+
+// Rendition of a Multiple-Rendition Publication, declared by a rootfile of the container.
+// The first rendition is the default one.
+// Reference: https://www.w3.org/publishing/epub32/epub-multi-rend.html
+type Rendition struct {
+	Path        string              // Path to the package document.
+	Media       string              // CSS media query of the device the rendition is meant for.
+	Layout      manifest.EPUBLayout // Layout of the rendition, empty when unspecified.
+	Language    string              // Language of the rendition (BCP 47 tag).
+	AccessModes []string            // Access modes required to consume the rendition, e.g. auditory or visual.
+	Label       string              // Human-readable description of the rendition.
+}
+
+// Returns the link to the package document of this rendition, with its selection attributes as properties.
+func (r Rendition) Link() manifest.Link {
+	link := manifest.Link{
+		Href:  r.Path,
+		Type:  mediatype.OPF.String(),
+		Title: r.Label,
+	}
+	if r.Language != "" {
+		link.Languages = manifest.Strings{r.Language}
+	}
+	if r.Layout != "" {
+		link.Properties.Add(manifest.Properties{"layout": string(r.Layout)})
+	}
+	if r.Media != "" {
+		link.Properties.Add(manifest.Properties{"media": r.Media})
+	}
+	if len(r.AccessModes) > 0 {
+		link.Properties.Add(manifest.Properties{"accessMode": r.AccessModes})
+	}
+	return link
+}
+
+// Reads the renditions declared in the container of the EPUB, in document order.
+func GetRenditions(f fetcher.Fetcher) ([]Rendition, error) {
+	return getRenditions(f, fetcher.DefaultXMLRecovery)
+}
+
+func getRenditions(f fetcher.Fetcher, recovery fetcher.XMLRecovery) ([]Rendition, error) {
+	xml, err := readXML(f, manifest.Link{Href: "/META-INF/container.xml"}, map[string]string{
+		NamespaceOPC:       "cn",
+		NamespaceRendition: "rendition",
+	}, recovery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed loading container.xml")
+	}
+	renditions := ParseContainer(xml)
+	if len(renditions) == 0 {
+		return nil, errors.New("rootfile not found in container")
+	}
+	return renditions, nil
+}
+
+// Parses the package document rootfiles of the container, with their rendition selection attributes.
+func ParseContainer(document *xmlquery.Node) []Rendition {
+	var renditions []Rendition
+	for _, n := range document.SelectElements("/container/rootfiles/rootfile") {
+		path := n.SelectAttr("full-path")
+		if path == "" {
+			continue
+		}
+		if mt := n.SelectAttr("media-type"); mt != "" && !mediatype.OPF.ContainsFromString(mt) {
+			continue
+		}
+		if path[0] != '/' {
+			path = "/" + path
+		}
+
+		rendition := Rendition{
+			Path:     path,
+			Media:    strings.TrimSpace(SelectNodeAttrNs(n, NamespaceRendition, "media")),
+			Language: strings.TrimSpace(SelectNodeAttrNs(n, NamespaceRendition, "language")),
+			Label:    strings.TrimSpace(SelectNodeAttrNs(n, NamespaceRendition, "label")),
+		}
+		switch strings.TrimSpace(SelectNodeAttrNs(n, NamespaceRendition, "layout")) {
+		case "pre-paginated":
+			rendition.Layout = manifest.EPUBLayoutFixed
+		case "reflowable":
+			rendition.Layout = manifest.EPUBLayoutReflowable
+		}
+		if modes := strings.Fields(SelectNodeAttrNs(n, NamespaceRendition, "accessMode")); len(modes) > 0 {
+			rendition.AccessModes = modes
+		}
+		renditions = append(renditions, rendition)
+	}
+	return renditions
+}
+
+// RenditionSelector chooses the rendition of a Multiple-Rendition Publication to open.
+type RenditionSelector interface {
+	SelectRendition(renditions []Rendition) int // Returns the index of the selected rendition in [renditions].
+}
+
+// Selects the default rendition, which is the first one.
+type DefaultRendition struct{}
+
+// SelectRendition implements RenditionSelector
+func (DefaultRendition) SelectRendition(renditions []Rendition) int {
+	return 0
+}
+
+// Selects the rendition best matching the preferences of the user and the capabilities of the device.
+//
+// A rendition is eligible when none of its selection attributes contradicts the preferences, and the eligible
+// rendition matching the most preferences is selected. The default rendition is used when no rendition is
+// eligible, or when several ones are equally good.
+type RenditionPreferences struct {
+	Layout      manifest.EPUBLayout     // Preferred layout.
+	Languages   []string                // Languages understood by the user, by order of preference.
+	AccessModes []string                // Access modes available to the user. When empty, all access modes are accepted.
+	Media       func(query string) bool // Evaluates a CSS media query against the device. When nil, media queries are ignored.
+}
+
+// SelectRendition implements RenditionSelector
+func (p RenditionPreferences) SelectRendition(renditions []Rendition) int {
+	selected, best := 0, -1
+	for i, r := range renditions {
+		score := p.score(r)
+		if score > best {
+			selected, best = i, score
+		}
+	}
+	return selected
+}
+
+// Returns how well the rendition matches the preferences, or -1 if it is not eligible.
+func (p RenditionPreferences) score(r Rendition) int {
+	score := 0
+	if r.Layout != "" && p.Layout != "" {
+		if r.Layout != p.Layout {
+			return -1
+		}
+		score++
+	}
+	if r.Language != "" && len(p.Languages) > 0 {
+		rank := languageRank(p.Languages, r.Language)
+		if rank < 0 {
+			return -1
+		}
+		// Preferred languages weigh more than the other attributes.
+		score += 2 * (len(p.Languages) - rank)
+	}
+	if len(r.AccessModes) > 0 && len(p.AccessModes) > 0 {
+		for _, mode := range r.AccessModes {
+			if !containsFold(p.AccessModes, mode) {
+				return -1
+			}
+		}
+		score++
+	}
+	if r.Media != "" && p.Media != nil {
+		if !p.Media(r.Media) {
+			return -1
+		}
+		score++
+	}
+	return score
+}
+
+// Returns the index of the first language of [languages] matching [tag], comparing their primary subtags when
+// they are not identical.
+func languageRank(languages []string, tag string) int {
+	primary := func(tag string) string {
+		return strings.SplitN(tag, "-", 2)[0]
+	}
+	for i, language := range languages {
+		if strings.EqualFold(language, tag) {
+			return i
+		}
+	}
+	for i, language := range languages {
+		if strings.EqualFold(primary(language), primary(tag)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package epub
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadRenditions(t *testing.T) []Rendition {
+	n, rerr := fetcher.NewFileResource(manifest.Link{}, "./testdata/container/multiple-renditions.xml").ReadAsXML(map[string]string{
+		NamespaceOPC:       "cn",
+		NamespaceRendition: "rendition",
+	})
+	if !assert.Nil(t, rerr) {
+		t.FailNow()
+	}
+	return ParseContainer(n)
+}
+
+func TestRenditionsParseContainer(t *testing.T) {
+	assert.Equal(t, []Rendition{
+		{Path: "/EPUB/reflowable.opf", Layout: manifest.EPUBLayoutReflowable, Language: "en", AccessModes: []string{"textual"}, Label: "Text"},
+		{Path: "/EPUB/fixed.opf", Layout: manifest.EPUBLayoutFixed, Language: "en", AccessModes: []string{"visual", "textual"}, Media: "(min-width: 1024px)", Label: "Print replica"},
+		{Path: "/EPUB/fr.opf", Language: "fr-CA", Label: "Français"},
+		{Path: "/EPUB/audio.opf", AccessModes: []string{"auditory"}, Label: "Audio"},
+	}, loadRenditions(t))
+}
+
+func TestRenditionsLink(t *testing.T) {
+	assert.Equal(t, manifest.Link{
+		Href:      "/EPUB/fixed.opf",
+		Type:      "application/oebps-package+xml",
+		Title:     "Print replica",
+		Languages: manifest.Strings{"en"},
+		Properties: manifest.Properties{
+			"layout":     "fixed",
+			"media":      "(min-width: 1024px)",
+			"accessMode": []string{"visual", "textual"},
+		},
+	}, loadRenditions(t)[1].Link())
+}
+
+func TestRenditionsDefaultSelection(t *testing.T) {
+	assert.Equal(t, 0, DefaultRendition{}.SelectRendition(loadRenditions(t)))
+	assert.Equal(t, 0, RenditionPreferences{}.SelectRendition(loadRenditions(t)))
+}
+
+func TestRenditionsSelectionByLayout(t *testing.T) {
+	renditions := loadRenditions(t)
+	assert.Equal(t, 1, RenditionPreferences{Layout: manifest.EPUBLayoutFixed}.SelectRendition(renditions))
+	assert.Equal(t, 0, RenditionPreferences{Layout: manifest.EPUBLayoutReflowable}.SelectRendition(renditions))
+}
+
+func TestRenditionsSelectionByLanguage(t *testing.T) {
+	renditions := loadRenditions(t)
+	assert.Equal(t, 2, RenditionPreferences{Languages: []string{"fr-FR", "en"}}.SelectRendition(renditions))
+	assert.Equal(t, 0, RenditionPreferences{Languages: []string{"en", "fr"}}.SelectRendition(renditions))
+	// No rendition matches, so the default one is used.
+	assert.Equal(t, 0, RenditionPreferences{Languages: []string{"de"}, AccessModes: []string{"visual"}}.SelectRendition(renditions))
+}
+
+func TestRenditionsSelectionByAccessModeAndMedia(t *testing.T) {
+	renditions := loadRenditions(t)
+	assert.Equal(t, 3, RenditionPreferences{Languages: []string{"de"}, AccessModes: []string{"auditory"}}.SelectRendition(renditions))
+
+	wide := func(query string) bool { return query == "(min-width: 1024px)" }
+	narrow := func(query string) bool { return false }
+	assert.Equal(t, 1, RenditionPreferences{AccessModes: []string{"visual", "textual"}, Media: wide, Layout: manifest.EPUBLayoutFixed}.SelectRendition(renditions))
+	// The French rendition doesn't contradict any preference.
+	assert.Equal(t, 2, RenditionPreferences{AccessModes: []string{"visual", "textual"}, Media: narrow, Layout: manifest.EPUBLayoutFixed}.SelectRendition(renditions))
+}
+
+func TestRenditionsParserOpensDefaultRendition(t *testing.T) {
+	p := openTestEPUB(t, "./testdata/renditions", NewParser(nil))
+	assert.Equal(t, "Multiple Renditions (reflowable)", p.Manifest.Metadata.Title())
+	assert.Equal(t, "/reflowable/chapter.xhtml", p.Manifest.ReadingOrder[0].Href)
+
+	alternate := p.Manifest.LinksWithRel("alternate")
+	assert.Equal(t, []manifest.Link{{
+		Href:       "/fixed/package.opf",
+		Type:       "application/oebps-package+xml",
+		Title:      "Fixed layout",
+		Rels:       manifest.Strings{"alternate"},
+		Properties: manifest.Properties{"layout": "fixed"},
+	}}, alternate)
+}
+
+func TestRenditionsParserUsesSelector(t *testing.T) {
+	p := openTestEPUB(t, "./testdata/renditions", NewParser(nil).WithRenditionSelector(RenditionPreferences{Layout: manifest.EPUBLayoutFixed}))
+	assert.Equal(t, "Multiple Renditions (fixed)", p.Manifest.Metadata.Title())
+	assert.Equal(t, "/fixed/chapter.xhtml", p.Manifest.ReadingOrder[0].Href)
+	if assert.NotNil(t, p.Manifest.Metadata.Presentation) {
+		assert.Equal(t, manifest.EPUBLayoutFixed, *p.Manifest.Metadata.Presentation.Layout)
+	}
+
+	alternate := p.Manifest.LinksWithRel("alternate")
+	if assert.Len(t, alternate, 1) {
+		assert.Equal(t, "/reflowable/package.opf", alternate[0].Href)
+	}
+}
+
+func TestRenditionsRootFilePath(t *testing.T) {
+	a := asset.FileWithMediaType("./testdata/renditions", &mediatype.EPUB)
+	f, err := a.CreateFetcher(asset.Dependencies{ArchiveFactory: archive.NewArchiveFactory()}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	path, err := GetRootFilePath(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "reflowable/package.opf", path)
+}
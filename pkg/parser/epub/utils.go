@@ -2,30 +2,26 @@ package epub
 
 import (
 	"strconv"
+	"strings"
 
-	"github.com/pkg/errors"
 	"github.com/readium/go-toolkit/pkg/fetcher"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/xmlquery"
 )
 
+// Returns the path to the package document of the default rendition, relative to the root of the container
+// (e.g. OEBPS/content.opf).
 func GetRootFilePath(fetcher fetcher.Fetcher) (string, error) {
-	res := fetcher.Get(manifest.Link{Href: "/META-INF/container.xml"})
-	xml, err := res.ReadAsXML(map[string]string{
-		"urn:oasis:names:tc:opendocument:xmlns:container": "cn",
-	})
+	renditions, err := GetRenditions(fetcher)
 	if err != nil {
-		return "", errors.Wrap(err, "failed loading container.xml")
+		return "", err
 	}
-	n := xml.SelectElement("/container/rootfiles/rootfile")
-	if n == nil {
-		return "", errors.New("rootfile not found in container")
-	}
-	p := n.SelectAttr("full-path")
-	if p == "" {
-		return "", errors.New("no full-path in rootfile")
-	}
-	return p, nil
+	return strings.TrimPrefix(renditions[0].Path, "/"), nil
+}
+
+// Reads an XML document from the fetcher, recovering from malformed documents according to [recovery].
+func readXML(f fetcher.Fetcher, link manifest.Link, prefixes map[string]string, recovery fetcher.XMLRecovery) (*xmlquery.Node, *fetcher.ResourceError) {
+	return fetcher.ReadResourceAsXMLWithRecovery(f.Get(link), prefixes, recovery)
 }
 
 func NSSelect(namespace, localName string) string {
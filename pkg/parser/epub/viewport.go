@@ -0,0 +1,155 @@
+package epub
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+)
+
+// Viewports are declared in the head of the documents, so only their beginning is read.
+const viewportReadLength = 4096
+
+var (
+	viewportTagRegexp  = regexp.MustCompile(`(?is)<(meta|svg)[\s>][^>]*>`)
+	viewportAttrRegexp = regexp.MustCompile(`(?s)([\w:-]+)\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+// Viewport is the initial containing block of a fixed-layout resource, in CSS pixels.
+type Viewport = manifest.Viewport
+
+// Sets the dimensions of the fixed-layout resources of the reading order, from the viewport declared in their
+// head, and the most common one as the default viewport of the publication, in [manifest.Presentation.Viewport].
+// Reference: https://www.w3.org/TR/epub-33/#sec-fxl-icb
+func addViewports(m *manifest.Manifest, f fetcher.Fetcher) {
+	var presentation manifest.Presentation
+	if m.Metadata.Presentation != nil {
+		presentation = *m.Metadata.Presentation
+	}
+
+	var sizes []Viewport
+	counts := make(map[Viewport]int)
+	for i, link := range m.ReadingOrder {
+		if presentation.LayoutOf(link) != manifest.EPUBLayoutFixed || link.Properties.Encryption() != nil {
+			continue
+		}
+		viewport := readViewport(f, link)
+		if viewport == nil {
+			continue
+		}
+		m.ReadingOrder[i].Width = viewport.Width
+		m.ReadingOrder[i].Height = viewport.Height
+		if counts[*viewport] == 0 {
+			sizes = append(sizes, *viewport)
+		}
+		counts[*viewport]++
+	}
+
+	var common *Viewport
+	for i, size := range sizes {
+		if common == nil || counts[size] > counts[*common] {
+			common = &sizes[i]
+		}
+	}
+	if common == nil {
+		// EPUB 2 fixed-layout publications may declare the viewport in the package document instead.
+		switch meta := m.Metadata.OtherMetadata[VocabularyRendition+"viewport"].(type) {
+		case string:
+			common = ParseViewport(meta)
+		case map[string]interface{}:
+			if value, ok := meta["@value"].(string); ok {
+				common = ParseViewport(value)
+			}
+		}
+	}
+	if common != nil {
+		if m.Metadata.Presentation == nil {
+			m.Metadata.Presentation = &manifest.Presentation{}
+		}
+		m.Metadata.Presentation.Viewport = common
+	}
+}
+
+// Reads the viewport of the (X)HTML or SVG resource at [link].
+func readViewport(f fetcher.Fetcher, link manifest.Link) *Viewport {
+	mt := link.MediaType()
+	isSVG := mt.Equal(&mediatype.SVG)
+	if !isSVG && !mt.IsHTML() {
+		return nil
+	}
+	res := f.Get(link)
+	defer res.Close()
+	data, err := res.Read(0, viewportReadLength-1)
+	if err != nil {
+		return nil
+	}
+
+	for _, m := range viewportTagRegexp.FindAllSubmatch(data, -1) {
+		tag := strings.ToLower(string(m[1]))
+		attrs := make(map[string]string)
+		for _, a := range viewportAttrRegexp.FindAllSubmatch(m[0], -1) {
+			attrs[strings.ToLower(string(a[1]))] = string(bytes.Trim(a[2], `"'`))
+		}
+		if tag == "svg" && isSVG {
+			return svgViewport(attrs)
+		}
+		if tag == "meta" && !isSVG && strings.EqualFold(strings.TrimSpace(attrs["name"]), "viewport") {
+			return ParseViewport(attrs["content"])
+		}
+	}
+	return nil
+}
+
+// ParseViewport parses the content of a viewport declaration, e.g. "width=1200, height=1600".
+// Returns nil unless both dimensions are given in pixels.
+func ParseViewport(content string) *Viewport {
+	var width, height uint
+	for _, prop := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' }) {
+		kv := strings.SplitN(prop, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := parseViewportLength(kv[1])
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "width":
+			width = value
+		case "height":
+			height = value
+		}
+	}
+	if width == 0 || height == 0 {
+		return nil
+	}
+	return &Viewport{Width: width, Height: height}
+}
+
+// The viewport of an SVG document is its viewBox, or its width and height in pixels.
+func svgViewport(attrs map[string]string) *Viewport {
+	if box := strings.FieldsFunc(attrs["viewbox"], func(r rune) bool { return r == ',' || r == ' ' }); len(box) == 4 {
+		width, height := parseViewportLength(box[2]), parseViewportLength(box[3])
+		if width > 0 && height > 0 {
+			return &Viewport{Width: width, Height: height}
+		}
+	}
+	width, height := parseViewportLength(attrs["width"]), parseViewportLength(attrs["height"])
+	if width == 0 || height == 0 {
+		return nil
+	}
+	return &Viewport{Width: width, Height: height}
+}
+
+// Parses a length in CSS pixels, e.g. "1200" or "1200px".
+func parseViewportLength(raw string) uint {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimSpace(strings.TrimSuffix(strings.ToLower(raw), "px"))
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f <= 0 || math.IsInf(f, 0) {
+		return 0
+	}
+	return uint(math.Round(f))
+}
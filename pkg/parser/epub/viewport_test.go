@@ -0,0 +1,69 @@
+package epub
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewportParse(t *testing.T) {
+	assert.Equal(t, &Viewport{Width: 1200, Height: 1600}, ParseViewport("width=1200, height=1600"))
+	assert.Equal(t, &Viewport{Width: 1200, Height: 1601}, ParseViewport(" height = 1600.6 ; width=1200 "))
+	assert.Equal(t, &Viewport{Width: 800, Height: 600}, ParseViewport("width=800,height=600,initial-scale=1.0"))
+	assert.Equal(t, &Viewport{Width: 1200, Height: 1600}, ParseViewport("width=1200px, height=1600 px"))
+	assert.Nil(t, ParseViewport("width=device-width, initial-scale=1"))
+	assert.Nil(t, ParseViewport("width=1200"))
+	assert.Nil(t, ParseViewport(""))
+}
+
+func TestViewportSVG(t *testing.T) {
+	assert.Equal(t, &Viewport{Width: 1200, Height: 1600}, svgViewport(map[string]string{"viewbox": "0 0 1200 1600", "width": "100%"}))
+	assert.Equal(t, &Viewport{Width: 600, Height: 800}, svgViewport(map[string]string{"viewbox": "0,0,600,800"}))
+	assert.Equal(t, &Viewport{Width: 300, Height: 400}, svgViewport(map[string]string{"width": "300px", "height": "400"}))
+	assert.Equal(t, &Viewport{Width: 600, Height: 800}, svgViewport(map[string]string{"viewbox": "0 0 600px 800px"}))
+	assert.Nil(t, svgViewport(map[string]string{"width": "100%", "height": "100%"}))
+	assert.Nil(t, svgViewport(map[string]string{"width": "30em", "height": "40em"}))
+}
+
+func TestViewportFixedLayoutResources(t *testing.T) {
+	p := openTestEPUB(t, "./testdata/fixed-layout", NewParser(nil))
+
+	sizes := make(map[string][2]uint)
+	for _, link := range p.Manifest.ReadingOrder {
+		sizes[link.Href] = [2]uint{link.Width, link.Height}
+	}
+	assert.Equal(t, map[string][2]uint{
+		"/OEBPS/cover.svg":   {1200, 1600},
+		"/OEBPS/page1.xhtml": {1200, 1600},
+		"/OEBPS/page2.xhtml": {1200, 1600},
+		"/OEBPS/page3.xhtml": {2400, 1600},
+		"/OEBPS/notes.xhtml": {0, 0}, // Reflowable
+	}, sizes)
+
+	assert.Equal(t, &manifest.Viewport{Width: 1200, Height: 1600}, p.Manifest.Metadata.Presentation.Viewport)
+}
+
+func TestViewportFromPackageMetadata(t *testing.T) {
+	layout := manifest.EPUBLayoutFixed
+	m := manifest.Manifest{
+		Metadata: manifest.Metadata{
+			Presentation: &manifest.Presentation{Layout: &layout},
+			OtherMetadata: map[string]interface{}{
+				VocabularyRendition + "viewport": "width=1024, height=768",
+			},
+		},
+		ReadingOrder: manifest.LinkList{{Href: "/page.xhtml", Type: "application/xhtml+xml"}},
+	}
+	addViewports(&m, fetcher.EmptyFetcher{})
+	assert.Equal(t, &manifest.Viewport{Width: 1024, Height: 768}, m.Metadata.Presentation.Viewport)
+}
+
+func TestViewportReflowablePublication(t *testing.T) {
+	p := openTestEPUB(t, "./testdata/renditions", NewParser(nil))
+	assert.Zero(t, p.Manifest.ReadingOrder[0].Width)
+	if presentation := p.Manifest.Metadata.Presentation; presentation != nil {
+		assert.Nil(t, presentation.Viewport)
+	}
+}
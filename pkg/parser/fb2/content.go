@@ -0,0 +1,588 @@
+package fb2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/xmlquery"
+)
+
+// Content of an FB2 document converted to XHTML documents, which can be served as a Web Publication.
+type content struct {
+	Documents []*document
+	Images    []image
+	TOC       manifest.LinkList
+}
+
+// An XHTML document generated from a part of the FB2 document.
+type document struct {
+	Href  string
+	Title string
+	Data  []byte
+
+	body       strings.Builder
+	hasContent bool // Whether the document contains more than the headings of the sections it starts.
+}
+
+// An image embedded in the FB2 document as a base64 <binary> element.
+type image struct {
+	ID   string
+	Link manifest.Link
+	node *xmlquery.Node
+}
+
+// Decodes the base64 content of the image.
+func (i image) Read() ([]byte, error) {
+	data := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, i.node.InnerText())
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		// Some documents omit the padding.
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	return decoded, err
+}
+
+const (
+	textDirectory  = "/text/"
+	imageDirectory = "/images/"
+	stylesheetHref = "/styles/fb2.css"
+)
+
+// Converts the bodies of an FB2 document into XHTML documents.
+type converter struct {
+	language  string
+	images    map[string]string // HREF of the embedded images, indexed by their binary ID.
+	documents []*document
+	current   *document
+	ids       map[string]string // HREF of the document containing each element ID.
+	usedIDs   map[string]struct{}
+	sectionID int
+}
+
+// Converts the FB2 document at [root] into XHTML documents.
+//
+// Each section of the main body starts a new document, except when the current document only contains the
+// headings of its parent sections. The other bodies, e.g. notes and comments, are converted to a single document
+// each.
+func convert(root *xmlquery.Node, language string) content {
+	c := &converter{
+		language: language,
+		images:   make(map[string]string),
+		ids:      make(map[string]string),
+		usedIDs:  make(map[string]struct{}),
+	}
+	for _, el := range root.SelectElements("//*[@id]") {
+		c.usedIDs[el.SelectAttr("id")] = struct{}{}
+	}
+
+	var result content
+	for _, binary := range root.SelectElements(fb2Select("binary")) {
+		if img := c.addImage(binary); img != nil {
+			result.Images = append(result.Images, *img)
+		}
+	}
+
+	bodies := root.SelectElements(fb2Select("body"))
+	main := 0
+	for i, body := range bodies {
+		if body.SelectAttr("name") == "" {
+			main = i
+			break
+		}
+	}
+	if len(bodies) > 0 {
+		result.TOC = append(result.TOC, c.convertBody(bodies[main], true)...)
+	}
+	for i, body := range bodies {
+		if i != main {
+			result.TOC = append(result.TOC, c.convertBody(body, false)...)
+		}
+	}
+
+	for _, doc := range c.documents {
+		doc.Data = c.render(doc)
+	}
+	result.Documents = c.documents
+	return result
+}
+
+// Registers an embedded image under an HREF derived from its ID.
+func (c *converter) addImage(binary *xmlquery.Node) *image {
+	id := binary.SelectAttr("id")
+	if id == "" {
+		return nil
+	}
+	contentType := binary.SelectAttr("content-type")
+	name := sanitizeFilename(id)
+	if path.Ext(name) == "" {
+		if mt := mediatype.OfString(contentType); mt != nil && mt.FileExtension() != "" {
+			name += "." + mt.FileExtension()
+		}
+	}
+	href := imageDirectory + name
+	for i := 1; ; i++ {
+		if !c.isImageHrefUsed(href) {
+			break
+		}
+		href = imageDirectory + strings.TrimSuffix(name, path.Ext(name)) + fmt.Sprintf("-%d", i) + path.Ext(name)
+	}
+	c.images[id] = href
+
+	return &image{
+		ID:   id,
+		Link: manifest.Link{Href: href, Type: contentType},
+		node: binary,
+	}
+}
+
+func (c *converter) isImageHrefUsed(href string) bool {
+	for _, h := range c.images {
+		if h == href {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the characters which are not safe in a file name.
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (c *converter) startDocument() {
+	c.current = &document{
+		Href: fmt.Sprintf("%ssection-%d.xhtml", textDirectory, len(c.documents)+1),
+	}
+	c.documents = append(c.documents, c.current)
+}
+
+// Converts a <body> element, returning the entries of the table of contents.
+// The [main] body is split by sections, and its title is the title of the book rather than a table of contents entry.
+func (c *converter) convertBody(body *xmlquery.Node, main bool) manifest.LinkList {
+	c.startDocument()
+	doc := c.current
+
+	var toc manifest.LinkList
+	for _, child := range elements(body) {
+		switch child.Data {
+		case "title":
+			title := titleText(child)
+			if doc.Title == "" {
+				doc.Title = title
+			}
+			c.writeHeading(child, 1, "")
+		case "section":
+			if main {
+				toc = append(toc, c.convertSection(child, 2)...)
+			} else {
+				c.writeNote(child)
+			}
+		default:
+			c.writeBlock(child)
+		}
+	}
+
+	if !main {
+		title := doc.Title
+		if title == "" {
+			title = body.SelectAttr("name")
+		}
+		if title != "" {
+			toc = append(toc, manifest.Link{Href: doc.Href, Title: title})
+		}
+	}
+	return toc
+}
+
+// Converts a <section> element of the main body, returning its entries in the table of contents.
+func (c *converter) convertSection(section *xmlquery.Node, level int) manifest.LinkList {
+	if c.current.hasContent {
+		c.startDocument()
+	}
+	isDocumentStart := c.current.body.Len() == 0
+
+	id := section.SelectAttr("id")
+	if id == "" {
+		id = c.generateSectionID()
+	}
+	c.ids[id] = c.current.Href
+
+	entry := manifest.Link{Href: c.current.Href}
+	if !isDocumentStart {
+		entry.Href += "#" + id
+	}
+	if title := section.SelectElement(fb2Select("title")); title != nil {
+		entry.Title = titleText(title)
+		c.writeHeading(title, level, id)
+	} else {
+		c.current.body.WriteString(`<a id="` + html.EscapeString(id) + `"></a>` + "\n")
+	}
+	if c.current.Title == "" {
+		c.current.Title = entry.Title
+	}
+
+	for _, child := range elements(section) {
+		switch child.Data {
+		case "title":
+			continue
+		case "section":
+			entry.Children = append(entry.Children, c.convertSection(child, level+1)...)
+		default:
+			c.writeBlock(child)
+		}
+	}
+	c.current.hasContent = true
+
+	if entry.Title == "" {
+		return entry.Children
+	}
+	return manifest.LinkList{entry}
+}
+
+// Generates a unique ID for a section which doesn't have one, to link to it from the table of contents.
+func (c *converter) generateSectionID() string {
+	for {
+		c.sectionID++
+		id := fmt.Sprintf("section-%d", c.sectionID)
+		if _, used := c.usedIDs[id]; !used {
+			c.usedIDs[id] = struct{}{}
+			return id
+		}
+	}
+}
+
+// Writes a section of a notes or comments body as a footnote.
+func (c *converter) writeNote(section *xmlquery.Node) {
+	b := &c.current.body
+	b.WriteString("<aside epub:type=\"footnote\"" + c.idAttr(section) + ">\n")
+	for _, child := range elements(section) {
+		switch child.Data {
+		case "title":
+			b.WriteString("<p class=\"title\"><strong>")
+			c.writeTitleLines(child)
+			b.WriteString("</strong></p>\n")
+		case "section":
+			c.writeNote(child)
+		default:
+			c.writeBlock(child)
+		}
+	}
+	b.WriteString("</aside>\n")
+}
+
+func (c *converter) writeHeading(title *xmlquery.Node, level int, id string) {
+	if level > 6 {
+		level = 6
+	}
+	tag := fmt.Sprintf("h%d", level)
+	b := &c.current.body
+	b.WriteString("<" + tag)
+	if id != "" {
+		b.WriteString(` id="` + html.EscapeString(id) + `"`)
+	}
+	b.WriteString(">")
+	c.writeTitleLines(title)
+	b.WriteString("</" + tag + ">\n")
+}
+
+// Writes the paragraphs of a <title> element as lines.
+func (c *converter) writeTitleLines(title *xmlquery.Node) {
+	first := true
+	for _, child := range elements(title) {
+		if child.Data != "p" {
+			continue
+		}
+		if !first {
+			c.current.body.WriteString("<br/>")
+		}
+		first = false
+		c.writeInlines(child)
+	}
+}
+
+// Writes a block element, such as a paragraph or a poem.
+func (c *converter) writeBlock(el *xmlquery.Node) {
+	b := &c.current.body
+	hadContent := c.current.hasContent
+	switch el.Data {
+	case "p":
+		c.writeInlineElement("p", el, "")
+	case "subtitle":
+		c.writeInlineElement("p", el, "subtitle")
+	case "text-author":
+		c.writeInlineElement("p", el, "text-author")
+	case "date":
+		c.writeInlineElement("p", el, "date")
+	case "v":
+		c.writeInlineElement("p", el, "v")
+	case "empty-line":
+		b.WriteString("<p class=\"empty-line\">&#160;</p>\n")
+	case "image":
+		b.WriteString("<div class=\"image\"" + c.idAttr(el) + ">")
+		c.writeImage(el)
+		b.WriteString("</div>\n")
+	case "title":
+		// Titles of poems and stanzas.
+		b.WriteString("<p class=\"title\"" + c.idAttr(el) + "><strong>")
+		c.writeTitleLines(el)
+		b.WriteString("</strong></p>\n")
+	case "epigraph", "cite":
+		c.writeContainer("blockquote", el)
+	case "annotation", "poem", "stanza":
+		c.writeContainer("div", el)
+	case "table":
+		c.writeTable(el)
+	default:
+		for _, child := range elements(el) {
+			c.writeBlock(child)
+		}
+		return
+	}
+
+	// Epigraphs and annotations introduce the content of a section, like its title.
+	switch el.Data {
+	case "epigraph", "annotation":
+		c.current.hasContent = hadContent
+	case "p", "subtitle", "empty-line", "image", "poem", "cite", "table":
+		c.current.hasContent = true
+	}
+}
+
+func (c *converter) writeContainer(tag string, el *xmlquery.Node) {
+	b := &c.current.body
+	b.WriteString("<" + tag + ` class="` + el.Data + `"` + c.idAttr(el) + ">\n")
+	for _, child := range elements(el) {
+		c.writeBlock(child)
+	}
+	b.WriteString("</" + tag + ">\n")
+}
+
+func (c *converter) writeTable(table *xmlquery.Node) {
+	b := &c.current.body
+	b.WriteString("<table" + c.idAttr(table) + ">\n")
+	for _, row := range elements(table) {
+		if row.Data != "tr" {
+			continue
+		}
+		b.WriteString("<tr>")
+		for _, cell := range elements(row) {
+			if cell.Data != "th" && cell.Data != "td" {
+				continue
+			}
+			b.WriteString("<" + cell.Data + c.idAttr(cell))
+			for _, attr := range []string{"colspan", "rowspan"} {
+				if value := cell.SelectAttr(attr); value != "" {
+					b.WriteString(" " + attr + `="` + html.EscapeString(value) + `"`)
+				}
+			}
+			if align := cell.SelectAttr("align"); align != "" {
+				b.WriteString(` style="text-align: ` + html.EscapeString(align) + `"`)
+			}
+			b.WriteString(">")
+			c.writeInlines(cell)
+			b.WriteString("</" + cell.Data + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+}
+
+func (c *converter) writeInlineElement(tag string, el *xmlquery.Node, class string) {
+	b := &c.current.body
+	b.WriteString("<" + tag)
+	if class != "" {
+		b.WriteString(` class="` + class + `"`)
+	}
+	b.WriteString(c.idAttr(el) + ">")
+	c.writeInlines(el)
+	b.WriteString("</" + tag + ">\n")
+}
+
+var inlineTags = map[string]string{
+	"strong":        "strong",
+	"emphasis":      "em",
+	"strikethrough": "del",
+	"sub":           "sub",
+	"sup":           "sup",
+	"code":          "code",
+}
+
+// Writes the text content of an element, with its inline formatting, links and images.
+func (c *converter) writeInlines(el *xmlquery.Node) {
+	b := &c.current.body
+	for child := el.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case xmlquery.TextNode, xmlquery.CharDataNode:
+			b.WriteString(html.EscapeString(child.Data))
+		case xmlquery.ElementNode:
+			if tag, ok := inlineTags[child.Data]; ok {
+				b.WriteString("<" + tag + c.idAttr(child) + ">")
+				c.writeInlines(child)
+				b.WriteString("</" + tag + ">")
+				continue
+			}
+			switch child.Data {
+			case "style":
+				b.WriteString("<span")
+				if name := child.SelectAttr("name"); name != "" {
+					b.WriteString(` class="` + html.EscapeString(name) + `"`)
+				}
+				b.WriteString(c.idAttr(child) + ">")
+				c.writeInlines(child)
+				b.WriteString("</span>")
+			case "a":
+				c.writeLink(child)
+			case "image":
+				c.writeImage(child)
+			default:
+				c.writeInlines(child)
+			}
+		}
+	}
+}
+
+func (c *converter) writeLink(a *xmlquery.Node) {
+	b := &c.current.body
+	href := hrefAttr(a)
+	b.WriteString("<a")
+	if strings.HasPrefix(href, "#") {
+		// Internal links are resolved once the documents containing their target are known.
+		b.WriteString(` href="` + linkPlaceholder + href[1:] + linkPlaceholder + `"`)
+	} else if href != "" {
+		b.WriteString(` href="` + html.EscapeString(href) + `"`)
+	}
+	if a.SelectAttr("type") == "note" {
+		b.WriteString(` class="note" epub:type="noteref"`)
+	}
+	b.WriteString(c.idAttr(a) + ">")
+	c.writeInlines(a)
+	b.WriteString("</a>")
+}
+
+func (c *converter) writeImage(el *xmlquery.Node) {
+	src := hrefAttr(el)
+	if strings.HasPrefix(src, "#") {
+		href, ok := c.images[src[1:]]
+		if !ok {
+			return // Missing binary.
+		}
+		src = "../" + strings.TrimPrefix(href, "/")
+	}
+	if src == "" {
+		return
+	}
+	alt := el.SelectAttr("alt")
+	if alt == "" {
+		alt = el.SelectAttr("title")
+	}
+	b := &c.current.body
+	b.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `"`)
+	if title := el.SelectAttr("title"); title != "" {
+		b.WriteString(` title="` + html.EscapeString(title) + `"`)
+	}
+	b.WriteString("/>")
+}
+
+// Returns the id attribute of an element, recording in which document it is.
+func (c *converter) idAttr(el *xmlquery.Node) string {
+	id := el.SelectAttr("id")
+	if id == "" {
+		return ""
+	}
+	c.ids[id] = c.current.Href
+	return ` id="` + html.EscapeString(id) + `"`
+}
+
+// Delimits the target ID of an internal link until it is resolved. A NUL character can't appear in an XML document.
+const linkPlaceholder = "\x00"
+
+// Renders the complete XHTML document, resolving the internal links.
+func (c *converter) render(doc *document) []byte {
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<!DOCTYPE html>\n")
+	sb.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"`)
+	if c.language != "" {
+		lang := html.EscapeString(c.language)
+		sb.WriteString(` lang="` + lang + `" xml:lang="` + lang + `"`)
+	}
+	sb.WriteString(">\n<head>\n<meta charset=\"UTF-8\"/>\n")
+	sb.WriteString("<title>" + html.EscapeString(doc.Title) + "</title>\n")
+	sb.WriteString(`<link rel="stylesheet" type="text/css" href="../` + strings.TrimPrefix(stylesheetHref, "/") + `"/>` + "\n")
+	sb.WriteString("</head>\n<body>\n")
+
+	parts := strings.Split(doc.body.String(), linkPlaceholder)
+	for i, part := range parts {
+		if i%2 == 0 {
+			sb.WriteString(part)
+			continue
+		}
+		target := html.EscapeString(part)
+		if href, ok := c.ids[part]; ok && href != doc.Href {
+			sb.WriteString(path.Base(href) + "#" + target)
+		} else {
+			sb.WriteString("#" + target)
+		}
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String())
+}
+
+// Returns the plain text of a <title> element, joining its lines.
+func titleText(title *xmlquery.Node) string {
+	var lines []string
+	for _, p := range title.SelectElements(fb2Select("p")) {
+		if line := normalizeText(p.InnerText()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return normalizeText(title.InnerText())
+	}
+	text := lines[0]
+	for _, line := range lines[1:] {
+		if last, _ := utf8.DecodeLastRuneInString(text); !strings.ContainsRune(".!?:;…", last) {
+			text += "."
+		}
+		text += " " + line
+	}
+	return text
+}
+
+// Returns the link target of an element, usually declared with the xlink:href attribute, regardless of its prefix.
+func hrefAttr(el *xmlquery.Node) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == "href" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Returns the child elements of a node.
+func elements(n *xmlquery.Node) []*xmlquery.Node {
+	var children []*xmlquery.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -0,0 +1,50 @@
+package fb2
+
+import (
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+)
+
+// Serves the XHTML documents, stylesheet and images generated from an FB2 document.
+// Other resources are requested from the fetcher of the FB2 file.
+func newContentFetcher(f fetcher.Fetcher, c content) *fetcher.OverlayFetcher {
+	cf := fetcher.NewOverlayFetcher(f)
+	for _, doc := range c.Documents {
+		data := doc.Data
+		cf.Add(manifest.Link{Href: doc.Href, Type: mediatype.XHTML.String()}, func() ([]byte, *fetcher.ResourceError) {
+			return data, nil
+		})
+	}
+	cf.Add(manifest.Link{Href: stylesheetHref, Type: mediatype.CSS.String()}, func() ([]byte, *fetcher.ResourceError) {
+		return []byte(stylesheet), nil
+	})
+	for _, img := range c.Images {
+		img := img
+		cf.Add(img.Link, func() ([]byte, *fetcher.ResourceError) {
+			data, err := img.Read()
+			if err != nil {
+				return nil, fetcher.Other(err)
+			}
+			return data, nil
+		})
+	}
+	return cf
+}
+
+// Default styles of the elements generated from the FB2 document.
+const stylesheet = `h1, h2, h3, h4, h5, h6 { text-align: center; }
+p { margin: 0; text-indent: 1.5em; }
+p.title, p.subtitle, p.empty-line { text-align: center; text-indent: 0; }
+p.text-author, p.date { text-align: right; font-style: italic; }
+blockquote.epigraph { margin: 1em 0 1em 30%; }
+blockquote.cite { margin: 1em 2em; }
+div.poem { margin: 1em 2em; }
+div.stanza { margin: 1em 0; }
+p.v { text-indent: 0; }
+div.image { text-align: center; margin: 1em 0; }
+div.image img { max-width: 100%; }
+aside { margin: 1em 0; }
+table { border-collapse: collapse; margin: 1em auto; }
+th, td { border: 1px solid; padding: 0.2em 0.5em; }
+`
@@ -0,0 +1,163 @@
+package fb2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// Selects a child element by its local name, as FB2 documents don't always declare the FictionBook namespace.
+func fb2Select(names ...string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = "*[local-name()='" + name + "']"
+	}
+	return strings.Join(parts, "/")
+}
+
+// Fills the publication [metadata] from the description of the FB2 document.
+// Reference: http://www.fictionbook.org/index.php/Eng:Description
+func fillMetadata(metadata *manifest.Metadata, description *xmlquery.Node) {
+	if description == nil {
+		return
+	}
+
+	if info := description.SelectElement(fb2Select("title-info")); info != nil {
+		if el := info.SelectElement(fb2Select("book-title")); el != nil {
+			if title := normalizeText(el.InnerText()); title != "" {
+				metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(title)
+			}
+		}
+		metadata.Authors = append(metadata.Authors, contributors(info.SelectElements(fb2Select("author")))...)
+		metadata.Translators = append(metadata.Translators, contributors(info.SelectElements(fb2Select("translator")))...)
+		for _, el := range info.SelectElements(fb2Select("genre")) {
+			if genre := normalizeText(el.InnerText()); genre != "" {
+				metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+					LocalizedName: manifest.NewLocalizedStringFromString(genre),
+					Scheme:        "http://www.fictionbook.org/index.php/Eng:FictionBook_genres",
+					Code:          genre,
+				})
+			}
+		}
+		if el := info.SelectElement(fb2Select("lang")); el != nil {
+			if lang := normalizeText(el.InnerText()); lang != "" {
+				metadata.Languages = manifest.Strings{lang}
+			}
+		}
+		if el := info.SelectElement(fb2Select("annotation")); el != nil {
+			metadata.Description = paragraphsText(el)
+		}
+		if el := info.SelectElement(fb2Select("date")); el != nil {
+			date := el.SelectAttr("value")
+			if date == "" {
+				date = normalizeText(el.InnerText())
+			}
+			metadata.Published = extensions.ParseDate(date)
+		}
+		for _, el := range info.SelectElements(fb2Select("sequence")) {
+			addSeries(metadata, el)
+		}
+	}
+
+	if info := description.SelectElement(fb2Select("publish-info")); info != nil {
+		if el := info.SelectElement(fb2Select("publisher")); el != nil {
+			if publisher := normalizeText(el.InnerText()); publisher != "" {
+				metadata.Publishers = append(metadata.Publishers, manifest.Contributor{
+					LocalizedName: manifest.NewLocalizedStringFromString(publisher),
+				})
+			}
+		}
+		if el := info.SelectElement(fb2Select("isbn")); el != nil {
+			if isbn := normalizeText(el.InnerText()); isbn != "" {
+				metadata.Identifier = "urn:isbn:" + isbn
+			}
+		}
+		if metadata.Published == nil {
+			if el := info.SelectElement(fb2Select("year")); el != nil {
+				metadata.Published = extensions.ParseDate(normalizeText(el.InnerText()))
+			}
+		}
+		if _, ok := metadata.BelongsTo["series"]; !ok {
+			for _, el := range info.SelectElements(fb2Select("sequence")) {
+				addSeries(metadata, el)
+			}
+		}
+	}
+
+	// The document ID identifies the FB2 file itself, and is only used when there's no ISBN.
+	if metadata.Identifier == "" {
+		if el := description.SelectElement(fb2Select("document-info", "id")); el != nil {
+			metadata.Identifier = normalizeText(el.InnerText())
+		}
+	}
+}
+
+// Adds the series declared by a <sequence> element, e.g. <sequence name="Alice" number="2"/>.
+func addSeries(metadata *manifest.Metadata, el *xmlquery.Node) {
+	name := normalizeText(el.SelectAttr("name"))
+	if name == "" {
+		return
+	}
+	series := manifest.Collection{
+		LocalizedName: manifest.NewLocalizedStringFromString(name),
+	}
+	if position, err := strconv.ParseFloat(el.SelectAttr("number"), 64); err == nil {
+		series.Position = &position
+	}
+	if metadata.BelongsTo == nil {
+		metadata.BelongsTo = make(map[string]manifest.Collections)
+	}
+	metadata.BelongsTo["series"] = append(metadata.BelongsTo["series"], series)
+}
+
+func contributors(elements []*xmlquery.Node) manifest.Contributors {
+	var contributors manifest.Contributors
+	for _, el := range elements {
+		if name := authorName(el); name != "" {
+			contributors = append(contributors, manifest.Contributor{
+				LocalizedName: manifest.NewLocalizedStringFromString(name),
+			})
+		}
+	}
+	return contributors
+}
+
+// Returns the full name of an author, or their nickname when the name is not given.
+func authorName(el *xmlquery.Node) string {
+	var parts []string
+	for _, name := range []string{"first-name", "middle-name", "last-name"} {
+		if part := el.SelectElement(fb2Select(name)); part != nil {
+			if text := normalizeText(part.InnerText()); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		if nickname := el.SelectElement(fb2Select("nickname")); nickname != nil {
+			return normalizeText(nickname.InnerText())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Returns the text of the paragraphs of an element, one per line.
+func paragraphsText(el *xmlquery.Node) string {
+	var paragraphs []string
+	for _, p := range el.SelectElements(fb2Select("p")) {
+		if text := normalizeText(p.InnerText()); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	if len(paragraphs) == 0 {
+		return normalizeText(el.InnerText())
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+// Collapses the whitespaces of a text, as FB2 documents are usually indented.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,130 @@
+package fb2
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/readium/xmlquery"
+)
+
+// Parses a FictionBook document, bare or zipped, into a Readium Web Publication.
+//
+// The sections of the document are converted to XHTML documents, and the images embedded as base64 binaries are
+// served as resources.
+// Reference: http://www.fictionbook.org/index.php/Eng:XML_Schema_Fictionbook_2.1
+type Parser struct {
+}
+
+func NewParser() Parser {
+	return Parser{}
+}
+
+// Parse implements PublicationParser
+func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	if !asset.MediaType().Matches(&mediatype.FB2, &mediatype.FB2ZIP) {
+		return nil, nil
+	}
+
+	links, err := f.Links()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch links")
+	}
+	link := findDocument(links)
+	if link == nil {
+		return nil, errors.New("unable to find FB2 document")
+	}
+	document, rerr := f.Get(*link).ReadAsXML(nil)
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed parsing FB2 document")
+	}
+	root := document.SelectElement(fb2Select("FictionBook"))
+	if root == nil {
+		return nil, errors.New("invalid FB2 document: missing FictionBook root element")
+	}
+
+	metadata := manifest.Metadata{}
+	fillMetadata(&metadata, root.SelectElement(fb2Select("description")))
+	if metadata.Title() == "" {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(fallbackTitle(asset.Name()))
+	}
+	language := ""
+	if len(metadata.Languages) > 0 {
+		language = metadata.Languages[0]
+	}
+
+	c := convert(root, language)
+	if len(c.Documents) == 0 {
+		return nil, errors.New("invalid FB2 document: missing body")
+	}
+
+	var readingOrder, resources manifest.LinkList
+	for _, doc := range c.Documents {
+		readingOrder = append(readingOrder, manifest.Link{
+			Href:  doc.Href,
+			Type:  mediatype.XHTML.String(),
+			Title: doc.Title,
+		})
+	}
+	resources = append(resources, manifest.Link{Href: stylesheetHref, Type: mediatype.CSS.String()})
+	coverID := coverImageID(root)
+	for _, img := range c.Images {
+		link := img.Link
+		if img.ID == coverID {
+			link.Rels = manifest.Strings{"cover"}
+		}
+		resources = append(resources, link)
+	}
+
+	manifest := manifest.Manifest{
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       resources,
+		TableOfContents: c.TOC,
+	}
+
+	builder := pub.NewServicesBuilder(map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: epub.PositionsServiceFactory(epub.OriginalLength{PageLength: 1024}),
+	})
+	return pub.NewBuilder(manifest, newContentFetcher(f, c), builder), nil
+}
+
+// Finds the FB2 document, which is the only file of a bare FB2 publication or the .fb2 entry of a zipped one.
+func findDocument(links manifest.LinkList) *manifest.Link {
+	for i, link := range links {
+		if strings.ToLower(path.Ext(link.Href)) == ".fb2" {
+			return &links[i]
+		}
+	}
+	if len(links) == 1 {
+		return &links[0]
+	}
+	return nil
+}
+
+// Returns the binary ID of the cover image declared in the title info.
+func coverImageID(root *xmlquery.Node) string {
+	image := root.SelectElement(fb2Select("description", "title-info", "coverpage", "image"))
+	if image == nil {
+		return ""
+	}
+	return strings.TrimPrefix(hrefAttr(image), "#")
+}
+
+// Derives a title from the file name, e.g. "book" for "book.fb2.zip".
+func fallbackTitle(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".fb2.zip", ".fbz", ".fb2"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
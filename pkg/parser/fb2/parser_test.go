@@ -0,0 +1,133 @@
+package fb2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withFB2(t *testing.T, filepath string, f func(*pub.Publication)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewParser().Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	f(p.Build())
+}
+
+func TestFB2Metadata(t *testing.T) {
+	withFB2(t, "./testdata/looking-glass.fb2", func(p *pub.Publication) {
+		m := p.Manifest.Metadata
+		assert.Equal(t, "Through the Looking-Glass", m.Title())
+		assert.Equal(t, "urn:isbn:978-0-00-000000-2", m.Identifier)
+		assert.Equal(t, manifest.Strings{"en"}, m.Languages)
+		assert.Equal(t, "Alice climbs through a mirror.\nShe finds a world where everything is reversed.", m.Description)
+		assert.Equal(t, time.Date(1871, 12, 27, 0, 0, 0, 0, time.UTC), *m.Published)
+		if assert.Len(t, m.Authors, 1) {
+			assert.Equal(t, "Lewis Carroll", m.Authors[0].Name())
+		}
+		if assert.Len(t, m.Translators, 1) {
+			assert.Equal(t, "Anonymous", m.Translators[0].Name())
+		}
+		if assert.Len(t, m.Publishers, 1) {
+			assert.Equal(t, "Macmillan", m.Publishers[0].Name())
+		}
+		if assert.Len(t, m.Subjects, 2) {
+			assert.Equal(t, "sf_fantasy", m.Subjects[0].Code)
+			assert.Equal(t, "adventure", m.Subjects[1].Name())
+		}
+		if assert.Len(t, m.BelongsTo["series"], 1) {
+			assert.Equal(t, "Alice", m.BelongsTo["series"][0].Name())
+			assert.Equal(t, 2.0, *m.BelongsTo["series"][0].Position)
+		}
+	})
+}
+
+func TestFB2Content(t *testing.T) {
+	withFB2(t, "./testdata/looking-glass.fb2", func(p *pub.Publication) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/section-1.xhtml", Type: "application/xhtml+xml", Title: "Through the Looking-Glass. and What Alice Found There"},
+			{Href: "/text/section-2.xhtml", Type: "application/xhtml+xml", Title: "Chapter II"},
+			{Href: "/text/section-3.xhtml", Type: "application/xhtml+xml", Title: "The Hill"},
+			{Href: "/text/section-4.xhtml", Type: "application/xhtml+xml", Title: "Notes"},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/section-1.xhtml#chapter1", Title: "Chapter I. Looking-Glass House"},
+			{Href: "/text/section-2.xhtml", Title: "Chapter II", Children: manifest.LinkList{
+				{Href: "/text/section-2.xhtml#garden", Title: "The Garden of Live Flowers"},
+				{Href: "/text/section-3.xhtml", Title: "The Hill"},
+			}},
+			{Href: "/text/section-4.xhtml", Title: "Notes"},
+		}, p.Manifest.TableOfContents)
+
+		doc, err := p.Get(p.Manifest.ReadingOrder[0]).ReadAsXML(nil)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "Chapter ILooking-Glass House", doc.SelectElement("//*[local-name()='h2'][@id='chapter1']").InnerText())
+			assert.Equal(t, "../images/kitten.png", doc.SelectElement("//*[local-name()='img']").SelectAttr("src"))
+			assert.Equal(t, "section-4.xhtml#note1", doc.SelectElement("//*[local-name()='a'][@class='note']").SelectAttr("href"))
+			assert.Equal(t, "Did gyre & gimble in the wabe", doc.SelectElements("//*[local-name()='p'][@class='v']")[1].InnerText())
+		}
+		doc, err = p.Get(p.Manifest.ReadingOrder[1]).ReadAsXML(nil)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "section-1.xhtml#chapter1", doc.SelectElement("//*[local-name()='p']/*[local-name()='a']").SelectAttr("href"))
+		}
+	})
+}
+
+func TestFB2Images(t *testing.T) {
+	withFB2(t, "./testdata/looking-glass.fb2", func(p *pub.Publication) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/styles/fb2.css", Type: "text/css"},
+			{Href: "/images/cover.png", Type: "image/png", Rels: manifest.Strings{"cover"}},
+			{Href: "/images/kitten.png", Type: "image/png"},
+		}, p.Manifest.Resources)
+
+		data, err := p.Get(p.Manifest.Resources[1]).Read(0, 0)
+		if assert.Nil(t, err) {
+			assert.Equal(t, []byte("\x89PNG"), data[:4])
+		}
+		data, err = p.Get(p.Manifest.Resources[1]).Read(1, 3)
+		if assert.Nil(t, err) {
+			assert.Equal(t, []byte("PNG"), data)
+		}
+	})
+}
+
+func TestFB2Zip(t *testing.T) {
+	withFB2(t, "./testdata/looking-glass.fb2.zip", func(p *pub.Publication) {
+		assert.Equal(t, "Through the Looking-Glass", p.Manifest.Metadata.Title())
+		assert.Len(t, p.Manifest.ReadingOrder, 4)
+		assert.Len(t, p.PositionsByReadingOrder(), 4)
+	})
+}
+
+func TestFB2LegacyEncoding(t *testing.T) {
+	withFB2(t, "./testdata/kashtanka.fb2", func(p *pub.Publication) {
+		assert.Equal(t, "Каштанка", p.Manifest.Metadata.Title())
+		assert.Equal(t, "Антон Чехов", p.Manifest.Metadata.Authors[0].Name())
+		if assert.Len(t, p.Manifest.ReadingOrder, 1) {
+			data, err := p.Get(p.Manifest.ReadingOrder[0]).ReadAsString()
+			if assert.Nil(t, err) {
+				assert.Contains(t, data, `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="ru" xml:lang="ru">`)
+				assert.Contains(t, data, "<p>Молодая рыжая собака.</p>")
+			}
+		}
+	})
+}
+
+func TestFB2FallbackTitle(t *testing.T) {
+	assert.Equal(t, "book", fallbackTitle("book.fb2.zip"))
+	assert.Equal(t, "Book", fallbackTitle("Book.FB2"))
+	assert.Equal(t, "book.v2", fallbackTitle("book.v2.fbz"))
+}
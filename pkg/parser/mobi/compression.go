@@ -0,0 +1,245 @@
+package mobi
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Decompresses a text record compressed with the PalmDOC flavour of LZ77.
+// Reference: https://wiki.mobileread.com/wiki/PalmDOC#PalmDoc_byte_pair_compression
+func decompressPalmDOC(data []byte) []byte {
+	out := make([]byte, 0, 4096)
+	for i := 0; i < len(data); {
+		c := data[i]
+		i++
+		switch {
+		case c >= 1 && c <= 8: // Literal run of c bytes.
+			end := i + int(c)
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		case c < 0x80: // Literal byte.
+			out = append(out, c)
+		case c >= 0xC0: // Space followed by a character.
+			out = append(out, ' ', c^0x80)
+		default: // Back-reference of 3 to 10 bytes, up to 2047 bytes behind.
+			if i >= len(data) {
+				return out
+			}
+			pair := uint16(c)<<8 | uint16(data[i])
+			i++
+			distance := int(pair>>3) & 0x7FF
+			length := int(pair&7) + 3
+			if distance == 0 || distance > len(out) {
+				continue
+			}
+			for j := 0; j < length; j++ {
+				out = append(out, out[len(out)-distance])
+			}
+		}
+	}
+	return out
+}
+
+// Decompresses text records compressed with the Huffman and dictionary (HUFF/CDIC) scheme of Mobipocket.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#HUFF_and_CDIC_records
+type huffCDIC struct {
+	codes      [256]huffCode
+	minCodes   [33]uint32
+	maxCodes   [33]uint32
+	dictionary []huffPhrase
+}
+
+type huffCode struct {
+	Length   uint8
+	Terminal bool
+	MaxCode  uint32
+}
+
+type huffPhrase struct {
+	Data         []byte
+	Decompressed bool
+	unpacking    bool
+}
+
+// Loads the decompressor from the HUFF record and its CDIC records.
+func newHuffCDIC(records [][]byte) (*huffCDIC, error) {
+	if len(records) < 2 {
+		return nil, errors.New("missing HUFF or CDIC records")
+	}
+	h := &huffCDIC{}
+
+	huff := records[0]
+	if len(huff) < 16 || string(huff[:8]) != "HUFF\x00\x00\x00\x18" {
+		return nil, errors.New("invalid HUFF record")
+	}
+	offset1 := int(binary.BigEndian.Uint32(huff[8:]))
+	offset2 := int(binary.BigEndian.Uint32(huff[12:]))
+	if offset1+256*4 > len(huff) || offset2+64*4 > len(huff) {
+		return nil, errors.New("invalid HUFF record: truncated tables")
+	}
+	for i := range h.codes {
+		v := binary.BigEndian.Uint32(huff[offset1+i*4:])
+		code := huffCode{
+			Length:   uint8(v & 0x1F),
+			Terminal: v&0x80 != 0,
+		}
+		if code.Length == 0 {
+			return nil, errors.New("invalid HUFF record: null code length")
+		}
+		code.MaxCode = uint32(((uint64(v>>8) + 1) << (32 - code.Length)) - 1)
+		h.codes[i] = code
+	}
+	for length := 1; length <= 32; length++ {
+		min := uint64(binary.BigEndian.Uint32(huff[offset2+(length-1)*8:]))
+		max := uint64(binary.BigEndian.Uint32(huff[offset2+(length-1)*8+4:]))
+		h.minCodes[length] = uint32(min << (32 - length))
+		h.maxCodes[length] = uint32(((max + 1) << (32 - length)) - 1)
+	}
+
+	for _, cdic := range records[1:] {
+		if len(cdic) < 16 || string(cdic[:8]) != "CDIC\x00\x00\x00\x10" {
+			return nil, errors.New("invalid CDIC record")
+		}
+		phrases := int(binary.BigEndian.Uint32(cdic[8:]))
+		bits := binary.BigEndian.Uint32(cdic[12:])
+		count := phrases - len(h.dictionary)
+		if bits < 32 && count > 1<<bits {
+			count = 1 << bits
+		}
+		for i := 0; i < count; i++ {
+			if 16+i*2+2 > len(cdic) {
+				return nil, errors.New("invalid CDIC record: truncated offsets")
+			}
+			offset := 16 + int(binary.BigEndian.Uint16(cdic[16+i*2:]))
+			if offset+2 > len(cdic) {
+				return nil, errors.New("invalid CDIC record: phrase out of bounds")
+			}
+			length := binary.BigEndian.Uint16(cdic[offset:])
+			end := offset + 2 + int(length&0x7FFF)
+			if end > len(cdic) {
+				return nil, errors.New("invalid CDIC record: phrase out of bounds")
+			}
+			h.dictionary = append(h.dictionary, huffPhrase{
+				Data:         cdic[offset+2 : end],
+				Decompressed: length&0x8000 != 0,
+			})
+		}
+	}
+	return h, nil
+}
+
+// Decompresses a text record. Phrases of the dictionary may themselves be compressed, in which case they are
+// decompressed once and cached.
+func (h *huffCDIC) decompress(data []byte) ([]byte, error) {
+	var out []byte
+	bitsLeft := len(data) * 8
+	padded := make([]byte, len(data)+8)
+	copy(padded, data)
+
+	pos := 0
+	x := binary.BigEndian.Uint64(padded[pos:])
+	n := 32
+	for {
+		if n <= 0 {
+			pos += 4
+			if pos+8 > len(padded) {
+				break
+			}
+			x = binary.BigEndian.Uint64(padded[pos:])
+			n += 32
+		}
+		code := uint32(x >> uint(n))
+		c := h.codes[code>>24]
+		length := int(c.Length)
+		maxCode := c.MaxCode
+		if !c.Terminal {
+			for length < 32 && code < h.minCodes[length] {
+				length++
+			}
+			maxCode = h.maxCodes[length]
+		}
+		n -= length
+		bitsLeft -= length
+		if bitsLeft < 0 {
+			break
+		}
+
+		index := int((maxCode - code) >> (32 - length))
+		if index >= len(h.dictionary) {
+			return nil, errors.Errorf("HUFF/CDIC phrase %d out of bounds", index)
+		}
+		phrase := &h.dictionary[index]
+		if !phrase.Decompressed {
+			if phrase.unpacking {
+				return nil, errors.New("recursive HUFF/CDIC phrase")
+			}
+			phrase.unpacking = true
+			decompressed, err := h.decompress(phrase.Data)
+			phrase.unpacking = false
+			if err != nil {
+				return nil, err
+			}
+			phrase.Data = decompressed
+			phrase.Decompressed = true
+		}
+		out = append(out, phrase.Data...)
+	}
+	return out, nil
+}
+
+// Returns the size of the trailing entries appended to a text record, according to the extra flags of the
+// MOBI header.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#Variable-width_integers
+func trailingEntriesSize(data []byte, flags uint16) int {
+	size := 0
+	for f := flags >> 1; f != 0; f >>= 1 {
+		if f&1 != 0 {
+			size += backwardVarint(data[:len(data)-size])
+			if size > len(data) {
+				return len(data)
+			}
+		}
+	}
+	if flags&1 != 0 && size < len(data) {
+		// Bytes of a multibyte character overlapping the next record.
+		size += int(data[len(data)-size-1]&0x3) + 1
+	}
+	if size > len(data) {
+		return len(data)
+	}
+	return size
+}
+
+// Reads a variable-width integer stored backward at the end of [data].
+func backwardVarint(data []byte) int {
+	value := 0
+	shift := 0
+	for i := len(data) - 1; i >= 0; i-- {
+		b := data[i]
+		value |= int(b&0x7F) << shift
+		shift += 7
+		if b&0x80 != 0 || shift >= 28 {
+			break
+		}
+	}
+	return value
+}
+
+// Reads a variable-width integer stored forward at the start of [data], returning its value and size.
+func forwardVarint(data []byte) (uint32, int) {
+	var value uint32
+	for i := 0; i < len(data) && i < 4; i++ {
+		value = value<<7 | uint32(data[i]&0x7F)
+		if data[i]&0x80 != 0 {
+			return value, i + 1
+		}
+	}
+	if len(data) < 4 {
+		return value, len(data)
+	}
+	return value, 4
+}
@@ -0,0 +1,69 @@
+package mobi
+
+import (
+	"path"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Documents generated from the text of a MOBI or KF8 book.
+type content struct {
+	Documents []document // Reading order
+	Assets    []document // Stylesheets and SVG images
+	TOC       manifest.LinkList
+}
+
+type document struct {
+	manifest.Link
+	Data []byte
+}
+
+// Builds the table of contents from the NCX index, whose entries are nested according to their depth.
+// [resolve] returns the HREF targeted by an entry.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#NCX_Index
+func ncxTableOfContents(ncx *index, resolve func(e indexEntry) string) manifest.LinkList {
+	var entries []tocEntry
+	for _, e := range ncx.Entries {
+		href := resolve(e)
+		if href == "" {
+			continue
+		}
+		entries = append(entries, tocEntry{
+			Depth: int(e.Tag(4, 0)),
+			Link: manifest.Link{
+				Href:  href,
+				Title: strings.TrimSpace(ncx.Strings[e.Tag(3, nullIndex)]),
+			},
+		})
+	}
+	return nestEntries(entries)
+}
+
+type tocEntry struct {
+	Depth int
+	Link  manifest.Link
+}
+
+func nestEntries(entries []tocEntry) manifest.LinkList {
+	var links manifest.LinkList
+	for len(entries) > 0 {
+		e := entries[0]
+		end := 1
+		for end < len(entries) && entries[end].Depth > e.Depth {
+			end++
+		}
+		e.Link.Children = nestEntries(entries[1:end])
+		links = append(links, e.Link)
+		entries = entries[end:]
+	}
+	return links
+}
+
+// Returns [href] relative to the generated document at [base], e.g. "../images/00001.jpg" from "/text/part0000.xhtml".
+func relativeHref(base string, href string) string {
+	if path.Dir(base) == path.Dir(strings.SplitN(href, "#", 2)[0]) {
+		return strings.TrimPrefix(href, path.Dir(base)+"/")
+	}
+	return ".." + href
+}
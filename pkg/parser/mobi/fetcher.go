@@ -0,0 +1,33 @@
+package mobi
+
+import (
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Serves the documents generated from a MOBI or KF8 book, and its images read from the records of the file.
+// Other resources are requested from the fetcher of the book.
+func newContentFetcher(f fetcher.Fetcher, file manifest.Link, c *content, images []image) *fetcher.OverlayFetcher {
+	cf := fetcher.NewOverlayFetcher(f)
+	add := func(link manifest.Link, loader func() ([]byte, *fetcher.ResourceError)) {
+		link.Rels = nil
+		cf.Add(link, loader)
+	}
+	for _, docs := range [][]document{c.Documents, c.Assets} {
+		for _, doc := range docs {
+			data := doc.Data
+			add(doc.Link, func() ([]byte, *fetcher.ResourceError) {
+				return data, nil
+			})
+		}
+	}
+	for _, img := range images {
+		img := img
+		add(img.Link, func() ([]byte, *fetcher.ResourceError) {
+			res := f.Get(file)
+			defer res.Close()
+			return res.Read(img.Start, img.End)
+		})
+	}
+	return cf
+}
@@ -0,0 +1,172 @@
+package mobi
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Value of an unused record index in the MOBI header.
+const nullIndex = 0xFFFFFFFF
+
+// Compression schemes of the text records.
+const (
+	compressionNone     = 1
+	compressionPalmDOC  = 2
+	compressionHuffCDIC = 17480
+)
+
+// EXTH record types.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#EXTH_Header
+const (
+	exthAuthor      = 100
+	exthPublisher   = 101
+	exthDescription = 103
+	exthISBN        = 104
+	exthSubject     = 105
+	exthPublished   = 106
+	exthKF8Boundary = 121
+	exthCoverOffset = 201
+	exthASIN        = 113
+	exthTitle       = 503
+	exthLanguage    = 524
+)
+
+// Content of the first record of a MOBI file: the PalmDOC header, followed by the MOBI header and the optional
+// EXTH header.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#MOBI_Header
+type header struct {
+	Compression     uint16
+	TextRecordCount int
+	Encryption      uint16
+
+	Encoding      uint32 // Code page of the text, 1252 or 65001 (UTF-8).
+	Version       uint32
+	FullName      string
+	FirstImage    uint32
+	HuffRecord    uint32
+	HuffCount     uint32
+	ExtraFlags    uint16 // Trailing entries of the text records.
+	NCXIndex      uint32
+	FDSTIndex     uint32 // KF8 only
+	FragmentIndex uint32 // KF8 only
+	SkeletonIndex uint32 // KF8 only
+
+	EXTH map[uint32][][]byte
+}
+
+func parseHeader(record []byte) (*header, error) {
+	if len(record) < 24 || string(record[16:20]) != "MOBI" {
+		return nil, errors.New("invalid MOBI file: missing MOBI header")
+	}
+	length := binary.BigEndian.Uint32(record[20:24])
+	end := 16 + int(length)
+	if end > len(record) {
+		end = len(record)
+	}
+	mobi := record[:end]
+	u32 := func(offset int) uint32 {
+		if offset+4 > len(mobi) {
+			return nullIndex
+		}
+		return binary.BigEndian.Uint32(mobi[offset:])
+	}
+
+	h := &header{
+		Compression:     binary.BigEndian.Uint16(record[0:2]),
+		TextRecordCount: int(binary.BigEndian.Uint16(record[8:10])),
+		Encryption:      binary.BigEndian.Uint16(record[12:14]),
+		Encoding:        u32(0x1C),
+		Version:         u32(0x24),
+		FirstImage:      u32(0x6C),
+		HuffRecord:      u32(0x70),
+		HuffCount:       u32(0x74),
+		NCXIndex:        u32(0xF4),
+		FDSTIndex:       nullIndex,
+		FragmentIndex:   nullIndex,
+		SkeletonIndex:   nullIndex,
+	}
+	if length >= 0xE4 && len(mobi) >= 0xF4 {
+		h.ExtraFlags = binary.BigEndian.Uint16(mobi[0xF2:0xF4])
+	}
+	if h.Version >= 8 {
+		h.FDSTIndex = u32(0xC0)
+		h.FragmentIndex = u32(0xF8)
+		h.SkeletonIndex = u32(0xFC)
+	}
+
+	if offset, size := u32(0x54), u32(0x58); offset != nullIndex && size != nullIndex && int(offset)+int(size) <= len(record) {
+		h.FullName = h.decode(record[offset : offset+size])
+	}
+	if u32(0x80)&0x40 != 0 {
+		h.EXTH = parseEXTH(record[end:])
+	}
+	return h, nil
+}
+
+// Parses the EXTH header, which holds most of the metadata of the book.
+func parseEXTH(data []byte) map[uint32][][]byte {
+	records := make(map[uint32][][]byte)
+	if len(data) < 12 || string(data[:4]) != "EXTH" {
+		return records
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	pos := 12
+	for i := uint32(0); i < count && pos+8 <= len(data); i++ {
+		typ := binary.BigEndian.Uint32(data[pos:])
+		size := int(binary.BigEndian.Uint32(data[pos+4:]))
+		if size < 8 || pos+size > len(data) {
+			break
+		}
+		records[typ] = append(records[typ], data[pos+8:pos+size])
+		pos += size
+	}
+	return records
+}
+
+// Returns the values of the EXTH records of type [typ] as strings.
+func (h *header) exthStrings(typ uint32) []string {
+	var values []string
+	for _, value := range h.EXTH[typ] {
+		if s := h.decode(value); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// Returns the first EXTH record of type [typ] as a string.
+func (h *header) exthString(typ uint32) string {
+	if values := h.exthStrings(typ); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Returns the first EXTH record of type [typ] as an integer.
+func (h *header) exthUint(typ uint32) (uint32, bool) {
+	for _, value := range h.EXTH[typ] {
+		if len(value) == 4 {
+			return binary.BigEndian.Uint32(value), true
+		}
+	}
+	return 0, false
+}
+
+func (h *header) encoding() encoding.Encoding {
+	if h.Encoding == 1252 {
+		return charmap.Windows1252
+	}
+	return encoding.Nop
+}
+
+// Decodes a string using the text encoding of the book.
+func (h *header) decode(data []byte) string {
+	decoded, err := h.encoding().NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
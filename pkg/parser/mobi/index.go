@@ -0,0 +1,231 @@
+package mobi
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Entry of an INDX index, such as the NCX or the KF8 skeleton and fragment tables.
+type indexEntry struct {
+	Label string
+	Tags  map[uint8][]uint32
+}
+
+// Returns the first value of the tag, or [def] if missing.
+func (e indexEntry) Tag(tag uint8, def uint32) uint32 {
+	if values := e.Tags[tag]; len(values) > 0 {
+		return values[0]
+	}
+	return def
+}
+
+// Describes how the values of a tag are encoded in the entries of an index.
+type tagDefinition struct {
+	Tag       uint8
+	ValueSize uint8 // Number of values per occurrence of the tag.
+	Mask      uint8
+	EOF       bool // Marks the end of a control byte.
+}
+
+// An INDX index, made of a main record followed by its index records and the CNCX records holding its strings.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#INDX
+type index struct {
+	Entries []indexEntry
+	Strings map[uint32]string // CNCX strings, by offset.
+}
+
+// Reads the index starting at the record [start].
+func readIndex(p *pdb, start int, h *header) (*index, error) {
+	data, err := p.Record(start)
+	if err != nil {
+		return nil, err
+	}
+	main, err := parseIndexHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if int(main.Length) > len(data) {
+		return nil, errors.New("invalid INDX record: truncated header")
+	}
+	controlBytes, tags, err := parseTagSection(data[main.Length:])
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &index{Strings: make(map[uint32]string)}
+	for i := 0; i < int(main.CNCXCount); i++ {
+		record, err := p.Record(start + int(main.Count) + 1 + i)
+		if err != nil {
+			return nil, err
+		}
+		for pos := 0; pos < len(record); {
+			length, consumed := forwardVarint(record[pos:])
+			if consumed == 0 {
+				break
+			}
+			end := pos + consumed + int(length)
+			if end > len(record) {
+				break
+			}
+			if length > 0 {
+				idx.Strings[uint32(i)<<16+uint32(pos)] = h.decode(record[pos+consumed : end])
+			}
+			pos = end
+		}
+	}
+
+	for i := 1; i <= int(main.Count); i++ {
+		data, err := p.Record(start + i)
+		if err != nil {
+			return nil, err
+		}
+		hdr, err := parseIndexHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		idxt := int(hdr.Start)
+		if idxt+4+int(hdr.Count)*2 > len(data) || string(data[idxt:idxt+4]) != "IDXT" {
+			return nil, errors.New("invalid INDX record: missing IDXT")
+		}
+		positions := make([]int, hdr.Count+1)
+		for j := 0; j < int(hdr.Count); j++ {
+			positions[j] = int(binary.BigEndian.Uint16(data[idxt+4+j*2:]))
+		}
+		positions[hdr.Count] = idxt
+		for j := 0; j < int(hdr.Count); j++ {
+			if positions[j] >= positions[j+1] || positions[j+1] > len(data) {
+				continue
+			}
+			entry := data[positions[j]:positions[j+1]]
+			labelLength := int(entry[0])
+			if 1+labelLength > len(entry) {
+				continue
+			}
+			idx.Entries = append(idx.Entries, indexEntry{
+				Label: h.decode(entry[1 : 1+labelLength]),
+				Tags:  parseTags(entry[1+labelLength:], controlBytes, tags),
+			})
+		}
+	}
+	return idx, nil
+}
+
+type indexHeader struct {
+	Length    uint32
+	Start     uint32 // Offset of the IDXT section in index records.
+	Count     uint32 // Number of index records in the main record, number of entries in index records.
+	CNCXCount uint32
+}
+
+func parseIndexHeader(data []byte) (*indexHeader, error) {
+	if len(data) < 56 || string(data[:4]) != "INDX" {
+		return nil, errors.New("invalid INDX record")
+	}
+	return &indexHeader{
+		Length:    binary.BigEndian.Uint32(data[4:]),
+		Start:     binary.BigEndian.Uint32(data[20:]),
+		Count:     binary.BigEndian.Uint32(data[24:]),
+		CNCXCount: binary.BigEndian.Uint32(data[52:]),
+	}, nil
+}
+
+// Parses the TAGX section of the main INDX record, which defines the tags of the entries.
+func parseTagSection(data []byte) (int, []tagDefinition, error) {
+	if len(data) < 12 || string(data[:4]) != "TAGX" {
+		return 0, nil, errors.New("invalid INDX record: missing TAGX")
+	}
+	length := int(binary.BigEndian.Uint32(data[4:]))
+	controlBytes := int(binary.BigEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return 0, nil, errors.New("invalid INDX record: truncated TAGX")
+	}
+	var tags []tagDefinition
+	for i := 12; i+4 <= length; i += 4 {
+		tags = append(tags, tagDefinition{
+			Tag:       data[i],
+			ValueSize: data[i+1],
+			Mask:      data[i+2],
+			EOF:       data[i+3]&1 != 0,
+		})
+	}
+	return controlBytes, tags, nil
+}
+
+// Decodes the tag values of an index entry, following its control bytes.
+func parseTags(data []byte, controlBytes int, tags []tagDefinition) map[uint8][]uint32 {
+	values := make(map[uint8][]uint32)
+	if controlBytes > len(data) {
+		return values
+	}
+	control := data[:controlBytes]
+	data = data[controlBytes:]
+
+	type occurrence struct {
+		def        tagDefinition
+		count      int // Number of occurrences, or -1 when [size] is given.
+		byteLength int // Number of bytes holding the values.
+	}
+	var occurrences []occurrence
+	for _, def := range tags {
+		if def.EOF {
+			if len(control) > 0 {
+				control = control[1:]
+			}
+			continue
+		}
+		if len(control) == 0 || def.Mask == 0 {
+			continue
+		}
+		value := control[0] & def.Mask
+		if value == 0 {
+			continue
+		}
+		o := occurrence{def: def, count: 1}
+		if value == def.Mask {
+			if bitCount(def.Mask) > 1 {
+				// All the bits are set: the length of the values is given by a variable-width integer.
+				length, consumed := forwardVarint(data)
+				data = data[consumed:]
+				o.count = -1
+				o.byteLength = int(length)
+			}
+		} else {
+			mask := def.Mask
+			for mask&1 == 0 {
+				mask >>= 1
+				value >>= 1
+			}
+			o.count = int(value)
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	for _, o := range occurrences {
+		var vs []uint32
+		if o.count >= 0 {
+			for i := 0; i < o.count*int(o.def.ValueSize) && len(data) > 0; i++ {
+				v, consumed := forwardVarint(data)
+				data = data[consumed:]
+				vs = append(vs, v)
+			}
+		} else {
+			for total := 0; total < o.byteLength && len(data) > 0; {
+				v, consumed := forwardVarint(data)
+				data = data[consumed:]
+				total += consumed
+				vs = append(vs, v)
+			}
+		}
+		values[o.def.Tag] = vs
+	}
+	return values
+}
+
+func bitCount(b uint8) int {
+	count := 0
+	for ; b != 0; b >>= 1 {
+		count += int(b & 1)
+	}
+	return count
+}
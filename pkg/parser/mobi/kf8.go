@@ -0,0 +1,274 @@
+package mobi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+)
+
+var (
+	kindlePosRegexp   = regexp.MustCompile(`kindle:pos:fid:([0-9A-Va-v]{4}):off:([0-9A-Va-v]{10})`)
+	kindleEmbedRegexp = regexp.MustCompile(`kindle:embed:([0-9A-Va-v]{4})(?:\?mime=[^'"\s)]*)?`)
+	kindleFlowRegexp  = regexp.MustCompile(`kindle:flow:([0-9A-Va-v]{4})(?:\?mime=[^'"\s)]*)?`)
+	startTagRegexp    = regexp.MustCompile(`<[A-Za-z][^>]*>`)
+	idAttrRegexp      = regexp.MustCompile(`(?i)\s(?:id|name)\s*=\s*["']([^"']+)["']`)
+	aidAttrRegexp     = regexp.MustCompile(`(?i)\said\s*=\s*["']([^"']+)["']`)
+)
+
+// Converts the text of a KF8 book to XHTML documents, by inserting its fragments into their skeleton.
+//
+// The text is split in flows: the first one holds the skeletons and fragments, while the next ones are the
+// stylesheets and SVG images referenced with kindle:flow URLs.
+// Reference: https://wiki.mobileread.com/wiki/KF8
+type kf8Book struct {
+	flows     [][]byte
+	parts     [][]byte // Assembled XHTML documents.
+	starts    []int    // Position of each document in the first flow.
+	ends      []int
+	fragments []int // Insert position of each fragment, indexed by fragment ID.
+	aids      []map[string]bool
+}
+
+func convertKF8(p *pdb, base int, h *header, text []byte, images map[int]manifest.Link) (*content, error) {
+	b := &kf8Book{flows: readFlows(p, base, h, text)}
+	if err := b.assemble(p, base, h); err != nil {
+		return nil, err
+	}
+
+	c := &content{}
+	for i := range b.parts {
+		c.Documents = append(c.Documents, document{Link: manifest.Link{Href: b.partHref(i), Type: mediatype.XHTML.String()}})
+	}
+	for i := 1; i < len(b.flows); i++ {
+		c.Assets = append(c.Assets, document{Link: b.flowLink(i)})
+	}
+
+	// Links are resolved against the original documents, before they are modified.
+	if h.NCXIndex != nullIndex {
+		if ncx, err := readIndex(p, base+int(h.NCXIndex), h); err == nil {
+			c.TOC = ncxTableOfContents(ncx, func(e indexEntry) string {
+				if fid := e.Tags[6]; len(fid) >= 2 {
+					return b.resolve(int(fid[0]), int(fid[1]))
+				}
+				return b.resolvePosition(int(e.Tag(1, 0)))
+			})
+		} // TODO log otherwise
+	}
+	for i, part := range b.parts {
+		href := b.partHref(i)
+		c.Documents[i].Data = kindlePosRegexp.ReplaceAllFunc(part, func(match []byte) []byte {
+			m := kindlePosRegexp.FindSubmatch(match)
+			fid, _ := strconv.ParseUint(string(m[1]), 32, 32)
+			off, _ := strconv.ParseUint(string(m[2]), 32, 32)
+			target := b.resolve(int(fid), int(off))
+			if target == "" {
+				return match
+			}
+			return []byte(relativeHref(href, target))
+		})
+	}
+
+	for i := range c.Documents {
+		data := b.rewriteResources(c.Documents[i].Data, c.Documents[i].Href, images)
+		c.Documents[i].Data = injectAIDs(data, b.aids[i])
+	}
+	for i := range c.Assets {
+		c.Assets[i].Data = b.rewriteResources(b.flows[i+1], c.Assets[i].Href, images)
+	}
+	return c, nil
+}
+
+// Splits the text in flows, according to the FDST record.
+func readFlows(p *pdb, base int, h *header, text []byte) [][]byte {
+	if h.FDSTIndex != nullIndex {
+		if fdst, err := p.Record(base + int(h.FDSTIndex)); err == nil && len(fdst) >= 12 && string(fdst[:4]) == "FDST" {
+			count := int(binary.BigEndian.Uint32(fdst[8:]))
+			var flows [][]byte
+			for i := 0; i < count && 12+i*8+8 <= len(fdst); i++ {
+				start := int(binary.BigEndian.Uint32(fdst[12+i*8:]))
+				end := int(binary.BigEndian.Uint32(fdst[12+i*8+4:]))
+				if start > end || end > len(text) {
+					break
+				}
+				flows = append(flows, text[start:end])
+			}
+			if len(flows) > 0 {
+				return flows
+			}
+		} // TODO log otherwise
+	}
+	return [][]byte{text}
+}
+
+// Inserts the fragments into their skeleton, following the skeleton and fragment indexes.
+func (b *kf8Book) assemble(p *pdb, base int, h *header) error {
+	flow := b.flows[0]
+	if h.SkeletonIndex == nullIndex || h.FragmentIndex == nullIndex {
+		b.addPart(flow, 0, len(flow))
+		return nil
+	}
+	skeletons, err := readIndex(p, base+int(h.SkeletonIndex), h)
+	if err != nil {
+		return errors.Wrap(err, "failed reading KF8 skeleton index")
+	}
+	fragments, err := readIndex(p, base+int(h.FragmentIndex), h)
+	if err != nil {
+		return errors.Wrap(err, "failed reading KF8 fragment index")
+	}
+	for _, f := range fragments.Entries {
+		pos, err := strconv.Atoi(f.Label)
+		if err != nil {
+			return errors.Errorf("invalid KF8 fragment insert position %q", f.Label)
+		}
+		b.fragments = append(b.fragments, pos)
+	}
+
+	next := 0
+	for _, skeleton := range skeletons.Entries {
+		count := int(skeleton.Tag(1, 0))
+		location := skeleton.Tags[6]
+		if len(location) < 2 {
+			return errors.New("invalid KF8 skeleton entry")
+		}
+		start, length := int(location[0]), int(location[1])
+		end := start + length
+		if end > len(flow) {
+			return errors.New("KF8 skeleton out of bounds")
+		}
+		part := append([]byte{}, flow[start:end]...)
+		for i := 0; i < count && next < len(fragments.Entries); i++ {
+			insert := b.fragments[next] - start
+			fragment := fragments.Entries[next].Tags[6]
+			next++
+			if len(fragment) < 2 || insert < 0 || insert > len(part) || end+int(fragment[1]) > len(flow) {
+				return errors.New("KF8 fragment out of bounds")
+			}
+			data := flow[end : end+int(fragment[1])]
+			end += len(data)
+			part = append(part[:insert], append(append([]byte{}, data...), part[insert:]...)...)
+		}
+		b.addPart(part, start, end)
+	}
+	return nil
+}
+
+func (b *kf8Book) addPart(part []byte, start, end int) {
+	b.parts = append(b.parts, part)
+	b.starts = append(b.starts, start)
+	b.ends = append(b.ends, end)
+	b.aids = append(b.aids, make(map[string]bool))
+}
+
+func (b *kf8Book) partHref(i int) string {
+	return fmt.Sprintf("/text/part%04d.xhtml", i)
+}
+
+// Flows are either stylesheets or SVG images.
+func (b *kf8Book) flowLink(i int) manifest.Link {
+	if bytes.Contains(b.flows[i], []byte("<svg")) {
+		return manifest.Link{Href: fmt.Sprintf("/images/flow%04d.svg", i), Type: mediatype.SVG.String()}
+	}
+	return manifest.Link{Href: fmt.Sprintf("/styles/flow%04d.css", i), Type: mediatype.CSS.String()}
+}
+
+// Resolves a kindle:pos link, given as an offset from the insert position of a fragment.
+func (b *kf8Book) resolve(fid int, offset int) string {
+	if fid < 0 || fid >= len(b.fragments) {
+		return ""
+	}
+	return b.resolvePosition(b.fragments[fid] + offset)
+}
+
+// Resolves a position in the assembled documents to the closest preceding anchor.
+func (b *kf8Book) resolvePosition(pos int) string {
+	for i := range b.parts {
+		if pos < b.starts[i] || pos >= b.ends[i] {
+			continue
+		}
+		href := b.partHref(i)
+		id, aid := findAnchor(b.parts[i], pos-b.starts[i])
+		switch {
+		case id != "":
+			return href + "#" + id
+		case aid != "":
+			// The element is only identified by its Amazon ID, so an ID will be added to it.
+			b.aids[i][aid] = true
+			return href + "#aid-" + aid
+		default:
+			return href
+		}
+	}
+	return ""
+}
+
+// Finds the ID or Amazon ID of the element at [pos], or of the closest preceding one.
+func findAnchor(part []byte, pos int) (id string, aid string) {
+	end := pos
+	if end > len(part) {
+		end = len(part)
+	}
+	// When the position is inside a tag or at its start, the tag itself is considered.
+	gt := bytes.IndexByte(part[end:], '>')
+	lt := bytes.IndexByte(part[end:], '<')
+	if gt >= 0 && (lt == 0 || lt < 0 || gt < lt) {
+		end += gt + 1
+	}
+
+	for end > 0 {
+		start := bytes.LastIndexByte(part[:end], '<')
+		if start < 0 {
+			break
+		}
+		tag := part[start:end]
+		if m := idAttrRegexp.FindSubmatch(tag); m != nil {
+			return string(m[1]), ""
+		}
+		if m := aidAttrRegexp.FindSubmatch(tag); m != nil {
+			return "", string(m[1])
+		}
+		end = start
+	}
+	return "", ""
+}
+
+// Adds an ID to the elements targeted by their Amazon ID.
+func injectAIDs(data []byte, aids map[string]bool) []byte {
+	if len(aids) == 0 {
+		return data
+	}
+	return startTagRegexp.ReplaceAllFunc(data, func(tag []byte) []byte {
+		m := aidAttrRegexp.FindSubmatch(tag)
+		if m == nil || !aids[string(m[1])] || idAttrRegexp.Match(tag) {
+			return tag
+		}
+		i := len(tag) - 1
+		if bytes.HasSuffix(tag, []byte("/>")) {
+			i--
+		}
+		return []byte(string(tag[:i]) + ` id="aid-` + string(m[1]) + `"` + string(tag[i:]))
+	})
+}
+
+// Replaces the kindle:embed and kindle:flow URLs with the HREFs of the images and flows.
+func (b *kf8Book) rewriteResources(data []byte, href string, images map[int]manifest.Link) []byte {
+	data = kindleEmbedRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		i, _ := strconv.ParseUint(string(kindleEmbedRegexp.FindSubmatch(match)[1]), 32, 32)
+		if image, ok := images[int(i)]; ok {
+			return []byte(relativeHref(href, image.Href))
+		}
+		return match
+	})
+	return kindleFlowRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		i, _ := strconv.ParseUint(string(kindleFlowRegexp.FindSubmatch(match)[1]), 32, 32)
+		if i == 0 || int(i) >= len(b.flows) {
+			return match
+		}
+		return []byte(relativeHref(href, b.flowLink(int(i)).Href))
+	})
+}
@@ -0,0 +1,200 @@
+package mobi
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"golang.org/x/net/html"
+)
+
+var (
+	fileposRegexp   = regexp.MustCompile(`(?i)filepos\s*=\s*["']?0*(\d+)["']?`)
+	recindexRegexp  = regexp.MustCompile(`(?i)(?:hi|lo)?recindex\s*=\s*["']?(\d+)["']?`)
+	guideRegexp     = regexp.MustCompile(`(?is)<guide[\s>].*?</guide>`)
+	pagebreakRegexp = regexp.MustCompile(`(?i)<mbp:pagebreak[^>]*>(?:\s*</mbp:pagebreak>)?`)
+)
+
+// Converts the text of a legacy MOBI book to HTML documents, split at its page breaks.
+//
+// Links and the NCX point to byte offsets in the text (filepos), so anchors are inserted at these offsets before the
+// text is decoded and split. Images are referenced by their record index (recindex).
+// Reference: https://wiki.mobileread.com/wiki/MOBI#Mobipocket_HTML
+func convertMOBI6(p *pdb, h *header, text []byte, images map[int]manifest.Link) (*content, error) {
+	var ncx *index
+	if h.NCXIndex != nullIndex {
+		ncx, _ = readIndex(p, int(h.NCXIndex), h) // TODO log errors
+	}
+
+	positions := make(map[int]bool)
+	for _, m := range fileposRegexp.FindAllSubmatch(text, -1) {
+		if pos, err := strconv.Atoi(string(m[1])); err == nil {
+			positions[pos] = true
+		}
+	}
+	if ncx != nil {
+		for _, e := range ncx.Entries {
+			if pos := e.Tag(1, nullIndex); pos != nullIndex {
+				positions[int(pos)] = true
+			}
+		}
+	}
+	text = insertFileposAnchors(text, positions)
+	text = fileposRegexp.ReplaceAll(text, []byte(`href="#filepos$1"`))
+	text = recindexRegexp.ReplaceAllFunc(text, func(match []byte) []byte {
+		i, _ := strconv.Atoi(string(recindexRegexp.FindSubmatch(match)[1]))
+		if image, ok := images[i]; ok {
+			return []byte(`src="` + relativeHref("/text/", image.Href) + `"`)
+		}
+		return match
+	})
+
+	// The guide is not rendered, and page breaks are made explicitly empty so the HTML parser doesn't nest the
+	// following content into them.
+	text = guideRegexp.ReplaceAll(text, nil)
+	text = pagebreakRegexp.ReplaceAll(text, []byte("<mbp:pagebreak></mbp:pagebreak>"))
+
+	doc, err := html.Parse(strings.NewReader(h.decode(text)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing MOBI HTML")
+	}
+	head, body := findElement(doc, "head"), findElement(doc, "body")
+	if body == nil {
+		return nil, errors.New("invalid MOBI HTML: missing body")
+	}
+
+	// Splits the body at its page breaks.
+	parts := [][]*html.Node{nil}
+	for n := body.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode && n.Data == "mbp:pagebreak" {
+			parts = append(parts, nil)
+			continue
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], n)
+	}
+	c := &content{}
+	anchors := make(map[string]string)
+	var kept [][]*html.Node
+	for _, nodes := range parts {
+		if isBlank(nodes) {
+			continue
+		}
+		href := fmt.Sprintf("/text/part%04d.html", len(kept))
+		for _, n := range nodes {
+			walk(n, func(n *html.Node) {
+				if id := attr(n, "id"); id != "" {
+					anchors[id] = href
+				}
+			})
+		}
+		kept = append(kept, nodes)
+		c.Documents = append(c.Documents, document{Link: manifest.Link{Href: href, Type: mediatype.HTML.String()}})
+	}
+
+	title := h.FullName
+	for i, nodes := range kept {
+		href := c.Documents[i].Href
+		var buf bytes.Buffer
+		buf.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"/><title>" + html.EscapeString(title) + "</title>")
+		if head != nil {
+			for n := head.FirstChild; n != nil; n = n.NextSibling {
+				if n.Type == html.ElementNode && (n.Data == "style" || n.Data == "link") {
+					html.Render(&buf, n)
+				}
+			}
+		}
+		buf.WriteString("</head><body>")
+		for _, n := range nodes {
+			walk(n, func(n *html.Node) {
+				for j, a := range n.Attr {
+					if a.Key == "href" && strings.HasPrefix(a.Val, "#") {
+						if target, ok := anchors[a.Val[1:]]; ok && target != href {
+							n.Attr[j].Val = relativeHref(href, target) + a.Val
+						}
+					}
+				}
+			})
+			if err := html.Render(&buf, n); err != nil {
+				return nil, errors.Wrap(err, "failed rendering MOBI HTML")
+			}
+		}
+		buf.WriteString("</body></html>\n")
+		c.Documents[i].Data = buf.Bytes()
+	}
+
+	if ncx != nil {
+		c.TOC = ncxTableOfContents(ncx, func(e indexEntry) string {
+			id := "filepos" + strconv.Itoa(int(e.Tag(1, 0)))
+			if href, ok := anchors[id]; ok {
+				return href + "#" + id
+			}
+			return ""
+		})
+	}
+	return c, nil
+}
+
+// Inserts an empty anchor at each of the given byte offsets of the text, or before the tag containing it.
+func insertFileposAnchors(text []byte, positions map[int]bool) []byte {
+	sorted := make([]int, 0, len(positions))
+	for pos := range positions {
+		if pos <= len(text) {
+			sorted = append(sorted, pos)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	for _, pos := range sorted {
+		at := pos
+		if lt := bytes.LastIndexByte(text[:pos], '<'); lt > bytes.LastIndexByte(text[:pos], '>') {
+			at = lt
+		}
+		anchor := []byte(`<a id="filepos` + strconv.Itoa(pos) + `"></a>`)
+		text = append(text[:at], append(anchor, text[at:]...)...)
+	}
+	return text
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func walk(n *html.Node, f func(n *html.Node)) {
+	f(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, f)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// Returns whether the nodes only contain whitespaces.
+func isBlank(nodes []*html.Node) bool {
+	for _, n := range nodes {
+		if n.Type == html.ElementNode || (n.Type == html.TextNode && strings.TrimSpace(n.Data) != "") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,298 @@
+package mobi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/bitmap"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/pub"
+)
+
+// Parses an unencrypted Mobipocket (MOBI) or Kindle Format 8 (AZW3) e-book into a Readium Web Publication.
+//
+// The text of KF8 books is split into XHTML documents following their skeleton and fragment structure, while the
+// text of legacy MOBI books is split into HTML documents at their page breaks. When a file contains both versions,
+// the KF8 one is used.
+// Reference: https://wiki.mobileread.com/wiki/MOBI
+type Parser struct {
+}
+
+func NewParser() Parser {
+	return Parser{}
+}
+
+// An image stored in a record of the book.
+type image struct {
+	manifest.Link
+	Start, End int64 // Inclusive byte range of the record in the file.
+}
+
+// Parse implements PublicationParser
+func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	if !asset.MediaType().Matches(&mediatype.MOBI, &mediatype.AZW3) {
+		return nil, nil
+	}
+
+	links, err := f.Links()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fetch links")
+	}
+	link := findBook(links)
+	if link == nil {
+		return nil, errors.New("unable to find MOBI file")
+	}
+	resource := f.Get(*link)
+	defer resource.Close()
+	db, err := newPDB(resource)
+	if err != nil {
+		return nil, err
+	}
+	if db.Type != "BOOKMOBI" {
+		return nil, errors.Errorf("unsupported Palm Database type %q", db.Type)
+	}
+	record, err := db.Record(0)
+	if err != nil {
+		return nil, err
+	}
+	h, err := parseHeader(record)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files generated by KindleGen hold a legacy MOBI book, followed by a KF8 one starting after the boundary record.
+	base, textHeader := 0, h
+	if boundary, ok := h.exthUint(exthKF8Boundary); ok && h.Version < 8 && int(boundary) < db.Len() {
+		if record, err := db.Record(int(boundary)); err == nil {
+			if kf8, err := parseHeader(record); err == nil && kf8.Version >= 8 {
+				base, textHeader = int(boundary), kf8
+			}
+		}
+	}
+	if textHeader.Encryption != 0 {
+		return nil, errors.New("encrypted MOBI files are not supported")
+	}
+
+	text, err := readText(db, base, textHeader)
+	if err != nil {
+		return nil, err
+	}
+	images := readImages(db, base, h, textHeader)
+	if offset, ok := h.exthUint(exthCoverOffset); ok {
+		if cover, ok := images[int(offset)+1]; ok {
+			cover.Rels = manifest.Strings{"cover"}
+			images[int(offset)+1] = cover
+		}
+	}
+	imagesByIndex := make(map[int]manifest.Link, len(images))
+	for i, img := range images {
+		imagesByIndex[i] = img.Link
+	}
+
+	var c *content
+	if textHeader.Version >= 8 {
+		c, err = convertKF8(db, base, textHeader, text, imagesByIndex)
+	} else {
+		c, err = convertMOBI6(db, textHeader, text, imagesByIndex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Documents) == 0 {
+		return nil, errors.New("MOBI file has no text")
+	}
+
+	metadata := h.metadata()
+	if metadata.Title() == "" {
+		title := strings.TrimSpace(db.Name)
+		if title == "" {
+			title = strings.TrimSuffix(asset.Name(), path.Ext(asset.Name()))
+		}
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(title)
+	}
+
+	var readingOrder, resources manifest.LinkList
+	for _, doc := range c.Documents {
+		readingOrder = append(readingOrder, doc.Link)
+	}
+	for _, asset := range c.Assets {
+		resources = append(resources, asset.Link)
+	}
+	indexes := make([]int, 0, len(images))
+	for i := range images {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	imageList := make([]image, 0, len(images))
+	for _, i := range indexes {
+		imageList = append(imageList, images[i])
+		resources = append(resources, images[i].Link)
+	}
+
+	manifest := manifest.Manifest{
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       resources,
+		TableOfContents: c.TOC,
+	}
+
+	builder := pub.NewServicesBuilder(map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: epub.PositionsServiceFactory(epub.OriginalLength{PageLength: 1024}),
+	})
+	return pub.NewBuilder(manifest, newContentFetcher(f, *link, c, imageList), builder), nil
+}
+
+// Finds the MOBI file, which is usually the only file of the asset.
+func findBook(links manifest.LinkList) *manifest.Link {
+	for i, link := range links {
+		switch strings.ToLower(path.Ext(link.Href)) {
+		case ".mobi", ".azw3", ".prc":
+			return &links[i]
+		}
+	}
+	if len(links) == 1 {
+		return &links[0]
+	}
+	return nil
+}
+
+// Reads and decompresses the text records following the record 0 at [base].
+func readText(db *pdb, base int, h *header) ([]byte, error) {
+	var decompress func(data []byte) ([]byte, error)
+	switch h.Compression {
+	case compressionNone:
+		decompress = func(data []byte) ([]byte, error) {
+			return data, nil
+		}
+	case compressionPalmDOC:
+		decompress = func(data []byte) ([]byte, error) {
+			return decompressPalmDOC(data), nil
+		}
+	case compressionHuffCDIC:
+		var records [][]byte
+		for i := 0; i < int(h.HuffCount); i++ {
+			record, err := db.Record(base + int(h.HuffRecord) + i)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed reading HUFF/CDIC records")
+			}
+			records = append(records, record)
+		}
+		huff, err := newHuffCDIC(records)
+		if err != nil {
+			return nil, err
+		}
+		decompress = huff.decompress
+	default:
+		return nil, errors.Errorf("unsupported MOBI compression %d", h.Compression)
+	}
+
+	var text []byte
+	for i := 1; i <= h.TextRecordCount; i++ {
+		record, err := db.Record(base + i)
+		if err != nil {
+			return nil, err
+		}
+		record = record[:len(record)-trailingEntriesSize(record, h.ExtraFlags)]
+		data, err := decompress(record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed decompressing text record %d", i)
+		}
+		text = append(text, data...)
+	}
+	return text, nil
+}
+
+// Finds the images stored in the records of the book, indexed from 1 as referenced by the text.
+// Other resources, such as fonts or the end-of-file markers, are skipped.
+func readImages(db *pdb, base int, h *header, textHeader *header) map[int]image {
+	// Record indexes of a KF8 book are relative to its record 0. Without a valid index, the images of the legacy part are used.
+	first := int(textHeader.FirstImage)
+	if textHeader.FirstImage == nullIndex || base+first >= db.Len() {
+		first = int(h.FirstImage)
+	} else {
+		first += base
+	}
+	images := make(map[int]image)
+	if first <= 0 || first >= db.Len() {
+		return images
+	}
+	for i := first; i < db.Len(); i++ {
+		start, end, _ := db.Range(i)
+		head := end
+		if head > start+bitmap.MagicLength-1 {
+			head = start + bitmap.MagicLength - 1
+		}
+		if head < start {
+			continue
+		}
+		magic, rerr := db.resource.Read(start, head)
+		if rerr != nil {
+			break
+		}
+		mt := mediatype.OfString(bitmap.SniffMediaType(magic))
+		if mt == nil {
+			continue
+		}
+		index := i - first + 1
+		images[index] = image{
+			Link: manifest.Link{
+				Href: fmt.Sprintf("/images/%05d.%s", index, mt.FileExtension()),
+				Type: mt.String(),
+			},
+			Start: start,
+			End:   end,
+		}
+	}
+	return images
+}
+
+// Maps the EXTH records and the full name of the book to the RWPM metadata.
+// Reference: https://wiki.mobileread.com/wiki/MOBI#EXTH_Header
+func (h *header) metadata() manifest.Metadata {
+	contributors := func(names []string) manifest.Contributors {
+		var contributors manifest.Contributors
+		for _, name := range names {
+			contributors = append(contributors, manifest.Contributor{
+				LocalizedName: manifest.NewLocalizedStringFromString(name),
+			})
+		}
+		return contributors
+	}
+
+	metadata := manifest.Metadata{
+		Authors:     contributors(h.exthStrings(exthAuthor)),
+		Publishers:  contributors(h.exthStrings(exthPublisher)),
+		Description: h.exthString(exthDescription),
+		Published:   extensions.ParseDate(h.exthString(exthPublished)),
+	}
+	title := h.exthString(exthTitle)
+	if title == "" {
+		title = h.FullName
+	}
+	if title != "" {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(title)
+	}
+	if isbn := h.exthString(exthISBN); isbn != "" {
+		metadata.Identifier = "urn:isbn:" + strings.ReplaceAll(isbn, "-", "")
+	} else {
+		metadata.Identifier = h.exthString(exthASIN)
+	}
+	if language := h.exthString(exthLanguage); language != "" {
+		metadata.Languages = manifest.Strings{language}
+	}
+	for _, subject := range h.exthStrings(exthSubject) {
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(subject),
+		})
+	}
+	return metadata
+}
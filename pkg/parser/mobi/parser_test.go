@@ -0,0 +1,141 @@
+package mobi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withMOBI(t *testing.T, filepath string, f func(*pub.Publication)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewParser().Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	f(p.Build())
+}
+
+func readString(t *testing.T, p *pub.Publication, href string) string {
+	data, err := p.Get(manifest.Link{Href: href}).Read(0, 0)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	return string(data)
+}
+
+func TestKF8Metadata(t *testing.T) {
+	withMOBI(t, "./testdata/alice.azw3", func(p *pub.Publication) {
+		m := p.Manifest.Metadata
+		assert.Equal(t, "Alice's Adventures in Wonderland", m.Title())
+		assert.Equal(t, "urn:isbn:9780000000001", m.Identifier)
+		assert.Equal(t, manifest.Strings{"en"}, m.Languages)
+		assert.Equal(t, "A girl falls down a rabbit hole.", m.Description)
+		assert.Equal(t, time.Date(1865, 11, 26, 0, 0, 0, 0, time.UTC), *m.Published)
+		if assert.Len(t, m.Authors, 1) {
+			assert.Equal(t, "Lewis Carroll", m.Authors[0].Name())
+		}
+		if assert.Len(t, m.Publishers, 1) {
+			assert.Equal(t, "Macmillan", m.Publishers[0].Name())
+		}
+		if assert.Len(t, m.Subjects, 2) {
+			assert.Equal(t, "Fantasy", m.Subjects[0].Name())
+			assert.Equal(t, "Children", m.Subjects[1].Name())
+		}
+	})
+}
+
+func TestKF8Resources(t *testing.T) {
+	withMOBI(t, "./testdata/alice.azw3", func(p *pub.Publication) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/part0000.xhtml", Type: "application/xhtml+xml"},
+			{Href: "/text/part0001.xhtml", Type: "application/xhtml+xml"},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/styles/flow0001.css", Type: "text/css"},
+			{Href: "/images/00001.png", Type: "image/png", Rels: manifest.Strings{"cover"}},
+			{Href: "/images/00002.gif", Type: "image/gif"},
+		}, p.Manifest.Resources)
+
+		data, err := p.Get(manifest.Link{Href: "/images/00001.png"}).Read(0, 0)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(data), "\x89PNG"))
+		assert.Equal(t, "body { margin: 0; }\n.cover { background: url(../images/00001.png); }\n", readString(t, p, "/styles/flow0001.css"))
+	})
+}
+
+func TestKF8Documents(t *testing.T) {
+	withMOBI(t, "./testdata/alice.azw3", func(p *pub.Publication) {
+		part0 := readString(t, p, "/text/part0000.xhtml")
+		assert.Contains(t, part0, `<link href="../styles/flow0001.css" rel="stylesheet" type="text/css"/>`)
+		assert.Contains(t, part0, `<body aid="0"><h1 id="ch1" aid="1">Down the Rabbit-Hole</h1><div class="cover" aid="2"><img src="../images/00001.png" alt=""/></div><p aid="3">`)
+		// The text records split a multibyte character.
+		assert.Equal(t, 230, strings.Count(part0, "Quelle curiosité ! "))
+		assert.Contains(t, part0, `<a href="part0001.xhtml#ch2">Next chapter</a></p></body></html>`)
+
+		part1 := readString(t, p, "/text/part0001.xhtml")
+		assert.Contains(t, part1, `<body aid="7"><h2 id="ch2" aid="4">The Pool of Tears</h2>`)
+		assert.Contains(t, part1, `<img src="../images/00002.gif" alt=""/>`)
+		assert.Contains(t, part1, `<p aid="6" id="aid-6">Back to the <a href="part0000.xhtml#ch1">start</a>.</p>`)
+	})
+}
+
+func TestKF8TableOfContents(t *testing.T) {
+	withMOBI(t, "./testdata/alice.azw3", func(p *pub.Publication) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/part0000.xhtml#ch1", Title: "Down the Rabbit-Hole"},
+			{Href: "/text/part0001.xhtml#ch2", Title: "The Pool of Tears", Children: manifest.LinkList{
+				{Href: "/text/part0001.xhtml#aid-6", Title: "Back to the start"},
+			}},
+		}, p.Manifest.TableOfContents)
+	})
+}
+
+func TestMOBI6(t *testing.T) {
+	withMOBI(t, "./testdata/petit-cafe.mobi", func(p *pub.Publication) {
+		m := p.Manifest.Metadata
+		assert.Equal(t, "Le Petit Café", m.Title())
+		assert.Equal(t, manifest.Strings{"fr"}, m.Languages)
+		if assert.Len(t, m.Authors, 1) {
+			assert.Equal(t, "Jean Dupont", m.Authors[0].Name())
+		}
+
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/part0000.html", Type: "text/html"},
+			{Href: "/text/part0001.html", Type: "text/html"},
+		}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/images/00001.gif", Type: "image/gif", Rels: manifest.Strings{"cover"}},
+		}, p.Manifest.Resources)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/text/part0000.html#filepos100", Title: "Le Petit Café"},
+			{Href: "/text/part0001.html#filepos259", Title: "Chapitre 2"},
+		}, p.Manifest.TableOfContents)
+
+		part0 := readString(t, p, "/text/part0000.html")
+		assert.Contains(t, part0, `<a id="filepos100"></a><h1>Le Petit Café</h1><p>Un café crème, s&#39;il vous plaît.</p>`)
+		assert.Contains(t, part0, `<a href="part0001.html#filepos259">Aller au chapitre 2</a>`)
+		assert.Contains(t, part0, `<img src="../images/00001.gif"/>`)
+		assert.NotContains(t, part0, "guide")
+
+		part1 := readString(t, p, "/text/part0001.html")
+		assert.Contains(t, part1, `<body><a id="filepos259"></a><h2>Chapitre 2</h2>`)
+	})
+}
+
+func TestPalmDOCDecompression(t *testing.T) {
+	// Literal, escaped byte, space + character and back-reference.
+	data := []byte{'a', 'b', 'c', 0x01, 0xE9, 0xE4, 0x80, 0x30}
+	assert.Equal(t, "abc\xe9 dabc", string(decompressPalmDOC(data)))
+}
@@ -0,0 +1,92 @@
+package mobi
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+)
+
+// A Palm Database file, the container of MOBI and KF8 e-books.
+// Its records are read on demand from the underlying resource.
+// Reference: https://wiki.mobileread.com/wiki/PDB
+type pdb struct {
+	Name     string
+	Type     string // Type and creator, e.g. "BOOKMOBI"
+	resource fetcher.Resource
+	offsets  []int64 // Start of each record, followed by the length of the file.
+}
+
+const pdbHeaderLength = 78
+
+func newPDB(resource fetcher.Resource) (*pdb, error) {
+	header, rerr := resource.Read(0, pdbHeaderLength-1)
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed reading Palm Database header")
+	}
+	if len(header) < pdbHeaderLength {
+		return nil, errors.New("invalid Palm Database: truncated header")
+	}
+	p := &pdb{
+		Name:     strings.TrimRight(string(header[:32]), "\x00"),
+		Type:     string(header[60:68]),
+		resource: resource,
+	}
+
+	count := int64(binary.BigEndian.Uint16(header[76:78]))
+	if count == 0 {
+		return nil, errors.New("invalid Palm Database: no records")
+	}
+	list, rerr := resource.Read(pdbHeaderLength, pdbHeaderLength+count*8-1)
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed reading Palm Database record list")
+	}
+	if int64(len(list)) < count*8 {
+		return nil, errors.New("invalid Palm Database: truncated record list")
+	}
+	length, rerr := resource.Length()
+	if rerr != nil {
+		return nil, errors.Wrap(rerr, "failed reading Palm Database length")
+	}
+	p.offsets = make([]int64, count+1)
+	for i := int64(0); i < count; i++ {
+		p.offsets[i] = int64(binary.BigEndian.Uint32(list[i*8:]))
+	}
+	p.offsets[count] = length
+	for i := int64(0); i < count; i++ {
+		if p.offsets[i] > p.offsets[i+1] {
+			return nil, errors.Errorf("invalid Palm Database: record %d has a negative length", i)
+		}
+	}
+	return p, nil
+}
+
+// Number of records in the database.
+func (p *pdb) Len() int {
+	return len(p.offsets) - 1
+}
+
+// Returns the inclusive byte range of the record at [index] in the file.
+func (p *pdb) Range(index int) (start int64, end int64, ok bool) {
+	if index < 0 || index >= p.Len() {
+		return 0, 0, false
+	}
+	return p.offsets[index], p.offsets[index+1] - 1, true
+}
+
+// Reads the record at [index].
+func (p *pdb) Record(index int) ([]byte, error) {
+	start, end, ok := p.Range(index)
+	if !ok {
+		return nil, errors.Errorf("record %d is out of bounds", index)
+	}
+	if end < start {
+		return []byte{}, nil
+	}
+	data, rerr := p.resource.Read(start, end)
+	if rerr != nil {
+		return nil, errors.Wrapf(rerr, "failed reading record %d", index)
+	}
+	return data, nil
+}
@@ -2,8 +2,10 @@ package parser
 
 import (
 	"errors"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/readium/go-toolkit/pkg/asset"
@@ -46,32 +48,187 @@ func (p AudioParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetcher
 		return nil, errors.New("no audio file found in the publication")
 	}
 
-	// Sort in alphabetical order
-	sort.Slice(readingOrder, func(i, j int) bool {
-		return readingOrder[i].Href < readingOrder[j].Href
+	// Reads the tags of each track, to order them and find the publication's metadata.
+	trackTags := make(map[string]*audioTags, len(readingOrder))
+	ordered := true
+	for i, link := range readingOrder {
+		tags := readAudioTags(fetcher, link)
+		if tags == nil {
+			tags = &audioTags{}
+		}
+		trackTags[link.Href] = tags
+		readingOrder[i].Title = tags.Title
+		readingOrder[i].Duration = tags.Duration
+		if tags.Track == 0 {
+			ordered = false
+		}
+	}
+
+	// Sort by disc and track numbers when all the tracks have one, otherwise in natural order
+	sort.SliceStable(readingOrder, func(i, j int) bool {
+		ti, tj := trackTags[readingOrder[i].Href], trackTags[readingOrder[j].Href]
+		if ordered && ti.Disc != tj.Disc {
+			return ti.Disc < tj.Disc
+		}
+		if ordered && ti.Track != tj.Track {
+			return ti.Track < tj.Track
+		}
+		return naturalHrefLess(readingOrder[i].Href, readingOrder[j].Href)
 	})
 
+	// A playlist or a cue sheet shipped with the audiobook takes precedence over the tags.
+	playlist := readAudioPlaylist(fetcher, links)
+	if playlist != nil {
+		readingOrder = applyAudioPlaylist(readingOrder, playlist, trackTags)
+	}
+
+	// The publication's tags are the first ones found in the tracks.
+	tags := &audioTags{}
+	var totalDuration float64
+	var coverTrack *manifest.Link
+	for i, link := range readingOrder {
+		t := trackTags[link.Href]
+		if tags.Picture == nil && t.Picture != nil {
+			coverTrack = &readingOrder[i]
+		}
+		tags.merge(t)
+		if totalDuration >= 0 && link.Duration > 0 {
+			totalDuration += link.Duration
+		} else {
+			totalDuration = -1 // Unknown when any track has no duration.
+		}
+	}
+
+	if playlist != nil {
+		tags.merge(&audioTags{Album: playlist.Title, Artist: playlist.Performer})
+	}
+
 	// Try to figure out the publication's title
-	title := guessPublicationTitleFromFileStructure(fetcher)
+	title := tags.Album
+	if title == "" && len(readingOrder) == 1 {
+		title = tags.Title
+	}
+	if title == "" {
+		title = guessPublicationTitleFromFileStructure(fetcher)
+	}
 	if title == "" {
 		title = asset.Name()
 	}
 
+	metadata := manifest.Metadata{
+		LocalizedTitle: manifest.NewLocalizedStringFromString(title),
+		ConformsTo:     manifest.Profiles{manifest.ProfileAudiobook},
+		Published:      extensions.ParseDate(tags.Date),
+	}
+	contributor := func(name string) manifest.Contributors {
+		if name == "" {
+			return nil
+		}
+		return manifest.Contributors{{LocalizedName: manifest.NewLocalizedStringFromString(name)}}
+	}
+	metadata.Authors = contributor(tags.author())
+	metadata.Narrators = contributor(tags.narrator())
+	metadata.Publishers = contributor(tags.Publisher)
+	if tags.Language != "" {
+		metadata.Languages = manifest.Strings{tags.Language}
+	}
+	if tags.Genre != "" {
+		metadata.Subjects = []manifest.Subject{{LocalizedName: manifest.NewLocalizedStringFromString(tags.Genre)}}
+	}
+	if totalDuration > 0 {
+		metadata.Duration = &totalDuration
+	}
+
+	// The cover is the artwork embedded in the tracks, or else the first bitmap of the publication.
+	var resources manifest.LinkList
+	services := map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: pub.AudioPositionsServiceFactory(pub.DefaultAudioPositionsInterval, "audio/*"),
+		pub.LocatorService_Name:   pub.AudioLocatorServiceFactory(),
+	}
+	if coverTrack != nil {
+		track := *coverTrack
+		cover := manifest.Link{
+			Href: "/~readium/cover." + audioPictureExtension(tags.Picture.MediaType),
+			Type: tags.Picture.MediaType,
+			Rels: manifest.Strings{"cover"},
+		}
+		resources = append(resources, cover)
+		services[pub.CoverService_Name] = pub.EmbeddedCoverServiceFactory(cover, func() []byte {
+			if t := readAudioTags(fetcher, track); t != nil && t.Picture != nil {
+				return t.Picture.Data
+			}
+			return nil
+		})
+	} else {
+		for _, link := range links {
+			if !extensions.IsHiddenOrThumbs(link.Href) && link.MediaType().IsBitmap() {
+				link.Rels = manifest.Strings{"cover"}
+				resources = append(resources, link)
+				break
+			}
+		}
+	}
+
+	// The table of contents lists the chapters embedded in the tracks, or the tracks themselves, nested in the folders
+	// of the publication.
+	toc := folderTableOfContents(readingOrder, func(i int) manifest.LinkList {
+		link := readingOrder[i]
+		if chapters := trackTags[link.Href].Chapters; len(chapters) > 0 {
+			return audioChapterLinks(link.Href, chapters)
+		} else if len(readingOrder) > 1 {
+			title := link.Title
+			if title == "" {
+				title = strings.TrimSuffix(path.Base(link.Href), path.Ext(link.Href))
+			}
+			return manifest.LinkList{{Href: link.Href, Title: title}}
+		}
+		return nil
+	})
+
 	manifest := manifest.Manifest{
-		Context: manifest.Strings{manifest.WebpubManifestContext},
-		Metadata: manifest.Metadata{
-			LocalizedTitle: manifest.NewLocalizedStringFromString(title),
-			ConformsTo:     manifest.Profiles{manifest.ProfileAudiobook},
-		},
-		ReadingOrder: readingOrder,
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       resources,
+		TableOfContents: toc,
 	}
 
-	return pub.NewBuilder(manifest, fetcher, nil), nil // TODO services!
+	return pub.NewBuilder(manifest, fetcher, pub.NewServicesBuilder(services)), nil
+}
+
+// Converts the chapters of the track at [href] to links using a media fragment for their start time.
+func audioChapterLinks(href string, chapters []audioChapter) manifest.LinkList {
+	if len(chapters) == 0 {
+		return nil
+	}
+	links := make(manifest.LinkList, 0, len(chapters))
+	for _, chapter := range chapters {
+		links = append(links, manifest.Link{
+			Href:     href + "#t=" + strconv.FormatFloat(chapter.Start, 'f', -1, 64),
+			Title:    chapter.Title,
+			Children: audioChapterLinks(href, chapter.Children),
+		})
+	}
+	return links
+}
+
+// File extension for the media type of an embedded picture.
+func audioPictureExtension(mediaType string) string {
+	switch mediaType {
+	case "image/png":
+		return "png"
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return "webp"
+	default:
+		return "jpg"
+	}
 }
 
 var allowed_extensions_audio_extra = map[string]struct{}{
 	"asx": {}, "bio": {}, "m3u": {}, "m3u8": {}, "pla": {}, "pls": {},
-	"smil": {}, "txt": {}, "vlc": {}, "wpl": {}, "xspf": {}, "zpl": {},
+	"cue": {}, "smil": {}, "txt": {}, "vlc": {}, "wpl": {}, "xspf": {}, "zpl": {},
 }
 var allowed_extensions_audio = map[string]struct{}{
 	"aac": {}, "aiff": {}, "alac": {}, "flac": {}, "m4a": {}, "m4b": {}, "mp3": {},
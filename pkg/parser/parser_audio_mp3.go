@@ -0,0 +1,617 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Reads the ID3 tags of an MP3 file, and computes its duration from the MPEG audio frames.
+// References:
+//  - https://id3.org/id3v2.4.0-structure
+//  - https://id3.org/id3v2.3.0
+//  - https://www.codeproject.com/Articles/8295/MPEG-Audio-Frame-Header
+func readMP3Tags(r *io.SectionReader) (*audioTags, error) {
+	tags := &audioTags{}
+	audioStart := int64(0)
+	audioEnd := r.Size()
+
+	if id3, size, err := readID3v2(r, 0); err != nil {
+		return nil, err
+	} else if id3 != nil {
+		tags = id3
+		audioStart = size
+	}
+
+	if v1 := readID3v1(r); v1 != nil {
+		tags.merge(v1)
+		audioEnd -= 128
+	}
+
+	if duration := mpegAudioDuration(r, audioStart, audioEnd); duration > 0 {
+		tags.Duration = duration
+	}
+	return tags, nil
+}
+
+// Reads the ID3v2 tag at [offset], and returns its total size. Returns nil if there is no tag.
+func readID3v2(r *io.SectionReader, offset int64) (*audioTags, int64, error) {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, offset); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		return nil, 0, nil
+	}
+	version := header[3]
+	flags := header[5]
+	size := int64(syncsafeInt(header[6:10]))
+	if size > r.Size()-offset-10 {
+		return nil, 0, errors.New("ID3 tag is larger than the file")
+	}
+	total := 10 + size
+	if flags&0x10 != 0 { // Footer present
+		total += 10
+	}
+	if version < 2 || version > 4 {
+		return nil, total, nil
+	}
+
+	body := make([]byte, size)
+	if _, err := r.ReadAt(body, offset+10); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	if flags&0x80 != 0 && version < 4 {
+		// In ID3v2.4, unsynchronisation is applied per frame.
+		body = id3Unsynchronise(body)
+	}
+	if flags&0x40 != 0 && version > 2 && len(body) >= 4 { // Extended header
+		var extSize int
+		if version == 4 {
+			extSize = syncsafeInt(body[0:4])
+		} else {
+			extSize = int(binary.BigEndian.Uint32(body[0:4])) + 4
+		}
+		if extSize > len(body) {
+			return nil, total, nil
+		}
+		body = body[extSize:]
+	}
+
+	tags := &audioTags{}
+	var lengthMs float64
+	var pictureType byte = 0xFF
+	chapters := make(map[string]id3Chapter)
+	var tocs []id3TableOfContents
+	for _, frame := range parseID3Frames(body, version) {
+		data := frame.data
+		switch frame.id {
+		case "TIT2", "TT2":
+			tags.Title = id3Text(data)
+		case "TALB", "TAL":
+			tags.Album = id3Text(data)
+		case "TPE1", "TP1":
+			tags.Artist = id3Text(data)
+		case "TPE2", "TP2":
+			tags.AlbumArtist = id3Text(data)
+		case "TCOM", "TCM":
+			tags.Composer = id3Text(data)
+		case "TPUB", "TPB":
+			tags.Publisher = id3Text(data)
+		case "TLAN", "TLA":
+			tags.Language = id3Text(data)
+		case "TCON", "TCO":
+			tags.Genre = id3Genre(id3Text(data))
+		case "TDRC", "TYER", "TYE":
+			if tags.Date == "" {
+				tags.Date = id3Text(data)
+			}
+		case "TRCK", "TRK":
+			tags.Track = parseAudioTrackNumber(id3Text(data))
+		case "TPOS", "TPA":
+			tags.Disc = parseAudioTrackNumber(id3Text(data))
+		case "TLEN", "TLE":
+			if ms, err := strconv.ParseFloat(id3Text(data), 64); err == nil && ms > 0 {
+				lengthMs = ms
+			}
+		case "TXXX", "TXX":
+			if description, value := id3UserText(data); isNarratorTag(description) {
+				tags.Narrator = value
+			}
+		case "APIC", "PIC":
+			if picture, typ := id3Picture(data, version); picture != nil {
+				// Prefers the front cover (type 3) over other pictures.
+				if tags.Picture == nil || (typ == 3 && pictureType != 3) {
+					tags.Picture = picture
+					pictureType = typ
+				}
+			}
+		case "CHAP":
+			if chapter, ok := parseID3Chapter(data, version); ok {
+				chapters[chapter.id] = chapter
+			}
+		case "CTOC":
+			if toc, ok := parseID3TableOfContents(data, version); ok {
+				tocs = append(tocs, toc)
+			}
+		}
+	}
+	tags.Duration = lengthMs / 1000
+	tags.Chapters = id3ChapterTree(chapters, tocs)
+	return tags, total, nil
+}
+
+type id3Frame struct {
+	id   string
+	data []byte
+}
+
+// Splits the frames of an ID3v2 tag [body], or of the sub-frames embedded in a chapter frame.
+func parseID3Frames(body []byte, version byte) []id3Frame {
+	var frames []id3Frame
+	idSize, headerSize := 4, 10
+	if version == 2 {
+		idSize, headerSize = 3, 6
+	}
+	for len(body) >= headerSize && body[0] != 0 {
+		id := string(body[:idSize])
+		var frameSize int
+		var frameFlags uint16
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		case 4:
+			frameSize = syncsafeInt(body[4:8])
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		}
+		if frameSize <= 0 || headerSize+frameSize > len(body) {
+			break
+		}
+		data := body[headerSize : headerSize+frameSize]
+		body = body[headerSize+frameSize:]
+
+		// Skips compressed and encrypted frames.
+		if (version == 3 && frameFlags&0x00C0 != 0) || (version == 4 && frameFlags&0x000C != 0) {
+			continue
+		}
+		if version == 3 && frameFlags&0x0020 != 0 && len(data) > 0 { // Grouping identity
+			data = data[1:]
+		}
+		if version == 4 {
+			if frameFlags&0x0040 != 0 && len(data) > 0 { // Grouping identity
+				data = data[1:]
+			}
+			if frameFlags&0x0001 != 0 && len(data) >= 4 { // Data length indicator
+				data = data[4:]
+			}
+			if frameFlags&0x0002 != 0 {
+				data = id3Unsynchronise(data)
+			}
+		}
+		frames = append(frames, id3Frame{id: id, data: data})
+	}
+	return frames
+}
+
+// Chapter frame (CHAP) of the ID3v2 Chapter addendum.
+// Reference: https://id3.org/id3v2-chapters-1.0
+type id3Chapter struct {
+	id    string
+	start float64 // In seconds.
+	title string
+}
+
+// Table of contents frame (CTOC), listing chapters or other tables of contents.
+type id3TableOfContents struct {
+	id       string
+	topLevel bool
+	children []string
+	title    string
+}
+
+func parseID3Chapter(data []byte, version byte) (id3Chapter, bool) {
+	id, data := id3SplitString(0, data)
+	if len(data) < 16 {
+		return id3Chapter{}, false
+	}
+	chapter := id3Chapter{
+		id:    string(id),
+		start: float64(binary.BigEndian.Uint32(data[0:4])) / 1000,
+	}
+	chapter.title = id3EmbeddedTitle(data[16:], version)
+	return chapter, true
+}
+
+func parseID3TableOfContents(data []byte, version byte) (id3TableOfContents, bool) {
+	id, data := id3SplitString(0, data)
+	if len(data) < 2 {
+		return id3TableOfContents{}, false
+	}
+	toc := id3TableOfContents{
+		id:       string(id),
+		topLevel: data[0]&0x02 != 0,
+	}
+	count := int(data[1])
+	data = data[2:]
+	for i := 0; i < count && len(data) > 0; i++ {
+		var child []byte
+		child, data = id3SplitString(0, data)
+		toc.children = append(toc.children, string(child))
+	}
+	toc.title = id3EmbeddedTitle(data, version)
+	return toc, true
+}
+
+// Finds the title in the sub-frames of a chapter or table of contents frame.
+func id3EmbeddedTitle(data []byte, version byte) string {
+	for _, frame := range parseID3Frames(data, version) {
+		if frame.id == "TIT2" {
+			return id3Text(frame.data)
+		}
+	}
+	return ""
+}
+
+// Builds the hierarchy of chapters from the top-level table of contents, or lists all the chapters in
+// chronological order when there is none.
+func id3ChapterTree(chapters map[string]id3Chapter, tocs []id3TableOfContents) []audioChapter {
+	if len(chapters) == 0 {
+		return nil
+	}
+	tocsByID := make(map[string]id3TableOfContents, len(tocs))
+	var root *id3TableOfContents
+	for i, toc := range tocs {
+		tocsByID[toc.id] = toc
+		if toc.topLevel && root == nil {
+			root = &tocs[i]
+		}
+	}
+
+	if root == nil {
+		result := make([]audioChapter, 0, len(chapters))
+		for _, chapter := range chapters {
+			result = append(result, audioChapter{Title: chapter.title, Start: chapter.start})
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Start < result[j].Start
+		})
+		return result
+	}
+
+	visited := make(map[string]bool)
+	var build func(toc id3TableOfContents) []audioChapter
+	build = func(toc id3TableOfContents) []audioChapter {
+		visited[toc.id] = true
+		var result []audioChapter
+		for _, id := range toc.children {
+			if chapter, ok := chapters[id]; ok {
+				result = append(result, audioChapter{Title: chapter.title, Start: chapter.start})
+			} else if child, ok := tocsByID[id]; ok && !visited[id] {
+				// A nested table of contents starts at its first chapter.
+				children := build(child)
+				if len(children) > 0 {
+					result = append(result, audioChapter{
+						Title:    child.title,
+						Start:    children[0].Start,
+						Children: children,
+					})
+				}
+			}
+		}
+		return result
+	}
+	return build(*root)
+}
+
+// Returns whether the description of a custom text tag denotes the narrator.
+func isNarratorTag(description string) bool {
+	switch strings.ToUpper(strings.TrimSpace(description)) {
+	case "NARRATOR", "NARRATED BY", "NARRATEDBY", "READER", "READ BY":
+		return true
+	}
+	return false
+}
+
+// Reads the ID3v1 tag at the end of the file, if any.
+// Reference: https://id3.org/ID3v1
+func readID3v1(r *io.SectionReader) *audioTags {
+	if r.Size() < 128 {
+		return nil
+	}
+	tag := make([]byte, 128)
+	if _, err := r.ReadAt(tag, r.Size()-128); err != nil || !bytes.HasPrefix(tag, []byte("TAG")) {
+		return nil
+	}
+	field := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		s, _ := charmap.ISO8859_1.NewDecoder().Bytes(b)
+		return strings.TrimSpace(string(s))
+	}
+	tags := &audioTags{
+		Title:  field(tag[3:33]),
+		Artist: field(tag[33:63]),
+		Album:  field(tag[63:93]),
+		Date:   field(tag[93:97]),
+	}
+	if tag[125] == 0 && tag[126] != 0 { // ID3v1.1 track number
+		tags.Track = int(tag[126])
+	}
+	return tags
+}
+
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// Reverts the unsynchronisation scheme, which inserts a null byte after each 0xFF.
+func id3Unsynchronise(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// Decodes a string using the ID3 text encoding [enc].
+func id3DecodeString(enc byte, data []byte) string {
+	var s string
+	switch enc {
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		bigEndian := enc == 2
+		if len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				bigEndian = false
+				data = data[2:]
+			} else if data[0] == 0xFE && data[1] == 0xFF {
+				bigEndian = true
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(data[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(data[i:]))
+			}
+		}
+		s = string(utf16.Decode(units))
+	case 3: // UTF-8
+		s = string(data)
+	default: // ISO-8859-1
+		decoded, _ := charmap.ISO8859_1.NewDecoder().Bytes(data)
+		s = string(decoded)
+	}
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+// Splits [data] at the first null terminator for the text encoding [enc].
+func id3SplitString(enc byte, data []byte) ([]byte, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+// Reads the content of a text information frame. Only the first value of multiple-values frames is kept.
+func id3Text(data []byte) string {
+	if len(data) < 1 {
+		return ""
+	}
+	value, _ := id3SplitString(data[0], data[1:])
+	return id3DecodeString(data[0], value)
+}
+
+// Reads the description and the value of a user-defined text frame (TXXX).
+func id3UserText(data []byte) (string, string) {
+	if len(data) < 1 {
+		return "", ""
+	}
+	description, value := id3SplitString(data[0], data[1:])
+	value, _ = id3SplitString(data[0], value)
+	return id3DecodeString(data[0], description), id3DecodeString(data[0], value)
+}
+
+// Resolves the genre references of ID3v2.3, e.g. "(101)Speech" or "(101)", keeping only the free text.
+func id3Genre(genre string) string {
+	for strings.HasPrefix(genre, "(") && !strings.HasPrefix(genre, "((") {
+		end := strings.IndexByte(genre, ')')
+		if end < 0 {
+			break
+		}
+		genre = genre[end+1:]
+	}
+	genre = strings.TrimPrefix(genre, "(")
+	if _, err := strconv.Atoi(genre); err == nil {
+		// Numeric reference to an ID3v1 genre, without text.
+		return ""
+	}
+	return strings.TrimSpace(genre)
+}
+
+// Reads an attached picture frame, and returns its picture type.
+func id3Picture(data []byte, version byte) (*audioPicture, byte) {
+	if len(data) < 2 {
+		return nil, 0
+	}
+	enc := data[0]
+	data = data[1:]
+	var mediaType string
+	if version == 2 {
+		if len(data) < 3 {
+			return nil, 0
+		}
+		switch strings.ToUpper(string(data[:3])) {
+		case "PNG":
+			mediaType = "image/png"
+		default:
+			mediaType = "image/jpeg"
+		}
+		data = data[3:]
+	} else {
+		var mime []byte
+		mime, data = id3SplitString(0, data)
+		mediaType = strings.ToLower(strings.TrimSpace(string(mime)))
+		if !strings.Contains(mediaType, "/") { // Some taggers write only the format, e.g. "jpg"
+			mediaType = "image/" + mediaType
+		}
+		if mediaType == "image/jpg" || mediaType == "image/" {
+			mediaType = "image/jpeg"
+		}
+	}
+	if len(data) < 1 {
+		return nil, 0
+	}
+	typ := data[0]
+	_, data = id3SplitString(enc, data[1:])
+	if len(data) == 0 {
+		return nil, 0
+	}
+	return &audioPicture{MediaType: mediaType, Data: data}, typ
+}
+
+// Information from an MPEG audio frame header.
+type mpegAudioFrame struct {
+	version    int // 1, 2, or 25 for MPEG 2.5
+	layer      int
+	bitrate    int // In kbps.
+	sampleRate int
+	padding    int
+	mono       bool
+}
+
+var mpegBitrates = map[[2]int][]int{
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+var mpegSampleRates = map[int][]int{
+	1:  {44100, 48000, 32000},
+	2:  {22050, 24000, 16000},
+	25: {11025, 12000, 8000},
+}
+
+func isMPEGAudioFrameHeader(b []byte) bool {
+	_, ok := parseMPEGAudioFrameHeader(b)
+	return ok
+}
+
+func parseMPEGAudioFrameHeader(b []byte) (mpegAudioFrame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mpegAudioFrame{}, false
+	}
+	var frame mpegAudioFrame
+	switch (b[1] >> 3) & 0x03 {
+	case 0:
+		frame.version = 25
+	case 2:
+		frame.version = 2
+	case 3:
+		frame.version = 1
+	default:
+		return frame, false
+	}
+	frame.layer = 4 - int((b[1]>>1)&0x03)
+	if frame.layer == 4 {
+		return frame, false
+	}
+	bitrateIndex := int(b[2] >> 4)
+	sampleRateIndex := int((b[2] >> 2) & 0x03)
+	if bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return frame, false
+	}
+	bitrateVersion := frame.version
+	if bitrateVersion == 25 {
+		bitrateVersion = 2
+	}
+	frame.bitrate = mpegBitrates[[2]int{bitrateVersion, frame.layer}][bitrateIndex]
+	frame.sampleRate = mpegSampleRates[frame.version][sampleRateIndex]
+	frame.padding = int((b[2] >> 1) & 0x01)
+	frame.mono = (b[3] >> 6) == 0x03
+	return frame, true
+}
+
+func (f mpegAudioFrame) samples() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && f.version != 1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+// Offset of the Xing/Info header in a Layer III frame, after the side information.
+func (f mpegAudioFrame) xingOffset() int {
+	if f.version == 1 {
+		if f.mono {
+			return 4 + 17
+		}
+		return 4 + 32
+	}
+	if f.mono {
+		return 4 + 9
+	}
+	return 4 + 17
+}
+
+// Computes the duration of the MPEG audio stream between [start] and [end], using the VBR headers of the first
+// frame when available, or assuming a constant bitrate otherwise.
+func mpegAudioDuration(r io.ReaderAt, start int64, end int64) float64 {
+	// Looks for the first frame, in case of padding or garbage after the ID3 tag.
+	const maxScan = 64 * 1024
+	buf := make([]byte, maxScan)
+	n, _ := r.ReadAt(buf, start)
+	buf = buf[:n]
+	var frame mpegAudioFrame
+	offset := -1
+	for i := 0; i+4 <= len(buf); i++ {
+		if f, ok := parseMPEGAudioFrameHeader(buf[i:]); ok {
+			frame, offset = f, i
+			break
+		}
+	}
+	if offset < 0 || frame.sampleRate == 0 {
+		return 0
+	}
+	header := buf[offset:]
+
+	frames := 0
+	if frame.layer == 3 {
+		if x := frame.xingOffset(); len(header) >= x+12 {
+			if tag := string(header[x : x+4]); (tag == "Xing" || tag == "Info") && header[x+7]&0x01 != 0 {
+				frames = int(binary.BigEndian.Uint32(header[x+8 : x+12]))
+			}
+		}
+		if v := 4 + 32; frames == 0 && len(header) >= v+18 && string(header[v:v+4]) == "VBRI" {
+			frames = int(binary.BigEndian.Uint32(header[v+14 : v+18]))
+		}
+	}
+	if frames > 0 {
+		return float64(frames) * float64(frame.samples()) / float64(frame.sampleRate)
+	}
+
+	if frame.bitrate == 0 {
+		return 0
+	}
+	audioLength := end - start - int64(offset)
+	return float64(audioLength) * 8 / float64(frame.bitrate*1000)
+}
@@ -0,0 +1,403 @@
+package parser
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Maximum size of the moov atom loaded in memory, which holds the metadata of an MP4 file.
+const mp4MaxMoovSize = 64 * 1024 * 1024
+
+// Reads the iTunes-style metadata atoms of an MP4 file (M4A, M4B), and its duration from the movie header.
+// References:
+//  - https://developer.apple.com/documentation/quicktime-file-format
+//  - https://atomicparsley.sourceforge.net/mpeg-4files.html
+func readMP4Tags(r *io.SectionReader) (*audioTags, error) {
+	moov, err := readMP4TopLevelAtom(r, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := &audioTags{}
+	var traks [][]byte
+	var neroChapters []audioChapter
+	for _, atom := range mp4Atoms(moov) {
+		switch atom.typ {
+		case "mvhd":
+			tags.Duration = parseMP4MovieHeaderDuration(atom.data)
+		case "trak":
+			traks = append(traks, atom.data)
+		case "udta":
+			if meta := mp4Atom(atom.data, "meta"); meta != nil && len(meta) > 4 {
+				// meta is a full atom, with a version and flags.
+				if ilst := mp4Atom(meta[4:], "ilst"); ilst != nil {
+					parseMP4ItemList(ilst, tags)
+				}
+			}
+			if chpl := mp4Atom(atom.data, "chpl"); chpl != nil {
+				neroChapters = parseMP4NeroChapters(chpl)
+			}
+		}
+	}
+
+	// QuickTime chapters are preferred over Nero chapters, as they are the ones displayed by Apple players.
+	tags.Chapters = readMP4ChapterTrack(r, traks)
+	if len(tags.Chapters) == 0 {
+		tags.Chapters = neroChapters
+	}
+	return tags, nil
+}
+
+// Parses the Nero chapter list (chpl), whose start times are in 100 nanoseconds units.
+func parseMP4NeroChapters(chpl []byte) []audioChapter {
+	if len(chpl) < 5 {
+		return nil
+	}
+	data := chpl[4:]
+	if chpl[0] != 0 {
+		if len(data) < 4 {
+			return nil
+		}
+		data = data[4:]
+	}
+	if len(data) < 1 {
+		return nil
+	}
+	count := int(data[0])
+	data = data[1:]
+	chapters := make([]audioChapter, 0, count)
+	for i := 0; i < count && len(data) >= 9; i++ {
+		start := binary.BigEndian.Uint64(data[0:8])
+		length := int(data[8])
+		if len(data) < 9+length {
+			break
+		}
+		chapters = append(chapters, audioChapter{
+			Title: strings.TrimSpace(string(data[9 : 9+length])),
+			Start: float64(start) / 10000000,
+		})
+		data = data[9+length:]
+	}
+	return chapters
+}
+
+// Maximum number of chapters read from a QuickTime chapter track.
+const mp4MaxChapters = 10000
+
+// Reads the chapters from the text track referenced by a chap track reference, as written by iTunes.
+func readMP4ChapterTrack(r *io.SectionReader, traks [][]byte) []audioChapter {
+	var chapterTrackID uint32
+	for _, trak := range traks {
+		if chap := mp4Atom(mp4Atom(trak, "tref"), "chap"); len(chap) >= 4 {
+			chapterTrackID = binary.BigEndian.Uint32(chap[0:4])
+			break
+		}
+	}
+	if chapterTrackID == 0 {
+		return nil
+	}
+
+	var trak []byte
+	for _, t := range traks {
+		if tkhd := mp4Atom(t, "tkhd"); len(tkhd) >= 24 {
+			offset := 12
+			if tkhd[0] == 1 {
+				offset = 20
+			}
+			if binary.BigEndian.Uint32(tkhd[offset:offset+4]) == chapterTrackID {
+				trak = t
+				break
+			}
+		}
+	}
+	mdia := mp4Atom(trak, "mdia")
+	mdhd := mp4Atom(mdia, "mdhd")
+	stbl := mp4Atom(mp4Atom(mdia, "minf"), "stbl")
+	if len(mdhd) < 24 || stbl == nil {
+		return nil
+	}
+	timescaleOffset := 12
+	if mdhd[0] == 1 {
+		timescaleOffset = 20
+	}
+	if len(mdhd) < timescaleOffset+4 {
+		return nil
+	}
+	timescale := binary.BigEndian.Uint32(mdhd[timescaleOffset : timescaleOffset+4])
+	if timescale == 0 {
+		return nil
+	}
+
+	// Start time of each sample, from the time-to-sample table.
+	var starts []uint64
+	if stts := mp4Atom(stbl, "stts"); len(stts) >= 8 {
+		entries := int(binary.BigEndian.Uint32(stts[4:8]))
+		time := uint64(0)
+		for i := 0; i < entries && 16+i*8 <= len(stts) && len(starts) < mp4MaxChapters; i++ {
+			count := int(binary.BigEndian.Uint32(stts[8+i*8:]))
+			delta := uint64(binary.BigEndian.Uint32(stts[12+i*8:]))
+			for j := 0; j < count && len(starts) < mp4MaxChapters; j++ {
+				starts = append(starts, time)
+				time += delta
+			}
+		}
+	}
+
+	offsets, sizes := mp4SampleLocations(stbl, len(starts))
+	chapters := make([]audioChapter, 0, len(offsets))
+	for i := range offsets {
+		if i >= len(starts) || sizes[i] < 2 || sizes[i] > 64*1024 {
+			continue
+		}
+		sample := make([]byte, sizes[i])
+		if _, err := r.ReadAt(sample, int64(offsets[i])); err != nil && err != io.EOF {
+			continue
+		}
+		// A text sample starts with the length of the text.
+		length := int(binary.BigEndian.Uint16(sample[0:2]))
+		if 2+length > len(sample) {
+			length = len(sample) - 2
+		}
+		chapters = append(chapters, audioChapter{
+			Title: mp4DecodeText(sample[2 : 2+length]),
+			Start: float64(starts[i]) / float64(timescale),
+		})
+	}
+	return chapters
+}
+
+// Computes the file offset and size of the first [count] samples of a track, from its sample table.
+func mp4SampleLocations(stbl []byte, count int) ([]uint64, []uint32) {
+	// Sample sizes
+	var sizes []uint32
+	if stsz := mp4Atom(stbl, "stsz"); len(stsz) >= 12 {
+		uniform := binary.BigEndian.Uint32(stsz[4:8])
+		n := int(binary.BigEndian.Uint32(stsz[8:12]))
+		for i := 0; i < n && i < count; i++ {
+			if uniform != 0 {
+				sizes = append(sizes, uniform)
+			} else if 16+i*4 <= len(stsz) {
+				sizes = append(sizes, binary.BigEndian.Uint32(stsz[12+i*4:]))
+			}
+		}
+	}
+
+	// Chunk offsets
+	var chunks []uint64
+	if stco := mp4Atom(stbl, "stco"); len(stco) >= 8 {
+		n := int(binary.BigEndian.Uint32(stco[4:8]))
+		for i := 0; i < n && 12+i*4 <= len(stco); i++ {
+			chunks = append(chunks, uint64(binary.BigEndian.Uint32(stco[8+i*4:])))
+		}
+	} else if co64 := mp4Atom(stbl, "co64"); len(co64) >= 8 {
+		n := int(binary.BigEndian.Uint32(co64[4:8]))
+		for i := 0; i < n && 16+i*8 <= len(co64); i++ {
+			chunks = append(chunks, binary.BigEndian.Uint64(co64[8+i*8:]))
+		}
+	}
+
+	// Sample-to-chunk table: each entry gives the number of samples per chunk, from a first chunk (1-based).
+	type stscEntry struct{ firstChunk, samplesPerChunk int }
+	var stscEntries []stscEntry
+	if stsc := mp4Atom(stbl, "stsc"); len(stsc) >= 8 {
+		n := int(binary.BigEndian.Uint32(stsc[4:8]))
+		for i := 0; i < n && 20+i*12 <= len(stsc); i++ {
+			stscEntries = append(stscEntries, stscEntry{
+				firstChunk:      int(binary.BigEndian.Uint32(stsc[8+i*12:])),
+				samplesPerChunk: int(binary.BigEndian.Uint32(stsc[12+i*12:])),
+			})
+		}
+	}
+
+	offsets := make([]uint64, 0, len(sizes))
+	sample := 0
+	entry := 0
+	for chunk := 1; chunk <= len(chunks) && sample < len(sizes); chunk++ {
+		for entry+1 < len(stscEntries) && stscEntries[entry+1].firstChunk <= chunk {
+			entry++
+		}
+		samplesPerChunk := 1
+		if entry < len(stscEntries) {
+			samplesPerChunk = stscEntries[entry].samplesPerChunk
+		}
+		offset := chunks[chunk-1]
+		for j := 0; j < samplesPerChunk && sample < len(sizes); j++ {
+			offsets = append(offsets, offset)
+			offset += uint64(sizes[sample])
+			sample++
+		}
+	}
+	return offsets, sizes[:len(offsets)]
+}
+
+// Decodes a QuickTime text sample, which is either UTF-8 or UTF-16 with a byte order mark.
+func mp4DecodeText(data []byte) string {
+	if len(data) >= 2 && ((data[0] == 0xFE && data[1] == 0xFF) || (data[0] == 0xFF && data[1] == 0xFE)) {
+		return id3DecodeString(1, data)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Reads the content of the first top-level atom of the given type.
+func readMP4TopLevelAtom(r *io.SectionReader, typ string) ([]byte, error) {
+	offset := int64(0)
+	header := make([]byte, 16)
+	for offset+8 <= r.Size() {
+		n, err := r.ReadAt(header, offset)
+		if n < 8 {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0: // Extends to the end of the file.
+			size = r.Size() - offset
+		case 1: // 64-bit size
+			if n < 16 {
+				return nil, errors.New("invalid MP4 atom size")
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return nil, errors.New("invalid MP4 atom size")
+		}
+		if string(header[4:8]) == typ {
+			if size-headerSize > mp4MaxMoovSize {
+				return nil, errors.New("MP4 atom is too large")
+			}
+			data := make([]byte, size-headerSize)
+			if _, err := r.ReadAt(data, offset+headerSize); err != nil && err != io.EOF {
+				return nil, err
+			}
+			return data, nil
+		}
+		offset += size
+	}
+	return nil, errors.New("no " + typ + " atom found")
+}
+
+type mp4AtomData struct {
+	typ  string
+	data []byte
+}
+
+// Splits the content of a container atom into its children atoms.
+func mp4Atoms(data []byte) []mp4AtomData {
+	var atoms []mp4AtomData
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		headerSize := uint64(8)
+		if size == 1 && len(data) >= 16 {
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			break
+		}
+		atoms = append(atoms, mp4AtomData{
+			typ:  string(data[4:8]),
+			data: data[headerSize:size],
+		})
+		data = data[size:]
+	}
+	return atoms
+}
+
+// Returns the content of the first child atom of the given type.
+func mp4Atom(data []byte, typ string) []byte {
+	for _, atom := range mp4Atoms(data) {
+		if atom.typ == typ {
+			return atom.data
+		}
+	}
+	return nil
+}
+
+func parseMP4MovieHeaderDuration(mvhd []byte) float64 {
+	if len(mvhd) < 20 {
+		return 0
+	}
+	var timescale uint32
+	var duration uint64
+	if mvhd[0] == 1 { // Version 1 has 64-bit dates and duration.
+		if len(mvhd) < 32 {
+			return 0
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[20:24])
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(mvhd[12:16])
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+// Parses the metadata items of an ilst atom into [tags].
+func parseMP4ItemList(ilst []byte, tags *audioTags) {
+	for _, item := range mp4Atoms(ilst) {
+		name := item.typ
+		if name == "----" {
+			// Freeform item, identified by a mean (e.g. com.apple.iTunes) and a name.
+			if n := mp4Atom(item.data, "name"); len(n) > 4 {
+				name = strings.ToUpper(string(n[4:]))
+			}
+		}
+		data := mp4Atom(item.data, "data")
+		if len(data) < 8 {
+			continue
+		}
+		dataType := binary.BigEndian.Uint32(data[0:4]) & 0x00FFFFFF
+		value := data[8:]
+		text := strings.TrimSpace(string(value))
+
+		switch name {
+		case "\xa9nam":
+			tags.Title = text
+		case "\xa9alb":
+			tags.Album = text
+		case "\xa9ART":
+			tags.Artist = text
+		case "aART":
+			tags.AlbumArtist = text
+		case "\xa9wrt":
+			tags.Composer = text
+		case "\xa9nrt", "NARRATOR":
+			tags.Narrator = text
+		case "\xa9pub", "PUBLISHER":
+			tags.Publisher = text
+		case "LANGUAGE":
+			tags.Language = text
+		case "\xa9gen":
+			tags.Genre = text
+		case "\xa9day":
+			tags.Date = text
+		case "trkn", "disk":
+			// Binary: 2 reserved bytes, the number, and the total.
+			if len(value) >= 4 {
+				n := int(binary.BigEndian.Uint16(value[2:4]))
+				if name == "trkn" {
+					tags.Track = n
+				} else {
+					tags.Disc = n
+				}
+			}
+		case "covr":
+			if tags.Picture != nil || len(value) == 0 {
+				continue
+			}
+			mediaType := "image/jpeg"
+			if dataType == 14 {
+				mediaType = "image/png"
+			}
+			tags.Picture = &audioPicture{MediaType: mediaType, Data: value}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"bufio"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+)
+
+// Track listed in a playlist or a cue sheet.
+type audioPlaylistEntry struct {
+	Href     string // Absolute HREF of the audio file in the publication.
+	Title    string
+	Duration float64        // In seconds, 0 if unknown.
+	Chapters []audioChapter // Tracks of a cue sheet located in the file.
+}
+
+// Track order and titles declared by an M3U playlist or a cue sheet shipped with an audiobook.
+type audioPlaylist struct {
+	Title     string
+	Performer string
+	Entries   []audioPlaylistEntry
+}
+
+// Reads the first M3U playlist or cue sheet of the publication.
+// Playlists take precedence over cue sheets, as they usually list all the tracks of the audiobook.
+func readAudioPlaylist(f fetcher.Fetcher, links manifest.LinkList) *audioPlaylist {
+	var cue *audioPlaylist
+	for _, link := range links {
+		if extensions.IsHiddenOrThumbs(link.Href) {
+			continue
+		}
+		var playlist *audioPlaylist
+		switch strings.ToLower(path.Ext(link.Href)) {
+		case ".m3u", ".m3u8":
+			if data, rerr := f.Get(link).Read(0, 0); rerr == nil {
+				playlist = parseM3UPlaylist(decodePlaylistText(data), link.Href)
+			}
+			if playlist != nil && len(playlist.Entries) > 0 {
+				return playlist
+			}
+		case ".cue":
+			if cue == nil {
+				if data, rerr := f.Get(link).Read(0, 0); rerr == nil {
+					playlist = parseCueSheet(decodePlaylistText(data), link.Href)
+				}
+				if playlist != nil && len(playlist.Entries) > 0 {
+					cue = playlist
+				}
+			}
+		}
+	}
+	return cue
+}
+
+// Longest duration of a playlist entry, in seconds. Longer or infinite durations are ignored as absurd.
+const maxPlaylistEntryDuration = 7 * 24 * 60 * 60
+
+// Parses an M3U or extended M3U playlist located at [href].
+// Reference: https://en.wikipedia.org/wiki/M3U#Extended_M3U
+func parseM3UPlaylist(content string, href string) *audioPlaylist {
+	playlist := &audioPlaylist{}
+	var entry audioPlaylistEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<duration> [<attributes>],<title>
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			var title string
+			if i := strings.Index(info, ","); i >= 0 {
+				info, title = info[:i], info[i+1:]
+			}
+			entry.Title = strings.TrimSpace(title)
+			if fields := strings.Fields(info); len(fields) > 0 {
+				if duration, err := strconv.ParseFloat(fields[0], 64); err == nil && duration > 0 && duration <= maxPlaylistEntryDuration {
+					entry.Duration = duration
+				}
+			}
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			playlist.Title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTART:"):
+			playlist.Performer = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+		case strings.HasPrefix(line, "#"):
+			continue // Unsupported directive or comment
+		default:
+			if entryHref := resolvePlaylistPath(line, href); entryHref != "" {
+				entry.Href = entryHref
+				playlist.Entries = append(playlist.Entries, entry)
+			}
+			entry = audioPlaylistEntry{}
+		}
+	}
+	return playlist
+}
+
+// Parses a cue sheet located at [href]. Each TRACK becomes a chapter of its FILE, starting at its INDEX 01, or
+// INDEX 00 when missing.
+// Reference: https://wiki.hydrogenaud.io/index.php?title=Cue_sheet
+func parseCueSheet(content string, href string) *audioPlaylist {
+	playlist := &audioPlaylist{}
+	var file *audioPlaylistEntry
+	var track *audioChapter
+	var trackStart float64
+	trackHasIndex01 := false
+
+	endTrack := func() {
+		if file != nil && track != nil {
+			track.Start = trackStart
+			file.Chapters = append(file.Chapters, *track)
+		}
+		track = nil
+	}
+	endFile := func() {
+		endTrack()
+		if file != nil && file.Href != "" {
+			playlist.Entries = append(playlist.Entries, *file)
+		}
+		file = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		command, args := splitCueLine(scanner.Text())
+		switch command {
+		case "TITLE":
+			if track != nil {
+				track.Title = cueString(args)
+			} else if file == nil {
+				playlist.Title = cueString(args)
+			}
+		case "PERFORMER":
+			if file == nil {
+				playlist.Performer = cueString(args)
+			}
+		case "FILE":
+			endFile()
+			// FILE "<path>" <type>
+			name := args
+			if i := strings.LastIndex(args, " "); i > 0 && !strings.HasSuffix(args, "\"") {
+				name = args[:i]
+			}
+			file = &audioPlaylistEntry{Href: resolvePlaylistPath(cueString(name), href)}
+		case "TRACK":
+			endTrack()
+			if file != nil {
+				track = &audioChapter{}
+				trackStart = 0
+				trackHasIndex01 = false
+			}
+		case "INDEX":
+			// INDEX <number> <mm:ss:ff>
+			fields := strings.Fields(args)
+			if track == nil || len(fields) != 2 || trackHasIndex01 {
+				continue
+			}
+			start, ok := parseCueTime(fields[1])
+			if !ok {
+				continue
+			}
+			switch fields[0] {
+			case "01":
+				trackStart = start
+				trackHasIndex01 = true
+			case "00":
+				trackStart = start
+			}
+		}
+	}
+	endFile()
+
+	// Untitled tracks are named after their number.
+	for i := range playlist.Entries {
+		for j := range playlist.Entries[i].Chapters {
+			if chapter := &playlist.Entries[i].Chapters[j]; chapter.Title == "" {
+				chapter.Title = "Track " + strconv.Itoa(j+1)
+			}
+		}
+	}
+	return playlist
+}
+
+// Splits a cue sheet line into its upper-cased command and its arguments.
+func splitCueLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	command, args := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		command, args = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return strings.ToUpper(command), args
+}
+
+// Removes the quotes around a cue sheet string argument.
+func cueString(arg string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= 2 && strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
+		arg = arg[1 : len(arg)-1]
+	}
+	return arg
+}
+
+// Parses a cue sheet time in the mm:ss:ff format, where there are 75 frames per second.
+func parseCueTime(raw string) (float64, bool) {
+	parts := strings.Split(raw, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var values [3]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		values[i] = v
+	}
+	if values[1] >= 60 || values[2] >= 75 {
+		return 0, false
+	}
+	return float64(values[0]*60+values[1]) + float64(values[2])/75, true
+}
+
+// Resolves the path of a playlist entry relative to the playlist at [href].
+// Returns an empty string for remote URLs.
+func resolvePlaylistPath(entry string, href string) string {
+	entry = strings.ReplaceAll(strings.TrimSpace(entry), "\\", "/")
+	if strings.HasPrefix(entry, "file://") {
+		if u, err := url.Parse(entry); err == nil {
+			return u.Path
+		}
+	}
+	if strings.Contains(entry, "://") {
+		return ""
+	}
+	if strings.HasPrefix(entry, "/") {
+		return path.Clean(entry)
+	}
+	return path.Join(path.Dir(href), entry)
+}
+
+// Finds the link of the reading order referenced by a playlist entry.
+// The matching is done on the exact path first, then ignoring the case, as playlists are often authored on
+// case-insensitive file systems, and finally on the file name only for absolute paths of the machine that created
+// the playlist.
+func findPlaylistEntryLink(readingOrder manifest.LinkList, entryHref string) int {
+	candidates := []string{entryHref}
+	if decoded, err := url.PathUnescape(entryHref); err == nil && decoded != entryHref {
+		candidates = append(candidates, decoded)
+	}
+	matches := []func(href, candidate string) bool{
+		func(href, candidate string) bool { return href == candidate },
+		strings.EqualFold,
+		func(href, candidate string) bool { return strings.EqualFold(path.Base(href), path.Base(candidate)) },
+	}
+	for _, match := range matches {
+		for _, candidate := range candidates {
+			for i, link := range readingOrder {
+				if match(link.Href, candidate) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// Orders the [readingOrder] according to the [playlist], and sets the titles, durations and chapters it declares.
+// The tracks missing from the playlist are kept at the end, in their original order.
+func applyAudioPlaylist(readingOrder manifest.LinkList, playlist *audioPlaylist, trackTags map[string]*audioTags) manifest.LinkList {
+	ordered := make(manifest.LinkList, 0, len(readingOrder))
+	used := make([]bool, len(readingOrder))
+	for _, entry := range playlist.Entries {
+		i := findPlaylistEntryLink(readingOrder, entry.Href)
+		if i < 0 || used[i] {
+			continue
+		}
+		used[i] = true
+		link := readingOrder[i]
+		if entry.Title != "" {
+			link.Title = entry.Title
+		}
+		if link.Duration == 0 {
+			link.Duration = entry.Duration
+		}
+		if len(entry.Chapters) > 0 {
+			trackTags[link.Href].Chapters = entry.Chapters
+		}
+		ordered = append(ordered, link)
+	}
+	for i, link := range readingOrder {
+		if !used[i] {
+			ordered = append(ordered, link)
+		}
+	}
+	return ordered
+}
+
+// Decodes the content of a playlist, which is UTF-8 for M3U8 files but often in a legacy charset for older M3U
+// files and cue sheets.
+func decodePlaylistText(data []byte) string {
+	text, err := fetcher.DecodeText(data, mediatype.Text)
+	if err != nil {
+		return string(data)
+	}
+	return text
+}
@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Metadata read from the tags of an audio file, and its duration.
+type audioTags struct {
+	Title       string
+	Album       string
+	Artist      string
+	AlbumArtist string
+	Composer    string
+	Narrator    string
+	Publisher   string
+	Language    string
+	Genre       string
+	Date        string
+	Track       int
+	Disc        int
+	Duration    float64 // In seconds, 0 if unknown.
+	Picture     *audioPicture
+	Chapters    []audioChapter
+}
+
+// Chapter marker embedded in an audio file.
+type audioChapter struct {
+	Title    string
+	Start    float64 // In seconds from the beginning of the file.
+	Children []audioChapter
+}
+
+// Artwork embedded in an audio file.
+type audioPicture struct {
+	MediaType string
+	Data      []byte
+}
+
+// Reads the tags of the audio file at [link], according to its container format.
+// Returns nil if the format is not supported or the file can't be read.
+func readAudioTags(f fetcher.Fetcher, link manifest.Link) *audioTags {
+	res := f.Get(link)
+	defer res.Close()
+	length, rerr := res.Length()
+	if rerr != nil || length <= 0 {
+		return nil
+	}
+	r := io.NewSectionReader(resourceReaderAt{res}, 0, length)
+
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil && err != io.EOF {
+		return nil
+	}
+
+	var tags *audioTags
+	var err error
+	switch {
+	case bytes.HasPrefix(header, []byte("OggS")):
+		tags, err = readOggTags(r)
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		tags, err = readFLACTags(r, 0)
+	case bytes.Equal(header[4:8], []byte("ftyp")):
+		tags, err = readMP4Tags(r)
+	case bytes.HasPrefix(header, []byte("ID3")) || isMPEGAudioFrameHeader(header):
+		// Some FLAC files are prefixed with an ID3 tag.
+		id3, size, _ := readID3v2(r, 0)
+		magic := make([]byte, 4)
+		if _, rerr := r.ReadAt(magic, size); rerr == nil && bytes.Equal(magic, []byte("fLaC")) {
+			if tags, err = readFLACTags(r, size); err == nil {
+				tags.merge(id3)
+			}
+		} else {
+			tags, err = readMP3Tags(r)
+		}
+	}
+	if err != nil {
+		// TODO log
+		return nil
+	}
+	if !isPlausibleAudioDuration(tags.Duration, length) {
+		tags.Duration = 0
+	}
+	return tags
+}
+
+// Minimal bitrate of an audio stream in bits per second, used to reject the durations of corrupted files.
+// It is far below the bitrate of actual codecs, to only reject values which can't be right.
+const minAudioBitrate = 1
+
+// Whether [duration] is finite, positive and plausible for an audio file of [length] bytes.
+func isPlausibleAudioDuration(duration float64, length int64) bool {
+	return duration > 0 && !math.IsInf(duration, 0) && duration <= float64(length)*8/minAudioBitrate
+}
+
+// Parses a track or disc number, such as "3" or "3/12".
+func parseAudioTrackNumber(raw string) int {
+	raw = strings.TrimSpace(strings.SplitN(raw, "/", 2)[0])
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Fills the empty fields of [t] with the ones of [other].
+func (t *audioTags) merge(other *audioTags) {
+	if other == nil {
+		return
+	}
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&t.Title, other.Title)
+	fill(&t.Album, other.Album)
+	fill(&t.Artist, other.Artist)
+	fill(&t.AlbumArtist, other.AlbumArtist)
+	fill(&t.Composer, other.Composer)
+	fill(&t.Narrator, other.Narrator)
+	fill(&t.Publisher, other.Publisher)
+	fill(&t.Language, other.Language)
+	fill(&t.Genre, other.Genre)
+	fill(&t.Date, other.Date)
+	if t.Track == 0 {
+		t.Track = other.Track
+	}
+	if t.Disc == 0 {
+		t.Disc = other.Disc
+	}
+	if t.Duration == 0 {
+		t.Duration = other.Duration
+	}
+	if t.Picture == nil {
+		t.Picture = other.Picture
+	}
+}
+
+// Author of the audiobook, as the album artist takes precedence over the track artist.
+func (t audioTags) author() string {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist
+	}
+	return t.Artist
+}
+
+// Narrator of the audiobook, which is usually stored in the composer tag when there is no dedicated one.
+func (t audioTags) narrator() string {
+	if t.Narrator != "" {
+		return t.Narrator
+	}
+	return t.Composer
+}
@@ -0,0 +1,323 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withAudioParser(t *testing.T, filepath string, f func(*pub.Builder)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	assert.NoError(t, err)
+	p, err := AudioParser{}.Parse(a, fet)
+	assert.NoError(t, err)
+	f(p)
+}
+
+func TestAudioZABAccepted(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		assert.NotNil(t, p)
+	})
+}
+
+func TestAudioReadingOrderByTrackNumber(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		ro := p.Build().Manifest.ReadingOrder
+		if assert.Len(t, ro, 2) {
+			assert.Equal(t, "/b-intro.mp3", ro[0].Href)
+			assert.Equal(t, "Introduction", ro[0].Title)
+			assert.InDelta(t, 1000*1152/44100.0, ro[0].Duration, 0.001)
+			assert.Equal(t, "/a-chapter.mp3", ro[1].Href)
+			assert.Equal(t, "Chapter One", ro[1].Title)
+			assert.InDelta(t, 2000*1152/44100.0, ro[1].Duration, 0.001)
+		}
+	})
+}
+
+func TestAudioID3Metadata(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		m := p.Build().Manifest.Metadata
+		assert.Equal(t, "The Great Audiobook", m.Title())
+		assert.Equal(t, manifest.Profiles{manifest.ProfileAudiobook}, m.ConformsTo)
+		assert.Equal(t, "Jane Doe", m.Authors[0].Name())
+		// A dedicated narrator tag takes precedence over the composer.
+		assert.Equal(t, "Anna Narrator", m.Narrators[0].Name())
+		assert.Equal(t, "Readium Audio", m.Publishers[0].Name())
+		assert.Equal(t, manifest.Strings{"eng"}, m.Languages)
+		assert.Equal(t, "Speech", m.Subjects[0].Name())
+		assert.Equal(t, 2021, m.Published.Year())
+		if assert.NotNil(t, m.Duration) {
+			assert.InDelta(t, 3000*1152/44100.0, *m.Duration, 0.001)
+		}
+	})
+}
+
+func TestAudioEmbeddedCover(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		pub := p.Build()
+		cover := pub.Manifest.Resources.FirstWithRel("cover")
+		if !assert.NotNil(t, cover) {
+			return
+		}
+		// The front cover is preferred over the other pictures.
+		assert.Equal(t, "image/png", cover.Type)
+		data, err := pub.Get(*cover).Read(0, 0)
+		if assert.Nil(t, err) {
+			assert.Equal(t, []byte("\x89PNG"), data[:4])
+		}
+	})
+}
+
+func TestAudioCBRDuration(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/cbr.mp3", func(p *pub.Builder) {
+		ro := p.Build().Manifest.ReadingOrder
+		// 10 frames of 417 bytes at 128 kbps
+		assert.InDelta(t, 4170*8/128000.0, ro[0].Duration, 0.001)
+	})
+}
+
+func TestAudioVorbisComments(t *testing.T) {
+	for _, path := range []string{"./testdata/audio/tags.ogg", "./testdata/audio/tags.opus", "./testdata/audio/tags.flac"} {
+		withAudioParser(t, path, func(p *pub.Builder) {
+			pub := p.Build()
+			m := pub.Manifest.Metadata
+			assert.Equal(t, "Vorbis Tales", m.Title(), path)
+			assert.Equal(t, "Album Author", m.Authors[0].Name(), path)
+			assert.Equal(t, "Ogg Narrator", m.Narrators[0].Name(), path)
+			assert.Equal(t, "Xiph Press", m.Publishers[0].Name(), path)
+			assert.Equal(t, manifest.Strings{"fr"}, m.Languages, path)
+			assert.Equal(t, time.Date(2019, 5, 4, 0, 0, 0, 0, time.UTC), *m.Published, path)
+			assert.Equal(t, "Opening", pub.Manifest.ReadingOrder[0].Title, path)
+			assert.NotNil(t, pub.Manifest.Resources.FirstWithRel("cover"), path)
+		})
+	}
+
+	durations := map[string]float64{
+		"./testdata/audio/tags.ogg":  90,
+		"./testdata/audio/tags.opus": 75,
+		"./testdata/audio/tags.flac": 125,
+	}
+	for path, duration := range durations {
+		withAudioParser(t, path, func(p *pub.Builder) {
+			assert.Equal(t, duration, p.Build().Manifest.ReadingOrder[0].Duration, path)
+		})
+	}
+}
+
+func TestAudioMP4Atoms(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/tags.m4a", func(p *pub.Builder) {
+		pub := p.Build()
+		m := pub.Manifest.Metadata
+		assert.Equal(t, "The M4B Book", m.Title())
+		assert.Equal(t, "MP4 Author", m.Authors[0].Name())
+		assert.Equal(t, "MP4 Narrator", m.Narrators[0].Name())
+		assert.Equal(t, "MP4 House", m.Publishers[0].Name())
+		assert.Equal(t, manifest.Strings{"de"}, m.Languages)
+		assert.Equal(t, "Fiction", m.Subjects[0].Name())
+		if assert.NotNil(t, m.Duration) {
+			assert.Equal(t, 3725.5, *m.Duration)
+		}
+		assert.Equal(t, "Part Two", pub.Manifest.ReadingOrder[0].Title)
+		if cover := pub.Manifest.Resources.FirstWithRel("cover"); assert.NotNil(t, cover) {
+			assert.Equal(t, "/~readium/cover.png", cover.Href)
+		}
+	})
+}
+
+func TestAudioImplausibleDuration(t *testing.T) {
+	for name, corrupt := range map[string]func(data []byte){
+		"tags.ogg": func(data []byte) {
+			// Granule position of the last page.
+			i := bytes.LastIndex(data, []byte("OggS"))
+			binary.LittleEndian.PutUint64(data[i+6:i+14], 1<<62)
+		},
+		"tags.flac": func(data []byte) {
+			// Sample rate of 1 Hz and maximum total samples in STREAMINFO.
+			data[18], data[19], data[20] = 0x00, 0x00, 0x1F
+			data[21] |= 0x0F
+			copy(data[22:26], []byte{0xFF, 0xFF, 0xFF, 0xFF})
+		},
+	} {
+		data, err := os.ReadFile(filepath.Join("testdata", "audio", name))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		corrupt(data)
+		path := filepath.Join(t.TempDir(), name)
+		if !assert.NoError(t, os.WriteFile(path, data, 0o644)) {
+			continue
+		}
+		withAudioParser(t, path, func(p *pub.Builder) {
+			m := p.Build().Manifest
+			assert.Equal(t, 0.0, m.ReadingOrder[0].Duration, name)
+			assert.Nil(t, m.Metadata.Duration, name)
+		})
+	}
+}
+
+func TestParseAudioTrackNumber(t *testing.T) {
+	assert.Equal(t, 3, parseAudioTrackNumber("3"))
+	assert.Equal(t, 3, parseAudioTrackNumber(" 03/12"))
+	assert.Equal(t, 0, parseAudioTrackNumber("A"))
+	assert.Equal(t, 0, parseAudioTrackNumber(""))
+}
+
+func TestID3Genre(t *testing.T) {
+	assert.Equal(t, "Speech", id3Genre("(101)Speech"))
+	assert.Equal(t, "", id3Genre("(101)"))
+	assert.Equal(t, "", id3Genre("101"))
+	assert.Equal(t, "Audiobook", id3Genre("Audiobook"))
+	assert.Equal(t, "(Remix)", id3Genre("((Remix)"))
+}
+
+func TestID3TagLargerThanFile(t *testing.T) {
+	// The syncsafe size declares a 256 MB tag.
+	data := append([]byte("ID3\x03\x00\x00\x7F\x7F\x7F\x7F"), make([]byte, 32)...)
+	_, _, err := readID3v2(io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))), 0)
+	assert.Error(t, err)
+}
+
+func TestAudioID3Chapters(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/chapters.mp3", func(p *pub.Builder) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/chapters.mp3#t=0", Title: "Prologue"},
+			{Href: "/chapters.mp3#t=30", Title: "Part One", Children: manifest.LinkList{
+				{Href: "/chapters.mp3#t=30", Title: "Chapter 1"},
+				{Href: "/chapters.mp3#t=90.5", Title: "Chapter 2"},
+			}},
+		}, p.Build().Manifest.TableOfContents)
+	})
+}
+
+func TestAudioMP4Chapters(t *testing.T) {
+	// The QuickTime chapter track is preferred over the Nero chapters.
+	withAudioParser(t, "./testdata/audio/chapters.m4b", func(p *pub.Builder) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/chapters.m4b#t=0", Title: "Opening Credits"},
+			{Href: "/chapters.m4b#t=45", Title: "The Beginning"},
+			{Href: "/chapters.m4b#t=112", Title: "The End"},
+		}, p.Build().Manifest.TableOfContents)
+	})
+
+	withAudioParser(t, "./testdata/audio/nero.m4b", func(p *pub.Builder) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/nero.m4b#t=0", Title: "Nero Start"},
+			{Href: "/nero.m4b#t=60", Title: "Nero Middle"},
+		}, p.Build().Manifest.TableOfContents)
+	})
+}
+
+func TestAudioTableOfContentsFromTracks(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/b-intro.mp3", Title: "Introduction"},
+			{Href: "/a-chapter.mp3", Title: "Chapter One"},
+		}, p.Build().Manifest.TableOfContents)
+	})
+
+	// A single file without chapters has no table of contents.
+	withAudioParser(t, "./testdata/audio/tags.ogg", func(p *pub.Builder) {
+		assert.Empty(t, p.Build().Manifest.TableOfContents)
+	})
+}
+
+func TestAudioPositions(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/audiobook.zab", func(p *pub.Builder) {
+		pub := p.Build()
+		// ~26 s and ~52 s, so one position per resource.
+		positions := pub.Positions()
+		if assert.Len(t, positions, 2) {
+			assert.Equal(t, "/a-chapter.mp3", positions[1].Href)
+			assert.Equal(t, "audio/mpeg", positions[1].Type)
+			assert.Equal(t, []string{"t=0"}, positions[1].Locations.Fragments)
+			assert.InDelta(t, 1.0/3.0, *positions[1].Locations.TotalProgression, 0.001)
+		}
+	})
+}
+
+func TestAudioM3UPlaylist(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/playlist", func(p *pub.Builder) {
+		pub := p.Build()
+		ro := pub.Manifest.ReadingOrder
+		if assert.Len(t, ro, 3) {
+			// The playlist order takes precedence over the alphabetical order.
+			assert.Equal(t, "/tracks/Intro.mp3", ro[0].Href)
+			assert.Equal(t, "Introduction", ro[0].Title)
+			assert.Equal(t, "/tracks/Chapter 2.mp3", ro[1].Href)
+			assert.Equal(t, "Chapter Two", ro[1].Title)
+			assert.Equal(t, "/tracks/Chapter 10.mp3", ro[2].Href)
+			assert.Equal(t, "Chapter Ten", ro[2].Title)
+			// The durations of the tags are more precise than the playlist ones.
+			assert.InDelta(t, 1000*1152/44100.0, ro[2].Duration, 0.001)
+		}
+		assert.Equal(t, "Playlist Book", pub.Manifest.Metadata.Title())
+		assert.Equal(t, "Chapter Two", pub.Manifest.TableOfContents[1].Title)
+	})
+}
+
+func TestAudioCueSheet(t *testing.T) {
+	withAudioParser(t, "./testdata/audio/cuesheet.zab", func(p *pub.Builder) {
+		m := p.Build().Manifest
+		assert.Equal(t, "The Cue Book", m.Metadata.Title())
+		assert.Equal(t, "Cue Author", m.Metadata.Authors[0].Name())
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/disc.mp3#t=0", Title: "Opening"},
+			{Href: "/disc.mp3#t=60.49333333333333", Title: "Middle Part"},
+			{Href: "/disc.mp3#t=120", Title: "Track 3"},
+		}, m.TableOfContents)
+	})
+}
+
+func TestParseM3UPlaylist(t *testing.T) {
+	playlist := parseM3UPlaylist("#EXTM3U\n#EXTINF:12.5,Title, with comma\n..\\a.mp3\nhttp://example.com/b.mp3\nfile:///C:/Music/c.mp3\n/abs/d.mp3\n", "/dir/list.m3u")
+	assert.Equal(t, []audioPlaylistEntry{
+		{Href: "/a.mp3", Title: "Title, with comma", Duration: 12.5},
+		{Href: "/C:/Music/c.mp3"},
+		{Href: "/abs/d.mp3"},
+	}, playlist.Entries)
+}
+
+func TestParseM3UPlaylistAbsurdDurations(t *testing.T) {
+	playlist := parseM3UPlaylist("#EXTM3U\n#EXTINF:1e300,Huge\na.mp3\n#EXTINF:+Inf,Infinite\nb.mp3\n#EXTINF:NaN,Unknown\nc.mp3\n", "/list.m3u")
+	assert.Equal(t, []audioPlaylistEntry{
+		{Href: "/a.mp3", Title: "Huge"},
+		{Href: "/b.mp3", Title: "Infinite"},
+		{Href: "/c.mp3", Title: "Unknown"},
+	}, playlist.Entries)
+}
+
+func TestDecodePlaylistText(t *testing.T) {
+	assert.Equal(t, "#EXTINF:10,Café\n", decodePlaylistText([]byte("\xEF\xBB\xBF#EXTINF:10,Café\n")))
+	assert.Equal(t, "#EXTINF:10,Café crème\n", decodePlaylistText([]byte("#EXTINF:10,Caf\xe9 cr\xe8me\n")))
+}
+
+func TestFindPlaylistEntryLink(t *testing.T) {
+	ro := manifest.LinkList{{Href: "/a/One Track.mp3"}, {Href: "/a/two.mp3"}}
+	assert.Equal(t, 0, findPlaylistEntryLink(ro, "/a/One%20Track.mp3"))
+	assert.Equal(t, 1, findPlaylistEntryLink(ro, "/a/TWO.mp3"))
+	assert.Equal(t, 1, findPlaylistEntryLink(ro, "/C:/Music/two.mp3"))
+	assert.Equal(t, -1, findPlaylistEntryLink(ro, "/a/three.mp3"))
+}
+
+func TestParseCueTime(t *testing.T) {
+	time, ok := parseCueTime("61:02:15")
+	assert.True(t, ok)
+	assert.Equal(t, 3662.2, time)
+	_, ok = parseCueTime("01:02:75")
+	assert.False(t, ok)
+	_, ok = parseCueTime("01:02")
+	assert.False(t, ok)
+}
@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Reads the Vorbis comments of an Ogg file (Vorbis or Opus), and computes its duration from the granule position of
+// the last page.
+// References:
+//  - https://www.xiph.org/ogg/doc/framing.html
+//  - https://www.xiph.org/vorbis/doc/Vorbis_I_spec.html
+//  - https://www.rfc-editor.org/rfc/rfc7845
+func readOggTags(r *io.SectionReader) (*audioTags, error) {
+	pages := oggPageReader{r: r}
+
+	identification, err := pages.nextPacket()
+	if err != nil {
+		return nil, err
+	}
+	var sampleRate, preSkip uint64
+	var commentPrefix []byte
+	switch {
+	case bytes.HasPrefix(identification, []byte("\x01vorbis")) && len(identification) >= 16:
+		sampleRate = uint64(binary.LittleEndian.Uint32(identification[12:16]))
+		commentPrefix = []byte("\x03vorbis")
+	case bytes.HasPrefix(identification, []byte("OpusHead")) && len(identification) >= 12:
+		// The granule position of Opus streams is always at 48 kHz.
+		sampleRate = 48000
+		preSkip = uint64(binary.LittleEndian.Uint16(identification[10:12]))
+		commentPrefix = []byte("OpusTags")
+	default:
+		return nil, errors.New("unsupported Ogg codec")
+	}
+
+	tags := &audioTags{}
+	comments, err := pages.nextPacket()
+	if err == nil && bytes.HasPrefix(comments, commentPrefix) {
+		parseVorbisComments(comments[len(commentPrefix):], tags)
+	}
+
+	if granule := lastOggGranulePosition(r, pages.serial); granule > preSkip && sampleRate > 0 {
+		tags.Duration = float64(granule-preSkip) / float64(sampleRate)
+	}
+	return tags, nil
+}
+
+// Maximum size of a packet read from an Ogg stream, to avoid allocating huge buffers for corrupted files.
+const oggMaxPacketSize = 16 * 1024 * 1024
+
+// Reads the packets of the first logical stream of an Ogg file.
+type oggPageReader struct {
+	r        *io.SectionReader
+	offset   int64
+	serial   uint32
+	segments []byte // Remaining lacing values of the current page.
+	data     []byte // Remaining data of the current page.
+}
+
+func (p *oggPageReader) nextPage() error {
+	for {
+		header := make([]byte, 27)
+		if _, err := p.r.ReadAt(header, p.offset); err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(header, []byte("OggS")) {
+			return errors.New("invalid Ogg page")
+		}
+		serial := binary.LittleEndian.Uint32(header[14:18])
+		segments := make([]byte, header[26])
+		if _, err := p.r.ReadAt(segments, p.offset+27); err != nil {
+			return err
+		}
+		size := 0
+		for _, s := range segments {
+			size += int(s)
+		}
+		data := make([]byte, size)
+		if _, err := p.r.ReadAt(data, p.offset+27+int64(len(segments))); err != nil && !(err == io.EOF && size == 0) {
+			return err
+		}
+		first := p.offset == 0
+		p.offset += 27 + int64(len(segments)) + int64(size)
+		if first {
+			p.serial = serial
+		} else if serial != p.serial {
+			continue // Page of another logical stream.
+		}
+		p.segments, p.data = segments, data
+		return nil
+	}
+}
+
+func (p *oggPageReader) nextPacket() ([]byte, error) {
+	var packet []byte
+	for {
+		if len(p.segments) == 0 {
+			if err := p.nextPage(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		s := int(p.segments[0])
+		p.segments = p.segments[1:]
+		packet = append(packet, p.data[:s]...)
+		p.data = p.data[s:]
+		if len(packet) > oggMaxPacketSize {
+			return nil, errors.New("Ogg packet is too large")
+		}
+		if s < 255 {
+			return packet, nil
+		}
+	}
+}
+
+// Finds the granule position of the last page of the logical stream [serial], by scanning the end of the file.
+func lastOggGranulePosition(r *io.SectionReader, serial uint32) uint64 {
+	const chunkSize = 64 * 1024
+	for end := r.Size(); end > 0; end -= chunkSize - 27 {
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		n, _ := r.ReadAt(chunk, start)
+		chunk = chunk[:n]
+		for i := bytes.LastIndex(chunk, []byte("OggS")); i >= 0; i = bytes.LastIndex(chunk[:i], []byte("OggS")) {
+			if i+27 > len(chunk) || binary.LittleEndian.Uint32(chunk[i+14:i+18]) != serial {
+				continue
+			}
+			granule := binary.LittleEndian.Uint64(chunk[i+6 : i+14])
+			if granule != ^uint64(0) { // -1 means that no packet finishes on this page.
+				return granule
+			}
+		}
+		if start == 0 {
+			break
+		}
+	}
+	return 0
+}
+
+// Reads the metadata blocks of a FLAC file starting at [offset].
+// Reference: https://xiph.org/flac/format.html#metadata_block
+func readFLACTags(r *io.SectionReader, offset int64) (*audioTags, error) {
+	tags := &audioTags{}
+	offset += 4 // "fLaC"
+	for {
+		header := make([]byte, 4)
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		offset += 4
+
+		switch blockType {
+		case 0, 4, 6: // STREAMINFO, VORBIS_COMMENT, PICTURE
+			if length > oggMaxPacketSize {
+				return nil, errors.New("FLAC metadata block is too large")
+			}
+			block := make([]byte, length)
+			if _, err := r.ReadAt(block, offset); err != nil && err != io.EOF {
+				return nil, err
+			}
+			switch blockType {
+			case 0:
+				if len(block) >= 18 {
+					sampleRate := uint64(block[10])<<12 | uint64(block[11])<<4 | uint64(block[12])>>4
+					totalSamples := uint64(block[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(block[14:18]))
+					if sampleRate > 0 {
+						tags.Duration = float64(totalSamples) / float64(sampleRate)
+					}
+				}
+			case 4:
+				parseVorbisComments(block, tags)
+			case 6:
+				if picture, typ := parseFLACPicture(block); picture != nil && (tags.Picture == nil || typ == 3) {
+					tags.Picture = picture
+				}
+			}
+		}
+		offset += length
+		if last {
+			return tags, nil
+		}
+	}
+}
+
+// Parses a FLAC PICTURE block, also used base64-encoded in the METADATA_BLOCK_PICTURE Vorbis comment.
+func parseFLACPicture(block []byte) (*audioPicture, uint32) {
+	readUint32 := func() (uint32, bool) {
+		if len(block) < 4 {
+			return 0, false
+		}
+		v := binary.BigEndian.Uint32(block)
+		block = block[4:]
+		return v, true
+	}
+	readBytes := func() ([]byte, bool) {
+		n, ok := readUint32()
+		if !ok || uint32(len(block)) < n {
+			return nil, false
+		}
+		v := block[:n]
+		block = block[n:]
+		return v, true
+	}
+
+	typ, ok := readUint32()
+	if !ok {
+		return nil, 0
+	}
+	mime, ok := readBytes()
+	if !ok {
+		return nil, 0
+	}
+	if _, ok := readBytes(); !ok { // Description
+		return nil, 0
+	}
+	if len(block) < 16 { // Width, height, color depth and number of colors
+		return nil, 0
+	}
+	block = block[16:]
+	data, ok := readBytes()
+	if !ok || len(data) == 0 {
+		return nil, 0
+	}
+	mediaType := strings.ToLower(string(mime))
+	if mediaType == "" || mediaType == "image/jpg" {
+		mediaType = "image/jpeg"
+	}
+	return &audioPicture{MediaType: mediaType, Data: data}, typ
+}
+
+// Parses a Vorbis comment header into [tags].
+// Reference: https://www.xiph.org/vorbis/doc/v-comment.html
+func parseVorbisComments(data []byte, tags *audioTags) {
+	readUint32 := func() (uint32, bool) {
+		if len(data) < 4 {
+			return 0, false
+		}
+		v := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		return v, true
+	}
+
+	vendorLength, ok := readUint32()
+	if !ok || uint32(len(data)) < vendorLength {
+		return
+	}
+	data = data[vendorLength:]
+	count, ok := readUint32()
+	if !ok {
+		return
+	}
+	var pictureType uint32 = 0xFFFFFFFF
+	for i := uint32(0); i < count; i++ {
+		length, ok := readUint32()
+		if !ok || uint32(len(data)) < length {
+			return
+		}
+		comment := string(data[:length])
+		data = data[length:]
+
+		parts := strings.SplitN(comment, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		set := func(field *string) {
+			// Only the first value of repeated fields is kept.
+			if *field == "" {
+				*field = value
+			}
+		}
+		switch strings.ToUpper(parts[0]) {
+		case "TITLE":
+			set(&tags.Title)
+		case "ALBUM":
+			set(&tags.Album)
+		case "ARTIST":
+			set(&tags.Artist)
+		case "ALBUMARTIST", "ALBUM ARTIST":
+			set(&tags.AlbumArtist)
+		case "COMPOSER":
+			set(&tags.Composer)
+		case "NARRATOR", "NARRATEDBY", "READER", "PERFORMER":
+			set(&tags.Narrator)
+		case "PUBLISHER", "ORGANIZATION", "LABEL":
+			set(&tags.Publisher)
+		case "LANGUAGE":
+			set(&tags.Language)
+		case "GENRE":
+			set(&tags.Genre)
+		case "DATE", "YEAR":
+			set(&tags.Date)
+		case "TRACKNUMBER":
+			tags.Track = parseAudioTrackNumber(value)
+		case "DISCNUMBER":
+			tags.Disc = parseAudioTrackNumber(value)
+		case "METADATA_BLOCK_PICTURE":
+			block, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				continue
+			}
+			if picture, typ := parseFLACPicture(block); picture != nil && (tags.Picture == nil || (typ == 3 && pictureType != 3)) {
+				tags.Picture = picture
+				pictureType = typ
+			}
+		}
+	}
+}
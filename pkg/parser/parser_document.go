@@ -0,0 +1,367 @@
+package parser
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/internal/markdown"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"golang.org/x/net/html"
+)
+
+// Parses a standalone HTML, XHTML, Markdown or plain text document, or a ZIP archive of such documents, into a
+// Readium Web Publication.
+//
+// Markdown and plain text documents are served as XHTML, converted when they are read.
+type DocumentParser struct{}
+
+// Parse implements PublicationParser
+func (p DocumentParser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	if !p.accepts(asset, f) {
+		return nil, nil
+	}
+
+	links, err := f.Links()
+	if err != nil {
+		return nil, err
+	}
+	assetMediaType := asset.MediaType()
+	formats := make(map[string]*mediatype.MediaType)
+	var readingOrder, resources manifest.LinkList
+	for _, link := range links {
+		if extensions.IsHiddenOrThumbs(link.Href) {
+			continue
+		}
+		format := documentFormatOf(link)
+		if format == nil && len(links) == 1 {
+			format = &assetMediaType
+		}
+		if format == nil {
+			resources = append(resources, link)
+			continue
+		}
+		formats[link.Href] = format
+		if format.Matches(&mediatype.Markdown, &mediatype.Text) {
+			link.Type = mediatype.XHTML.String()
+		} else {
+			link.Type = format.String()
+		}
+		readingOrder = append(readingOrder, link)
+	}
+
+	if len(readingOrder) == 0 {
+		return nil, errors.New("no document found in the publication")
+	}
+
+	// Sort in natural order
+	sort.Slice(readingOrder, func(i, j int) bool {
+		return naturalHrefLess(readingOrder[i].Href, readingOrder[j].Href)
+	})
+
+	f = fetcher.NewTransformingFetcher(f, func(resource fetcher.Resource) fetcher.Resource {
+		return transformDocument(resource, formats[resource.Link().Href])
+	})
+
+	// The title, language and table of contents are read from the HTML content of the documents.
+	var title, language string
+	var toc manifest.LinkList
+	for i, link := range readingOrder {
+		info := readDocumentInfo(f, link)
+		if info == nil {
+			continue
+		}
+		if i == 0 {
+			title = info.title
+		}
+		if language == "" {
+			language = info.language
+		}
+		readingOrder[i].Title = info.title
+		if len(readingOrder) == 1 {
+			toc = info.toc
+		} else {
+			entryTitle := info.title
+			if entryTitle == "" {
+				entryTitle = cleanFolderName(strings.TrimSuffix(path.Base(link.Href), path.Ext(link.Href)))
+			}
+			entry := manifest.Link{Href: link.Href, Title: entryTitle, Children: info.toc}
+			// A document whose single top-level heading is its title doesn't need a nested entry.
+			if len(info.toc) == 1 && info.toc[0].Title == entryTitle {
+				entry.Children = info.toc[0].Children
+			}
+			toc = append(toc, entry)
+		}
+	}
+	if title == "" {
+		title = guessPublicationTitleFromFileStructure(f)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(asset.Name(), path.Ext(asset.Name()))
+	}
+
+	metadata := manifest.Metadata{
+		LocalizedTitle: manifest.NewLocalizedStringFromString(title),
+	}
+	if language != "" {
+		metadata.Languages = []string{language}
+	}
+
+	manifest := manifest.Manifest{
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		Resources:       resources,
+		TableOfContents: toc,
+	}
+
+	// Positions are computed from the length of the served documents, as the converted documents are not archive
+	// entries.
+	builder := pub.NewServicesBuilder(map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: epub.PositionsServiceFactory(epub.OriginalLength{PageLength: 1024}),
+	})
+	return pub.NewBuilder(manifest, f, builder), nil
+}
+
+var allowed_extensions_document = map[string]struct{}{
+	"html": {}, "htm": {}, "xhtml": {}, "xht": {}, "md": {}, "markdown": {}, "txt": {}, "text": {},
+	"css": {}, "js": {}, "svg": {}, "otf": {}, "ttf": {}, "woff": {}, "woff2": {},
+}
+
+func (p DocumentParser) accepts(asset asset.PublicationAsset, f fetcher.Fetcher) bool {
+	mt := asset.MediaType()
+	if mt.Matches(&mediatype.HTML, &mediatype.XHTML, &mediatype.Markdown, &mediatype.Text) {
+		return true
+	}
+	if !mt.Equal(&mediatype.ZIP) {
+		return false
+	}
+
+	// A ZIP archive containing only documents and their assets.
+	links, err := f.Links()
+	if err != nil {
+		// TODO log
+		return false
+	}
+	hasDocument := false
+	for _, link := range links {
+		if extensions.IsHiddenOrThumbs(link.Href) {
+			continue
+		}
+		if documentFormatOf(link) != nil {
+			hasDocument = true
+			continue
+		}
+		if link.MediaType().IsBitmap() {
+			continue
+		}
+		fext := strings.TrimPrefix(path.Ext(strings.ToLower(link.Href)), ".")
+		if _, contains := allowed_extensions_document[fext]; !contains {
+			return false
+		}
+	}
+	return hasDocument
+}
+
+// Returns the format of a document in the reading order, or nil if [link] is not a document.
+// The extension takes precedence over the sniffed media type, as text documents can't be recognized from their
+// content.
+func documentFormatOf(link manifest.Link) *mediatype.MediaType {
+	switch strings.ToLower(path.Ext(link.Href)) {
+	case ".html", ".htm":
+		return &mediatype.HTML
+	case ".xhtml", ".xht":
+		return &mediatype.XHTML
+	case ".md", ".markdown":
+		return &mediatype.Markdown
+	case ".txt", ".text":
+		return &mediatype.Text
+	}
+	if mt := link.MediaType(); mt.IsHTML() {
+		return &mt
+	}
+	return nil
+}
+
+// Converts Markdown and plain text documents to XHTML.
+func transformDocument(resource fetcher.Resource, format *mediatype.MediaType) fetcher.Resource {
+	if format == nil || !format.Matches(&mediatype.Markdown, &mediatype.Text) {
+		return resource
+	}
+	link := resource.Link()
+	// The declared charset, if any, is needed to decode the document.
+	mt, _ := mediatype.NewOfString(link.Type)
+	link.Type = mediatype.XHTML.String()
+	isMarkdown := format.Matches(&mediatype.Markdown)
+	return fetcher.NewTransformingResource(resource, link, func(data []byte) ([]byte, error) {
+		text, err := fetcher.DecodeText(data, mt)
+		if err != nil {
+			return nil, err
+		}
+		if isMarkdown {
+			xhtml, _ := markdown.ToXHTML(text, "", "")
+			return xhtml, nil
+		}
+		return textToXHTML(text), nil
+	}, true)
+}
+
+// Wraps a plain text document in an XHTML document, with one paragraph per block of lines.
+func textToXHTML(text string) []byte {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<!DOCTYPE html>\n")
+	sb.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\">\n<head>\n<meta charset=\"UTF-8\"/>\n<title></title>\n</head>\n<body>\n")
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			sb.WriteString("<p>")
+			sb.WriteString(html.EscapeString(strings.Join(paragraph, "\n")))
+			sb.WriteString("</p>\n")
+			paragraph = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRightFunc(line, isTextSpace)
+		if line == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String())
+}
+
+func isTextSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\f' || r == '\v'
+}
+
+type documentInfo struct {
+	title    string
+	language string
+	toc      manifest.LinkList
+}
+
+// Reads the title, language and headings of the HTML document at [link].
+func readDocumentInfo(f fetcher.Fetcher, link manifest.Link) *documentInfo {
+	// The HTML parser expects UTF-8, so legacy encodings are converted first.
+	data, rerr := f.Get(link).ReadAsString()
+	if rerr != nil {
+		// TODO log
+		return nil
+	}
+	doc, err := parseHTML([]byte(data))
+	if err != nil {
+		return nil
+	}
+
+	info := &documentInfo{}
+	if root := findHTMLElement(doc, func(n *htmlNode) bool { return n.Data == "html" }); root != nil {
+		info.language = htmlAttr(root, "lang")
+		if info.language == "" {
+			info.language = htmlAttr(root, "xml:lang")
+		}
+	}
+	if t := findHTMLElement(doc, func(n *htmlNode) bool { return n.Data == "title" }); t != nil {
+		info.title = normalizeHTMLText(htmlText(t))
+	}
+
+	headings := findHTMLHeadings(doc)
+	if info.title == "" && len(headings) > 0 {
+		// The first heading with the highest level is the title.
+		first := headings[0]
+		for _, h := range headings {
+			if h.level < first.level {
+				first = h
+			}
+		}
+		info.title = first.title
+	}
+	info.toc = headingsTableOfContents(headings, link.Href)
+	return info
+}
+
+type htmlHeading struct {
+	level int
+	id    string
+	title string
+}
+
+// Finds the h1 to h3 headings of an HTML document.
+func findHTMLHeadings(n *htmlNode) []htmlHeading {
+	var headings []htmlHeading
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		if n.Type == html.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '3' {
+			if title := normalizeHTMLText(htmlText(n)); title != "" {
+				headings = append(headings, htmlHeading{
+					level: int(n.Data[1] - '0'),
+					id:    htmlHeadingID(n),
+					title: title,
+				})
+			}
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return headings
+}
+
+// Returns the fragment identifying a heading, from its own id or from an anchor it contains.
+func htmlHeadingID(heading *htmlNode) string {
+	if id := htmlAttr(heading, "id"); id != "" {
+		return id
+	}
+	anchor := findHTMLElement(heading, func(n *htmlNode) bool {
+		return htmlAttr(n, "id") != "" || (n.Data == "a" && htmlAttr(n, "name") != "")
+	})
+	if anchor == nil {
+		return ""
+	}
+	if id := htmlAttr(anchor, "id"); id != "" {
+		return id
+	}
+	return htmlAttr(anchor, "name")
+}
+
+// Builds a nested table of contents from the headings of the document at [href].
+// Headings without an identifier can't be linked to, so they are skipped.
+func headingsTableOfContents(headings []htmlHeading, href string) manifest.LinkList {
+	type node struct {
+		level int
+		link  *manifest.Link
+	}
+	root := &manifest.Link{}
+	stack := []node{{level: 0, link: root}}
+	for _, h := range headings {
+		if h.id == "" {
+			continue
+		}
+		for len(stack) > 1 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].link
+		parent.Children = append(parent.Children, manifest.Link{Href: href + "#" + h.id, Title: h.title})
+		stack = append(stack, node{level: h.level, link: &parent.Children[len(parent.Children)-1]})
+	}
+	return root.Children
+}
+
+func normalizeHTMLText(text string) string {
+	return strings.TrimSpace(muchSpaceMatcher.ReplaceAllString(text, " "))
+}
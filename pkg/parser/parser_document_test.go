@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withDocumentParser(t *testing.T, filepath string, f func(*pub.Publication)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := DocumentParser{}.Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	f(p.Build())
+}
+
+func TestDocumentHTML(t *testing.T) {
+	withDocumentParser(t, "./testdata/document/moby-dick.html", func(p *pub.Publication) {
+		assert.Equal(t, "Moby-Dick; or, The Whale", p.Manifest.Metadata.Title())
+		assert.Equal(t, manifest.Strings{"en"}, p.Manifest.Metadata.Languages)
+		assert.Equal(t, manifest.LinkList{{
+			Href:  "/moby-dick.html",
+			Type:  "text/html",
+			Title: "Moby-Dick; or, The Whale",
+		}}, p.Manifest.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/moby-dick.html#chap1", Title: "Chapter 1. Loomings."},
+			{Href: "/moby-dick.html#chap2", Title: "Chapter 2. The Carpet-Bag.", Children: manifest.LinkList{
+				{Href: "/moby-dick.html#chap2-bag", Title: "The bag"},
+			}},
+		}, p.Manifest.TableOfContents)
+		assert.Len(t, p.Positions(), 1)
+	})
+}
+
+func TestDocumentMarkdown(t *testing.T) {
+	withDocumentParser(t, "./testdata/document/alice.md", func(p *pub.Publication) {
+		assert.Equal(t, "Alice's Adventures in Wonderland", p.Manifest.Metadata.Title())
+		if assert.Len(t, p.Manifest.ReadingOrder, 1) {
+			link := p.Manifest.ReadingOrder[0]
+			assert.Equal(t, "/alice.md", link.Href)
+			assert.Equal(t, "application/xhtml+xml", link.Type)
+
+			res := p.Get(link)
+			assert.Equal(t, "application/xhtml+xml", res.Link().Type)
+			doc, err := res.ReadAsXML(nil)
+			if assert.Nil(t, err) {
+				assert.Equal(t, "Alice's Adventures in Wonderland", doc.SelectElement("//title").InnerText())
+				assert.Equal(t, "tired", doc.SelectElement("//em").InnerText())
+			}
+		}
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/alice.md#alices-adventures-in-wonderland", Title: "Alice's Adventures in Wonderland", Children: manifest.LinkList{
+				{Href: "/alice.md#chapter-i-down-the-rabbit-hole", Title: "Chapter I. Down the Rabbit-Hole"},
+				{Href: "/alice.md#chapter-ii-the-pool-of-tears", Title: "Chapter II. The Pool of Tears"},
+			}},
+		}, p.Manifest.TableOfContents)
+	})
+}
+
+func TestDocumentTextCharset(t *testing.T) {
+	withDocumentParser(t, "./testdata/document/latin1.txt", func(p *pub.Publication) {
+		assert.Equal(t, "latin1", p.Manifest.Metadata.Title())
+		if assert.Len(t, p.Manifest.ReadingOrder, 1) {
+			data, err := p.Get(p.Manifest.ReadingOrder[0]).ReadAsString()
+			if assert.Nil(t, err) {
+				assert.Contains(t, data, "<p>Café crème\nA naïve story.</p>\n<p>The end.</p>")
+			}
+		}
+		assert.Empty(t, p.Manifest.TableOfContents)
+	})
+}
+
+func TestDocumentArchive(t *testing.T) {
+	withDocumentParser(t, "./testdata/document/stories.zip", func(p *pub.Publication) {
+		assert.Equal(t, "Introduction", p.Manifest.Metadata.Title())
+		assert.Equal(t, manifest.Strings{"fr"}, p.Manifest.Metadata.Languages)
+		assert.Equal(t, []string{
+			"/Stories/01 - Introduction.md",
+			"/Stories/02 - Chapter.html",
+			"/Stories/10 - Notes.txt",
+		}, []string{p.Manifest.ReadingOrder[0].Href, p.Manifest.ReadingOrder[1].Href, p.Manifest.ReadingOrder[2].Href})
+		if assert.Len(t, p.Manifest.Resources, 1) {
+			assert.Equal(t, "/Stories/style.css", p.Manifest.Resources[0].Href)
+		}
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/Stories/01 - Introduction.md", Title: "Introduction", Children: manifest.LinkList{
+				{Href: "/Stories/01 - Introduction.md#why", Title: "Why"},
+			}},
+			{Href: "/Stories/02 - Chapter.html", Title: "Le Chapitre", Children: manifest.LinkList{
+				{Href: "/Stories/02 - Chapter.html#s1", Title: "Section"},
+			}},
+			{Href: "/Stories/10 - Notes.txt", Title: "Notes"},
+		}, p.Manifest.TableOfContents)
+	})
+}
+
+func TestDocumentRejectsOtherArchives(t *testing.T) {
+	a := asset.File("./testdata/image/futuristic_tales.cbz")
+	fet, err := a.CreateFetcher(asset.Dependencies{ArchiveFactory: archive.NewArchiveFactory()}, "")
+	if assert.NoError(t, err) {
+		p, err := DocumentParser{}.Parse(a, fet)
+		assert.NoError(t, err)
+		assert.Nil(t, p)
+	}
+}
+
+func TestDocumentTextLegacyCJKCharset(t *testing.T) {
+	withDocumentParser(t, "./testdata/document/sjis.txt", func(p *pub.Publication) {
+		if assert.Len(t, p.Manifest.ReadingOrder, 1) {
+			data, err := p.Get(p.Manifest.ReadingOrder[0]).ReadAsString()
+			if assert.Nil(t, err) {
+				assert.Contains(t, data, "<p>吾輩は猫である。名前はまだ無い。どこで生れたかとんと見当がつかぬ。</p>")
+			}
+		}
+	})
+}
+
+func TestDocumentTextDeclaredCharset(t *testing.T) {
+	// Valid UTF-8 by chance, but declared as windows-1252.
+	link := manifest.Link{Href: "/doc.txt", Type: "text/plain; charset=windows-1252"}
+	res := transformDocument(fetcher.NewBytesResource(link, func() []byte {
+		return []byte("caf\xc3\xa9")
+	}), &mediatype.Text)
+	data, err := res.ReadAsString()
+	if assert.Nil(t, err) {
+		assert.Contains(t, data, "<p>cafÃ©</p>")
+	}
+}
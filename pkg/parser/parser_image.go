@@ -8,13 +8,14 @@ import (
 
 	"github.com/readium/go-toolkit/pkg/asset"
 	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/bitmap"
 	"github.com/readium/go-toolkit/pkg/internal/extensions"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/go-toolkit/pkg/mediatype"
 	"github.com/readium/go-toolkit/pkg/pub"
 )
 
-// Parses an image–based Publication from an unstructured archive format containing bitmap files, such as CBZ or a simple ZIP.
+// Parses an image–based Publication from an unstructured archive format containing bitmap files, such as CBZ, CBR, CB7 or a simple ZIP.
 // It can also work for a standalone bitmap file.
 type ImageParser struct{}
 
@@ -41,9 +42,9 @@ func (p ImageParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetcher
 		return nil, errors.New("no bitmap found in the publication")
 	}
 
-	// Sort in alphabetical order
+	// Sort in natural order
 	sort.Slice(readingOrder, func(i, j int) bool {
-		return readingOrder[i].Href < readingOrder[j].Href
+		return naturalHrefLess(readingOrder[i].Href, readingOrder[j].Href)
 	})
 
 	// Try to figure out the publication's title
@@ -52,16 +53,44 @@ func (p ImageParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetcher
 		title = asset.Name()
 	}
 
-	// First valid resource is the cover.
-	readingOrder[0].Rels = []string{"cover"}
+	metadata := manifest.Metadata{
+		LocalizedTitle: manifest.NewLocalizedStringFromString(title),
+		ConformsTo:     manifest.Profiles{manifest.ProfileDivina},
+	}
+
+	// First valid resource is the cover, unless the comic metadata says otherwise.
+	coverIndex := 0
+	var toc manifest.LinkList
+	var subcollections manifest.PublicationCollectionMap
+	if acbf := readACBF(fetcher, links); acbf != nil {
+		readingOrder = acbf.sortReadingOrder(readingOrder)
+		acbf.fillMetadata(&metadata)
+		toc = acbf.tableOfContents(readingOrder)
+		if guided := acbf.guidedNavigation(readingOrder); guided != nil {
+			subcollections = manifest.PublicationCollectionMap{
+				"guided": {{Links: guided}},
+			}
+		}
+	} else {
+		if info := readComicInfo(fetcher, links); info != nil {
+			info.fillMetadata(&metadata)
+			if i := info.coverIndex(); i >= 0 && i < len(readingOrder) {
+				coverIndex = i
+			}
+		}
+		toc = folderTableOfContents(readingOrder, nil)
+	}
+	readingOrder[coverIndex].Rels = []string{"cover"}
+
+	readBitmapDimensions(fetcher, readingOrder)
+	setPageSpreadProperties(readingOrder, metadata.ReadingProgression)
 
 	manifest := manifest.Manifest{
-		Context: manifest.Strings{manifest.WebpubManifestContext},
-		Metadata: manifest.Metadata{
-			LocalizedTitle: manifest.NewLocalizedStringFromString(title),
-			ConformsTo:     manifest.Profiles{manifest.ProfileDivina},
-		},
-		ReadingOrder: readingOrder,
+		Context:         manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:        metadata,
+		ReadingOrder:    readingOrder,
+		TableOfContents: toc,
+		Subcollections:  subcollections,
 	}
 
 	builder := pub.NewServicesBuilder(map[string]pub.ServiceFactory{
@@ -70,10 +99,59 @@ func (p ImageParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetcher
 	return pub.NewBuilder(manifest, fetcher, builder), nil
 }
 
+// Size of the beginning of a bitmap read at once, which is usually enough to find its dimensions.
+const bitmapHeaderPrefixSize = 64 * 1024
+
+// Reads the dimensions of each bitmap of the [readingOrder] from its header.
+func readBitmapDimensions(f fetcher.Fetcher, readingOrder manifest.LinkList) {
+	for i, link := range readingOrder {
+		res := f.Get(link)
+		prefix, rerr := res.Read(0, bitmapHeaderPrefixSize-1)
+		if rerr != nil {
+			res.Close()
+			continue
+		}
+		if header, err := bitmap.ReadHeader(prefixReaderAt{prefix, resourceReaderAt{res}}); err == nil {
+			readingOrder[i].Width = header.Width
+			readingOrder[i].Height = header.Height
+		}
+		res.Close()
+	}
+}
+
+// Infers how the pages are laid out in synthetic spreads, as in a printed book: the first page is displayed alone
+// like a cover, and the next pages alternate between the two sides of a spread. Wide images are double-page spreads,
+// displayed alone in the center.
+func setPageSpreadProperties(readingOrder manifest.LinkList, progression manifest.ReadingProgression) {
+	leading, trailing := manifest.PageLeft, manifest.PageRight
+	if progression == manifest.RTL {
+		leading, trailing = manifest.PageRight, manifest.PageLeft
+	}
+
+	next := trailing
+	for i := range readingOrder {
+		link := &readingOrder[i]
+		properties := manifest.Properties{}
+		if link.Width > link.Height {
+			properties["page"] = string(manifest.PageCenter)
+			properties["spread"] = string(manifest.SpreadNone)
+			next = leading // The next page starts a new spread.
+		} else {
+			properties["page"] = string(next)
+			if next == leading {
+				next = trailing
+			} else {
+				next = leading
+			}
+		}
+		link.Properties.Add(properties)
+	}
+}
+
 var allowed_extensions_image = map[string]struct{}{"acbf": {}, "xml": {}, "txt": {}}
 
 func (p ImageParser) accepts(asset asset.PublicationAsset, fetcher fetcher.Fetcher) bool {
-	if asset.MediaType().Equal(&mediatype.CBZ) {
+	if asset.MediaType().Matches(&mediatype.CBZ, &mediatype.CBR, &mediatype.CB7) {
 		return true
 	}
 	links, err := fetcher.Links()
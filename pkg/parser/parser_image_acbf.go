@@ -0,0 +1,409 @@
+package parser
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// A comic book described by an ACBF (Advanced Comic Book Format) document, with its metadata and the panels of its pages.
+// Reference: https://acbf.fandom.com/wiki/Advanced_Comic_Book_Format_Wiki
+type acbfDocument struct {
+	Titles           map[string]string // Indexed by language.
+	Authors          []acbfAuthor
+	Genres           []string
+	Annotation       string
+	Languages        []string
+	SeriesTitle      string
+	SeriesNumber     string
+	ReadingDirection string
+	Publisher        string
+	PublishDate      string
+	ISBN             string
+	Cover            *acbfPage
+	Pages            []acbfPage
+}
+
+type acbfAuthor struct {
+	Name     string
+	Activity string // e.g. Writer, Penciller, Inker, Colorist
+}
+
+type acbfPage struct {
+	Href   string            // Absolute HREF of the image in the archive.
+	Titles map[string]string // Indexed by language.
+	Frames []acbfFrame
+}
+
+// Bounding box of a panel, in pixels.
+type acbfFrame struct {
+	X, Y, Width, Height int
+}
+
+// Finds the ACBF document in the [links] of a comic archive and parses it, if any.
+func readACBF(f fetcher.Fetcher, links manifest.LinkList) *acbfDocument {
+	for _, link := range links {
+		if strings.ToLower(path.Ext(link.Href)) != ".acbf" {
+			continue
+		}
+		document, err := f.Get(link).ReadAsXML(nil)
+		if err != nil {
+			// TODO log
+			return nil
+		}
+		return parseACBF(document, link.Href)
+	}
+	return nil
+}
+
+// Selects a child element by its local name, as the ACBF namespace changed across versions.
+func acbfSelect(name string) string {
+	return "*[local-name()='" + name + "']"
+}
+
+func parseACBF(document *xmlquery.Node, href string) *acbfDocument {
+	root := document.SelectElement(acbfSelect("ACBF"))
+	if root == nil {
+		return nil
+	}
+	acbf := &acbfDocument{
+		Titles: make(map[string]string),
+	}
+
+	if info := root.SelectElement(acbfSelect("meta-data") + "/" + acbfSelect("book-info")); info != nil {
+		for _, el := range info.SelectElements(acbfSelect("book-title")) {
+			acbf.Titles[el.SelectAttr("lang")] = strings.TrimSpace(el.InnerText())
+		}
+		for _, el := range info.SelectElements(acbfSelect("author")) {
+			if name := acbfAuthorName(el); name != "" {
+				acbf.Authors = append(acbf.Authors, acbfAuthor{
+					Name:     name,
+					Activity: el.SelectAttr("activity"),
+				})
+			}
+		}
+		for _, el := range info.SelectElements(acbfSelect("genre")) {
+			if genre := strings.TrimSpace(el.InnerText()); genre != "" {
+				acbf.Genres = append(acbf.Genres, genre)
+			}
+		}
+		if el := info.SelectElement(acbfSelect("annotation")); el != nil {
+			var paragraphs []string
+			for _, p := range el.SelectElements(acbfSelect("p")) {
+				paragraphs = append(paragraphs, strings.TrimSpace(p.InnerText()))
+			}
+			if len(paragraphs) == 0 {
+				paragraphs = append(paragraphs, strings.TrimSpace(el.InnerText()))
+			}
+			acbf.Annotation = strings.Join(paragraphs, "\n")
+		}
+		for _, el := range info.SelectElements(acbfSelect("languages") + "/" + acbfSelect("text-layer")) {
+			lang := el.SelectAttr("lang")
+			if lang != "" && !extensions.Contains(acbf.Languages, lang) {
+				acbf.Languages = append(acbf.Languages, lang)
+			}
+		}
+		if el := info.SelectElement(acbfSelect("sequence")); el != nil {
+			acbf.SeriesTitle = el.SelectAttr("title")
+			acbf.SeriesNumber = strings.TrimSpace(el.InnerText())
+		}
+		if el := info.SelectElement(acbfSelect("reading-direction")); el != nil {
+			acbf.ReadingDirection = strings.TrimSpace(el.InnerText())
+		}
+		if el := info.SelectElement(acbfSelect("coverpage")); el != nil {
+			acbf.Cover = parseACBFPage(el, href)
+		}
+	}
+
+	if info := root.SelectElement(acbfSelect("meta-data") + "/" + acbfSelect("publish-info")); info != nil {
+		if el := info.SelectElement(acbfSelect("publisher")); el != nil {
+			acbf.Publisher = strings.TrimSpace(el.InnerText())
+		}
+		if el := info.SelectElement(acbfSelect("publish-date")); el != nil {
+			acbf.PublishDate = el.SelectAttr("value")
+			if acbf.PublishDate == "" {
+				acbf.PublishDate = strings.TrimSpace(el.InnerText())
+			}
+		}
+		if el := info.SelectElement(acbfSelect("isbn")); el != nil {
+			acbf.ISBN = strings.TrimSpace(el.InnerText())
+		}
+	}
+
+	for _, el := range root.SelectElements(acbfSelect("body") + "/" + acbfSelect("page")) {
+		if page := parseACBFPage(el, href); page != nil {
+			acbf.Pages = append(acbf.Pages, *page)
+		}
+	}
+
+	return acbf
+}
+
+func acbfAuthorName(el *xmlquery.Node) string {
+	var parts []string
+	for _, name := range []string{"first-name", "middle-name", "last-name"} {
+		if part := el.SelectElement(acbfSelect(name)); part != nil {
+			if text := strings.TrimSpace(part.InnerText()); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		if nickname := el.SelectElement(acbfSelect("nickname")); nickname != nil {
+			return strings.TrimSpace(nickname.InnerText())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Parses a page or the cover page, whose image is resolved relatively to the ACBF document at [href].
+func parseACBFPage(el *xmlquery.Node, href string) *acbfPage {
+	image := el.SelectElement(acbfSelect("image"))
+	if image == nil {
+		return nil
+	}
+	imageHref := image.SelectAttr("href")
+	if imageHref == "" {
+		// Although the attribute is supposed to be xlink:href, some files use another prefix.
+		for _, attr := range image.Attr {
+			if attr.Name.Local == "href" {
+				imageHref = attr.Value
+			}
+		}
+	}
+	if imageHref == "" || strings.HasPrefix(imageHref, "#") {
+		return nil // Images embedded in the ACBF document are not supported.
+	}
+
+	page := &acbfPage{
+		Href:   path.Join(path.Dir(href), imageHref),
+		Titles: make(map[string]string),
+	}
+	for _, title := range el.SelectElements(acbfSelect("title")) {
+		page.Titles[title.SelectAttr("lang")] = strings.TrimSpace(title.InnerText())
+	}
+	for _, frame := range el.SelectElements(acbfSelect("frame")) {
+		if f, ok := parseACBFFrame(frame.SelectAttr("points")); ok {
+			page.Frames = append(page.Frames, f)
+		}
+	}
+	return page
+}
+
+// Computes the bounding box of a polygon, given as a list of points such as `0,0 100,0 100,50 0,50`.
+func parseACBFFrame(points string) (acbfFrame, bool) {
+	var minX, minY, maxX, maxY int
+	count := 0
+	for _, point := range strings.Fields(points) {
+		coords := strings.SplitN(point, ",", 2)
+		if len(coords) != 2 {
+			return acbfFrame{}, false
+		}
+		x, errx := strconv.Atoi(coords[0])
+		y, erry := strconv.Atoi(coords[1])
+		if errx != nil || erry != nil {
+			return acbfFrame{}, false
+		}
+		if count == 0 || x < minX {
+			minX = x
+		}
+		if count == 0 || y < minY {
+			minY = y
+		}
+		if count == 0 || x > maxX {
+			maxX = x
+		}
+		if count == 0 || y > maxY {
+			maxY = y
+		}
+		count++
+	}
+	if count < 2 || maxX == minX || maxY == minY {
+		return acbfFrame{}, false
+	}
+	return acbfFrame{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}, true
+}
+
+// Cover and pages, in reading order.
+func (a acbfDocument) allPages() []acbfPage {
+	if a.Cover == nil {
+		return a.Pages
+	}
+	pages := make([]acbfPage, 0, len(a.Pages)+1)
+	pages = append(pages, *a.Cover)
+	for _, page := range a.Pages {
+		if page.Href != a.Cover.Href {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// Sorts the [readingOrder] in the order of the ACBF pages.
+// The images which are not referenced by the ACBF document are kept after the pages, in their original order.
+func (a acbfDocument) sortReadingOrder(readingOrder manifest.LinkList) manifest.LinkList {
+	sorted := make(manifest.LinkList, 0, len(readingOrder))
+	used := make(map[string]bool)
+	for _, page := range a.allPages() {
+		if used[page.Href] {
+			continue
+		}
+		if link := readingOrder.IndexOfFirstWithHref(page.Href); link >= 0 {
+			sorted = append(sorted, readingOrder[link])
+			used[page.Href] = true
+		}
+	}
+	for _, link := range readingOrder {
+		if !used[link.Href] {
+			sorted = append(sorted, link)
+		}
+	}
+	return sorted
+}
+
+// Table of contents made of the pages with a title.
+func (a acbfDocument) tableOfContents(readingOrder manifest.LinkList) manifest.LinkList {
+	var toc manifest.LinkList
+	for _, page := range a.allPages() {
+		title := a.localized(page.Titles)
+		if title == "" {
+			continue
+		}
+		if i := readingOrder.IndexOfFirstWithHref(page.Href); i >= 0 {
+			toc = append(toc, manifest.Link{
+				Href:  page.Href,
+				Type:  readingOrder[i].Type,
+				Title: title,
+			})
+		}
+	}
+	return toc
+}
+
+// Divina guided navigation, going through each page and then its panels, using media fragments.
+// Returns nil when no page has panels.
+// Reference: https://readium.org/webpub-manifest/profiles/divina.html
+func (a acbfDocument) guidedNavigation(readingOrder manifest.LinkList) manifest.LinkList {
+	var guided manifest.LinkList
+	hasFrames := false
+	for _, page := range a.allPages() {
+		i := readingOrder.IndexOfFirstWithHref(page.Href)
+		if i < 0 {
+			continue
+		}
+		guided = append(guided, manifest.Link{
+			Href: page.Href,
+			Type: readingOrder[i].Type,
+		})
+		for _, frame := range page.Frames {
+			hasFrames = true
+			guided = append(guided, manifest.Link{
+				Href: page.Href + "#xywh=" + strconv.Itoa(frame.X) + "," + strconv.Itoa(frame.Y) + "," + strconv.Itoa(frame.Width) + "," + strconv.Itoa(frame.Height),
+				Type: readingOrder[i].Type,
+			})
+		}
+	}
+	if !hasFrames {
+		return nil
+	}
+	return guided
+}
+
+// Picks the translation in the main language of the comic, or any other one.
+func (a acbfDocument) localized(translations map[string]string) string {
+	if len(a.Languages) > 0 {
+		if t, ok := translations[a.Languages[0]]; ok && t != "" {
+			return t
+		}
+	}
+	if t, ok := translations[""]; ok && t != "" {
+		return t
+	}
+	best := ""
+	for lang, t := range translations { // Deterministic choice among the remaining languages.
+		if t != "" && (best == "" || lang < best) {
+			best = lang
+		}
+	}
+	return translations[best]
+}
+
+// Fills the publication [metadata] with the information available in the ACBF document.
+func (a acbfDocument) fillMetadata(metadata *manifest.Metadata) {
+	titles := make(map[string]string)
+	for lang, title := range a.Titles {
+		if title != "" {
+			titles[lang] = title
+		}
+	}
+	if len(titles) > 0 {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromStrings(titles)
+	}
+	metadata.Description = a.Annotation
+	if len(a.Languages) > 0 {
+		metadata.Languages = a.Languages
+	}
+	if strings.EqualFold(a.ReadingDirection, "RTL") {
+		metadata.ReadingProgression = manifest.RTL
+	}
+	if a.ISBN != "" {
+		metadata.Identifier = "urn:isbn:" + a.ISBN
+	}
+	metadata.Published = extensions.ParseDate(a.PublishDate)
+
+	if a.SeriesTitle != "" {
+		series := manifest.Collection{
+			LocalizedName: manifest.NewLocalizedStringFromString(a.SeriesTitle),
+		}
+		if position, err := strconv.ParseFloat(a.SeriesNumber, 64); err == nil {
+			series.Position = &position
+		}
+		if metadata.BelongsTo == nil {
+			metadata.BelongsTo = make(map[string]manifest.Collections)
+		}
+		metadata.BelongsTo["series"] = append(metadata.BelongsTo["series"], series)
+	}
+
+	for _, author := range a.Authors {
+		contributor := manifest.Contributor{
+			LocalizedName: manifest.NewLocalizedStringFromString(author.Name),
+		}
+		switch author.Activity {
+		case "", "Writer", "Adapter":
+			metadata.Authors = append(metadata.Authors, contributor)
+		case "Artist", "CoverArtist", "Photographer":
+			metadata.Artists = append(metadata.Artists, contributor)
+		case "Penciller":
+			metadata.Pencilers = append(metadata.Pencilers, contributor)
+		case "Inker":
+			metadata.Inkers = append(metadata.Inkers, contributor)
+		case "Colorist":
+			metadata.Colorists = append(metadata.Colorists, contributor)
+		case "Letterer":
+			metadata.Letterers = append(metadata.Letterers, contributor)
+		case "Editor", "Assistant Editor":
+			metadata.Editors = append(metadata.Editors, contributor)
+		case "Translator":
+			metadata.Translators = append(metadata.Translators, contributor)
+		default:
+			contributor.Roles = manifest.Strings{author.Activity}
+			metadata.Contributors = append(metadata.Contributors, contributor)
+		}
+	}
+	if a.Publisher != "" {
+		metadata.Publishers = append(metadata.Publishers, manifest.Contributor{
+			LocalizedName: manifest.NewLocalizedStringFromString(a.Publisher),
+		})
+	}
+
+	for _, genre := range a.Genres {
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(genre),
+		})
+	}
+}
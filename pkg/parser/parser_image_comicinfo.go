@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// Metadata of a comic book archive, from a ComicInfo.xml file as written by ComicRack and most comic managers.
+// Reference: https://anansi-project.github.io/docs/comicinfo/documentation
+type comicInfo struct {
+	Title        string
+	Series       string
+	Number       string
+	Summary      string
+	Year         int
+	Month        int
+	Day          int
+	Writers      []string
+	Pencillers   []string
+	Inkers       []string
+	Colorists    []string
+	Letterers    []string
+	CoverArtists []string
+	Editors      []string
+	Translators  []string
+	Publisher    string
+	Imprint      string
+	Genres       []string
+	LanguageISO  string
+	PageCount    uint
+	Manga        string
+	Pages        []comicInfoPage
+}
+
+type comicInfoPage struct {
+	Image int    // Index of the page in the archive's images.
+	Type  string // e.g. FrontCover, Story, Advertisement
+}
+
+// Finds the ComicInfo.xml file in the [links] of a comic archive and parses it, if any.
+func readComicInfo(f fetcher.Fetcher, links manifest.LinkList) *comicInfo {
+	var infoLink *manifest.Link
+	for i, link := range links {
+		if !strings.EqualFold(path.Base(link.Href), "ComicInfo.xml") {
+			continue
+		}
+		// Prefer the file closest to the root of the archive.
+		if infoLink == nil || strings.Count(link.Href, "/") < strings.Count(infoLink.Href, "/") {
+			infoLink = &links[i]
+		}
+	}
+	if infoLink == nil {
+		return nil
+	}
+
+	document, err := f.Get(*infoLink).ReadAsXML(nil)
+	if err != nil {
+		// TODO log
+		return nil
+	}
+	return parseComicInfo(document)
+}
+
+func parseComicInfo(document *xmlquery.Node) *comicInfo {
+	root := document.SelectElement("ComicInfo")
+	if root == nil {
+		return nil
+	}
+	text := func(name string) string {
+		el := root.SelectElement(name)
+		if el == nil {
+			return ""
+		}
+		return strings.TrimSpace(el.InnerText())
+	}
+	number := func(name string) int {
+		n, _ := strconv.Atoi(text(name))
+		return n
+	}
+	list := func(name string) []string {
+		var values []string
+		for _, value := range strings.Split(text(name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	info := &comicInfo{
+		Title:        text("Title"),
+		Series:       text("Series"),
+		Number:       text("Number"),
+		Summary:      text("Summary"),
+		Year:         number("Year"),
+		Month:        number("Month"),
+		Day:          number("Day"),
+		Writers:      list("Writer"),
+		Pencillers:   list("Penciller"),
+		Inkers:       list("Inker"),
+		Colorists:    list("Colorist"),
+		Letterers:    list("Letterer"),
+		CoverArtists: list("CoverArtist"),
+		Editors:      list("Editor"),
+		Translators:  list("Translator"),
+		Publisher:    text("Publisher"),
+		Imprint:      text("Imprint"),
+		Genres:       list("Genre"),
+		LanguageISO:  text("LanguageISO"),
+		Manga:        text("Manga"),
+	}
+	if pageCount := number("PageCount"); pageCount > 0 {
+		info.PageCount = uint(pageCount)
+	}
+
+	if pages := root.SelectElement("Pages"); pages != nil {
+		for _, page := range pages.SelectElements("Page") {
+			image, err := strconv.Atoi(page.SelectAttr("Image"))
+			if err != nil {
+				continue
+			}
+			info.Pages = append(info.Pages, comicInfoPage{
+				Image: image,
+				Type:  page.SelectAttr("Type"),
+			})
+		}
+	}
+
+	return info
+}
+
+// Index of the page marked as the front cover, or -1.
+func (c comicInfo) coverIndex() int {
+	for _, page := range c.Pages {
+		if page.Type == "FrontCover" {
+			return page.Image
+		}
+	}
+	return -1
+}
+
+// Fills the publication [metadata] with the information available in the ComicInfo.xml file.
+func (c comicInfo) fillMetadata(metadata *manifest.Metadata) {
+	if c.Title != "" {
+		metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(c.Title)
+	}
+	metadata.Description = c.Summary
+	if c.LanguageISO != "" {
+		metadata.Languages = manifest.Strings{c.LanguageISO}
+	}
+	if c.PageCount > 0 {
+		pageCount := c.PageCount
+		metadata.NumberOfPages = &pageCount
+	}
+	if c.Manga == "YesAndRightToLeft" {
+		metadata.ReadingProgression = manifest.RTL
+	}
+	if c.Year > 0 {
+		month, day := c.Month, c.Day
+		if month < 1 || month > 12 {
+			month = 1
+		}
+		if day < 1 || day > 31 {
+			day = 1
+		}
+		published := time.Date(c.Year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		metadata.Published = &published
+	}
+
+	if c.Series != "" {
+		series := manifest.Collection{
+			LocalizedName: manifest.NewLocalizedStringFromString(c.Series),
+		}
+		if position, err := strconv.ParseFloat(c.Number, 64); err == nil {
+			series.Position = &position
+		}
+		if metadata.BelongsTo == nil {
+			metadata.BelongsTo = make(map[string]manifest.Collections)
+		}
+		metadata.BelongsTo["series"] = append(metadata.BelongsTo["series"], series)
+	}
+
+	contributors := func(names []string) manifest.Contributors {
+		var contributors manifest.Contributors
+		for _, name := range names {
+			contributors = append(contributors, manifest.Contributor{
+				LocalizedName: manifest.NewLocalizedStringFromString(name),
+			})
+		}
+		return contributors
+	}
+	metadata.Authors = append(metadata.Authors, contributors(c.Writers)...)
+	metadata.Pencilers = append(metadata.Pencilers, contributors(c.Pencillers)...)
+	metadata.Inkers = append(metadata.Inkers, contributors(c.Inkers)...)
+	metadata.Colorists = append(metadata.Colorists, contributors(c.Colorists)...)
+	metadata.Letterers = append(metadata.Letterers, contributors(c.Letterers)...)
+	metadata.Artists = append(metadata.Artists, contributors(c.CoverArtists)...)
+	metadata.Editors = append(metadata.Editors, contributors(c.Editors)...)
+	metadata.Translators = append(metadata.Translators, contributors(c.Translators)...)
+	if c.Publisher != "" {
+		metadata.Publishers = append(metadata.Publishers, contributors([]string{c.Publisher})...)
+	}
+	if c.Imprint != "" {
+		metadata.Imprints = append(metadata.Imprints, contributors([]string{c.Imprint})...)
+	}
+
+	for _, genre := range c.Genres {
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(genre),
+		})
+	}
+}
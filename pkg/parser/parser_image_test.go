@@ -87,3 +87,231 @@ func TestImageTitleBasedOnRoot(t *testing.T) {
 		)
 	})
 }
+
+func TestImageCBRAndCB7SameAsCBZ(t *testing.T) {
+	var expected manifest.Manifest
+	withImageParser(t, "./testdata/image/futuristic_tales.cbz", func(p *pub.Builder) {
+		expected = p.Build().Manifest
+	})
+
+	for _, path := range []string{"./testdata/image/futuristic_tales.cbr", "./testdata/image/futuristic_tales.cb7"} {
+		withImageParser(t, path, func(p *pub.Builder) {
+			if !assert.NotNil(t, p, path) {
+				return
+			}
+			pub := p.Build()
+			assert.Equal(t, expected.Metadata.Title(), pub.Manifest.Metadata.Title(), path)
+			assert.Equal(t, expected.Metadata.ConformsTo, pub.Manifest.Metadata.ConformsTo, path)
+			if assert.Len(t, pub.Manifest.ReadingOrder, len(expected.ReadingOrder), path) {
+				for i, link := range pub.Manifest.ReadingOrder {
+					assert.Equal(t, expected.ReadingOrder[i].Href, link.Href, path)
+					assert.Equal(t, expected.ReadingOrder[i].Type, link.Type, path)
+					assert.Equal(t, expected.ReadingOrder[i].Rels, link.Rels, path)
+				}
+			}
+
+			// Reads the last page, then the cover, to make sure the archive can be read in any order.
+			for _, link := range (manifest.LinkList{pub.Manifest.ReadingOrder[3], pub.Manifest.ReadingOrder[0]}) {
+				data, err := pub.Get(link).Read(0, 0)
+				if assert.Nil(t, err, path) {
+					assert.Equal(t, []byte{0xFF, 0xD8, 0xFF}, data[:3], path)
+				}
+			}
+		})
+	}
+}
+
+func TestImageComicInfoMetadata(t *testing.T) {
+	withImageParser(t, "./testdata/image/comicinfo.cbz", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		pub := p.Build()
+		metadata := pub.Manifest.Metadata
+
+		assert.Equal(t, "The Voyage", metadata.Title())
+		assert.Equal(t, "A voyage & its perils.", metadata.Description)
+		assert.Equal(t, manifest.Strings{"ja"}, metadata.Languages)
+		assert.Equal(t, manifest.RTL, metadata.ReadingProgression)
+		if assert.NotNil(t, metadata.NumberOfPages) {
+			assert.EqualValues(t, 3, *metadata.NumberOfPages)
+		}
+		if assert.NotNil(t, metadata.Published) {
+			assert.Equal(t, "2021-03-01", metadata.Published.Format("2006-01-02"))
+		}
+
+		series := metadata.BelongsToSeries()
+		if assert.Len(t, series, 1) {
+			assert.Equal(t, "Space Tales", series[0].Name())
+			if assert.NotNil(t, series[0].Position) {
+				assert.Equal(t, 2.0, *series[0].Position)
+			}
+		}
+
+		names := func(contributors manifest.Contributors) []string {
+			var names []string
+			for _, c := range contributors {
+				names = append(names, c.Name())
+			}
+			return names
+		}
+		assert.Equal(t, []string{"Jane Doe", "John Smith"}, names(metadata.Authors))
+		assert.Equal(t, []string{"Alex Pen"}, names(metadata.Pencilers))
+		assert.Equal(t, []string{"Ina Ink"}, names(metadata.Inkers))
+		assert.Equal(t, []string{"Comics Inc."}, names(metadata.Publishers))
+		if assert.Len(t, metadata.Subjects, 2) {
+			assert.Equal(t, "Science Fiction", metadata.Subjects[0].Name())
+			assert.Equal(t, "Adventure", metadata.Subjects[1].Name())
+		}
+
+		coverItem := pub.Manifest.ReadingOrder.FirstWithRel("cover")
+		if assert.NotNil(t, coverItem) {
+			assert.Equal(t, "/page-02.png", coverItem.Href, "the FrontCover page should be the cover")
+		}
+		assert.Empty(t, pub.Manifest.ReadingOrder[0].Rels)
+	})
+}
+
+func TestImageACBFMetadata(t *testing.T) {
+	withImageParser(t, "./testdata/image/acbf.cbz", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		pub := p.Build()
+		metadata := pub.Manifest.Metadata
+
+		assert.Equal(t, manifest.NewLocalizedStringFromStrings(map[string]string{
+			"en": "The Panels", "fr": "Les Cases",
+		}), metadata.LocalizedTitle)
+		assert.Equal(t, "First paragraph.\nSecond paragraph.", metadata.Description)
+		assert.Equal(t, manifest.Strings{"en", "fr"}, metadata.Languages)
+		assert.Equal(t, manifest.RTL, metadata.ReadingProgression)
+		assert.Equal(t, "urn:isbn:9780000000001", metadata.Identifier)
+		if assert.NotNil(t, metadata.Published) {
+			assert.Equal(t, "2019-05-04", metadata.Published.Format("2006-01-02"))
+		}
+		if series := metadata.BelongsToSeries(); assert.Len(t, series, 1) {
+			assert.Equal(t, "Panel Stories", series[0].Name())
+			assert.Equal(t, 3.0, *series[0].Position)
+		}
+		if assert.Len(t, metadata.Authors, 1) {
+			assert.Equal(t, "Jane Doe", metadata.Authors[0].Name())
+		}
+		if assert.Len(t, metadata.Colorists, 1) {
+			assert.Equal(t, "Hue", metadata.Colorists[0].Name())
+		}
+		if assert.Len(t, metadata.Artists, 1) {
+			assert.Equal(t, "Pat Photo", metadata.Artists[0].Name())
+		}
+		if assert.Len(t, metadata.Contributors, 1) {
+			assert.Equal(t, manifest.Strings{"Other"}, metadata.Contributors[0].Roles)
+		}
+		if assert.Len(t, metadata.Publishers, 1) {
+			assert.Equal(t, "Frames Press", metadata.Publishers[0].Name())
+		}
+		if assert.Len(t, metadata.Subjects, 1) {
+			assert.Equal(t, "science_fiction", metadata.Subjects[0].Name())
+		}
+	})
+}
+
+func TestImageACBFNavigation(t *testing.T) {
+	withImageParser(t, "./testdata/image/acbf.cbz", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		pub := p.Build()
+
+		hrefs := make([]string, 0, len(pub.Manifest.ReadingOrder))
+		for _, link := range pub.Manifest.ReadingOrder {
+			hrefs = append(hrefs, link.Href)
+		}
+		assert.Equal(t, []string{"/zz-cover.png", "/p1.png", "/p2.png"}, hrefs, "readingOrder should follow the ACBF pages")
+		assert.Equal(t, manifest.Strings{"cover"}, pub.Manifest.ReadingOrder[0].Rels)
+
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/p1.png", Type: "image/png", Title: "Chapter 1"},
+		}, pub.Manifest.TableOfContents)
+
+		if guided := pub.Manifest.Subcollections["guided"]; assert.Len(t, guided, 1) {
+			assert.Equal(t, []manifest.Link{
+				{Href: "/zz-cover.png", Type: "image/png"},
+				{Href: "/p1.png", Type: "image/png"},
+				{Href: "/p1.png#xywh=0,0,40,30", Type: "image/png"},
+				{Href: "/p1.png#xywh=40,0,40,32", Type: "image/png"},
+				{Href: "/p2.png", Type: "image/png"},
+			}, guided[0].Links)
+		}
+	})
+}
+
+func TestImageReadingOrderNatural(t *testing.T) {
+	withImageParser(t, "./testdata/image/chapters.cbz", func(p *pub.Builder) {
+		pub := p.Build()
+		hrefs := make([]string, 0, len(pub.Manifest.ReadingOrder))
+		for _, roi := range pub.Manifest.ReadingOrder {
+			hrefs = append(hrefs, roi.Href)
+		}
+		assert.Equal(t, []string{
+			"/Comic/01 - Chapter_1/page1.png",
+			"/Comic/01 - Chapter_1/page2.png",
+			"/Comic/Chapter 2/02_Second_Part/page9.png",
+			"/Comic/Chapter 2/02_Second_Part/page10.png",
+			"/Comic/Chapter 2/page1.png",
+			"/Comic/Chapter 10/page1.png",
+			"/Comic/cover.png",
+		}, hrefs)
+	})
+}
+
+func TestImageTableOfContentsFromFolders(t *testing.T) {
+	withImageParser(t, "./testdata/image/chapters.cbz", func(p *pub.Builder) {
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/Comic/01 - Chapter_1/page1.png", Title: "Chapter 1"},
+			{Href: "/Comic/Chapter 2/02_Second_Part/page9.png", Title: "Chapter 2", Children: manifest.LinkList{
+				{Href: "/Comic/Chapter 2/02_Second_Part/page9.png", Title: "Second Part"},
+			}},
+			{Href: "/Comic/Chapter 10/page1.png", Title: "Chapter 10"},
+		}, p.Build().Manifest.TableOfContents)
+	})
+
+	// Without subfolders, there's no table of contents.
+	withImageParser(t, "./testdata/image/futuristic_tales.cbz", func(p *pub.Builder) {
+		assert.Empty(t, p.Build().Manifest.TableOfContents)
+	})
+}
+
+func TestImageDimensionsAndPageSpreads(t *testing.T) {
+	withImageParser(t, "./testdata/image/futuristic_tales.cbz", func(p *pub.Builder) {
+		cover := p.Build().Manifest.ReadingOrder[0]
+		assert.Equal(t, uint(654), cover.Width)
+		assert.Equal(t, uint(1040), cover.Height)
+	})
+
+	withImageParser(t, "./testdata/image/spreads.cbz", func(p *pub.Builder) {
+		ro := p.Build().Manifest.ReadingOrder
+		if assert.Len(t, ro, 4) {
+			assert.Equal(t, uint(300), ro[2].Width)
+			assert.Equal(t, uint(150), ro[2].Height)
+
+			assert.Equal(t, manifest.PageRight, ro[0].Properties.Page())
+			assert.Equal(t, manifest.PageLeft, ro[1].Properties.Page())
+			assert.Equal(t, manifest.PageCenter, ro[2].Properties.Page())
+			assert.Equal(t, manifest.SpreadNone, ro[2].Properties.Spread())
+			// The page following a double-page spread starts a new spread.
+			assert.Equal(t, manifest.PageLeft, ro[3].Properties.Page())
+		}
+	})
+}
+
+func TestImagePageSpreadsRTL(t *testing.T) {
+	ro := manifest.LinkList{
+		{Href: "1.jpg", Width: 100, Height: 150},
+		{Href: "2.jpg"},
+		{Href: "3.jpg", Width: 100, Height: 150},
+	}
+	setPageSpreadProperties(ro, manifest.RTL)
+	assert.Equal(t, manifest.PageLeft, ro[0].Properties.Page())
+	assert.Equal(t, manifest.PageRight, ro[1].Properties.Page())
+	assert.Equal(t, manifest.PageLeft, ro[2].Properties.Page())
+}
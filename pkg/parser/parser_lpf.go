@@ -0,0 +1,431 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/readium/go-toolkit/pkg/util"
+)
+
+// Conformance URL of the W3C Audiobooks profile.
+const w3cAudiobooksProfile = "https://www.w3.org/TR/audiobooks/"
+
+// Parses a W3C Publication Manifest, either packaged in a Lightweight Packaging Format (LPF) archive or standalone,
+// and converts it into a Readium Web Publication Manifest. W3C Audiobooks are given the audiobook profile.
+// References:
+//   - https://www.w3.org/TR/pub-manifest/
+//   - https://www.w3.org/TR/audiobooks/
+//   - https://www.w3.org/TR/lpf/
+type LPFParser struct {
+	client *http.Client
+}
+
+// NewLPFParser creates a parser serving the resources of remote standalone manifests with the given HTTP client.
+// When client is nil, http.DefaultClient is used.
+func NewLPFParser(client *http.Client) LPFParser {
+	return LPFParser{client: client}
+}
+
+// Parse implements PublicationParser
+func (p LPFParser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	mediaType := asset.MediaType()
+	if !mediaType.Matches(&mediatype.LPF, &mediatype.W3CWPUBManifest) {
+		return nil, nil
+	}
+
+	links, err := f.Links()
+	if err != nil {
+		return nil, err
+	}
+
+	var manifestJSON map[string]interface{}
+	var manifestHref string
+	if mediaType.Equal(&mediatype.LPF) {
+		manifestJSON, manifestHref, err = readLPFManifest(f, links)
+	} else {
+		// For a single manifest file, reads the first (and only) file in the fetcher.
+		if len(links) == 0 {
+			return nil, errors.New("links is empty")
+		}
+		manifestHref = links[0].Href
+		js, rerr := f.Get(links[0]).ReadAsJSON()
+		if rerr != nil {
+			return nil, errors.Wrap(rerr, "failed reading W3C Publication Manifest")
+		}
+		manifestJSON = js
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading W3C Publication Manifest")
+	}
+
+	w := w3cManifest{
+		json:     manifestJSON,
+		href:     manifestHref,
+		packaged: links,
+	}
+	m := w.toManifest()
+	if len(m.ReadingOrder) == 0 {
+		return nil, errors.New("W3C Publication Manifest has an empty readingOrder")
+	}
+
+	// A standalone manifest fetched over HTTP references remote resources, which are served with an [HTTPFetcher]
+	// instead of the fetcher that was only used to read the manifest file.
+	if !mediaType.Equal(&mediatype.LPF) && isHTTPURL(manifestHref) {
+		f, err = fetcher.NewHTTPFetcher(p.client, manifestHref, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed creating the HTTP fetcher")
+		}
+	}
+
+	// The table of contents is found in the resource with the "contents" relation, or in the primary entry page.
+	tocLink := m.Resources.FirstWithRel("contents")
+	if tocLink == nil {
+		tocLink = m.ReadingOrder.FirstWithRel("contents")
+	}
+	if tocLink == nil && mediaType.Equal(&mediatype.LPF) {
+		tocLink = links.FirstWithHref("/index.html")
+	}
+	if tocLink != nil {
+		m.TableOfContents = readHTMLTableOfContents(f, *tocLink)
+	}
+
+	var services *pub.ServicesBuilder
+	if m.ConformsTo(manifest.ProfileAudiobook) {
+		services = pub.NewServicesBuilder(map[string]pub.ServiceFactory{
+			pub.PositionsService_Name: pub.AudioPositionsServiceFactory(pub.DefaultAudioPositionsInterval, "audio/*"),
+			pub.LocatorService_Name:   pub.AudioLocatorServiceFactory(),
+		})
+	}
+
+	return pub.NewBuilder(m, f, services), nil
+}
+
+// Reads the manifest of an LPF package, which is either the publication.json file at the root of the archive,
+// or embedded in the primary entry page (index.html).
+func readLPFManifest(f fetcher.Fetcher, links manifest.LinkList) (map[string]interface{}, string, error) {
+	if link := links.FirstWithHref("/publication.json"); link != nil {
+		js, err := f.Get(*link).ReadAsJSON()
+		if err != nil {
+			return nil, "", err
+		}
+		return js, link.Href, nil
+	}
+	if link := links.FirstWithHref("/index.html"); link != nil {
+		data, err := f.Get(*link).Read(0, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		js, perr := parseEmbeddedPublicationManifest(data)
+		return js, link.Href, perr
+	}
+	return nil, "", errors.New("no publication.json or index.html in the LPF package")
+}
+
+// A W3C Publication Manifest, as parsed from its JSON-LD representation.
+type w3cManifest struct {
+	json     map[string]interface{}
+	href     string            // HREF of the manifest, used to resolve relative URLs.
+	packaged manifest.LinkList // Links of the package's fetcher, used to find the media types of the resources.
+	language string            // Default language declared in the @context.
+}
+
+func (w *w3cManifest) toManifest() manifest.Manifest {
+	w.language, _ = w.contextValue("language").(string)
+
+	metadata := manifest.Metadata{
+		LocalizedTitle: w.localizedString(w.json["name"]),
+		Description:    w.string("description"),
+		Published:      extensions.ParseDate(w.string("datePublished")),
+		Modified:       extensions.ParseDate(w.string("dateModified")),
+		Authors:        w.contributors("author"),
+		Narrators:      w.contributors("readBy"),
+		Editors:        w.contributors("editor"),
+		Translators:    w.contributors("translator"),
+		Artists:        w.contributors("artist"),
+		Illustrators:   w.contributors("illustrator"),
+		Colorists:      w.contributors("colorist"),
+		Inkers:         w.contributors("inker"),
+		Letterers:      w.contributors("letterer"),
+		Pencilers:      w.contributors("penciler"),
+		Contributors:   append(w.contributors("creator"), w.contributors("contributor")...),
+		Publishers:     w.contributors("publisher"),
+	}
+
+	if id := w.string("id"); id != "" {
+		metadata.Identifier = id
+	} else if urls := w.strings("url"); len(urls) > 0 {
+		metadata.Identifier = urls[0]
+	}
+	if typ := w.string("type"); typ != "" && !strings.Contains(typ, ":") {
+		metadata.Type = "http://schema.org/" + typ
+	}
+	if languages := w.strings("inLanguage"); len(languages) > 0 {
+		metadata.Languages = languages
+	} else if w.language != "" {
+		metadata.Languages = manifest.Strings{w.language}
+	}
+	switch w.string("readingProgression") {
+	case "ltr":
+		metadata.ReadingProgression = manifest.LTR
+	case "rtl":
+		metadata.ReadingProgression = manifest.RTL
+	}
+
+	readingOrder := w.linkedResources("readingOrder")
+	if extensions.Contains(w.strings("conformsTo"), w3cAudiobooksProfile) || (len(readingOrder) > 0 && readingOrder.AllAreAudio()) {
+		metadata.ConformsTo = manifest.Profiles{manifest.ProfileAudiobook}
+	}
+
+	if duration, ok := parseISO8601Duration(w.string("duration")); ok {
+		metadata.Duration = &duration
+	} else if len(readingOrder) > 0 {
+		// Falls back on the sum of the durations of the reading order, if they are all known.
+		total := 0.0
+		for _, link := range readingOrder {
+			if link.Duration <= 0 {
+				total = 0
+				break
+			}
+			total += link.Duration
+		}
+		if total > 0 {
+			metadata.Duration = &total
+		}
+	}
+
+	return manifest.Manifest{
+		Context:      manifest.Strings{manifest.WebpubManifestContext},
+		Metadata:     metadata,
+		Links:        w.linkedResources("links"),
+		ReadingOrder: readingOrder,
+		Resources:    w.linkedResources("resources"),
+	}
+}
+
+// Finds the value of [key] in the object items of the @context, such as the default language.
+func (w w3cManifest) contextValue(key string) interface{} {
+	for _, item := range w3cArray(w.json["@context"]) {
+		if obj, ok := item.(map[string]interface{}); ok {
+			if value, ok := obj[key]; ok {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+func (w w3cManifest) string(key string) string {
+	values := w.strings(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (w w3cManifest) strings(key string) manifest.Strings {
+	var values manifest.Strings
+	for _, item := range w3cArray(w.json[key]) {
+		if value, ok := item.(string); ok && strings.TrimSpace(value) != "" {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+// Parses a localizable string, which is either a plain string or an object with a value and a language, or an array of them.
+func (w w3cManifest) localizedString(raw interface{}) manifest.LocalizedString {
+	translations := make(map[string]string)
+	for _, item := range w3cArray(raw) {
+		switch value := item.(type) {
+		case string:
+			translations[w.language] = value
+		case map[string]interface{}:
+			text, _ := value["value"].(string)
+			if text == "" {
+				continue
+			}
+			language, ok := value["language"].(string)
+			if !ok {
+				language = w.language
+			}
+			translations[language] = text
+		}
+	}
+	return manifest.NewLocalizedStringFromStrings(translations)
+}
+
+// Parses the entities for [key], which are either names or Person/Organization objects.
+func (w w3cManifest) contributors(key string) manifest.Contributors {
+	var contributors manifest.Contributors
+	for _, item := range w3cArray(w.json[key]) {
+		switch value := item.(type) {
+		case string:
+			if value != "" {
+				contributors = append(contributors, manifest.Contributor{
+					LocalizedName: w.localizedString(value),
+				})
+			}
+		case map[string]interface{}:
+			name := w.localizedString(value["name"])
+			if name.Length() == 0 {
+				continue
+			}
+			contributor := manifest.Contributor{LocalizedName: name}
+			if id, ok := value["id"].(string); ok {
+				contributor.Identifier = id
+			} else if identifier, ok := value["identifier"].(string); ok {
+				contributor.Identifier = identifier
+			}
+			for _, u := range w3cArray(value["url"]) {
+				if href, ok := u.(string); ok && href != "" {
+					contributor.Links = append(contributor.Links, manifest.Link{Href: href})
+				}
+			}
+			contributors = append(contributors, contributor)
+		}
+	}
+	return contributors
+}
+
+// Parses the list of linked resources for [key], which are either URLs or LinkedResource objects.
+func (w w3cManifest) linkedResources(key string) manifest.LinkList {
+	var links manifest.LinkList
+	for _, item := range w3cArray(w.json[key]) {
+		if link := w.linkedResource(item); link != nil {
+			links = append(links, *link)
+		}
+	}
+	return links
+}
+
+func (w w3cManifest) linkedResource(raw interface{}) *manifest.Link {
+	var obj map[string]interface{}
+	switch value := raw.(type) {
+	case string:
+		obj = map[string]interface{}{"url": value}
+	case map[string]interface{}:
+		obj = value
+	default:
+		return nil
+	}
+
+	rawURL, _ := obj["url"].(string)
+	if rawURL == "" {
+		return nil
+	}
+	href, err := util.NewHREF(rawURL, w.href).String()
+	if err != nil {
+		return nil
+	}
+	link := &manifest.Link{Href: href}
+
+	if name := w.localizedString(obj["name"]); name.Length() > 0 {
+		link.Title = name.String()
+	}
+	for _, rel := range w3cArray(obj["rel"]) {
+		if rel, ok := rel.(string); ok && rel != "" {
+			link.Rels = append(link.Rels, rel)
+		}
+	}
+	if duration, ok := obj["duration"].(string); ok {
+		link.Duration, _ = parseISO8601Duration(duration)
+	}
+	for _, alternate := range w3cArray(obj["alternate"]) {
+		if alt := w.linkedResource(alternate); alt != nil {
+			link.Alternates = append(link.Alternates, *alt)
+		}
+	}
+
+	if encodingFormat, ok := obj["encodingFormat"].(string); ok && encodingFormat != "" {
+		link.Type = encodingFormat
+	} else if packaged := w.packaged.FirstWithHref(href); packaged != nil && packaged.Type != "" {
+		link.Type = packaged.Type
+	} else if ext := strings.TrimPrefix(path.Ext(strings.SplitN(href, "?", 2)[0]), "."); ext != "" {
+		if mt := mediatype.OfExtension(ext); mt != nil {
+			link.Type = mt.String()
+		}
+	}
+
+	if length, ok := obj["length"].(float64); ok && length > 0 && link.MediaType().IsAudio() {
+		// Length of an audio resource is its duration in seconds.
+		link.Duration = length
+	}
+
+	return link
+}
+
+// JSON-LD values are either a single item or an array of items.
+func w3cArray(raw interface{}) []interface{} {
+	switch value := raw.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return value
+	default:
+		return []interface{}{value}
+	}
+}
+
+var iso8601DurationMatcher = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// Parses an ISO 8601 duration (e.g. PT1H2M30.5S) into a number of seconds.
+// Years and months are not supported, since their length is ambiguous.
+func parseISO8601Duration(raw string) (float64, bool) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	if raw == "" || raw == "P" || strings.HasSuffix(raw, "T") {
+		return 0, false
+	}
+	matches := iso8601DurationMatcher.FindStringSubmatch(raw)
+	if matches == nil {
+		return 0, false
+	}
+	units := []float64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1}
+	seconds := 0.0
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.Replace(matches[i+1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds += value * unit
+	}
+	return seconds, true
+}
+
+// Extracts the manifest embedded in a primary entry page, as referenced by a <link rel="publication" href="#id">.
+func parseEmbeddedPublicationManifest(data []byte) (map[string]interface{}, error) {
+	doc, err := parseHTML(data)
+	if err != nil {
+		return nil, err
+	}
+	id := ""
+	if link := findHTMLElement(doc, func(n *htmlNode) bool {
+		return n.Data == "link" && htmlAttrContains(n, "rel", "publication")
+	}); link != nil {
+		id = strings.TrimPrefix(htmlAttr(link, "href"), "#")
+	}
+	script := findHTMLElement(doc, func(n *htmlNode) bool {
+		return n.Data == "script" && htmlAttr(n, "type") == "application/ld+json" && (id == "" || htmlAttr(n, "id") == id)
+	})
+	if script == nil {
+		return nil, errors.New("no publication manifest embedded in the primary entry page")
+	}
+	var js map[string]interface{}
+	if err := json.Unmarshal([]byte(htmlText(script)), &js); err != nil {
+		return nil, err
+	}
+	return js, nil
+}
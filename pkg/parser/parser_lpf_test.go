@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func withLPFParser(t *testing.T, filepath string, f func(*pub.Builder)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	assert.NoError(t, err)
+	p, err := LPFParser{}.Parse(a, fet)
+	assert.NoError(t, err)
+	f(p)
+}
+
+func TestLPFIgnoresOtherFormats(t *testing.T) {
+	withLPFParser(t, "./testdata/image/futuristic_tales.cbz", func(p *pub.Builder) {
+		assert.Nil(t, p)
+	})
+}
+
+func TestLPFAudiobookMetadata(t *testing.T) {
+	withLPFParser(t, "./testdata/lpf/flatland.lpf", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		m := p.Build().Manifest.Metadata
+
+		assert.Equal(t, manifest.Profiles{manifest.ProfileAudiobook}, m.ConformsTo)
+		assert.Equal(t, "http://schema.org/Audiobook", m.Type)
+		assert.Equal(t, "id1", m.Identifier)
+		assert.Equal(t, map[string]string{"en": "Flatland: A Romance of Many Dimensions"}, m.LocalizedTitle.Translations)
+		assert.Equal(t, manifest.Strings{"en"}, m.Languages)
+		assert.Equal(t, "Edwin Abbott Abbott", m.Authors[0].Name())
+		if assert.Len(t, m.Narrators, 1) {
+			assert.Equal(t, "Ruth Golding", m.Narrators[0].Name())
+			assert.Equal(t, "https://librivox.org/reader/3946", m.Narrators[0].Links[0].Href)
+		}
+		assert.Equal(t, "Anonymous", m.Publishers[0].Name())
+		assert.Equal(t, time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC), *m.Published)
+		assert.Equal(t, time.Date(2018, 3, 21, 12, 0, 0, 0, time.UTC), *m.Modified)
+		if assert.NotNil(t, m.Duration) {
+			assert.Equal(t, 943.0, *m.Duration)
+		}
+	})
+}
+
+func TestLPFAudiobookLinks(t *testing.T) {
+	withLPFParser(t, "./testdata/lpf/flatland.lpf", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		m := p.Build().Manifest
+
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/audio/flatland_01.mp3", Type: "audio/mpeg", Title: "Part 1, Sections 1 - 3", Duration: 372},
+			{Href: "/audio/flatland_02.mp3", Type: "audio/mpeg", Title: "Part 1, Sections 4 - 5", Duration: 571},
+		}, m.ReadingOrder)
+		assert.Equal(t, manifest.LinkList{
+			{Href: "/images/cover.jpg", Type: "image/jpeg", Title: "Cover", Rels: manifest.Strings{"cover"}},
+			{Href: "/toc.html", Type: "text/html", Rels: manifest.Strings{"contents"}},
+		}, m.Resources)
+	})
+}
+
+func TestLPFTableOfContents(t *testing.T) {
+	toc := manifest.LinkList{
+		{Href: "/audio/flatland_01.mp3", Title: "Part 1", Children: manifest.LinkList{
+			{Href: "/audio/flatland_01.mp3#t=0", Title: "Section 1"},
+			{Href: "/audio/flatland_01.mp3#t=93", Title: "Section 2"},
+		}},
+		{Href: "#", Title: "Part 2", Children: manifest.LinkList{
+			{Href: "/audio/flatland_02.mp3", Title: "Sections 4 - 5"},
+		}},
+	}
+
+	withLPFParser(t, "./testdata/lpf/flatland.lpf", func(p *pub.Builder) {
+		if assert.NotNil(t, p) {
+			assert.Equal(t, toc, p.Build().Manifest.TableOfContents)
+		}
+	})
+
+	// The manifest and the table of contents are embedded in the primary entry page.
+	withLPFParser(t, "./testdata/lpf/embedded.lpf", func(p *pub.Builder) {
+		if assert.NotNil(t, p) {
+			m := p.Build().Manifest
+			assert.Equal(t, "Flatland: A Romance of Many Dimensions", m.Metadata.Title())
+			assert.Len(t, m.ReadingOrder, 2)
+			assert.Equal(t, toc, m.TableOfContents)
+		}
+	})
+}
+
+func TestLPFStandaloneManifest(t *testing.T) {
+	withLPFParser(t, "./testdata/lpf/publication.json", func(p *pub.Builder) {
+		if !assert.NotNil(t, p) {
+			return
+		}
+		m := p.Build().Manifest
+		assert.Equal(t, "Flatland: A Romance of Many Dimensions", m.Metadata.Title())
+		assert.Equal(t, "/audio/flatland_01.mp3", m.ReadingOrder[0].Href)
+	})
+}
+
+func TestLPFRemoteStandaloneManifest(t *testing.T) {
+	manifestJSON, err := os.ReadFile("./testdata/lpf/publication.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books/flatland/publication.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/ld+json")
+		w.Write(manifestJSON)
+	})
+	mux.HandleFunc("/books/flatland/audio/flatland_01.mp3", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ID3"))
+	})
+	mux.HandleFunc("/books/flatland/toc.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><nav role="doc-toc"><ol><li><a href="audio/flatland_01.mp3">Part 1</a></li></ol></nav></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	a := asset.HTTP(server.Client(), server.URL+"/books/flatland/publication.json")
+	assert.True(t, a.MediaType().Equal(&mediatype.W3CWPUBManifest))
+	fet, err := a.CreateFetcher(asset.Dependencies{}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewLPFParser(server.Client()).Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	pub := p.Build()
+	if assert.Len(t, pub.Manifest.ReadingOrder, 2) {
+		assert.Equal(t, server.URL+"/books/flatland/audio/flatland_01.mp3", pub.Manifest.ReadingOrder[0].Href)
+		data, rerr := pub.Get(pub.Manifest.ReadingOrder[0]).Read(0, 0)
+		if assert.Nil(t, rerr) {
+			assert.Equal(t, "ID3", string(data))
+		}
+		_, rerr = pub.Get(pub.Manifest.ReadingOrder[1]).Read(0, 0)
+		if assert.NotNil(t, rerr) {
+			assert.Equal(t, fetcher.CodeNotFound, rerr.Code)
+		}
+	}
+	assert.Equal(t, manifest.LinkList{
+		{Href: server.URL + "/books/flatland/audio/flatland_01.mp3", Title: "Part 1"},
+	}, pub.Manifest.TableOfContents)
+}
+
+func TestLPFLengthIsDurationOfAudioResources(t *testing.T) {
+	w := w3cManifest{href: "/publication.json"}
+	link := w.linkedResource(map[string]interface{}{"url": "track.mp3", "length": 372.0})
+	if assert.NotNil(t, link) {
+		assert.Equal(t, 372.0, link.Duration)
+	}
+	link = w.linkedResource(map[string]interface{}{"url": "chapter.html", "length": 2048.0})
+	if assert.NotNil(t, link) {
+		assert.Zero(t, link.Duration)
+	}
+}
+
+func TestParseISO8601Duration(t *testing.T) {
+	for raw, expected := range map[string]float64{
+		"PT6M12S":    372,
+		"PT1H":       3600,
+		"PT1.5S":     1.5,
+		"P1DT1H":     90000,
+		"P1W":        604800,
+		"pt0,5m":     30,
+		"PT1H2M3.5S": 3723.5,
+	} {
+		d, ok := parseISO8601Duration(raw)
+		assert.True(t, ok, raw)
+		assert.Equal(t, expected, d, raw)
+	}
+
+	for _, raw := range []string{"", "P", "PT", "P1Y", "1H", "PT1H2"} {
+		_, ok := parseISO8601Duration(raw)
+		assert.False(t, ok, raw)
+	}
+}
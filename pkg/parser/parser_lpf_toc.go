@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/util"
+	"golang.org/x/net/html"
+)
+
+type htmlNode = html.Node
+
+var muchSpaceMatcher = regexp.MustCompile(`\s+`)
+
+// Reads the table of contents of a W3C publication from the HTML resource at [link].
+// Reference: https://www.w3.org/TR/pub-manifest/#app-toc-structure
+func readHTMLTableOfContents(f fetcher.Fetcher, link manifest.Link) manifest.LinkList {
+	data, rerr := f.Get(link).Read(0, 0)
+	if rerr != nil {
+		// TODO log
+		return nil
+	}
+	doc, err := parseHTML(data)
+	if err != nil {
+		return nil
+	}
+	return parseHTMLTableOfContents(doc, link.Href)
+}
+
+func parseHTML(data []byte) (*htmlNode, error) {
+	return html.Parse(bytes.NewReader(data))
+}
+
+// The table of contents is the first list in the first element with the doc-toc role.
+func parseHTMLTableOfContents(doc *htmlNode, href string) manifest.LinkList {
+	toc := findHTMLElement(doc, func(n *htmlNode) bool {
+		return htmlAttrContains(n, "role", "doc-toc")
+	})
+	if toc == nil {
+		return nil
+	}
+	list := findHTMLElement(toc, isHTMLList)
+	if list == nil {
+		return nil
+	}
+	return parseHTMLListElement(list, href)
+}
+
+func parseHTMLListElement(list *htmlNode, href string) manifest.LinkList {
+	var links manifest.LinkList
+	for li := list.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		if link := parseHTMLListItemElement(li, href); link != nil {
+			links = append(links, *link)
+		}
+	}
+	return links
+}
+
+func parseHTMLListItemElement(li *htmlNode, href string) *manifest.Link {
+	var title, rawHref string
+	var children manifest.LinkList
+	for child := li.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if isHTMLList(child) {
+			children = parseHTMLListElement(child, href)
+			break
+		}
+		if title == "" {
+			// The label is either a link, or a non-link element for an entry grouping its children.
+			anchor := child
+			if anchor.Data != "a" {
+				if a := findHTMLElement(child, func(n *htmlNode) bool { return n.Data == "a" }); a != nil {
+					anchor = a
+				}
+			}
+			title = strings.TrimSpace(muchSpaceMatcher.ReplaceAllString(htmlText(anchor), " "))
+			if anchor.Data == "a" {
+				rawHref = htmlAttr(anchor, "href")
+			}
+		}
+	}
+
+	linkHref := "#"
+	if rawHref != "" {
+		if s, err := util.NewHREF(rawHref, href).String(); err == nil {
+			linkHref = s
+		}
+	}
+	if len(children) == 0 && (linkHref == "#" || title == "") {
+		return nil
+	}
+	return &manifest.Link{
+		Title:    title,
+		Href:     linkHref,
+		Children: children,
+	}
+}
+
+func isHTMLList(n *htmlNode) bool {
+	return n.Type == html.ElementNode && (n.Data == "ol" || n.Data == "ul")
+}
+
+// Finds the first element in the subtree of [n], including itself, satisfying [predicate].
+func findHTMLElement(n *htmlNode, predicate func(n *htmlNode) bool) *htmlNode {
+	if n.Type == html.ElementNode && predicate(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findHTMLElement(child, predicate); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func htmlAttr(n *htmlNode, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// Returns whether the space-separated attribute [key] of [n] contains [value].
+func htmlAttrContains(n *htmlNode, key string, value string) bool {
+	for _, v := range strings.Fields(htmlAttr(n, key)) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlText(n *htmlNode) string {
+	var sb strings.Builder
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
@@ -2,12 +2,15 @@ package parser
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/readium/go-toolkit/pkg/asset"
 	"github.com/readium/go-toolkit/pkg/fetcher"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/parser/pdf"
 	"github.com/readium/go-toolkit/pkg/pub"
 )
 
@@ -23,8 +26,8 @@ func NewWebPubParser(client *http.Client) WebPubParser {
 }
 
 // Parse implements PublicationParser
-func (p WebPubParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetcher) (*pub.Builder, error) {
-	lFetcher := fetcher
+func (p WebPubParser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Builder, error) {
+	lFetcher := f
 	mediaType := asset.MediaType()
 
 	if !isMediatypeReadiumWebPubProfile(mediaType) {
@@ -34,6 +37,7 @@ func (p WebPubParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetche
 	isPackage := !mediaType.IsRwpm()
 
 	var manifestJSON map[string]interface{}
+	manifestHref := ""
 	if isPackage {
 		res := lFetcher.Get(manifest.Link{Href: "/manifest.json"})
 		mjr, err := res.ReadAsJSON()
@@ -50,10 +54,12 @@ func (p WebPubParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetche
 		if len(links) == 0 {
 			return nil, errors.New("links is empty")
 		}
-		manifestJSON, err = lFetcher.Get(links[0]).ReadAsJSON()
-		if err != nil {
-			return nil, err
+		manifestHref = links[0].Href
+		mjr, rerr := lFetcher.Get(links[0]).ReadAsJSON()
+		if rerr != nil {
+			return nil, rerr
 		}
+		manifestJSON = mjr
 	}
 
 	manifest, err := manifest.ManifestFromJSON(manifestJSON, isPackage)
@@ -62,16 +68,21 @@ func (p WebPubParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetche
 	}
 
 	// For a manifest, we discard the [fetcher] provided by the Streamer, because it was only
-	// used to read the manifest file. We use an [HttpFetcher] instead to serve the remote resources.
+	// used to read the manifest file. We use an [HTTPFetcher] instead to serve the remote resources,
+	// relative to the self link or to the URL the manifest was read from.
 	if !isPackage {
-		/*baseURL := ""
-		if link := manifest.LinkWithRel("self"); link != nil {
-			baseURL = path.Base(link.Href)
-		}*/
-
-		panic("remote HttpFetcher not implemented!")
-
-		lFetcher = fetcher // TODO HttpFetcher using p.client
+		baseURL := ""
+		if link := manifest.LinkWithRel("self"); link != nil && isHTTPURL(link.Href) {
+			baseURL = link.Href
+		} else if isHTTPURL(manifestHref) {
+			baseURL = manifestHref
+		}
+		if baseURL != "" {
+			lFetcher, err = fetcher.NewHTTPFetcher(p.client, baseURL, nil)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed creating the HTTP fetcher")
+			}
+		}
 	}
 
 	// Checks the requirements from the LCPDF specification.
@@ -81,5 +92,39 @@ func (p WebPubParser) Parse(asset asset.PublicationAsset, fetcher fetcher.Fetche
 		return nil, errors.New("invalid LCP protected PDF")
 	}
 
-	return pub.NewBuilder(*manifest, lFetcher, nil), nil // TODO services!
+	return pub.NewBuilder(*manifest, lFetcher, pub.NewServicesBuilder(webPubServices(*manifest, mediaType, isPackage))), nil
+}
+
+// Selects the services of a Web Publication according to the profile it conforms to.
+func webPubServices(m manifest.Manifest, mediaType mediatype.MediaType, isPackage bool) map[string]pub.ServiceFactory {
+	switch {
+	case m.ConformsTo(manifest.ProfileDivina):
+		return map[string]pub.ServiceFactory{
+			pub.PositionsService_Name: pub.PerResourcePositionsServiceFactory("image/*"),
+		}
+	case m.ConformsTo(manifest.ProfileAudiobook):
+		return map[string]pub.ServiceFactory{
+			pub.PositionsService_Name: pub.AudioPositionsServiceFactory(pub.DefaultAudioPositionsInterval, "audio/*"),
+			pub.LocatorService_Name:   pub.AudioLocatorServiceFactory(),
+		}
+	case mediaType.Equal(&mediatype.LCPProtectedPDF) || m.ConformsTo(manifest.ProfilePDF):
+		return map[string]pub.ServiceFactory{
+			pub.PositionsService_Name: pdf.LCPDFPositionsServiceFactory(),
+		}
+	}
+
+	// The length of the archive entries is only known in a package, a remote resource is measured with its
+	// original length instead.
+	var strategy epub.ReflowableStrategy = epub.RecommendedReflowableStrategy
+	if !isPackage {
+		strategy = epub.OriginalLength{PageLength: 1024}
+	}
+	return map[string]pub.ServiceFactory{
+		pub.PositionsService_Name: epub.PositionsServiceFactory(strategy),
+	}
+}
+
+// Whether the given HREF is an absolute HTTP(S) URL.
+func isHTTPURL(href string) bool {
+	return strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")
 }
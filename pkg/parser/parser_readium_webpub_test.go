@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/archive"
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebPubRemoteManifest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books/moby/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/webpub+json")
+		w.Write([]byte(`{
+			"metadata": {"title": "Moby-Dick"},
+			"readingOrder": [
+				{"href": "chapter1.html", "type": "text/html"},
+				{"href": "/cdn/chapter2.html", "type": "text/html"}
+			]
+		}`))
+	})
+	mux.HandleFunc("/books/moby/chapter1.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Call me Ishmael."))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	a := asset.HTTP(server.Client(), server.URL+"/books/moby/manifest.json")
+	assert.Equal(t, "manifest.json", a.Name())
+	assert.True(t, a.MediaType().Equal(&mediatype.ReadiumWebpubManifest))
+
+	fet, err := a.CreateFetcher(asset.Dependencies{}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewWebPubParser(server.Client()).Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	pub := p.Build()
+	assert.Equal(t, "Moby-Dick", pub.Manifest.Metadata.Title())
+	if assert.Len(t, pub.Manifest.ReadingOrder, 2) {
+		data, rerr := pub.Get(pub.Manifest.ReadingOrder[0]).Read(0, 0)
+		if assert.Nil(t, rerr) {
+			assert.Equal(t, "Call me Ishmael.", string(data))
+		}
+		_, rerr = pub.Get(pub.Manifest.ReadingOrder[1]).Read(0, 0)
+		if assert.NotNil(t, rerr) {
+			assert.Equal(t, fetcher.CodeNotFound, rerr.Code)
+		}
+	}
+}
+
+func withWebPubPackage(t *testing.T, filepath string, f func(*pub.Publication)) {
+	a := asset.File(filepath)
+	fet, err := a.CreateFetcher(asset.Dependencies{
+		ArchiveFactory: archive.NewArchiveFactory(),
+	}, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := NewWebPubParser(nil).Parse(a, fet)
+	if !assert.NoError(t, err) || !assert.NotNil(t, p) {
+		return
+	}
+	f(p.Build())
+}
+
+func TestWebPubPackagePositions(t *testing.T) {
+	withWebPubPackage(t, "./testdata/webpub/book.webpub", func(p *pub.Publication) {
+		positions := p.PositionsByReadingOrder()
+		if assert.Len(t, positions, 2) {
+			assert.Len(t, positions[0], 1)
+			assert.Len(t, positions[1], 6)
+			assert.Equal(t, "text/html", positions[1][0].Type)
+			assert.Equal(t, uint(7), *positions[1][5].Locations.Position)
+		}
+	})
+}
+
+func TestWebPubDivinaPositions(t *testing.T) {
+	withWebPubPackage(t, "./testdata/webpub/comic.divina", func(p *pub.Publication) {
+		positions := p.Positions()
+		if assert.Len(t, positions, 3) {
+			assert.Equal(t, "/page2.jpg", positions[1].Href)
+			assert.Equal(t, "image/jpeg", positions[1].Type)
+			assert.Equal(t, uint(2), *positions[1].Locations.Position)
+		}
+	})
+}
+
+func TestWebPubAudiobookPositions(t *testing.T) {
+	withWebPubPackage(t, "./testdata/webpub/audio.audiobook", func(p *pub.Publication) {
+		positions := p.PositionsByReadingOrder()
+		if assert.Len(t, positions, 2) {
+			assert.Len(t, positions[0], 2)
+			assert.Len(t, positions[1], 1)
+			assert.Equal(t, []string{"t=60"}, positions[0][1].Locations.Fragments)
+		}
+	})
+}
+
+func TestWebPubPDFPositions(t *testing.T) {
+	withWebPubPackage(t, "./testdata/webpub/documents.lcpdf", func(p *pub.Publication) {
+		positions := p.Positions()
+		if assert.Len(t, positions, 5) {
+			assert.Equal(t, "/part1.pdf", positions[0].Href)
+			assert.Equal(t, "Part 1", positions[0].Title)
+			assert.Equal(t, "/part2.pdf", positions[3].Href)
+			assert.Equal(t, []string{"page=2"}, positions[3].Locations.Fragments)
+			assert.Equal(t, "Appendix", positions[3].Title)
+			assert.Equal(t, uint(4), *positions[3].Locations.Position)
+			assert.Equal(t, 0.6, *positions[3].Locations.TotalProgression)
+		}
+	})
+}
@@ -43,18 +43,11 @@ func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Bui
 		return nil, errors.New("unable to find PDF file: no matching link found")
 	}
 
-	conf := pdfcpu.NewDefaultConfiguration()
-	conf.ValidationMode = pdfcpu.ValidationRelaxed
-	ctx, err := pdfcpu.Read(fetcher.NewResourceReadSeeker(f.Get(*link)), conf)
+	ctx, err := openPDF(f.Get(*link))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed opening PDF")
 	}
 
-	// Clean up and prepare document
-	validate.XRefTable(ctx.XRefTable)
-	pdfcpu.OptimizeXRefTable(ctx)
-	ctx.EnsurePageCount()
-
 	m, err := ParseMetadata(ctx, link)
 
 	// Fallback title
@@ -68,3 +61,19 @@ func (p Parser) Parse(asset asset.PublicationAsset, f fetcher.Fetcher) (*pub.Bui
 	})
 	return pub.NewBuilder(m, f, builder), nil
 }
+
+// Reads the PDF document of the given [resource] and prepares it for extracting its metadata.
+func openPDF(resource fetcher.Resource) (*pdfcpu.Context, error) {
+	conf := pdfcpu.NewDefaultConfiguration()
+	conf.ValidationMode = pdfcpu.ValidationRelaxed
+	ctx, err := pdfcpu.Read(fetcher.NewResourceReadSeeker(resource), conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up and prepare document
+	validate.XRefTable(ctx.XRefTable)
+	pdfcpu.OptimizeXRefTable(ctx)
+	ctx.EnsurePageCount()
+	return ctx, nil
+}
@@ -5,30 +5,23 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
-	"github.com/pkg/errors"
 	"github.com/readium/go-toolkit/pkg/internal/extensions"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/go-toolkit/pkg/mediatype"
-	"github.com/trimmer-io/go-xmp/xmp"
 )
 
 // This is completely random
 // var UUIDNameSpaceForPDF = uuid.Must(uuid.Parse("4a706cb0-458c-4180-9601-086121ee8d9f"))
 
-func loadDecoder(meta pdfcpu.Metadata) (*xmp.Document, []byte, error) {
-	metabin, err := io.ReadAll(meta.Reader)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed reading XMP metadata block")
-	}
-	doc := &xmp.Document{}
-	if err := xmp.Unmarshal(metabin, doc); err != nil {
-		return nil, nil, errors.Wrap(err, "failed decoding XMP metadata")
-	}
-	return doc, metabin, nil
-}
-
+// ParseMetadata creates the manifest of the PDF document [ctx].
+//
+// The metadata is read from the XMP packet of the document catalog, then completed with the document information
+// dictionary. When both declare a property, the XMP value takes precedence, unless the information dictionary was
+// modified more recently than the XMP packet, which happens when the document was edited by a processor unaware of
+// XMP (ISO 32000-1, section 14.3.2).
 func ParseMetadata(ctx *pdfcpu.Context, link *manifest.Link) (m manifest.Manifest, err error) {
 	if link != nil {
 		m.ReadingOrder = manifest.LinkList{{
@@ -47,20 +40,21 @@ func ParseMetadata(ctx *pdfcpu.Context, link *manifest.Link) (m manifest.Manifes
 	// hashmaterial := make([]string, 0, 64)
 	metas, _ := ctx.ExtractMetadata()
 	for _, meta := range metas {
-		doc, _, derr := loadDecoder(meta)
-		if derr != nil {
-			err = derr
-			return
+		// Pages and images can have their own XMP packet, only the one of the catalog describes the document.
+		if ctx.Root == nil || meta.ParentObjNr != ctx.Root.ObjectNumber.Value() {
+			continue
+		}
+		// A malformed XMP packet is skipped, the metadata is then only read from the information dictionary.
+		metabin, rerr := io.ReadAll(meta.Reader)
+		if rerr != nil {
+			// TODO log
+			continue
 		}
-		defer doc.Close()
 		// hashmaterial = append(hashmaterial, string(metabin))
 
-		// b, _ := json.MarshalIndent(doc, "", "  ")
-		// println(string(b))
-
-		err = ParseXMPMetadata(doc, &m.Metadata)
-		if err != nil {
-			return
+		if xerr := ParseXMPMetadata(metabin, &m.Metadata); xerr != nil {
+			// TODO log
+			continue
 		}
 	}
 
@@ -119,16 +113,6 @@ func ParseMetadata(ctx *pdfcpu.Context, link *manifest.Link) (m manifest.Manifes
 	return
 }
 
-func ParseXMPMetadata(doc *xmp.Document, metadata *manifest.Metadata) error {
-	if doc == nil {
-		return nil
-	}
-
-	// TODO
-
-	return nil
-}
-
 func ParsePDFMetadata(ctx *pdfcpu.Context, m *manifest.Manifest) error {
 	// Page count
 	if ctx.PageCount > 0 && m.Metadata.NumberOfPages == nil {
@@ -136,49 +120,57 @@ func ParsePDFMetadata(ctx *pdfcpu.Context, m *manifest.Manifest) error {
 		m.Metadata.NumberOfPages = &pc
 	}
 
+	// The information dictionary overrides the XMP metadata only when it is more recent.
+	modDate := parsePDFDate(ctx.ModDate)
+	override := modDate != nil && m.Metadata.Modified != nil && modDate.After(*m.Metadata.Modified)
+
 	// Identifier
-	if len(ctx.XRefTable.ID) > 0 {
+	if len(ctx.XRefTable.ID) > 0 && m.Metadata.Identifier == "" {
 		m.Metadata.Identifier = hex.EncodeToString([]byte(ctx.XRefTable.ID[0].String()))
 	}
 
 	// Title
-	// TODO determine whether XMP or PDF title should take precendence. For now it's XMP
-	if ctx.Title != "" && m.Metadata.LocalizedTitle.String() == "" {
+	if ctx.Title != "" && (override || m.Metadata.LocalizedTitle.String() == "") {
 		m.Metadata.LocalizedTitle = manifest.NewLocalizedStringFromString(ctx.Title)
 	}
 
 	// Author
 	// Note: XMP can have multiple authors, PDF "Author" seems to only have the first one for some PDFs
-	if ctx.Author != "" && len(m.Metadata.Authors) == 0 {
+	if ctx.Author != "" && (override || len(m.Metadata.Authors) == 0) {
 		m.Metadata.Authors = manifest.Contributors{{
 			LocalizedName: manifest.NewLocalizedStringFromString(ctx.Author),
 		}}
 	}
 
-	// Subject
-	if ctx.Subject != "" && len(m.Metadata.Subjects) == 0 {
-		subtitle := manifest.NewLocalizedStringFromString(ctx.Subject)
-		m.Metadata.LocalizedSubtitle = &subtitle
+	// Subject, which is the equivalent of dc:description.
+	if ctx.Subject != "" && (override || m.Metadata.Description == "") {
+		m.Metadata.Description = ctx.Subject
 	}
 
 	// Keywords
-	if ctx.Keywords != "" && len(m.Metadata.Subjects) == 0 {
-		m.Metadata.Subjects = append(m.Metadata.Subjects, manifest.Subject{
-			LocalizedName: manifest.NewLocalizedStringFromString(ctx.Keywords),
-		})
+	if keywords := splitKeywords(ctx.Keywords); len(keywords) > 0 && (override || len(m.Metadata.Subjects) == 0) {
+		m.Metadata.Subjects = nil
+		for _, keyword := range keywords {
+			m.Metadata.Subjects = append(m.Metadata.Subjects, manifest.Subject{
+				LocalizedName: manifest.NewLocalizedStringFromString(keyword),
+			})
+		}
 	}
 
-	if ctx.ModDate != "" && m.Metadata.Modified == nil {
-		modDate := extensions.ParseDate(ctx.ModDate)
-		if modDate != nil {
-			m.Metadata.Modified = modDate
-		}
+	// Software used to create the document.
+	if ctx.Creator != "" && (override || m.Metadata.OtherMetadata[NamespaceXMP+"CreatorTool"] == nil) {
+		setOtherMetadata(&m.Metadata, NamespaceXMP+"CreatorTool", ctx.Creator)
 	}
-	if ctx.CreationDate != "" && m.Metadata.Published == nil {
-		createDate := extensions.ParseDate(ctx.CreationDate)
-		if createDate != nil {
-			m.Metadata.Published = createDate
-		}
+	if ctx.Producer != "" && (override || m.Metadata.OtherMetadata[NamespacePDF+"Producer"] == nil) {
+		setOtherMetadata(&m.Metadata, NamespacePDF+"Producer", ctx.Producer)
+	}
+
+	// Dates
+	if modDate != nil && (override || m.Metadata.Modified == nil) {
+		m.Metadata.Modified = modDate
+	}
+	if m.Metadata.Published == nil {
+		m.Metadata.Published = parsePDFDate(ctx.CreationDate)
 	}
 
 	// Bookmarks (TOC)
@@ -207,3 +199,14 @@ func ParsePDFMetadata(ctx *pdfcpu.Context, m *manifest.Manifest) error {
 
 	return nil
 }
+
+// Parses a date of the information dictionary, e.g. D:20230102150405+01'00'.
+func parsePDFDate(raw string) *time.Time {
+	if raw == "" {
+		return nil
+	}
+	if t, ok := pdfcpu.DateTime(raw, true); ok {
+		return &t
+	}
+	return extensions.ParseDate(raw)
+}
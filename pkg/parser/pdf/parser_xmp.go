@@ -0,0 +1,288 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/xmlquery"
+)
+
+// Namespaces of the XMP schemas mapped to the publication metadata.
+const (
+	NamespaceRDF   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NamespaceXML   = "http://www.w3.org/XML/1998/namespace"
+	NamespaceDC    = "http://purl.org/dc/elements/1.1/"
+	NamespaceXMP   = "http://ns.adobe.com/xap/1.0/"
+	NamespacePDF   = "http://ns.adobe.com/pdf/1.3/"
+	NamespacePRISM = "http://prismstandard.org/namespaces/basic/2.0/"
+)
+
+// Every version of PRISM uses a namespace with this prefix, e.g. basic/1.2/ or basic/3.0/.
+const namespacePRISMPrefix = "http://prismstandard.org/namespaces/basic/"
+
+// ParseXMPMetadata maps the XMP metadata packet [data] of a PDF document to [metadata].
+//
+// The following schemas are supported:
+//   - Dublin Core: title, creator, contributor, publisher, subject, description, rights, date, language and identifier.
+//   - PRISM: ISBN, DOI, publication name, volume and number.
+//   - XMP Basic and Adobe PDF: creation and modification dates, creator tool, producer and keywords.
+//
+// Values with xml:lang alternatives are mapped to a [manifest.LocalizedString] when the metadata allows it.
+func ParseXMPMetadata(data []byte, metadata *manifest.Metadata) error {
+	doc, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed decoding XMP metadata")
+	}
+	x := xmpPacket{descriptions: doc.SelectElements("//" + nsSelect(NamespaceRDF, "Description"))}
+
+	if title := x.localizedString(NamespaceDC, "title"); title != nil {
+		metadata.LocalizedTitle = *title
+	}
+	if description := x.localizedString(NamespaceDC, "description"); description != nil {
+		metadata.Description = description.String()
+	}
+	if rights := x.localizedString(NamespaceDC, "rights"); rights != nil {
+		setOtherMetadata(metadata, NamespaceDC+"rights", *rights)
+	}
+
+	metadata.Authors = append(metadata.Authors, x.contributors(NamespaceDC, "creator")...)
+	metadata.Contributors = append(metadata.Contributors, x.contributors(NamespaceDC, "contributor")...)
+	metadata.Publishers = append(metadata.Publishers, x.contributors(NamespaceDC, "publisher")...)
+
+	subjects := x.strings(NamespaceDC, "subject")
+	if len(subjects) == 0 {
+		subjects = splitKeywords(x.string(NamespacePDF, "Keywords"))
+	}
+	for _, subject := range subjects {
+		metadata.Subjects = append(metadata.Subjects, manifest.Subject{
+			LocalizedName: manifest.NewLocalizedStringFromString(subject),
+		})
+	}
+
+	for _, language := range x.strings(NamespaceDC, "language") {
+		metadata.Languages = extensions.AddToSet(metadata.Languages, language)
+	}
+
+	// Dates
+	for _, date := range append(x.strings(NamespaceDC, "date"), x.string(NamespaceXMP, "CreateDate")) {
+		if published := extensions.ParseDate(date); published != nil {
+			metadata.Published = published
+			break
+		}
+	}
+	for _, date := range []string{x.string(NamespaceXMP, "ModifyDate"), x.string(NamespaceXMP, "MetadataDate")} {
+		if modified := extensions.ParseDate(date); modified != nil {
+			metadata.Modified = modified
+			break
+		}
+	}
+
+	// Identifier, by order of preference.
+	if isbn := normalizeISBN(x.prismString("isbn")); isbn != "" {
+		metadata.Identifier = "urn:isbn:" + isbn
+	} else if doi := strings.TrimSpace(x.prismString("doi")); doi != "" {
+		metadata.Identifier = "https://doi.org/" + strings.TrimPrefix(doi, "doi:")
+	} else if identifiers := x.strings(NamespaceDC, "identifier"); len(identifiers) > 0 {
+		metadata.Identifier = identifiers[0]
+	} else if identifier := x.string(NamespaceXMP, "Identifier"); identifier != "" {
+		metadata.Identifier = identifier
+	}
+
+	// Periodical
+	if name := x.prismString("publicationName"); name != "" {
+		if metadata.BelongsTo == nil {
+			metadata.BelongsTo = make(map[string]manifest.Collections)
+		}
+		metadata.BelongsTo["collection"] = append(metadata.BelongsTo["collection"], manifest.Contributor{
+			LocalizedName: manifest.NewLocalizedStringFromString(name),
+		})
+	}
+	if volume := x.prismString("volume"); volume != "" {
+		setOtherMetadata(metadata, NamespacePRISM+"volume", volume)
+	}
+	if number := x.prismString("number"); number != "" {
+		setOtherMetadata(metadata, NamespacePRISM+"number", number)
+	}
+
+	// Software used to create the document.
+	if tool := x.string(NamespaceXMP, "CreatorTool"); tool != "" {
+		setOtherMetadata(metadata, NamespaceXMP+"CreatorTool", tool)
+	}
+	if producer := x.string(NamespacePDF, "Producer"); producer != "" {
+		setOtherMetadata(metadata, NamespacePDF+"Producer", producer)
+	}
+
+	return nil
+}
+
+// A value of an XMP property, with its language when it is a language alternative.
+type xmpValue struct {
+	value    string
+	language string
+}
+
+// The rdf:Description elements of an XMP packet, holding the properties either as attributes or as child elements.
+type xmpPacket struct {
+	descriptions []*xmlquery.Node
+}
+
+// Returns all the values of the property, which can be a simple value or an array (rdf:Seq, rdf:Bag or rdf:Alt).
+func (x xmpPacket) values(namespace func(string) bool, name string) []xmpValue {
+	var values []xmpValue
+	for _, desc := range x.descriptions {
+		for _, attr := range desc.Attr {
+			if namespace(attr.NamespaceURI) && attr.Name.Local == name {
+				if v := strings.TrimSpace(attr.Value); v != "" {
+					values = append(values, xmpValue{value: v})
+				}
+			}
+		}
+		for prop := desc.FirstChild; prop != nil; prop = prop.NextSibling {
+			if prop.Type != xmlquery.ElementNode || !namespace(prop.NamespaceURI) || prop.Data != name {
+				continue
+			}
+			items := prop.SelectElements(nsSelect(NamespaceRDF, "*") + "/" + nsSelect(NamespaceRDF, "li"))
+			if len(items) == 0 {
+				items = []*xmlquery.Node{prop}
+			}
+			for _, item := range items {
+				v := strings.TrimSpace(item.InnerText())
+				if v == "" {
+					v = strings.TrimSpace(attrNS(item, NamespaceRDF, "resource"))
+				}
+				if v != "" {
+					values = append(values, xmpValue{value: v, language: xmlLang(item)})
+				}
+			}
+		}
+	}
+	return values
+}
+
+func (x xmpPacket) strings(namespace, name string) []string {
+	var res []string
+	for _, v := range x.values(namespaceIs(namespace), name) {
+		res = append(res, v.value)
+	}
+	return res
+}
+
+func (x xmpPacket) string(namespace, name string) string {
+	if values := x.values(namespaceIs(namespace), name); len(values) > 0 {
+		return values[0].value
+	}
+	return ""
+}
+
+// Returns the first value of a PRISM property, in any version of the schema.
+func (x xmpPacket) prismString(name string) string {
+	values := x.values(func(ns string) bool { return strings.HasPrefix(ns, namespacePRISMPrefix) }, name)
+	if len(values) > 0 {
+		return values[0].value
+	}
+	return ""
+}
+
+// Returns the language alternatives of the property, x-default being the default translation.
+func (x xmpPacket) localizedString(namespace, name string) *manifest.LocalizedString {
+	values := x.values(namespaceIs(namespace), name)
+	if len(values) == 0 {
+		return nil
+	}
+	translations := make(map[string]string)
+	for _, v := range values {
+		language := v.language
+		if language == "x-default" {
+			language = ""
+		}
+		if _, ok := translations[language]; !ok {
+			translations[language] = v.value
+		}
+	}
+	ls := manifest.NewLocalizedStringFromStrings(translations)
+	return &ls
+}
+
+func (x xmpPacket) contributors(namespace, name string) manifest.Contributors {
+	var contributors manifest.Contributors
+	for _, v := range x.strings(namespace, name) {
+		contributors = append(contributors, manifest.Contributor{
+			LocalizedName: manifest.NewLocalizedStringFromString(v),
+		})
+	}
+	return contributors
+}
+
+func namespaceIs(namespace string) func(string) bool {
+	return func(ns string) bool {
+		return ns == namespace
+	}
+}
+
+func nsSelect(namespace, localName string) string {
+	if localName == "*" {
+		return "*[namespace-uri()='" + namespace + "']"
+	}
+	return "*[namespace-uri()='" + namespace + "' and local-name()='" + localName + "']"
+}
+
+func attrNS(n *xmlquery.Node, namespace, name string) string {
+	for _, a := range n.Attr {
+		if a.NamespaceURI == namespace && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Returns the language of the node, inherited from its ancestors.
+func xmlLang(n *xmlquery.Node) string {
+	for ; n != nil; n = n.Parent {
+		for _, a := range n.Attr {
+			if a.Name.Local == "lang" && (a.Name.Space == "xml" || a.NamespaceURI == NamespaceXML) {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Splits the keywords of a PDF document, which are usually separated by commas or semicolons.
+func splitKeywords(keywords string) []string {
+	var res []string
+	for _, k := range strings.FieldsFunc(keywords, func(r rune) bool { return r == ',' || r == ';' }) {
+		if k = strings.TrimSpace(k); k != "" {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// Strips the hyphens and spaces of an ISBN, returning an empty string if it is not a valid ISBN-10 or ISBN-13.
+func normalizeISBN(isbn string) string {
+	isbn = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(isbn), "urn:isbn:"), "ISBN")
+	var b strings.Builder
+	for _, r := range isbn {
+		switch {
+		case r >= '0' && r <= '9', r == 'X' || r == 'x':
+			b.WriteRune(r)
+		case r == '-' || r == ' ' || r == ':':
+		default:
+			return ""
+		}
+	}
+	if n := b.Len(); n != 10 && n != 13 {
+		return ""
+	}
+	return strings.ToUpper(b.String())
+}
+
+func setOtherMetadata(metadata *manifest.Metadata, key string, value interface{}) {
+	if metadata.OtherMetadata == nil {
+		metadata.OtherMetadata = make(map[string]interface{})
+	}
+	metadata.OtherMetadata[key] = value
+}
@@ -0,0 +1,214 @@
+package pdf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+  <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about=""
+        xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+        xmlns:pdf="http://ns.adobe.com/pdf/1.3/"
+        xmp:CreatorTool="Writer"
+        xmp:CreateDate="2020-03-01T10:00:00Z"
+        xmp:ModifyDate="2021-06-15T12:30:00Z"
+        pdf:Producer="LibreOffice 7.0"
+        pdf:Keywords="ignored, keywords"/>
+    <rdf:Description rdf:about=""
+        xmlns:dc="http://purl.org/dc/elements/1.1/"
+        xmlns:prism="http://prismstandard.org/namespaces/basic/3.0/">
+      <dc:title>
+        <rdf:Alt>
+          <rdf:li xml:lang="x-default">Les Misérables</rdf:li>
+          <rdf:li xml:lang="en">The Miserables</rdf:li>
+        </rdf:Alt>
+      </dc:title>
+      <dc:description>
+        <rdf:Alt><rdf:li xml:lang="x-default">A novel</rdf:li></rdf:Alt>
+      </dc:description>
+      <dc:rights>
+        <rdf:Alt><rdf:li xml:lang="x-default">Public domain</rdf:li></rdf:Alt>
+      </dc:rights>
+      <dc:creator>
+        <rdf:Seq>
+          <rdf:li>Victor Hugo</rdf:li>
+          <rdf:li>Isabel Hapgood</rdf:li>
+        </rdf:Seq>
+      </dc:creator>
+      <dc:publisher><rdf:Bag><rdf:li>Gutenberg</rdf:li></rdf:Bag></dc:publisher>
+      <dc:subject>
+        <rdf:Bag>
+          <rdf:li>Fiction</rdf:li>
+          <rdf:li>France</rdf:li>
+        </rdf:Bag>
+      </dc:subject>
+      <dc:language><rdf:Bag><rdf:li>fr</rdf:li></rdf:Bag></dc:language>
+      <dc:identifier>urn:uuid:1234</dc:identifier>
+      <prism:isbn>978-2-07-040850-4</prism:isbn>
+      <prism:publicationName>Classics</prism:publicationName>
+      <prism:volume>2</prism:volume>
+    </rdf:Description>
+  </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestXMPMetadata(t *testing.T) {
+	var m manifest.Metadata
+	if !assert.NoError(t, ParseXMPMetadata([]byte(testXMP), &m)) {
+		return
+	}
+
+	assert.Equal(t, manifest.NewLocalizedStringFromStrings(map[string]string{
+		"":   "Les Misérables",
+		"en": "The Miserables",
+	}), m.LocalizedTitle)
+	assert.Equal(t, "A novel", m.Description)
+	assert.Equal(t, manifest.NewLocalizedStringFromString("Public domain"), m.OtherMetadata[NamespaceDC+"rights"])
+	assert.Equal(t, manifest.Contributors{
+		{LocalizedName: manifest.NewLocalizedStringFromString("Victor Hugo")},
+		{LocalizedName: manifest.NewLocalizedStringFromString("Isabel Hapgood")},
+	}, m.Authors)
+	assert.Equal(t, manifest.Contributors{
+		{LocalizedName: manifest.NewLocalizedStringFromString("Gutenberg")},
+	}, m.Publishers)
+	assert.Equal(t, []manifest.Subject{
+		{LocalizedName: manifest.NewLocalizedStringFromString("Fiction")},
+		{LocalizedName: manifest.NewLocalizedStringFromString("France")},
+	}, m.Subjects)
+	assert.Equal(t, manifest.Strings{"fr"}, m.Languages)
+	assert.Equal(t, "urn:isbn:9782070408504", m.Identifier)
+	assert.Equal(t, time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), m.Published.UTC())
+	assert.Equal(t, time.Date(2021, 6, 15, 12, 30, 0, 0, time.UTC), m.Modified.UTC())
+	assert.Equal(t, manifest.Collections{
+		{LocalizedName: manifest.NewLocalizedStringFromString("Classics")},
+	}, m.BelongsTo["collection"])
+	assert.Equal(t, "2", m.OtherMetadata[NamespacePRISM+"volume"])
+	assert.Equal(t, "Writer", m.OtherMetadata[NamespaceXMP+"CreatorTool"])
+	assert.Equal(t, "LibreOffice 7.0", m.OtherMetadata[NamespacePDF+"Producer"])
+}
+
+func TestXMPMetadataKeywords(t *testing.T) {
+	var m manifest.Metadata
+	assert.NoError(t, ParseXMPMetadata([]byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+  <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+      <pdf:Keywords>history; war , peace</pdf:Keywords>
+    </rdf:Description>
+  </rdf:RDF>
+</x:xmpmeta>`), &m))
+	assert.Equal(t, []manifest.Subject{
+		{LocalizedName: manifest.NewLocalizedStringFromString("history")},
+		{LocalizedName: manifest.NewLocalizedStringFromString("war")},
+		{LocalizedName: manifest.NewLocalizedStringFromString("peace")},
+	}, m.Subjects)
+}
+
+func TestXMPMetadataInvalid(t *testing.T) {
+	var m manifest.Metadata
+	assert.Error(t, ParseXMPMetadata([]byte("<x:xmpmeta"), &m))
+}
+
+func TestXMPMetadataDOI(t *testing.T) {
+	var m manifest.Metadata
+	assert.NoError(t, ParseXMPMetadata([]byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:prism="http://prismstandard.org/namespaces/basic/2.0/" prism:doi="10.1000/182" prism:isbn="invalid"/>
+</rdf:RDF>`), &m))
+	assert.Equal(t, "https://doi.org/10.1000/182", m.Identifier)
+}
+
+func testPDFContext(t *testing.T) *pdfcpu.Context {
+	ctx, err := pdfcpu.CreateContextWithXRefTable(nil, pdfcpu.PaperSize["A4"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Title = "Info Title"
+	ctx.Author = "Info Author"
+	ctx.Subject = "Info Subject"
+	ctx.Keywords = "one, two"
+	ctx.Producer = "Info Producer"
+	return ctx
+}
+
+func TestPDFMetadataCompletesXMP(t *testing.T) {
+	ctx := testPDFContext(t)
+	ctx.ModDate = "D:20200101000000Z"
+	ctx.CreationDate = "D:20190101000000+01'00'"
+
+	var m manifest.Manifest
+	assert.NoError(t, ParseXMPMetadata([]byte(testXMP), &m.Metadata))
+	assert.NoError(t, ParsePDFMetadata(ctx, &m))
+
+	// The XMP packet is more recent, so it takes precedence.
+	assert.Equal(t, "Les Misérables", m.Metadata.LocalizedTitle.String())
+	assert.Equal(t, "Victor Hugo", m.Metadata.Authors[0].Name())
+	assert.Equal(t, "A novel", m.Metadata.Description)
+	assert.Equal(t, "LibreOffice 7.0", m.Metadata.OtherMetadata[NamespacePDF+"Producer"])
+	assert.Equal(t, time.Date(2021, 6, 15, 12, 30, 0, 0, time.UTC), m.Metadata.Modified.UTC())
+	assert.Equal(t, time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), m.Metadata.Published.UTC())
+	assert.Equal(t, "urn:isbn:9782070408504", m.Metadata.Identifier)
+}
+
+func TestPDFMetadataOverridesOutdatedXMP(t *testing.T) {
+	ctx := testPDFContext(t)
+	ctx.ModDate = "D:20220101000000Z"
+
+	var m manifest.Manifest
+	assert.NoError(t, ParseXMPMetadata([]byte(testXMP), &m.Metadata))
+	assert.NoError(t, ParsePDFMetadata(ctx, &m))
+
+	assert.Equal(t, "Info Title", m.Metadata.LocalizedTitle.String())
+	assert.Equal(t, manifest.Contributors{
+		{LocalizedName: manifest.NewLocalizedStringFromString("Info Author")},
+	}, m.Metadata.Authors)
+	assert.Equal(t, "Info Subject", m.Metadata.Description)
+	assert.Equal(t, []manifest.Subject{
+		{LocalizedName: manifest.NewLocalizedStringFromString("one")},
+		{LocalizedName: manifest.NewLocalizedStringFromString("two")},
+	}, m.Metadata.Subjects)
+	assert.Equal(t, "Info Producer", m.Metadata.OtherMetadata[NamespacePDF+"Producer"])
+	assert.Equal(t, "Writer", m.Metadata.OtherMetadata[NamespaceXMP+"CreatorTool"])
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), m.Metadata.Modified.UTC())
+}
+
+func TestPDFMetadataWithoutXMP(t *testing.T) {
+	ctx := testPDFContext(t)
+	ctx.CreationDate = "D:20190101000000+01'00'"
+
+	var m manifest.Manifest
+	assert.NoError(t, ParsePDFMetadata(ctx, &m))
+
+	assert.Equal(t, "Info Title", m.Metadata.LocalizedTitle.String())
+	assert.Equal(t, "Info Subject", m.Metadata.Description)
+	assert.Nil(t, m.Metadata.LocalizedSubtitle)
+	assert.Equal(t, time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC), m.Metadata.Published.UTC())
+}
+
+func TestPDFMetadataWithCorruptXMP(t *testing.T) {
+	ctx := testPDFContext(t)
+	ctx.PageCount = 3
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := pdfcpu.NewStreamDict(pdfcpu.Dict{}, 0, nil, nil, nil)
+	sd.Raw = []byte("<x:xmpmeta")
+	ref, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog.Insert("Metadata", *ref)
+
+	m, err := ParseMetadata(ctx, &manifest.Link{Href: "/book.pdf"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Info Title", m.Metadata.LocalizedTitle.String())
+	assert.Equal(t, "Info Author", m.Metadata.Authors[0].Name())
+	if assert.NotNil(t, m.Metadata.NumberOfPages) {
+		assert.Equal(t, uint(3), *m.Metadata.NumberOfPages)
+	}
+}
@@ -9,12 +9,13 @@ import (
 	"github.com/readium/go-toolkit/pkg/pub"
 )
 
-// Positions Service for an PDF.
+// Positions Service for a publication whose reading order is made of one or several PDF documents.
+// Each page of each document is a position.
 type PositionsService struct {
-	link            manifest.Link        // The [Link] to the PDF document in the [Publication].
-	pageCount       uint                 // Total page count in the PDF document.
-	tableOfContents manifest.LinkList    // Table of contents used to compute the position titles.
-	positions       [][]manifest.Locator // Cached calculated positions
+	readingOrder    manifest.LinkList        // The [Link]s to the PDF documents in the [Publication].
+	pageCount       func(manifest.Link) uint // Returns the page count of a PDF document in the reading order.
+	tableOfContents manifest.LinkList        // Table of contents used to compute the position titles.
+	positions       [][]manifest.Locator     // Cached calculated positions
 }
 
 func (s *PositionsService) Close() {}
@@ -46,41 +47,58 @@ func (s *PositionsService) PositionsByReadingOrder() [][]manifest.Locator {
 }
 
 func (s *PositionsService) computePositions() [][]manifest.Locator {
-	if s.pageCount <= 0 {
+	pageCounts := make([]uint, len(s.readingOrder))
+	var totalPageCount uint
+	for i, link := range s.readingOrder {
+		pageCounts[i] = s.pageCount(link)
+		totalPageCount += pageCounts[i]
+	}
+	if totalPageCount == 0 {
 		// Not suppsed to happen
 		return [][]manifest.Locator{}
 	}
 
-	positions := make([][]manifest.Locator, s.pageCount)
-	for i := uint(0); i < s.pageCount; i++ {
-		progression := float64(i) / float64(s.pageCount)
-		typ := s.link.Type
+	positions := make([][]manifest.Locator, len(s.readingOrder))
+	var lastPositionOfPreviousResource uint
+	for i, link := range s.readingOrder {
+		typ := link.Type
 		if typ == "" {
 			typ = mediatype.PDF.String()
 		}
-		position := i + 1
-		fragment := fmt.Sprintf("page=%d", i+1)
+		pageCount := pageCounts[i]
+		lpositions := make([]manifest.Locator, pageCount)
+		for p := uint(0); p < pageCount; p++ {
+			progression := float64(p) / float64(pageCount)
+			position := lastPositionOfPreviousResource + p + 1
+			totalProgression := float64(position-1) / float64(totalPageCount)
+			fragment := fmt.Sprintf("page=%d", p+1)
 
-		var title string
-		if link := s.tableOfContents.FirstWithHref(s.link.Href + "#" + fragment); link != nil {
-			title = link.Title
-		}
+			var title string
+			if tocLink := s.tableOfContents.FirstWithHref(link.Href + "#" + fragment); tocLink != nil {
+				title = tocLink.Title
+			} else if p == 0 {
+				title = link.Title
+			}
 
-		positions[i] = []manifest.Locator{{
-			Href: s.link.Href,
-			Type: s.link.Type,
-			Locations: manifest.Locations{
-				Fragments:        []string{fragment},
-				Progression:      &progression,
-				TotalProgression: &progression,
-				Position:         &position,
-			},
-			Title: title,
-		}}
+			lpositions[p] = manifest.Locator{
+				Href: link.Href,
+				Type: typ,
+				Locations: manifest.Locations{
+					Fragments:        []string{fragment},
+					Progression:      &progression,
+					TotalProgression: &totalProgression,
+					Position:         &position,
+				},
+				Title: title,
+			}
+		}
+		positions[i] = lpositions
+		lastPositionOfPreviousResource += pageCount
 	}
 	return positions
 }
 
+// Creates the positions of a single PDF document, whose page count is found in the metadata.
 func PositionsServiceFactory() pub.ServiceFactory {
 	return func(context pub.Context) pub.Service {
 		if len(context.Manifest.ReadingOrder) == 0 {
@@ -93,8 +111,35 @@ func PositionsServiceFactory() pub.ServiceFactory {
 		}
 
 		return &PositionsService{
-			link:            context.Manifest.ReadingOrder[0],
-			pageCount:       count,
+			readingOrder:    context.Manifest.ReadingOrder[:1],
+			pageCount:       func(manifest.Link) uint { return count },
+			tableOfContents: context.Manifest.TableOfContents,
+		}
+	}
+}
+
+// Creates the positions of a publication whose reading order is made of several PDF documents, such as a Readium
+// Web Publication conforming to the PDF profile or an LCP protected PDF package.
+//
+// The page count is read from the documents, which are only opened when the positions are first requested. A
+// document which can't be read, for example because it is encrypted, gets a single position.
+func LCPDFPositionsServiceFactory() pub.ServiceFactory {
+	return func(context pub.Context) pub.Service {
+		if len(context.Manifest.ReadingOrder) == 0 {
+			return nil
+		}
+
+		return &PositionsService{
+			readingOrder: context.Manifest.ReadingOrder,
+			pageCount: func(link manifest.Link) uint {
+				resource := context.Fetcher.Get(link)
+				defer resource.Close()
+				ctx, err := openPDF(resource)
+				if err != nil || ctx.PageCount <= 0 {
+					return 1
+				}
+				return uint(ctx.PageCount)
+			},
 			tableOfContents: context.Manifest.TableOfContents,
 		}
 	}
@@ -0,0 +1,30 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/pub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositionsSingleDocument(t *testing.T) {
+	pageCount := uint(3)
+	service := PositionsServiceFactory()(pub.Context{Manifest: manifest.Manifest{
+		Metadata:        manifest.Metadata{NumberOfPages: &pageCount},
+		ReadingOrder:    manifest.LinkList{{Href: "/book.pdf"}},
+		TableOfContents: manifest.LinkList{{Href: "/book.pdf#page=2", Title: "Chapter 1"}},
+	}}).(*PositionsService)
+
+	positions := service.PositionsByReadingOrder()
+	if assert.Len(t, positions, 1) && assert.Len(t, positions[0], 3) {
+		second := positions[0][1]
+		assert.Equal(t, "/book.pdf", second.Href)
+		assert.Equal(t, "application/pdf", second.Type)
+		assert.Equal(t, "Chapter 1", second.Title)
+		assert.Equal(t, []string{"page=2"}, second.Locations.Fragments)
+		assert.Equal(t, uint(2), *second.Locations.Position)
+		assert.InDelta(t, 1.0/3, *second.Locations.Progression, 1e-9)
+		assert.InDelta(t, 1.0/3, *second.Locations.TotalProgression, 1e-9)
+	}
+}
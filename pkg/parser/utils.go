@@ -1,7 +1,12 @@
 package parser
 
 import (
+	"io"
+	"path"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/readium/go-toolkit/pkg/fetcher"
 	"github.com/readium/go-toolkit/pkg/manifest"
@@ -23,7 +28,7 @@ func hrefCommonFirstComponent(links manifest.LinkList) string {
 	return latest
 }
 
-func guessPublicationTitleFromFileStructure(fetcher fetcher.Fetcher) string { // TODO test for this
+func guessPublicationTitleFromFileStructure(fetcher fetcher.Fetcher) string {
 	links, err := fetcher.Links()
 	if err != nil || len(links) == 0 {
 		return ""
@@ -32,7 +37,7 @@ func guessPublicationTitleFromFileStructure(fetcher fetcher.Fetcher) string { //
 	if commonFirstComponent == "" {
 		return ""
 	}
-	if commonFirstComponent == strings.TrimPrefix("/", links[0].Href) {
+	if commonFirstComponent == strings.TrimPrefix(links[0].Href, "/") {
 		return ""
 	}
 
@@ -46,3 +51,192 @@ func isMediatypeReadiumWebPubProfile(mt mediatype.MediaType) bool {
 		&mediatype.Divina, &mediatype.DivinaManifest, &mediatype.LCPProtectedPDF,
 	)
 }
+
+// Compares two HREFs in natural order, segment by segment, so that "Chapter 2/page.jpg" comes before
+// "Chapter 10/page.jpg".
+func naturalHrefLess(a, b string) bool {
+	sa := strings.Split(strings.TrimPrefix(a, "/"), "/")
+	sb := strings.Split(strings.TrimPrefix(b, "/"), "/")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if sa[i] != sb[i] {
+			return naturalLess(sa[i], sb[i])
+		}
+	}
+	return len(sa) < len(sb)
+}
+
+// Compares two strings in natural order: runs of digits are compared by their numeric value, and letters without
+// regard to case. Strings which are equal in natural order are compared byte-wise, to get a stable order.
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isASCIIDigit(a[i]) && isASCIIDigit(b[j]) {
+			// Compare the numbers without their leading zeros, by length and then digit by digit.
+			si, sj := i, j
+			for i < len(a) && isASCIIDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isASCIIDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+
+		ra, wa := utf8.DecodeRuneInString(a[i:])
+		rb, wb := utf8.DecodeRuneInString(b[j:])
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return la < lb
+		}
+		i += wa
+		j += wb
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Derives a hierarchical table of contents from the subdirectories of the [readingOrder] resources. Each folder
+// entry points to the first resource it contains, and is titled after the folder name.
+// The optional [entries] function returns the entries of the resource at the given index, which are nested in the
+// entry of its folder.
+func folderTableOfContents(readingOrder manifest.LinkList, entries func(i int) manifest.LinkList) manifest.LinkList {
+	type node struct {
+		link     manifest.Link
+		children []*node
+	}
+
+	dirs := make([][]string, len(readingOrder))
+	for i, link := range readingOrder {
+		if dir := path.Dir(strings.TrimPrefix(link.Href, "/")); dir != "." {
+			dirs[i] = strings.Split(dir, "/")
+		}
+	}
+
+	// Folders shared by all the resources, such as the root folder of an archive, are not part of the table of
+	// contents.
+	common := -1
+	for i := range dirs {
+		if common < 0 || common > len(dirs[i]) {
+			common = len(dirs[i])
+		}
+		for k := 0; k < common; k++ {
+			if dirs[i][k] != dirs[0][k] {
+				common = k
+				break
+			}
+		}
+	}
+
+	root := &node{}
+	stack := []*node{root}
+	var current []string
+	for i, link := range readingOrder {
+		dir := dirs[i][common:]
+		k := 0
+		for k < len(dir) && k < len(current) && dir[k] == current[k] {
+			k++
+		}
+		stack, current = stack[:k+1], current[:k]
+		for _, name := range dir[k:] {
+			folder := &node{link: manifest.Link{Href: link.Href, Title: cleanFolderName(name)}}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, folder)
+			stack = append(stack, folder)
+			current = append(current, name)
+		}
+		if entries != nil {
+			parent := stack[len(stack)-1]
+			for _, entry := range entries(i) {
+				parent.children = append(parent.children, &node{link: entry})
+			}
+		}
+	}
+
+	var toLinks func(nodes []*node) manifest.LinkList
+	toLinks = func(nodes []*node) manifest.LinkList {
+		if len(nodes) == 0 {
+			return nil
+		}
+		links := make(manifest.LinkList, len(nodes))
+		for i, n := range nodes {
+			links[i] = n.link
+			if children := toLinks(n.children); children != nil {
+				links[i].Children = append(links[i].Children, children...)
+			}
+		}
+		return links
+	}
+	return toLinks(root.children)
+}
+
+var folderOrderingPrefixMatcher = regexp.MustCompile(`^\d+\s*(?:[-–)_]|\.\s)\s*`)
+
+// Cleans up a folder name to be used as a title, for example "01 - The_Beginning" becomes "The Beginning".
+func cleanFolderName(name string) string {
+	title := name
+	if stripped := folderOrderingPrefixMatcher.ReplaceAllString(title, ""); stripped != "" {
+		title = stripped
+	}
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	if title == "" {
+		return name
+	}
+	return title
+}
+
+// Adapts a [fetcher.Resource] to an [io.ReaderAt], to read only the parts of a file holding its headers.
+type resourceReaderAt struct {
+	resource fetcher.Resource
+}
+
+func (r resourceReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	var data []byte
+	var rerr *fetcher.ResourceError
+	if off == 0 && len(p) == 1 {
+		// A (0, 0) range would read the whole resource.
+		data, rerr = r.resource.Read(0, 1)
+		if len(data) > 1 {
+			data = data[:1]
+		}
+	} else {
+		data, rerr = r.resource.Read(off, off+int64(len(p))-1)
+	}
+	if rerr != nil {
+		return 0, rerr
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Serves the reads from a [prefix] of the content loaded at once, and the other ones from [r].
+// Useful when each read is expensive, such as with archives which can only be decompressed sequentially.
+type prefixReaderAt struct {
+	prefix []byte
+	r      io.ReaderAt
+}
+
+func (p prefixReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if off >= 0 && off+int64(len(b)) <= int64(len(p.prefix)) {
+		return copy(b, p.prefix[off:]), nil
+	}
+	return p.r.ReadAt(b, off)
+}
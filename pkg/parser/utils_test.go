@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/readium/go-toolkit/pkg/fetcher"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +33,74 @@ func TestHCFCCorrectWhenSameDir(t *testing.T) {
 		{Href: "/root/xml/toc.xml"},
 	}), "hrefCommonFirstComponent is empty when files are in different directories")
 }
+
+func TestGuessPublicationTitleFromFileStructure(t *testing.T) {
+	guess := func(hrefs ...string) string {
+		links := make(manifest.LinkList, len(hrefs))
+		for i, href := range hrefs {
+			links[i] = manifest.Link{Href: href}
+		}
+		f, err := fetcher.NewHTTPFetcher(nil, "", links)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		return guessPublicationTitleFromFileStructure(f)
+	}
+
+	assert.Equal(t, "Title", guess("/Title/im1.jpg", "/Title/im2.jpg"))
+	assert.Equal(t, "", guess("/im1.jpg", "/im2.jpg"))
+	assert.Equal(t, "", guess("/book.html"), "a single file is not a directory giving the title")
+	assert.Equal(t, "", guess())
+}
+
+func TestNaturalLess(t *testing.T) {
+	assert.True(t, naturalLess("Chapter 2", "Chapter 10"))
+	assert.False(t, naturalLess("Chapter 10", "Chapter 2"))
+	assert.True(t, naturalLess("page9.png", "page10.png"))
+	assert.True(t, naturalLess("a", "B"))
+	assert.True(t, naturalLess("x-2", "x-002b"))
+	assert.True(t, naturalLess("x-002", "x-2"), "equal numbers are ordered byte-wise")
+	assert.True(t, naturalLess("file", "file1"))
+	assert.False(t, naturalLess("same", "same"))
+}
+
+func TestNaturalHrefLess(t *testing.T) {
+	assert.True(t, naturalHrefLess("/Chapter 2/p.jpg", "/Chapter 10/p.jpg"))
+	assert.True(t, naturalHrefLess("/a/p.jpg", "/a b/p.jpg"))
+}
+
+func TestFolderTableOfContents(t *testing.T) {
+	readingOrder := manifest.LinkList{
+		{Href: "/Book/intro.mp3"},
+		{Href: "/Book/Disc_1/01.mp3"},
+		{Href: "/Book/Disc_1/Part A/02.mp3"},
+		{Href: "/Book/Disc 2/03.mp3"},
+	}
+	entries := func(i int) manifest.LinkList {
+		return manifest.LinkList{{Href: readingOrder[i].Href + "#t=0", Title: "Track"}}
+	}
+	assert.Equal(t, manifest.LinkList{
+		{Href: "/Book/intro.mp3#t=0", Title: "Track"},
+		{Href: "/Book/Disc_1/01.mp3", Title: "Disc 1", Children: manifest.LinkList{
+			{Href: "/Book/Disc_1/01.mp3#t=0", Title: "Track"},
+			{Href: "/Book/Disc_1/Part A/02.mp3", Title: "Part A", Children: manifest.LinkList{
+				{Href: "/Book/Disc_1/Part A/02.mp3#t=0", Title: "Track"},
+			}},
+		}},
+		{Href: "/Book/Disc 2/03.mp3", Title: "Disc 2", Children: manifest.LinkList{
+			{Href: "/Book/Disc 2/03.mp3#t=0", Title: "Track"},
+		}},
+	}, folderTableOfContents(readingOrder, entries))
+
+	assert.Nil(t, folderTableOfContents(manifest.LinkList{{Href: "/a/1.jpg"}, {Href: "/a/2.jpg"}}, nil))
+}
+
+func TestCleanFolderName(t *testing.T) {
+	assert.Equal(t, "The Beginning", cleanFolderName("01 - The_Beginning"))
+	assert.Equal(t, "Intro", cleanFolderName("2. Intro"))
+	assert.Equal(t, "Second Part", cleanFolderName("02_Second_Part"))
+	assert.Equal(t, "Chapter 1", cleanFolderName("Chapter  1"))
+	assert.Equal(t, "1.5 Interlude", cleanFolderName("1.5 Interlude"))
+	assert.Equal(t, "42", cleanFolderName("42"))
+	assert.Equal(t, "_", cleanFolderName("_"))
+}
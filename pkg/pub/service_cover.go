@@ -0,0 +1,37 @@
+package pub
+
+import (
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// EmbeddedCoverService implements Service
+// Serves a cover which is not a file of the publication, but is embedded in one of its resources,
+// such as the artwork of an audio file. The parser declares the cover [link] in the manifest.
+type EmbeddedCoverService struct {
+	link   manifest.Link
+	loader func() []byte
+}
+
+func (s EmbeddedCoverService) Close() {}
+
+func (s EmbeddedCoverService) Links() manifest.LinkList {
+	return nil
+}
+
+func (s EmbeddedCoverService) Get(link manifest.Link) (fetcher.Resource, bool) {
+	if link.Href != s.link.Href {
+		return nil, false
+	}
+	return fetcher.NewBytesResource(s.link, s.loader), true
+}
+
+// Creates an [EmbeddedCoverService] serving the cover at [link], extracted lazily with [loader].
+func EmbeddedCoverServiceFactory(link manifest.Link, loader func() []byte) ServiceFactory {
+	return func(context Context) Service {
+		return EmbeddedCoverService{
+			link:   link,
+			loader: loader,
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pub
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// AudioLocatorService implements Service
+// Converts a time offset from the beginning of an audiobook to a [manifest.Locator], and back.
+// This can be used to synchronize the listening progress with players which only know about a global time offset.
+type AudioLocatorService struct {
+	timeline audioTimeline
+}
+
+func (s AudioLocatorService) Close() {}
+
+func (s AudioLocatorService) Links() manifest.LinkList {
+	return nil
+}
+
+func (s AudioLocatorService) Get(link manifest.Link) (fetcher.Resource, bool) {
+	return nil, false
+}
+
+// Duration of the publication in seconds, or 0 if the duration of a resource is unknown.
+func (s AudioLocatorService) Duration() float64 {
+	return s.timeline.total
+}
+
+// Returns the locator at [offset] seconds from the beginning of the publication, or nil if the durations of the
+// resources are unknown.
+func (s AudioLocatorService) LocateOffset(offset float64) *manifest.Locator {
+	t := s.timeline
+	if t.total <= 0 {
+		return nil
+	}
+	if offset < 0 {
+		offset = 0
+	} else if offset > t.total {
+		offset = t.total
+	}
+	index := len(t.readingOrder) - 1
+	for i := range t.readingOrder {
+		if offset < t.starts[i]+t.readingOrder[i].Duration {
+			index = i
+			break
+		}
+	}
+	locator := t.locator(index, offset-t.starts[index])
+	return &locator
+}
+
+// Returns the offset in seconds from the beginning of the publication of the given [locator].
+// The time in the resource is taken from a `t=` media fragment, or else from the progressions of the locator.
+func (s AudioLocatorService) Offset(locator manifest.Locator) (float64, bool) {
+	t := s.timeline
+	if t.total <= 0 {
+		return 0, false
+	}
+	href, fragment := locator.Href, ""
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		href, fragment = href[:i], href[i+1:]
+	}
+	index := t.readingOrder.IndexOfFirstWithHref(href)
+	if index < 0 {
+		if locator.Locations.TotalProgression != nil {
+			return *locator.Locations.TotalProgression * t.total, true
+		}
+		return 0, false
+	}
+	duration := t.readingOrder[index].Duration
+
+	fragments := locator.Locations.Fragments
+	if fragment != "" {
+		fragments = append([]string{fragment}, fragments...)
+	}
+	for _, fragment := range fragments {
+		for _, param := range strings.Split(fragment, "&") {
+			if time, ok := parseMediaFragmentTime(param); ok {
+				if time > duration {
+					time = duration
+				}
+				return t.starts[index] + time, true
+			}
+		}
+	}
+	if progression := locator.Locations.Progression; progression != nil {
+		return t.starts[index] + *progression*duration, true
+	}
+	return t.starts[index], true
+}
+
+// Parses the start time of a temporal media fragment, e.g. `t=10`, `t=10,20`, `t=npt:1:02:03.5`.
+// Reference: https://www.w3.org/TR/media-frags/#naming-time
+func parseMediaFragmentTime(fragment string) (float64, bool) {
+	if !strings.HasPrefix(fragment, "t=") {
+		return 0, false
+	}
+	value := strings.TrimPrefix(strings.TrimPrefix(fragment, "t="), "npt:")
+	value = strings.SplitN(value, ",", 2)[0]
+	if value == "" {
+		// Only an end time, so the fragment starts at the beginning of the resource.
+		return 0, true
+	}
+
+	// Either a number of seconds, or [[hh:]mm:]ss[.fraction]
+	time := 0.0
+	for _, component := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(component, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		time = time*60 + n
+	}
+	return time, true
+}
+
+// Creates an [AudioLocatorService] for the reading order of the publication.
+func AudioLocatorServiceFactory() ServiceFactory {
+	return func(context Context) Service {
+		return AudioLocatorService{
+			timeline: newAudioTimeline(context.Manifest.ReadingOrder),
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pub
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAudioLocatorService() AudioLocatorService {
+	return AudioLocatorService{
+		timeline: newAudioTimeline(manifest.LinkList{
+			{Href: "track1", Type: "audio/mpeg", Duration: 100},
+			{Href: "track2", Type: "audio/mpeg", Duration: 300},
+		}),
+	}
+}
+
+func TestAudioLocatorServiceLocateOffset(t *testing.T) {
+	service := newTestAudioLocatorService()
+	assert.Equal(t, 400.0, service.Duration())
+
+	assert.Equal(t, &manifest.Locator{
+		Href: "track2",
+		Type: "audio/mpeg",
+		Locations: manifest.Locations{
+			Fragments:        []string{"t=50"},
+			Progression:      extensions.Pointer(50.0 / 300.0),
+			TotalProgression: extensions.Pointer(150.0 / 400.0),
+		},
+	}, service.LocateOffset(150))
+
+	assert.Equal(t, "track1", service.LocateOffset(-10).Href)
+	assert.Equal(t, []string{"t=0"}, service.LocateOffset(-10).Locations.Fragments)
+	assert.Equal(t, "track2", service.LocateOffset(100).Href)
+	assert.Equal(t, []string{"t=300"}, service.LocateOffset(1000).Locations.Fragments)
+}
+
+func TestAudioLocatorServiceOffset(t *testing.T) {
+	service := newTestAudioLocatorService()
+
+	offset := func(locator manifest.Locator) float64 {
+		o, ok := service.Offset(locator)
+		assert.True(t, ok)
+		return o
+	}
+	assert.Equal(t, 100.0, offset(manifest.Locator{Href: "track2"}))
+	assert.Equal(t, 150.0, offset(manifest.Locator{Href: "track2", Locations: manifest.Locations{Fragments: []string{"t=50"}}}))
+	assert.Equal(t, 162.5, offset(manifest.Locator{Href: "track2#t=npt:1:02.5,70"}))
+	assert.Equal(t, 175.0, offset(manifest.Locator{Href: "track2", Locations: manifest.Locations{Progression: extensions.Pointer(0.25)}}))
+	assert.Equal(t, 200.0, offset(manifest.Locator{Href: "unknown", Locations: manifest.Locations{TotalProgression: extensions.Pointer(0.5)}}))
+
+	_, ok := service.Offset(manifest.Locator{Href: "unknown"})
+	assert.False(t, ok)
+
+	// Round trip
+	for _, o := range []float64{0, 42.5, 100, 399} {
+		assert.Equal(t, o, offset(*service.LocateOffset(o)))
+	}
+}
+
+func TestAudioLocatorServiceUnknownDurations(t *testing.T) {
+	service := AudioLocatorService{
+		timeline: newAudioTimeline(manifest.LinkList{{Href: "track1"}}),
+	}
+	assert.Nil(t, service.LocateOffset(10))
+	_, ok := service.Offset(manifest.Locator{Href: "track1"})
+	assert.False(t, ok)
+}
+
+func TestParseMediaFragmentTime(t *testing.T) {
+	for fragment, expected := range map[string]float64{
+		"t=10":          10,
+		"t=10.5,20":     10.5,
+		"t=,20":         0,
+		"t=npt:10":      10,
+		"t=00:01:05":    65,
+		"t=npt:1:02:03": 3723,
+	} {
+		time, ok := parseMediaFragmentTime(fragment)
+		assert.True(t, ok, fragment)
+		assert.Equal(t, expected, time, fragment)
+	}
+	for _, fragment := range []string{"", "x=10", "t=abc", "t=-5"} {
+		_, ok := parseMediaFragmentTime(fragment)
+		assert.False(t, ok, fragment)
+	}
+}
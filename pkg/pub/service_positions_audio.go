@@ -0,0 +1,138 @@
+package pub
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+)
+
+// Default time interval between two positions of an audiobook, in seconds.
+const DefaultAudioPositionsInterval = 60.0
+
+// Maximum number of positions generated for a single resource, guarding against implausible durations.
+const maxAudioPositionsPerResource = 10000
+
+// Whether [duration] is a usable duration in seconds, which excludes unknown (0), negative, infinite and NaN values.
+func isValidAudioDuration(duration float64) bool {
+	return duration > 0 && !math.IsInf(duration, 0)
+}
+
+// AudioPositionsService implements PositionsService
+// Generates the positions of an audiobook at fixed time intervals in each [readingOrder] resource, using `t=` media
+// fragments. The progressions are computed from the durations of the resources.
+type AudioPositionsService struct {
+	readingOrder      manifest.LinkList
+	interval          float64 // In seconds.
+	fallbackMediaType string
+}
+
+func (s AudioPositionsService) Close() {}
+
+func (s AudioPositionsService) Links() manifest.LinkList {
+	return manifest.LinkList{PositionsLink}
+}
+
+func (s AudioPositionsService) Get(link manifest.Link) (fetcher.Resource, bool) {
+	return GetForPositionsService(s, link)
+}
+
+func (s AudioPositionsService) Positions() []manifest.Locator {
+	var positions []manifest.Locator
+	for _, v := range s.PositionsByReadingOrder() {
+		positions = append(positions, v...)
+	}
+	return positions
+}
+
+func (s AudioPositionsService) PositionsByReadingOrder() [][]manifest.Locator {
+	timeline := newAudioTimeline(s.readingOrder)
+	interval := s.interval
+	if interval <= 0 {
+		interval = DefaultAudioPositionsInterval
+	}
+
+	positions := make([][]manifest.Locator, len(s.readingOrder))
+	position := uint(1)
+	for i, link := range s.readingOrder {
+		typ := link.Type
+		if typ == "" {
+			typ = s.fallbackMediaType
+		}
+
+		// Resources without a known duration have a single position.
+		count := 1
+		if isValidAudioDuration(link.Duration) {
+			count = int(math.Min(math.Ceil(link.Duration/interval), maxAudioPositionsPerResource))
+		}
+		positions[i] = make([]manifest.Locator, 0, count)
+		for j := 0; j < count; j++ {
+			time := float64(j) * interval
+			locator := timeline.locator(i, time)
+			locator.Type = typ
+			locator.Locations.Position = extensions.Pointer(position)
+			positions[i] = append(positions[i], locator)
+			position++
+		}
+	}
+	return positions
+}
+
+// Creates an [AudioPositionsService] with a position every [interval] seconds.
+func AudioPositionsServiceFactory(interval float64, fallbackMediaType string) ServiceFactory {
+	return func(context Context) Service {
+		return AudioPositionsService{
+			readingOrder:      context.Manifest.ReadingOrder,
+			interval:          interval,
+			fallbackMediaType: fallbackMediaType,
+		}
+	}
+}
+
+// The reading order of an audiobook laid out on a single time axis.
+type audioTimeline struct {
+	readingOrder manifest.LinkList
+	starts       []float64 // Start offset of each resource in the publication, in seconds.
+	total        float64   // Total duration of the publication, 0 if any resource has an unknown duration.
+}
+
+func newAudioTimeline(readingOrder manifest.LinkList) audioTimeline {
+	t := audioTimeline{
+		readingOrder: readingOrder,
+		starts:       make([]float64, len(readingOrder)),
+	}
+	for i, link := range readingOrder {
+		t.starts[i] = t.total
+		if !isValidAudioDuration(link.Duration) {
+			t.total = 0
+			break
+		}
+		t.total += link.Duration
+	}
+	return t
+}
+
+// Builds the locator at [time] seconds in the resource at [index].
+func (t audioTimeline) locator(index int, time float64) manifest.Locator {
+	link := t.readingOrder[index]
+	locator := manifest.Locator{
+		Href:  link.Href,
+		Type:  link.Type,
+		Title: link.Title,
+		Locations: manifest.Locations{
+			Fragments: []string{"t=" + strconv.FormatFloat(time, 'f', -1, 64)},
+		},
+	}
+	if isValidAudioDuration(link.Duration) {
+		locator.Locations.Progression = extensions.Pointer(time / link.Duration)
+	}
+	if t.total > 0 {
+		locator.Locations.TotalProgression = extensions.Pointer((t.starts[index] + time) / t.total)
+	} else if len(t.readingOrder) > 0 {
+		// Without durations, the progression is based on the number of resources.
+		locator.Locations.TotalProgression = extensions.Pointer(float64(index) / float64(len(t.readingOrder)))
+	}
+	return locator
+}
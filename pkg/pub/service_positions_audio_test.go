@@ -0,0 +1,126 @@
+package pub
+
+import (
+	"math"
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/internal/extensions"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudioPositionsServiceEmptyReadingOrder(t *testing.T) {
+	service := AudioPositionsService{}
+	assert.Equal(t, 0, len(service.Positions()))
+}
+
+func TestAudioPositionsServiceIntervals(t *testing.T) {
+	service := AudioPositionsService{
+		readingOrder: manifest.LinkList{
+			{Href: "track1", Type: "audio/mpeg", Duration: 50},
+			{Href: "track2", Title: "Track 2", Duration: 30},
+		},
+		interval:          20,
+		fallbackMediaType: "audio/*",
+	}
+
+	assert.Equal(t, [][]manifest.Locator{
+		{
+			{
+				Href: "track1",
+				Type: "audio/mpeg",
+				Locations: manifest.Locations{
+					Fragments:        []string{"t=0"},
+					Progression:      extensions.Pointer(0.0),
+					Position:         extensions.Pointer(uint(1)),
+					TotalProgression: extensions.Pointer(0.0),
+				},
+			},
+			{
+				Href: "track1",
+				Type: "audio/mpeg",
+				Locations: manifest.Locations{
+					Fragments:        []string{"t=20"},
+					Progression:      extensions.Pointer(20.0 / 50.0),
+					Position:         extensions.Pointer(uint(2)),
+					TotalProgression: extensions.Pointer(20.0 / 80.0),
+				},
+			},
+			{
+				Href: "track1",
+				Type: "audio/mpeg",
+				Locations: manifest.Locations{
+					Fragments:        []string{"t=40"},
+					Progression:      extensions.Pointer(40.0 / 50.0),
+					Position:         extensions.Pointer(uint(3)),
+					TotalProgression: extensions.Pointer(40.0 / 80.0),
+				},
+			},
+		},
+		{
+			{
+				Href:  "track2",
+				Type:  "audio/*",
+				Title: "Track 2",
+				Locations: manifest.Locations{
+					Fragments:        []string{"t=0"},
+					Progression:      extensions.Pointer(0.0),
+					Position:         extensions.Pointer(uint(4)),
+					TotalProgression: extensions.Pointer(50.0 / 80.0),
+				},
+			},
+			{
+				Href:  "track2",
+				Type:  "audio/*",
+				Title: "Track 2",
+				Locations: manifest.Locations{
+					Fragments:        []string{"t=20"},
+					Progression:      extensions.Pointer(20.0 / 30.0),
+					Position:         extensions.Pointer(uint(5)),
+					TotalProgression: extensions.Pointer(70.0 / 80.0),
+				},
+			},
+		},
+	}, service.PositionsByReadingOrder())
+	assert.Len(t, service.Positions(), 5)
+}
+
+func TestAudioPositionsServiceUnknownDurations(t *testing.T) {
+	service := AudioPositionsService{
+		readingOrder: manifest.LinkList{
+			{Href: "track1", Duration: 50},
+			{Href: "track2"},
+		},
+		interval: 20,
+	}
+
+	positions := service.Positions()
+	if assert.Len(t, positions, 4) {
+		// The total progression falls back on the index of the resource.
+		assert.Equal(t, 0.0, *positions[2].Locations.TotalProgression)
+		assert.Equal(t, "track2", positions[3].Href)
+		assert.Nil(t, positions[3].Locations.Progression)
+		assert.Equal(t, 0.5, *positions[3].Locations.TotalProgression)
+	}
+}
+
+func TestAudioPositionsServiceImplausibleDurations(t *testing.T) {
+	service := AudioPositionsService{
+		readingOrder: manifest.LinkList{
+			{Href: "huge", Duration: 1.1e12},
+			{Href: "infinite", Duration: math.Inf(1)},
+			{Href: "nan", Duration: math.NaN()},
+		},
+		interval: 60,
+	}
+
+	positions := service.PositionsByReadingOrder()
+	if assert.Len(t, positions, 3) {
+		assert.Len(t, positions[0], maxAudioPositionsPerResource)
+		// Durations which are not finite are unknown.
+		assert.Len(t, positions[1], 1)
+		assert.Nil(t, positions[1][0].Locations.Progression)
+		assert.Len(t, positions[2], 1)
+		assert.Nil(t, positions[2][0].Locations.Progression)
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/readium/go-toolkit/pkg/archive"
 	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
 	"github.com/readium/go-toolkit/pkg/manifest"
 	"github.com/readium/go-toolkit/pkg/parser"
+	"github.com/readium/go-toolkit/pkg/parser/daisy"
 	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/readium/go-toolkit/pkg/parser/fb2"
+	"github.com/readium/go-toolkit/pkg/parser/mobi"
 	"github.com/readium/go-toolkit/pkg/parser/pdf"
 	"github.com/readium/go-toolkit/pkg/pub"
 )
@@ -37,6 +41,8 @@ type Config struct {
 	InferPageCount       bool                       // When true, will infer `Metadata.NumberOfPages` from the generated position list.
 	ArchiveFactory       archive.ArchiveFactory     // Opens an archive (e.g. ZIP, RAR), optionally protected by credentials.
 	HttpClient           *http.Client               // Service performing HTTP requests.
+	RenditionSelector    epub.RenditionSelector     // Chooses the rendition to open in EPUBs with multiple renditions, the first one by default.
+	XMLRecovery          *fetcher.XMLRecovery       // Recovers from malformed XML documents in EPUBs, fetcher.DefaultXMLRecovery when nil.
 }
 
 type InferA11yMetadata uint8
@@ -59,11 +65,19 @@ func New(config Config) Streamer { // TODO contentProtections
 	if config.ArchiveFactory == nil {
 		config.ArchiveFactory = archive.NewArchiveFactory()
 	}
+	if config.XMLRecovery == nil {
+		config.XMLRecovery = &fetcher.DefaultXMLRecovery
+	}
 
 	defaultParsers := []parser.PublicationParser{
-		epub.NewParser(nil), // TODO pass strategy
+		epub.NewParser(nil).WithRenditionSelector(config.RenditionSelector).WithXMLRecovery(*config.XMLRecovery), // TODO pass strategy
 		pdf.NewParser(),
+		fb2.NewParser(),
+		daisy.NewParser(),
+		mobi.NewParser(),
 		parser.NewWebPubParser(config.HttpClient),
+		parser.NewLPFParser(config.HttpClient),
+		parser.DocumentParser{},
 		parser.ImageParser{},
 		parser.AudioParser{},
 	}
@@ -0,0 +1,40 @@
+package streamer
+
+import (
+	"testing"
+
+	"github.com/readium/go-toolkit/pkg/asset"
+	"github.com/readium/go-toolkit/pkg/fetcher"
+	"github.com/readium/go-toolkit/pkg/manifest"
+	"github.com/readium/go-toolkit/pkg/mediatype"
+	"github.com/readium/go-toolkit/pkg/parser/epub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenWithRenditionSelector(t *testing.T) {
+	a := asset.FileWithMediaType("../parser/epub/testdata/renditions", &mediatype.EPUB)
+
+	p, err := New(Config{}).Open(a, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/reflowable/chapter.xhtml", p.Manifest.ReadingOrder[0].Href)
+	}
+
+	p, err = New(Config{
+		RenditionSelector: epub.RenditionPreferences{Layout: manifest.EPUBLayoutFixed},
+	}).Open(a, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/fixed/chapter.xhtml", p.Manifest.ReadingOrder[0].Href)
+	}
+}
+
+func TestOpenWithXMLRecovery(t *testing.T) {
+	a := asset.FileWithMediaType("../parser/epub/testdata/malformed", &mediatype.EPUB)
+
+	p, err := New(Config{}).Open(a, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Pride & Prejudice", p.Manifest.Metadata.Title())
+	}
+
+	_, err = New(Config{XMLRecovery: &fetcher.StrictXML}).Open(a, "")
+	assert.Error(t, err)
+}